@@ -2,9 +2,14 @@ module github.com/iVampireSP/autodi
 
 go 1.25.0
 
-require golang.org/x/tools v0.42.0
+require (
+	github.com/spf13/cobra v1.8.1
+	github.com/spf13/pflag v1.0.5
+	golang.org/x/mod v0.33.0
+	golang.org/x/tools v0.42.0
+)
 
 require (
-	golang.org/x/mod v0.33.0 // indirect
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	golang.org/x/sync v0.19.0 // indirect
 )
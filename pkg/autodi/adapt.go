@@ -0,0 +1,136 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// AnnotAdapt lets a group member that doesn't implement the group's interface
+// join the group through one of its methods:
+//
+//	//autodi:adapt Register
+//	func NewUserController(db *ent.Client) *UserController
+//
+// The group interface must have exactly one method, with the same signature
+// as Register. The generated file declares a func type implementing the
+// interface (like http.HandlerFunc) and appends the converted method value.
+const AnnotAdapt = "adapt"
+
+// adaptMethod returns the method named by a provider's //autodi:adapt, or "".
+func adaptMethod(p *Provider) string {
+	if values := GetAnnotationValues(p.Annotations, AnnotAdapt); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// checkAdapters validates //autodi:adapt on the members of groups whose
+// interface they don't implement.
+func (g *Graph) checkAdapters() []error {
+	var errs []error
+	for _, groupName := range sortedGroupNames(g.cfg.Groups) {
+		ifaceStr := g.resolveConfigType(g.cfg.Groups[groupName].Interface)
+		for _, p := range g.Groups[groupName] {
+			method := adaptMethod(p)
+			if method == "" || len(p.Returns) == 0 || p.Returns[0].Type == nil {
+				continue
+			}
+			iface := g.findIfaceType(ifaceStr)
+			if iface == nil {
+				errs = append(errs, fmt.Errorf("%s: //autodi:adapt %s: group %s's element %s is not a known interface",
+					p.Position, method, groupName, toShortTypeName(ifaceStr)))
+				continue
+			}
+			ret := p.Returns[0]
+			if implementsIface(ret.Type, iface) {
+				continue
+			}
+			if iface.NumMethods() != 1 {
+				errs = append(errs, fmt.Errorf("%s: //autodi:adapt %s: %s has %d methods\n  hint: an adapter implements a single-method interface; implement %[3]s on %[5]s instead",
+					p.Position, method, toShortTypeName(ifaceStr), iface.NumMethods(), toShortTypeName(ret.TypeStr)))
+				continue
+			}
+			want := iface.Method(0)
+			obj, _, _ := types.LookupFieldOrMethod(ret.Type, true, nil, method)
+			have, ok := obj.(*types.Func)
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: //autodi:adapt %s: %s has no method %[2]s",
+					p.Position, method, toShortTypeName(ret.TypeStr)))
+				continue
+			}
+			if !types.Identical(have.Type(), want.Type()) {
+				qualifier := func(pkg *types.Package) string { return pkg.Name() }
+				errs = append(errs, fmt.Errorf("%s: //autodi:adapt %s: signature %s doesn't match %s.%s%s",
+					p.Position, method, strings.TrimPrefix(types.TypeString(have.Type(), qualifier), "func"),
+					toShortTypeName(ifaceStr), want.Name(), strings.TrimPrefix(types.TypeString(want.Type(), qualifier), "func")))
+			}
+		}
+	}
+	return errs
+}
+
+// adapterName names the func type adapting methods to a group interface.
+func adapterName(ifaceStr string) string {
+	return localVarName(FieldName(ifaceStr)) + "Func"
+}
+
+// adaptedValue converts the method named by //autodi:adapt of a member's
+// value v to the interface's adapter, recording the adapter for the current
+// file.
+func (cg *CodeGen) adaptedValue(p *Provider, ifaceStr, v string) string {
+	if cg.adapters != nil {
+		cg.adapters[ifaceStr] = true
+	}
+	return fmt.Sprintf("%s(%s.%s)", adapterName(ifaceStr), v, adaptMethod(p))
+}
+
+// writeAdapters emits the adapter func types used in the current file.
+func (cg *CodeGen) writeAdapters(buf *bytes.Buffer) {
+	var ifaces []string
+	for ifaceStr := range cg.adapters {
+		ifaces = append(ifaces, ifaceStr)
+	}
+	sort.Strings(ifaces)
+
+	for i, ifaceStr := range ifaces {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		method := cg.graph.findIfaceType(ifaceStr).Method(0)
+		sig := method.Type().(*types.Signature)
+		name := adapterName(ifaceStr)
+
+		var params, args []string
+		for j := 0; j < sig.Params().Len(); j++ {
+			arg := fmt.Sprintf("a%d", j)
+			typ := cg.typeSource(sig.Params().At(j).Type())
+			if sig.Variadic() && j == sig.Params().Len()-1 {
+				typ = "..." + strings.TrimPrefix(typ, "[]")
+				arg += "..."
+			}
+			params = append(params, fmt.Sprintf("a%d %s", j, typ))
+			args = append(args, arg)
+		}
+		var results []string
+		for j := 0; j < sig.Results().Len(); j++ {
+			results = append(results, cg.typeSource(sig.Results().At(j).Type()))
+		}
+		resultList := strings.Join(results, ", ")
+		if len(results) > 1 {
+			resultList = "(" + resultList + ")"
+		}
+		ret := ""
+		if len(results) > 0 {
+			ret = "return "
+		}
+
+		fmt.Fprintf(buf, "// %s adapts a method to %s (//autodi:%s).\n", name, cg.shortType(ifaceStr), AnnotAdapt)
+		fmt.Fprintf(buf, "type %s func(%s) %s\n\n", name, strings.Join(params, ", "), resultList)
+		fmt.Fprintf(buf, "func (f %s) %s(%s) %s {\n", name, method.Name(), strings.Join(params, ", "), resultList)
+		fmt.Fprintf(buf, "\t%sf(%s)\n", ret, strings.Join(args, ", "))
+		buf.WriteString("}\n")
+	}
+}
@@ -0,0 +1,130 @@
+package autodi
+
+import (
+	"go/ast"
+	"slices"
+	"strings"
+)
+
+// Annotation types
+const (
+	AnnotBind        = "bind"         // //autodi:bind InterfaceName
+	AnnotIgnore      = "ignore"       // //autodi:ignore
+	AnnotInvoke      = "invoke"       // //autodi:invoke
+	AnnotOptional    = "optional"     // //autodi:optional ParamType
+	AnnotPrimary     = "primary"      // //autodi:primary
+	AnnotReplayable  = "replayable"   // //autodi:replayable [ReplayFunc]
+	AnnotAs          = "as"           // //autodi:as [<index|result>=]pkg.Interface
+	AnnotEnv         = "env"          // //autodi:env VAR[,VAR...]
+	AnnotTestReplace = "test-replace" // //autodi:test-replace pkg.RealClient
+	AnnotOrder       = "order"        // //autodi:order N
+	AnnotGroup       = "group"        // //autodi:group name
+)
+
+// funcAnnotations are the directive kinds accepted on a function.
+var funcAnnotations = []string{
+	AnnotBind, AnnotIgnore, AnnotInvoke, AnnotOptional, AnnotPrimary, AnnotReplayable, AnnotAs, AnnotEnv,
+	AnnotTestReplace, AnnotCmd, AnnotWhen, AnnotOrder, AnnotNoStart, AnnotScope, AnnotFactory,
+	AnnotGroup, AnnotStdin, AnnotStdout, AnnotStderr, AnnotArgs, AnnotRoute, AnnotAdapt, AnnotDeprecated, AnnotField,
+	AnnotLazy, AnnotOverride, AnnotPhase, AnnotMigrate, AnnotNoMigrate, AnnotKey, AnnotBindParam,
+}
+
+// Annotation represents a parsed //autodi: directive.
+type Annotation struct {
+	Kind  string // bind, ignore, invoke, optional, primary, replayable, as, env, test-replace, cmd, when, order, nostart, scope, factory, group, stdin, stdout, stderr, args, route, adapt, deprecated, field, lazy, override, phase, migrate, no-migrate, key, bind-param, x-<key>
+	Value string // argument (e.g., interface name for bind)
+}
+
+// ParseAnnotations extracts //autodi: directives from a function's doc comments.
+func ParseAnnotations(fn *ast.FuncDecl) []Annotation {
+	if fn.Doc == nil {
+		return nil
+	}
+
+	var annotations []Annotation
+	for _, comment := range fn.Doc.List {
+		text := strings.TrimSpace(comment.Text)
+		// Remove leading //
+		text = strings.TrimPrefix(text, "//")
+		text = strings.TrimSpace(text)
+
+		if !strings.HasPrefix(text, "autodi:") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "autodi:")
+
+		parts := strings.SplitN(text, " ", 2)
+		kind := strings.TrimSpace(parts[0])
+		value := ""
+		if len(parts) > 1 {
+			value = strings.TrimSpace(parts[1])
+		}
+
+		if slices.Contains(funcAnnotations, kind) || isCustomAnnotation(kind) {
+			annotations = append(annotations, Annotation{Kind: kind, Value: value})
+		}
+	}
+	return annotations
+}
+
+// Package-level directive kinds, accepted in a package's doc comment (doc.go).
+const (
+	PkgDirectiveGroup   = "group"   // //autodi:group name [[]Interface]
+	PkgDirectiveIgnore  = "ignore"  // //autodi:ignore
+	PkgDirectiveLayer   = "layer"   // //autodi:layer name
+	PkgDirectiveProfile = "profile" // //autodi:profile name
+)
+
+// pkgDirectives are the directive kinds accepted in a package doc comment.
+var pkgDirectives = []string{PkgDirectiveGroup, PkgDirectiveIgnore, PkgDirectiveLayer, PkgDirectiveProfile}
+
+// ParsePackageDirectives extracts //autodi: directives from the package doc
+// comments (the comment group above the package clause) of a package's files.
+func ParsePackageDirectives(files []*ast.File) []Annotation {
+	var annotations []Annotation
+	for _, f := range files {
+		if f.Doc == nil {
+			continue
+		}
+		for _, comment := range f.Doc.List {
+			text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+			if !strings.HasPrefix(text, "autodi:") {
+				continue
+			}
+			text = strings.TrimPrefix(text, "autodi:")
+
+			parts := strings.SplitN(text, " ", 2)
+			kind := strings.TrimSpace(parts[0])
+			value := ""
+			if len(parts) > 1 {
+				value = strings.TrimSpace(parts[1])
+			}
+
+			if slices.Contains(pkgDirectives, kind) {
+				annotations = append(annotations, Annotation{Kind: kind, Value: value})
+			}
+		}
+	}
+	return annotations
+}
+
+// HasAnnotation checks if annotations contain a specific kind.
+func HasAnnotation(annotations []Annotation, kind string) bool {
+	for _, a := range annotations {
+		if a.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAnnotationValues returns all values for a specific annotation kind.
+func GetAnnotationValues(annotations []Annotation, kind string) []string {
+	var values []string
+	for _, a := range annotations {
+		if a.Kind == kind && a.Value != "" {
+			values = append(values, a.Value)
+		}
+	}
+	return values
+}
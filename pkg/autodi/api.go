@@ -0,0 +1,62 @@
+// Package autodi is the analysis and code generation behind the autodi
+// command, for tools that embed them — custom generators, linters, editor
+// plugins — instead of running the binary:
+//
+//	scan, err := autodi.Scan(dir)       // load the module, find constructors and commands
+//	proj, err := autodi.Build(scan)     // wire and validate the dependency graph
+//	files, err := autodi.Generate(proj) // render the generated files, unwritten
+//
+// Scan, Build and Generate read generate.go and the package directives like
+// autodi generate run in dir; the Project and its Graph are the inputs of
+// every generator. Main runs the command line itself.
+package autodi
+
+import (
+	"errors"
+	"path/filepath"
+)
+
+// Scan loads the module containing dir, scans its packages for provider
+// candidates and detects its commands. dir plays the working directory of a
+// go generate run: it selects the directory-level app.
+func Scan(dir string) (*ScanResult, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	moduleRoot, err := moduleRootOf(dir)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := buildConfigIn(moduleRoot, dir)
+	if err != nil {
+		return nil, err
+	}
+	return scanProject(moduleRoot, cfg, &Options{})
+}
+
+// Build keeps the providers the scan's commands reach, builds their
+// dependency graph and validates it. Every error found is joined into the
+// returned one; warnings, like deprecated constructors, are in
+// Project.Warnings. A scan is built once.
+func Build(scan *ScanResult) (*Project, error) {
+	var errs []error
+	var warnings []string
+	proj, err := buildProject(scan, &Options{},
+		func(reported []error) { errs = append(errs, reported...) },
+		func(w string) { warnings = append(warnings, w) })
+	if errors.Is(err, errReported) {
+		return nil, errors.Join(errs...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	proj.Warnings = warnings
+	return proj, nil
+}
+
+// Generate renders the files autodi generate writes for a project, named
+// relative to its module root, without writing them.
+func Generate(proj *Project) ([]GeneratedFile, error) {
+	return generateFiles(proj)
+}
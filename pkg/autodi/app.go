@@ -0,0 +1,163 @@
+package autodi
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// AppConfig is one //autodi:app definition. A module with several apps gets
+// one entrypoint per app, each registering only its commands:
+//
+//	//autodi:app server "API server" commands=api,admin
+//	//autodi:app worker "Background jobs" commands=worker out=deploy/worker/main.go
+type AppConfig struct {
+	Name     string
+	Short    string
+	Long     string
+	Commands []string // command names (cmd/ dir, "/" → "_"); empty = every command
+	Output   string   // module-relative entrypoint; default cmd/<name>/main_gen.go
+}
+
+// parseAppDirective parses the text after //autodi:app: a name, up to two
+// quoted descriptions, then key=value options.
+func parseAppDirective(directive string) (AppConfig, error) {
+	rest := strings.TrimSpace(strings.TrimPrefix(directive, "app"))
+	name, rest, _ := strings.Cut(rest, " ")
+	app := AppConfig{Name: name}
+	if name == "" {
+		return app, fmt.Errorf("generate.go: //autodi:app needs a name")
+	}
+
+	// Options follow the last quoted string
+	opts := rest
+	if i := strings.LastIndex(rest, `"`); i >= 0 {
+		opts = rest[i+1:]
+		quoted := parseQuotedStrings(rest[:i+1])
+		if len(quoted) >= 1 {
+			app.Short = quoted[0]
+		}
+		if len(quoted) >= 2 {
+			app.Long = quoted[1]
+		}
+	}
+	for _, opt := range strings.Fields(opts) {
+		key, val, _ := strings.Cut(opt, "=")
+		switch key {
+		case "commands":
+			app.Commands = strings.Split(val, ",")
+		case "out":
+			if !strings.HasSuffix(val, ".go") || path.IsAbs(val) || strings.HasPrefix(path.Clean(val), "..") {
+				return app, fmt.Errorf("generate.go: //autodi:app %s out=%s: want a .go file inside the module", name, val)
+			}
+			app.Output = path.Clean(val)
+		default:
+			return app, fmt.Errorf("generate.go: //autodi:app %s: unknown option %q\n  hint: options are commands=<name,...> and out=<file.go>", name, opt)
+		}
+	}
+	return app, nil
+}
+
+// resolveApps checks the app definitions and fills in default outputs. A
+// single app keeps writing to cfg.Output.
+func resolveApps(cfg *Config) error {
+	if len(cfg.Apps) == 0 {
+		return nil
+	}
+	first := cfg.Apps[0]
+	cfg.AppName, cfg.AppShort, cfg.AppLong = first.Name, first.Short, first.Long
+	if len(cfg.Apps) == 1 {
+		if cfg.Apps[0].Output == "" {
+			cfg.Apps[0].Output = cfg.Output
+		}
+		return nil
+	}
+
+	if cfg.Layout == LayoutMultiBinary {
+		return fmt.Errorf("generate.go: several //autodi:app definitions can't be combined with layout %s\n  hint: the multi-binary layout already builds one binary per command", LayoutMultiBinary)
+	}
+	if cfg.Output != "main.go" {
+		return fmt.Errorf("generate.go: //autodi:output applies to a single app\n  hint: set out=<file.go> on each //autodi:app instead")
+	}
+	seen := make(map[string]string)
+	for i := range cfg.Apps {
+		app := &cfg.Apps[i]
+		if app.Output == "" {
+			app.Output = "cmd/" + app.Name + "/main_gen.go"
+		}
+		if other, ok := seen[path.Dir(app.Output)]; ok {
+			return fmt.Errorf("generate.go: apps %s and %s both write to %s\n  hint: give one of them out=<dir>/main.go", other, app.Name, path.Dir(app.Output))
+		}
+		seen[path.Dir(app.Output)] = app.Name
+	}
+	return nil
+}
+
+// validateApps checks that app command filters name discovered commands and
+// that no entrypoint lands in a command package.
+func validateApps(cfg *Config, commands []*DiscoveredCommand) error {
+	var names []string
+	for _, cmd := range commands {
+		names = append(names, cmd.Name)
+	}
+	for _, app := range cfg.Apps {
+		for _, name := range app.Commands {
+			if !slices.Contains(names, name) {
+				return fmt.Errorf("generate.go: //autodi:app %s: unknown command %q\n  hint: commands are %s", app.Name, name, strings.Join(names, ", "))
+			}
+		}
+		if len(cfg.Apps) < 2 {
+			continue
+		}
+		for _, cmd := range commands {
+			if cmd.Dir == path.Dir(app.Output) {
+				return fmt.Errorf("generate.go: //autodi:app %s: entrypoint %s would be written into command package %s\n  hint: set out=<file.go> outside that directory",
+					app.Name, app.Output, cmd.Dir)
+			}
+		}
+	}
+	return nil
+}
+
+// withApp runs fn with the generator scoped to one app: its name and
+// descriptions, its entrypoint, and only its commands.
+func (cg *CodeGen) withApp(app AppConfig, fn func() error) error {
+	savedCfg, savedCommands := *cg.cfg, cg.commands
+	defer func() {
+		*cg.cfg, cg.commands = savedCfg, savedCommands
+	}()
+
+	cg.cfg.AppName, cg.cfg.AppShort, cg.cfg.AppLong = app.Name, app.Short, app.Long
+	cg.cfg.Output = app.Output
+	if len(app.Commands) > 0 {
+		cg.commands = nil
+		for _, cmd := range savedCommands {
+			if slices.Contains(app.Commands, cmd.Name) {
+				cg.commands = append(cg.commands, cmd)
+			}
+		}
+	}
+	return fn()
+}
+
+// generateApps generates the entrypoint of every app, or main.go alone when
+// generate.go declares at most one app.
+func (cg *CodeGen) generateApps() ([]GeneratedFile, error) {
+	apps := cg.cfg.Apps
+	if len(apps) == 0 {
+		return cg.generateMain()
+	}
+	var files []GeneratedFile
+	for _, app := range apps {
+		err := cg.withApp(app, func() error {
+			fs, err := cg.generateMain()
+			files = append(files, fs...)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("app %s: %w", app.Name, err)
+		}
+	}
+	return files, nil
+}
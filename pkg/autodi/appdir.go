@@ -0,0 +1,147 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Directory-level apps: a generate.go declaring an //autodi:app below the
+// module root wires an application of its own, so a monorepo can host
+// several in one module:
+//
+//	services/billing/generate.go   //go:generate go run github.com/iVampireSP/autodi
+//	                               //autodi:app billing "Billing service"
+//	services/billing/cmd/...       its commands
+//	services/billing/main.go       its entrypoint, autodi.lock and diagrams
+//
+// autodi uses the generate.go nearest to the working directory, which go
+// generate sets to the file's directory, and the module root's otherwise.
+// //autodi:output paths are relative to that directory; group, exclude and
+// other package paths stay module-relative. Each app scans the module's
+// packages as before, minus the other apps' directories and its own cmd/.
+
+// findAppDir returns the module-relative directory of the generate.go used
+// from dir, the working directory: the nearest one declaring an
+// //autodi:app below the module root, "" for the module root's.
+func findAppDir(moduleRoot, dir string) string {
+	rel, err := filepath.Rel(moduleRoot, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "" // outside the module, e.g. autodi run against another tree
+	}
+	for rel != "." {
+		if isAppGenerateFile(filepath.Join(moduleRoot, rel)) {
+			return filepath.ToSlash(rel)
+		}
+		rel = filepath.Dir(rel)
+	}
+	return ""
+}
+
+// isAppGenerateFile reports whether dir has a generate.go declaring an
+// //autodi:app; a generate.go for other tools doesn't make an app.
+func isAppGenerateFile(dir string) bool {
+	data, err := os.ReadFile(filepath.Join(dir, "generate.go"))
+	if err != nil {
+		return false
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if bytes.HasPrefix(bytes.TrimSpace(line), []byte("//autodi:app ")) {
+			return true
+		}
+	}
+	return false
+}
+
+// findAppDirs returns the module-relative directories below the module root
+// with an app's generate.go, skipping what the scan skips: hidden, vendor,
+// testdata and _-prefixed directories and nested modules.
+func findAppDirs(moduleRoot string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(moduleRoot, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || p == moduleRoot {
+			return err
+		}
+		name := d.Name()
+		if strings.HasPrefix(name, ".") || name == "vendor" || skippedDirReason(name) != "" {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(p, "go.mod")); err == nil {
+			return filepath.SkipDir
+		}
+		if isAppGenerateFile(p) {
+			rel, _ := filepath.Rel(moduleRoot, p)
+			dirs = append(dirs, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// appPath joins a path relative to the app's directory into a
+// module-relative one.
+func (cfg *Config) appPath(rel string) string {
+	return path.Join(cfg.AppDir, rel)
+}
+
+// cmdDir is the module-relative directory of the app's commands.
+func (cfg *Config) cmdDir() string {
+	return cfg.appPath("cmd")
+}
+
+// inDir reports whether a module-relative path is dir or below it.
+func inDir(rel, dir string) bool {
+	return rel == dir || strings.HasPrefix(rel, dir+"/")
+}
+
+// outsideApp reports whether a module-relative package directory isn't
+// scanned for the app's providers: it belongs to another app, or it is an
+// entrypoint or command package. An enclosing app only contributes the
+// packages that aren't its own entrypoint or commands.
+func (cfg *Config) outsideApp(rel string) bool {
+	for _, dir := range cfg.OtherApps {
+		if inDir(cfg.AppDir, dir) {
+			if rel == dir || inDir(rel, dir+"/cmd") {
+				return true
+			}
+		} else if inDir(rel, dir) {
+			return true
+		}
+	}
+	if cfg.AppDir == "" {
+		return false
+	}
+	if cfg.CmdInternal && inDir(rel, cfg.appPath(cmdInternalDir)) {
+		return false
+	}
+	return rel == cfg.AppDir || inDir(rel, cfg.cmdDir())
+}
+
+// resolveAppDir points the outputs of a directory-level app into its
+// directory and records the other apps of the module.
+func resolveAppDir(moduleRoot string, cfg *Config) error {
+	var apps []string
+	var err error
+	if cfg.Files != nil {
+		apps = fileAppDirs(moduleRoot, cfg.Files)
+	} else if apps, err = findAppDirs(moduleRoot); err != nil {
+		return fmt.Errorf("find generate.go files: %w", err)
+	}
+	for _, dir := range apps {
+		if dir != cfg.AppDir {
+			cfg.OtherApps = append(cfg.OtherApps, dir)
+		}
+	}
+	if cfg.AppDir == "" {
+		return nil
+	}
+	cfg.Output = cfg.appPath(cfg.Output)
+	for i := range cfg.Apps {
+		cfg.Apps[i].Output = cfg.appPath(cfg.Apps[i].Output)
+	}
+	return nil
+}
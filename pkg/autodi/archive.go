@@ -0,0 +1,101 @@
+package autodi
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Output archives: build systems like Bazel run generators in a sandbox where
+// the source tree is read-only and every output is a declared artifact. With
+// --output-archive the generated files go into one .zip or .tar, named by
+// their module-relative paths, instead of the tree; "-" streams the tar to
+// stdout. Entries are sorted and carry a fixed time so the archive is
+// reproducible.
+
+// archiveStdout is the --output-archive value writing a tar to stdout.
+const archiveStdout = "-"
+
+// archiveTime is the modification time of every archive entry.
+var archiveTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// checkArchivePath validates an --output-archive destination.
+func checkArchivePath(path string) error {
+	if path == archiveStdout || strings.HasSuffix(path, ".zip") || strings.HasSuffix(path, ".tar") {
+		return nil
+	}
+	return fmt.Errorf("--output-archive %s: want a .zip or .tar file, or - for a tar on stdout", path)
+}
+
+// writeArchive writes the generated files into the archive at path.
+func writeArchive(path string, files []GeneratedFile) error {
+	files = slices.Clone(files)
+	slices.SortFunc(files, func(a, b GeneratedFile) int { return strings.Compare(a.Name, b.Name) })
+
+	if path == archiveStdout {
+		return writeTar(os.Stdout, files)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("--output-archive: %w", err)
+	}
+	if strings.HasSuffix(path, ".zip") {
+		err = writeZip(f, files)
+	} else {
+		err = writeTar(f, files)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("--output-archive %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeZip writes files into a zip archive.
+func writeZip(w io.Writer, files []GeneratedFile) error {
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     filepath.ToSlash(f.Name),
+			Method:   zip.Deflate,
+			Modified: archiveTime,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(f.Content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeTar writes files into a tar archive.
+func writeTar(w io.Writer, files []GeneratedFile) error {
+	tw := tar.NewWriter(w)
+	for _, f := range files {
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     filepath.ToSlash(f.Name),
+			Size:     int64(len(f.Content)),
+			Mode:     0644,
+			ModTime:  archiveTime,
+			Format:   tar.FormatPAX,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.Content); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
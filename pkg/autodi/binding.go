@@ -0,0 +1,297 @@
+package autodi
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// resolveConfigType resolves a short config type name to its full type string.
+func (g *Graph) resolveConfigType(shortName string) string {
+	if strings.Contains(shortName, "/") {
+		return shortName
+	}
+
+	if full, ok := g.shortToFull[shortName]; ok {
+		return full
+	}
+
+	prefix := ""
+	s := shortName
+	if strings.HasPrefix(s, "*") {
+		prefix = "*"
+		s = s[1:]
+	}
+	dotIdx := strings.Index(s, ".")
+	if dotIdx > 0 {
+		pkgName := s[:dotIdx]
+		typeName := s[dotIdx+1:]
+		if pkgPath, ok := g.pkgNameToPath[pkgName]; ok {
+			return prefix + pkgPath + "." + typeName
+		}
+
+		for _, group := range g.cfg.Groups {
+			for _, gpath := range group.Paths {
+				base := g.cfg.Module + "/"
+				if g.cfg.moduleOf(gpath) != "" {
+					base = "" // an import path already
+				}
+				parts := strings.Split(gpath, "/")
+				for i, part := range parts {
+					if part == pkgName {
+						fullPkg := base + strings.Join(parts[:i+1], "/")
+						g.pkgNameToPath[pkgName] = fullPkg
+						return prefix + fullPkg + "." + typeName
+					}
+				}
+			}
+		}
+	}
+
+	return shortName
+}
+
+// resolveBindings sets up interface → concrete type mappings.
+func (g *Graph) resolveBindings(providers []*Provider) []error {
+	var errs []error
+
+	// 1. Explicit bindings from config
+	for concreteShort, ifaces := range g.cfg.Bindings {
+		concreteFull := g.resolveConfigType(concreteShort)
+		for _, ifaceShort := range ifaces {
+			ifaceFull := g.resolveConfigType(ifaceShort)
+			if _, ok := g.Bindings[ifaceFull]; ok {
+				errs = append(errs, fmt.Errorf("interface %s has duplicate binding configuration", ifaceFull))
+				continue
+			}
+			g.Bindings[ifaceFull] = concreteFull
+			g.BindSource[ifaceFull] = BindConfig
+			if provider, ok := g.ProviderMap[concreteFull]; ok {
+				g.ProviderMap[ifaceFull] = provider
+				g.TypeToField[ifaceFull] = g.fieldName(ifaceFull)
+			}
+		}
+	}
+
+	// 2. Explicit bindings from annotations, checked against the interface
+	// when its type was loaded
+	for _, p := range providers {
+		bindTargets := GetAnnotationValues(p.Annotations, AnnotBind)
+		for _, target := range bindTargets {
+			ifaceStr := g.resolveConfigType(target)
+			if _, ok := g.Bindings[ifaceStr]; ok || len(p.Returns) == 0 {
+				continue
+			}
+			ret := p.Returns[0]
+			if iface := g.findIfaceType(ifaceStr); iface != nil && ret.Type != nil {
+				if missing := missingMethods(ret.Type, iface); len(missing) > 0 {
+					errs = append(errs, diagf(CodeBadBinding, p.Position,
+						p.Position, target, toShortTypeName(ret.TypeStr), strings.Join(missing, "\n")))
+					continue
+				}
+			}
+			g.Bindings[ifaceStr] = ret.TypeStr
+			g.BindSource[ifaceStr] = BindAnnotation
+			g.ProviderMap[ifaceStr] = p
+		}
+	}
+
+	// 3. Auto-detect bindings using pre-built impl index (Step 1)
+	errs = append(errs, g.autoDetectBindings(providers)...)
+
+	return errs
+}
+
+// missingMethods lists the methods of iface that t (or *t) lacks or has
+// with another signature, one "  missing ..." or "  wrong type ..." line each.
+func missingMethods(t types.Type, iface *types.Interface) []string {
+	if implementsIface(t, iface) {
+		return nil
+	}
+	if _, ok := t.Underlying().(*types.Interface); !ok {
+		if _, ok := t.(*types.Pointer); !ok {
+			t = types.NewPointer(t)
+		}
+	}
+	qualifier := func(pkg *types.Package) string { return pkg.Name() }
+	var missing []string
+	for i := 0; i < iface.NumMethods(); i++ {
+		want := iface.Method(i)
+		wantSig := strings.TrimPrefix(types.TypeString(want.Type(), qualifier), "func")
+		obj, _, _ := types.LookupFieldOrMethod(t, false, want.Pkg(), want.Name())
+		have, ok := obj.(*types.Func)
+		switch {
+		case !ok:
+			missing = append(missing, fmt.Sprintf("  missing %s%s", want.Name(), wantSig))
+		case !types.Identical(have.Type(), want.Type()):
+			haveSig := strings.TrimPrefix(types.TypeString(have.Type(), qualifier), "func")
+			missing = append(missing, fmt.Sprintf("  wrong type %s: have %[1]s%[2]s, want %[1]s%[3]s", want.Name(), haveSig, wantSig))
+		}
+	}
+	return missing
+}
+
+// autoDetectBindings automatically binds interfaces to concrete types using the impl index.
+func (g *Graph) autoDetectBindings(providers []*Provider) []error {
+	// Collect all interface types needed as parameters
+	neededIfaces := make(map[string]bool)
+	for _, p := range providers {
+		for _, param := range p.Params {
+			if param.IsIface {
+				if _, bound := g.Bindings[param.TypeStr]; !bound {
+					if _, provided := g.ProviderMap[param.TypeStr]; !provided {
+						neededIfaces[param.TypeStr] = true
+					}
+				}
+			}
+		}
+	}
+
+	// Use pre-built impl index for O(1) lookup per interface (Step 1)
+	var errs []error
+	for _, ifaceStr := range sortedKeys(neededIfaces) {
+		entries := g.implIndex[ifaceStr]
+		if len(entries) == 0 {
+			continue
+		}
+		// Filter to entries that are in ProviderMap (singleton providers only)
+		var candidates []implEntry
+		for _, e := range entries {
+			for typeStr, p := range g.ProviderMap {
+				if p == e.provider {
+					candidates = append(candidates, implEntry{provider: p, retTypeStr: typeStr})
+					break
+				}
+			}
+		}
+		chosen, err := pickBinding(ifaceStr, candidates)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if chosen != nil {
+			g.Bindings[ifaceStr] = chosen.retTypeStr
+			g.BindSource[ifaceStr] = autoSource(candidates)
+			g.ProviderMap[ifaceStr] = chosen.provider
+		}
+	}
+	return errs
+}
+
+// How an interface binding was chosen, for the --bindings report.
+const (
+	BindConfig     = "config"     // Config.Bindings
+	BindAnnotation = "annotation" // //autodi:bind on the constructor
+	BindAuto       = "auto"       // the only implementation
+	BindPrimary    = "primary"    // //autodi:primary among several
+	BindReplace    = "replace"    // //autodi:replace in generate.go
+)
+
+// autoSource returns the source of an auto-detected binding.
+func autoSource(candidates []implEntry) string {
+	if len(candidates) == 1 {
+		return BindAuto
+	}
+	return BindPrimary
+}
+
+// pickBinding selects the implementation to bind an interface to. A single
+// candidate wins outright; among several, the one marked //autodi:primary is
+// the default. Returns nil when the choice stays ambiguous.
+func pickBinding(ifaceStr string, candidates []implEntry) (*implEntry, error) {
+	if len(candidates) == 1 {
+		return &candidates[0], nil
+	}
+	var primaries []implEntry
+	for _, c := range candidates {
+		if HasAnnotation(c.provider.Annotations, AnnotPrimary) {
+			primaries = append(primaries, c)
+		}
+	}
+	switch len(primaries) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &primaries[0], nil
+	}
+	var lines []string
+	for i, c := range primaries {
+		lines = append(lines, fmt.Sprintf("  %d. %s.%s (%s)", i+1, c.provider.PkgName, c.provider.FuncName, c.provider.Position))
+	}
+	return nil, diagf(CodeMultiplePrimary, primaries[1].provider.Position, toShortTypeName(ifaceStr), strings.Join(lines, "\n"))
+}
+
+// BindCommandInterfaces resolves interface bindings for command parameters
+// using the pre-built type index and impl index.
+func (g *Graph) BindCommandInterfaces(commands []*DiscoveredCommand) []error {
+	var errs []error
+	var bindParams func(cmd *DiscoveredCommand)
+	bindParams = func(cmd *DiscoveredCommand) {
+		errs = append(errs, g.bindParams(cmd.Params, cmd.Dir+": "+cmd.FuncName)...)
+		for _, child := range cmd.Children {
+			bindParams(child.Cmd)
+		}
+	}
+	for _, cmd := range commands {
+		bindParams(cmd)
+	}
+	for _, cmd := range commands {
+		for _, param := range cmd.Params {
+			if !param.IsIface {
+				continue
+			}
+			if _, bound := g.Bindings[param.TypeStr]; bound {
+				continue
+			}
+
+			// Find the interface type from our type index or provider universe
+			ifaceUnderlying := g.findIfaceType(param.TypeStr)
+			if ifaceUnderlying == nil {
+				continue
+			}
+
+			// Use impl index for O(1) lookup
+			chosen, err := pickBinding(param.TypeStr, g.implIndex[param.TypeStr])
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if chosen != nil {
+				g.Bindings[param.TypeStr] = chosen.retTypeStr
+				g.BindSource[param.TypeStr] = autoSource(g.implIndex[param.TypeStr])
+				if p, ok := g.ProviderMap[chosen.retTypeStr]; ok {
+					g.ProviderMap[param.TypeStr] = p
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// resolveType follows interface bindings to find the concrete type.
+func (g *Graph) resolveType(typeStr string) string {
+	if concrete, ok := g.Bindings[typeStr]; ok {
+		return concrete
+	}
+	return typeStr
+}
+
+// findIfaceType finds the *types.Interface underlying type for a given type string.
+// Uses O(1) typeIndex lookup (Step 3) instead of linear scan.
+func (g *Graph) findIfaceType(typeStr string) *types.Interface {
+	// Step 3: O(1) lookup from type index
+	if t, ok := g.typeIndex[typeStr]; ok {
+		if iface, ok := t.Underlying().(*types.Interface); ok {
+			return iface
+		}
+	}
+
+	// Fallback: scanner-discovered interfaces
+	if g.ifaceTypes != nil {
+		if iface, ok := g.ifaceTypes[typeStr]; ok {
+			return iface
+		}
+	}
+
+	return nil
+}
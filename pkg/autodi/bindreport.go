@@ -0,0 +1,100 @@
+package autodi
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// BindProvided marks an interface provided directly: a constructor returns
+// it, or //autodi:as registers the return type only as the interface.
+const BindProvided = "provided"
+
+// runBindings prints every interface parameter in the graph, what it resolved
+// to and how, and the implementations that were not chosen. Unresolved and
+// ambiguous interfaces are listed rather than failing the run.
+func runBindings(opts *Options) error {
+	opts.Bindings = true
+	proj, err := analyzeProject(opts)
+	if err != nil {
+		return err
+	}
+	g := proj.Graph
+
+	// Interface → the constructors and commands taking it
+	users := make(map[string][]string)
+	optional := make(map[string]bool)
+	for _, p := range g.Providers {
+		for _, param := range p.Params {
+			// Process streams and the Container aren't bound, nor is the ctx
+			// NewRequestScope passes to the request-scoped providers
+			if param.Stream != "" || p.Scope != "" && isContextTypeStr(param.TypeStr) {
+				continue
+			}
+			if param.IsIface {
+				users[param.TypeStr] = append(users[param.TypeStr], p.PkgName+"."+p.FuncName)
+				optional[param.TypeStr] = optional[param.TypeStr] || param.Optional
+			}
+		}
+	}
+	for _, cmd := range proj.Commands {
+		for _, param := range cmd.Params {
+			if param.IsIface && param.Stream == "" {
+				users[param.TypeStr] = append(users[param.TypeStr], "command "+cmd.Name)
+			}
+		}
+	}
+	if len(users) == 0 {
+		fmt.Fprintln(os.Stdout, "no interface parameters")
+		return nil
+	}
+
+	ifaces := make([]string, 0, len(users))
+	for iface := range users {
+		ifaces = append(ifaces, iface)
+	}
+	sort.Strings(ifaces)
+
+	for _, iface := range ifaces {
+		fmt.Fprintf(os.Stdout, "%s\n", toShortTypeName(iface))
+
+		chosen := g.ProviderMap[iface]
+		switch {
+		case chosen != nil:
+			source, ok := g.BindSource[iface]
+			if !ok {
+				source = BindProvided
+			}
+			concrete := iface
+			if c, ok := g.Bindings[iface]; ok {
+				concrete = c
+			}
+			fmt.Fprintf(os.Stdout, "  → %s  %s.%s (%s) [%s]\n",
+				toShortTypeName(concrete), chosen.PkgName, chosen.FuncName, chosen.Position, source)
+		case optional[iface]:
+			fmt.Fprintf(os.Stdout, "  → unresolved, optional (nil)\n")
+		case len(g.implIndex[iface]) > 1:
+			fmt.Fprintf(os.Stdout, "  → ambiguous\n")
+		default:
+			fmt.Fprintf(os.Stdout, "  → unresolved\n")
+		}
+
+		for _, c := range g.implIndex[iface] {
+			if c.provider == chosen {
+				continue
+			}
+			label := ""
+			if HasAnnotation(c.provider.Annotations, AnnotPrimary) {
+				label = " [primary]"
+			}
+			fmt.Fprintf(os.Stdout, "    candidate %s  %s.%s (%s)%s\n",
+				toShortTypeName(c.retTypeStr), c.provider.PkgName, c.provider.FuncName, c.provider.Position, label)
+		}
+		if chosen == nil && len(g.implIndex[iface]) > 1 {
+			fmt.Fprintf(os.Stdout, "    hint: mark one //autodi:primary or add //autodi:bind %s to it\n", toShortTypeName(iface))
+		}
+		fmt.Fprintf(os.Stdout, "  used by %s\n\n", strings.Join(users[iface], ", "))
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build/constraint"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// AnnotCmd configures a command constructor:
+//
+//	//autodi:cmd buildtag=enterprise
+//	func NewAudit(db *ent.Client) *Audit
+//
+// buildtag=<expr> generates the command into main_<tag>_gen.go behind a
+// //go:build line, so `go build -tags enterprise` adds it to the binary and a
+// plain build leaves it out. profile=<a,b> generates the command only when
+// autodi runs with a matching --profile.
+const AnnotCmd = "cmd"
+
+// applyCommandDirectives reads //autodi:cmd on a command constructor. Returns
+// false when the command's profile isn't active.
+func (d *CommandDetector) applyCommandDirectives(pkg *packages.Package, cmd *DiscoveredCommand) (bool, error) {
+	fn := funcDecl(pkg, cmd.FuncName)
+	if fn == nil {
+		return true, nil
+	}
+	annotations := ParseAnnotations(fn)
+	if err := applyStreams(cmd.Params, annotations, cmd.Dir+": "+cmd.FuncName); err != nil {
+		return false, err
+	}
+	if err := applyParamBindings(pkg, fn, cmd.Params, annotations, cmd.Dir+": "+cmd.FuncName); err != nil {
+		return false, err
+	}
+	cmd.NoStart = HasAnnotation(annotations, AnnotNoStart)
+	cmd.NoMigrate = HasAnnotation(annotations, AnnotNoMigrate)
+	for _, value := range GetAnnotationValues(annotations, AnnotCmd) {
+		for _, opt := range strings.Fields(value) {
+			key, val, _ := strings.Cut(opt, "=")
+			switch key {
+			case "buildtag":
+				if _, err := constraint.Parse("//go:build " + val); err != nil || val == "" {
+					return false, fmt.Errorf("%s: //autodi:cmd buildtag=%s: not a build constraint\n  hint: use a tag or expression, e.g. buildtag=enterprise",
+						cmd.Dir, val)
+				}
+				cmd.BuildTag = val
+			case "profile":
+				if !profileActive(val, d.cfg.Profile) {
+					return false, nil
+				}
+			default:
+				return false, fmt.Errorf("%s: //autodi:cmd: unknown option %q\n  hint: options are buildtag=<expr> and profile=<name,...>; alias, group and hidden go on handler methods",
+					cmd.Dir, opt)
+			}
+		}
+	}
+	if err := applyHandlerDirectives(pkg, cmd); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// funcDecl finds the declaration of a package-level function.
+func funcDecl(pkg *packages.Package, name string) *ast.FuncDecl {
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == name {
+				return fn
+			}
+		}
+	}
+	return nil
+}
+
+// splitTaggedCommands separates commands behind a build constraint, keyed by
+// the constraint, from the ones always compiled in.
+func splitTaggedCommands(commands []*DiscoveredCommand) ([]*DiscoveredCommand, map[string][]*DiscoveredCommand) {
+	var plain []*DiscoveredCommand
+	tagged := make(map[string][]*DiscoveredCommand)
+	for _, cmd := range commands {
+		// Child commands are registered with their parent
+		if cmd.Parent != nil {
+			continue
+		}
+		if cmd.BuildTag == "" {
+			plain = append(plain, cmd)
+		} else {
+			tagged[cmd.BuildTag] = append(tagged[cmd.BuildTag], cmd)
+		}
+	}
+	return plain, tagged
+}
+
+// taggedFileName returns the output file for a build constraint. The _gen
+// suffix keeps tags like "linux" or "test" from acting as filename constraints.
+func taggedFileName(tag string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, tag)
+	return "main_" + strings.Trim(name, "_") + "_gen.go"
+}
+
+// writeTaggedCommandsVar emits the package-level registry that files behind
+// a build constraint append their commands to, and the initFunc type they share.
+func (cg *CodeGen) writeTaggedCommandsVar(buf *bytes.Buffer, cobraQualifier string) {
+	fmt.Fprintf(buf, "type initFunc func(cmd, top *%s.Command) (func(), error)\n\n", cobraQualifier)
+	buf.WriteString("// taggedCommands register commands compiled in by build tags.\n")
+	fmt.Fprintf(buf, "var taggedCommands []func(root *%s.Command, initFuncs map[*%s.Command]initFunc)\n",
+		cobraQualifier, cobraQualifier)
+}
+
+// generateTaggedCommands generates the file registering the commands behind
+// one build constraint, next to the entrypoint. Helpers it needs are emitted
+// into the entrypoint.
+func (cg *CodeGen) generateTaggedCommands(tag string, commands []*DiscoveredCommand) (GeneratedFile, error) {
+	name := filepath.Join(filepath.Dir(filepath.FromSlash(cg.cfg.Output)), taggedFileName(tag))
+	cg.imports.Reset()
+	cobraQualifier := cg.imports.Add("github.com/spf13/cobra", "cobra")
+
+	cmdAliases := make(map[string]string)
+	for _, cmd := range commands {
+		cmdAliases[cmd.PkgPath] = cg.imports.AddWithAlias(cmd.PkgPath, cmd.PkgName+"cmd")
+	}
+
+	var initBuf bytes.Buffer
+	for _, cmd := range commands {
+		if !cmd.HasDeps() {
+			continue
+		}
+		if err := cg.generateInitFunc(&initBuf, cmd, cmdAliases[cmd.PkgPath]); err != nil {
+			return GeneratedFile{}, fmt.Errorf("generate init for %s: %w", cmd.Name, err)
+		}
+		initBuf.WriteString("\n")
+	}
+
+	var regBuf bytes.Buffer
+	regBuf.WriteString("func init() {\n")
+	fmt.Fprintf(&regBuf, "\ttaggedCommands = append(taggedCommands, func(root *%s.Command, initFuncs map[*%s.Command]initFunc) {\n",
+		cobraQualifier, cobraQualifier)
+	for _, cmd := range commands {
+		cg.writeCommandRegistration(&regBuf, cmd, cmdAliases[cmd.PkgPath], cobraQualifier)
+	}
+	regBuf.WriteString("\t})\n")
+	regBuf.WriteString("}\n")
+
+	return cg.renderFile(name, buildConstraint(tag), cg.cfg.Package, regBuf.Bytes(), initBuf.Bytes())
+}
@@ -0,0 +1,188 @@
+package autodi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Environment variables configuring the remote generation cache.
+const (
+	cacheURLEnv   = "AUTODI_CACHE_URL"   // base URL; entries live at <url>/<key>.json
+	cacheTokenEnv = "AUTODI_CACHE_TOKEN" // optional bearer token
+)
+
+// remoteCache stores generated outputs keyed by a hash of every input autodi
+// reads, so cold CI machines skip package loading and analysis for a tree an
+// earlier build already generated. Any HTTP server accepting GET and PUT works,
+// including S3-compatible buckets behind a presigning proxy.
+type remoteCache struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// cacheEntry is the stored form of one generation run.
+type cacheEntry struct {
+	Version  string          `json:"version"`
+	Files    []GeneratedFile `json:"files"`
+	Warnings []string        `json:"warnings,omitempty"` // replayed on a hit
+}
+
+// newRemoteCache returns the configured cache, or nil when no URL is set.
+func newRemoteCache(url string) *remoteCache {
+	if url == "" {
+		return nil
+	}
+	return &remoteCache{
+		baseURL: strings.TrimSuffix(url, "/"),
+		token:   os.Getenv(cacheTokenEnv),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Get fetches the entry for key. A miss returns a nil entry and no error.
+func (c *remoteCache) Get(key string) (*cacheEntry, error) {
+	resp, err := c.do(http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", key, resp.Status)
+	}
+	var entry cacheEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entry); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", key, err)
+	}
+	// An entry of another autodi, or without files, is regenerated
+	if entry.Version != toolVersion() || len(entry.Files) == 0 {
+		return nil, nil
+	}
+	for _, f := range entry.Files {
+		if !filepath.IsLocal(f.Name) {
+			return nil, fmt.Errorf("%s: file name %q is outside the module", key, f.Name)
+		}
+	}
+	return &entry, nil
+}
+
+// Put uploads the generated files and analysis warnings for key.
+func (c *remoteCache) Put(key string, files []GeneratedFile, warnings []string) error {
+	data, err := json.Marshal(cacheEntry{Version: toolVersion(), Files: files, Warnings: warnings})
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("PUT %s: %s: %s", key, resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+func (c *remoteCache) do(method, key string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.baseURL+"/"+key+".json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.client.Do(req)
+}
+
+// cacheKey hashes every input of a generation run: the autodi version and
+// options, go.mod/go.sum, go.work, .gitignore, provider manifests and the Go
+// sources of every directory the run loads — the module, its go.work members
+// and its local replacements — except autodi's own output. Dependency
+// versions are pinned by go.sum.
+func cacheKey(moduleRoot string, opts *Options) (string, error) {
+	cfg, err := loadConfig(moduleRoot, opts)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	// Directory-level apps of one module generate different files
+	fmt.Fprintf(h, "autodi %s format %d profile %q profile-init %q inspect %t doc %q app %q\n", toolVersion(), outputFormat, opts.Profile, opts.ProfileInit, opts.Inspect, opts.Doc, cfg.AppDir)
+
+	roots := []string{moduleRoot}
+	for _, mod := range sortedKeys(cfg.Workspace) {
+		roots = append(roots, filepath.Join(moduleRoot, filepath.FromSlash(cfg.Workspace[mod])))
+	}
+	for _, mod := range sortedKeys(cfg.LocalMods) {
+		roots = append(roots, cfg.LocalMods[mod])
+	}
+	var paths []string
+	for _, root := range roots {
+		if root != moduleRoot && inDir(filepath.ToSlash(root), filepath.ToSlash(moduleRoot)) {
+			continue // walked with the module
+		}
+		found, err := cacheInputs(root)
+		if err != nil {
+			return "", err
+		}
+		paths = append(paths, found...)
+	}
+	if work := findWorkFile(moduleRoot); work != "" {
+		paths = append(paths, work)
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		if strings.HasSuffix(path, ".go") && bytes.Contains(data, []byte(generatedMarker)) {
+			continue
+		}
+		rel, _ := filepath.Rel(moduleRoot, path)
+		fmt.Fprintf(h, "%s %d\n", filepath.ToSlash(rel), len(data))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cacheInputs returns the files under root that cacheKey hashes, sorted.
+func cacheInputs(root string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+				name == "testdata" || name == "vendor" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(name, ".go") || name == "go.mod" || name == "go.sum" || name == ".gitignore" || name == ManifestFile {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	sort.Strings(paths)
+	return paths, err
+}
@@ -0,0 +1,312 @@
+package autodi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// errStale is returned by --check when generated files are out of date.
+var errStale = errors.New("generated files are out of date\n  hint: run autodi and commit the result")
+
+// checkGenerated compares what a run would write with the files on disk and
+// prints a unified diff for each difference. Returns the number of stale files.
+func checkGenerated(w io.Writer, moduleRoot string, files []GeneratedFile, opts *Options, rewrite map[string]bool) int {
+	stale := 0
+	for _, f := range files {
+		old, err := os.ReadFile(filepath.Join(moduleRoot, f.Name))
+		if err != nil {
+			old = nil
+		}
+		content := f.Content
+		if old != nil && !opts.Full && !rewrite[f.Name] && strings.HasSuffix(f.Name, ".go") {
+			content, _ = mergeGenerated(old, content)
+		}
+		if bytes.Equal(old, content) {
+			if opts.Verbose {
+				fmt.Fprintf(os.Stderr, "autodi: %s up to date\n", f.Name)
+			}
+			continue
+		}
+		stale++
+		from := "a/" + filepath.ToSlash(f.Name)
+		if old == nil {
+			from = "/dev/null"
+		}
+		io.WriteString(w, unifiedDiff(from, "b/"+filepath.ToSlash(f.Name), string(old), string(content)))
+	}
+	return stale
+}
+
+// unifiedDiff returns a unified diff with three lines of context between two
+// texts, or "" when they are equal.
+func unifiedDiff(fromName, toName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	edits := diffLines(splitLines(a), splitLines(b))
+
+	const context = 3
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for k := 0; k < len(edits); {
+		if edits[k].op == ' ' {
+			k++
+			continue
+		}
+		// Grow the hunk until a run of more than 2*context unchanged lines
+		start := max(k-context, 0)
+		end := k
+		for end < len(edits) {
+			if edits[end].op != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(edits) && edits[run].op == ' ' {
+				run++
+			}
+			if run == len(edits) || run-end > 2*context {
+				end = min(end+context, len(edits))
+				break
+			}
+			end = run
+		}
+
+		var body strings.Builder
+		oldLines, newLines := 0, 0
+		for _, e := range edits[start:end] {
+			body.WriteByte(e.op)
+			body.WriteString(e.line)
+			body.WriteByte('\n')
+			if e.op != '+' {
+				oldLines++
+			}
+			if e.op != '-' {
+				newLines++
+			}
+		}
+		oldStart, newStart := edits[start].i+1, edits[start].j+1
+		if oldLines == 0 {
+			oldStart--
+		}
+		if newLines == 0 {
+			newStart--
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", oldStart, oldLines, newStart, newLines)
+		out.WriteString(body.String())
+		k = end
+	}
+	return out.String()
+}
+
+// lineEdit is one line of an edit script: ' ' keep, '-' delete from the old
+// text, '+' insert from the new one; i and j are the line indexes in the old
+// and new text before it.
+type lineEdit struct {
+	op   byte
+	line string
+	i, j int
+}
+
+// diffLines returns a shortest edit script turning x into y, deletions before
+// insertions within a change.
+func diffLines(x, y []string) []lineEdit {
+	d := &lineDiff{x: x, y: y, del: make([]bool, len(x)), ins: make([]bool, len(y)),
+		off: len(y) + 1, fd: make([]int, len(x)+len(y)+3), bd: make([]int, len(x)+len(y)+3)}
+	d.compare(0, len(x), 0, len(y))
+
+	var edits []lineEdit
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && d.del[i]:
+			edits = append(edits, lineEdit{'-', x[i], i, j})
+			i++
+		case j < len(y) && d.ins[j]:
+			edits = append(edits, lineEdit{'+', y[j], i, j})
+			j++
+		default:
+			edits = append(edits, lineEdit{' ', x[i], i, j})
+			i++
+			j++
+		}
+	}
+	return edits
+}
+
+// lineDiff is Myers' O(ND) diff in linear space: compare splits the texts at
+// the middle snake of a shortest edit path and recurses on both halves,
+// marking the deleted and inserted lines.
+type lineDiff struct {
+	x, y     []string
+	del, ins []bool
+	off      int   // index of diagonal 0 (x-y) in fd and bd
+	fd, bd   []int // furthest x reached per diagonal, forward and backward
+}
+
+// compare marks the edits between x[xoff:xlim] and y[yoff:ylim].
+func (d *lineDiff) compare(xoff, xlim, yoff, ylim int) {
+	for xoff < xlim && yoff < ylim && d.x[xoff] == d.y[yoff] {
+		xoff++
+		yoff++
+	}
+	for xlim > xoff && ylim > yoff && d.x[xlim-1] == d.y[ylim-1] {
+		xlim--
+		ylim--
+	}
+	switch {
+	case xoff == xlim:
+		for j := yoff; j < ylim; j++ {
+			d.ins[j] = true
+		}
+	case yoff == ylim:
+		for i := xoff; i < xlim; i++ {
+			d.del[i] = true
+		}
+	default:
+		x, y := d.split(xoff, xlim, yoff, ylim)
+		d.compare(xoff, x, yoff, y)
+		d.compare(x, xlim, y, ylim)
+	}
+}
+
+// split returns a point on a shortest edit path between x[xoff:xlim] and
+// y[yoff:ylim], which differ in their first and last lines, about halfway
+// along it: the end of the snake where the forward and backward searches
+// meet.
+func (d *lineDiff) split(xoff, xlim, yoff, ylim int) (int, int) {
+	fd, bd, o := d.fd, d.bd, d.off
+	dmin, dmax := xoff-ylim, xlim-yoff
+	fmid, bmid := xoff-yoff, xlim-ylim
+	fmin, fmax, bmin, bmax := fmid, fmid, bmid, bmid
+	odd := (fmid-bmid)&1 != 0
+	fd[o+fmid] = xoff
+	bd[o+bmid] = xlim
+	for {
+		// Extend the forward search by an edit on every diagonal
+		if fmin > dmin {
+			fmin--
+			fd[o+fmin-1] = -1
+		} else {
+			fmin++
+		}
+		if fmax < dmax {
+			fmax++
+			fd[o+fmax+1] = -1
+		} else {
+			fmax--
+		}
+		for k := fmax; k >= fmin; k -= 2 {
+			lo, hi := fd[o+k-1], fd[o+k+1]
+			x := lo + 1
+			if lo < hi {
+				x = hi
+			}
+			y := x - k
+			for x < xlim && y < ylim && d.x[x] == d.y[y] {
+				x++
+				y++
+			}
+			fd[o+k] = x
+			if odd && bmin <= k && k <= bmax && bd[o+k] <= x {
+				return x, y
+			}
+		}
+
+		// And the backward one
+		if bmin > dmin {
+			bmin--
+			bd[o+bmin-1] = math.MaxInt
+		} else {
+			bmin++
+		}
+		if bmax < dmax {
+			bmax++
+			bd[o+bmax+1] = math.MaxInt
+		} else {
+			bmax--
+		}
+		for k := bmax; k >= bmin; k -= 2 {
+			lo, hi := bd[o+k-1], bd[o+k+1]
+			x := hi - 1
+			if lo < hi {
+				x = lo
+			}
+			y := x - k
+			for x > xoff && y > yoff && d.x[x-1] == d.y[y-1] {
+				x--
+				y--
+			}
+			bd[o+k] = x
+			if !odd && fmin <= k && k <= fmax && x <= fd[o+k] {
+				return x, y
+			}
+		}
+	}
+}
+
+// splitLines splits text into lines without their trailing newlines.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// verifyHeaders compares the version stamp of every generated Go file in the
+// module with the one this autodi would write — version, output format, go
+// directive and generate.go hash — without analyzing the graph, so CI can
+// reject output from another autodi or a generate.go edited since. Returns
+// the number of mismatched files.
+func verifyHeaders(w io.Writer, moduleRoot string, cfg *Config) (int, error) {
+	want := parseStamp([]byte(versionStamp(cfg)))
+	var names []string
+	err := filepath.WalkDir(moduleRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != moduleRoot && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+				name == "testdata" || name == "vendor" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".go") {
+			names = append(names, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	found, mismatched := 0, 0
+	for _, path := range names {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.Contains(data, []byte(generatedMarker)) {
+			continue
+		}
+		found++
+		rel, _ := filepath.Rel(moduleRoot, path)
+		if got := parseStamp(data); got != want {
+			mismatched++
+			fmt.Fprintf(w, "%s:\n  have %s\n  want %s\n", filepath.ToSlash(rel), got, want)
+		}
+	}
+	if found == 0 {
+		return 0, fmt.Errorf("no generated files found under %s\n  hint: run autodi first", moduleRoot)
+	}
+	return mismatched, nil
+}
@@ -0,0 +1,556 @@
+package autodi
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// errReported signals a failure whose details were already printed to stderr.
+var errReported = errors.New("errors reported")
+
+// Options holds command-line options shared by autodi subcommands.
+type Options struct {
+	Verbose  bool
+	Tree     bool // --tree: print each command's provider tree
+	DryRun   bool
+	Diff     bool // --diff: a dry run printing unified diffs against the files on disk
+	Check    bool
+	Full     bool
+	Only     []string // --only: regenerate just these commands' regions of the generated code
+	Migrate  bool
+	CacheURL string
+	Profile  string
+	Strict   bool
+
+	ProfileInit string
+	Inspect     bool
+	Lang        string
+	SARIF       string
+	Doc         string
+	Bindings    bool // --bindings: report instead of failing on unresolved interfaces
+	DiffLock    bool // --diff-lock: print the changes against the last lock file
+	KeepGoing   bool // --keep-going: leave broken packages out instead of failing
+
+	RequireVersion string // --require-version: autodi version constraint, like //autodi:require-version
+	VerifyHeader   bool   // --verify-header: only compare the version stamps of generated files
+	OutputArchive  string // --output-archive: write the generated files into a .zip or .tar ("-" for stdout)
+
+	Module    string   // --module: module path of a hermetic run, instead of go.mod
+	Files     []string // --files: the Go files of a hermetic run, @file for a list
+	GoVersion string   // --go-version: go directive recorded in headers of a hermetic run
+}
+
+// newRootCommand builds the autodi CLI. Running autodi without a subcommand
+// is equivalent to "autodi generate", so existing //go:generate lines keep working.
+func newRootCommand() *cobra.Command {
+	opts := &Options{}
+	var selftest, bindings, sizeReport bool
+
+	root := &cobra.Command{
+		Use:   "autodi",
+		Short: "Compile-time dependency injection code generator",
+		Long: "autodi scans Go packages for New* constructors, builds a dependency graph\n" +
+			"and generates a main.go wiring every cmd/ package with zero reflection.",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return setLang(opts.Lang)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if selftest {
+				return runSelfTest(opts)
+			}
+			if bindings {
+				return runBindings(opts)
+			}
+			if sizeReport {
+				return runSizeReport(opts)
+			}
+			return runGenerate(opts)
+		},
+	}
+	root.Flags().BoolVar(&selftest, "selftest", false, "generate and build every bundled example (release check)")
+	_ = root.Flags().MarkHidden("selftest")
+	root.Flags().BoolVar(&bindings, "bindings", false, "print every interface parameter, its binding and source, and the unchosen candidates")
+	root.Flags().BoolVar(&sizeReport, "size-report", false, "print per command the packages its providers import, the heaviest modules and what each provider alone pulls in")
+	root.PersistentFlags().BoolVar(&opts.Verbose, "verbose", false, "enable verbose logging")
+	root.PersistentFlags().StringVar(&opts.Profile, "profile", "", "activate packages marked //autodi:profile <name>")
+	root.PersistentFlags().StringVar(&opts.Lang, "lang", "", "language of graph diagnostics: en|zh (default from LC_ALL/LC_MESSAGES/LANG)")
+	root.PersistentFlags().StringVar(&opts.SARIF, "sarif", "", "also write analysis diagnostics as SARIF 2.1.0 to this file (GitHub code scanning)")
+	root.PersistentFlags().BoolVar(&opts.Strict, "strict", false, "fail on analysis warnings (os.Exit/log.Fatal in constructors)")
+	addGenerateFlags(root.Flags(), opts)
+
+	generate := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate main.go and diagrams for the current module",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGenerate(opts)
+		},
+	}
+	addGenerateFlags(generate.Flags(), opts)
+	root.AddCommand(generate)
+
+	export := &cobra.Command{
+		Use:   "export",
+		Short: "Write " + ManifestFile + " so other modules can //autodi:import this one",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExport(opts)
+		},
+	}
+	export.Flags().BoolVar(&opts.DryRun, "dry-run", false, "print the manifest without writing")
+	root.AddCommand(export)
+
+	docs := &cobra.Command{
+		Use:   "docs",
+		Short: "Generate documentation from the dependency graph",
+	}
+	var archOut string
+	arch := &cobra.Command{
+		Use:   "arch [command...]",
+		Short: "Architecture document per binary: command tree, composition, services, startup order",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDocsArch(opts, archOut, args)
+		},
+	}
+	arch.Flags().StringVar(&archOut, "out", "", "write <command>.md files into this directory instead of stdout")
+	docs.AddCommand(arch)
+	root.AddCommand(docs)
+
+	root.AddCommand(&cobra.Command{
+		Use:   "why <Type|Field>",
+		Short: "Show the dependency path from each command to a provider",
+		Example: "  autodi why '*kafka.Client'\n" +
+			"  autodi why KafkaClient",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWhy(opts, args[0])
+		},
+	})
+
+	var exampleModule string
+	example := &cobra.Command{
+		Use:   "example <template> [dir]",
+		Short: "Write a runnable sample project (" + strings.Join(exampleTemplates(), ", ") + ")",
+		Example: "  autodi example http-api\n" +
+			"  autodi example worker ./jobs --module github.com/acme/jobs",
+		Args:      cobra.RangeArgs(1, 2),
+		ValidArgs: exampleTemplates(),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := ""
+			if len(args) > 1 {
+				dir = args[1]
+			}
+			return runExample(args[0], dir, exampleModule)
+		},
+	}
+	example.Flags().StringVar(&exampleModule, "module", "", "module path of the written project (default example.com/<name>)")
+	root.AddCommand(example)
+
+	for _, topic := range helpTopics {
+		root.AddCommand(&cobra.Command{
+			Use:   topic.name,
+			Short: topic.short,
+			Long:  topic.long,
+		})
+	}
+
+	return root
+}
+
+// addGenerateFlags registers the flags accepted by generate (and the bare root command).
+func addGenerateFlags(fs *pflag.FlagSet, opts *Options) {
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "print generated code without writing")
+	fs.BoolVar(&opts.KeepGoing, "keep-going", false, "leave out packages that fail to load, and those importing them, reporting them instead of failing; the rest is wired")
+	fs.BoolVar(&opts.Tree, "tree", false, "print each command's dependency tree to stderr; a provider shown earlier in the tree is marked (*)")
+	fs.BoolVar(&opts.Diff, "diff", false, "dry run printing a unified diff against the files on disk instead of their full content")
+	fs.BoolVar(&opts.Check, "check", false, "exit non-zero with a unified diff if generated files are out of date, without writing")
+	fs.BoolVar(&opts.DiffLock, "diff-lock", false, "print the providers, bindings and command orders changed since the last generation's "+LockFile+", without writing")
+	fs.StringVar(&opts.OutputArchive, "output-archive", "", "write the generated files into this .zip or .tar instead of the source tree, - for a tar on stdout (for sandboxed builds like Bazel)")
+	fs.StringVar(&opts.Module, "module", "", "module path, for hermetic builds without go.mod; the working directory is the module root (requires --files)")
+	fs.StringSliceVar(&opts.Files, "files", nil, "analyze the packages of these Go files instead of walking the module, @file to read one path per line (requires --module; set GOPACKAGESDRIVER to load without the go command)")
+	fs.StringVar(&opts.GoVersion, "go-version", "", "go version recorded in the headers of generated files with --module, as go.mod's go directive otherwise")
+	fs.BoolVar(&opts.Full, "full", false, "rewrite generated Go files entirely instead of splicing changed sections")
+	fs.StringSliceVar(&opts.Only, "only", nil, "regenerate only the init functions and registrations of these commands (e.g. admin,worker), leaving the rest of the generated files as they are")
+	fs.StringVar(&opts.Doc, "doc", "", "also write a Markdown architecture document of the graph to this file (e.g. architecture.md)")
+	fs.BoolVar(&opts.Migrate, "migrate-output", false, "regenerate files written by an incompatible autodi version")
+	fs.StringVar(&opts.RequireVersion, "require-version", "", "fail unless this autodi's version matches a constraint, e.g. '>=v0.5' (also //autodi:require-version)")
+	fs.BoolVar(&opts.VerifyHeader, "verify-header", false, "exit non-zero if a generated file's header records another autodi version, output format, go version or generate.go hash, without analyzing")
+	fs.StringVar(&opts.ProfileInit, "profile-init", "", "time each constructor in the generated init code and report to "+ProfileInitStderr+" or "+ProfileInitOTel)
+	fs.Lookup("profile-init").NoOptDefVal = ProfileInitStderr
+	fs.BoolVar(&opts.Inspect, "inspect", false, "add a hidden "+InspectCommand+" command printing the providers a command constructs, in order, with init durations")
+	fs.StringVar(&opts.CacheURL, "cache-url", os.Getenv(cacheURLEnv),
+		"HTTP base URL of a shared generation cache (default $"+cacheURLEnv+"; token from $"+cacheTokenEnv+")")
+}
+
+// normalizeLegacyFlags rewrites Go-style single-dash long flags ("-verbose",
+// "-dry-run=true") to the double-dash form pflag expects, so invocations
+// written against the old flag package keep working.
+func normalizeLegacyFlags(args []string) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = arg
+		if arg == "--" {
+			copy(out[i+1:], args[i+1:])
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "--") {
+			continue
+		}
+		name := strings.TrimPrefix(arg, "-")
+		if idx := strings.Index(name, "="); idx >= 0 {
+			name = name[:idx]
+		}
+		if len(name) > 1 {
+			out[i] = "-" + arg
+		}
+	}
+	return out
+}
+
+// helpTopic is a documentation-only command shown by "autodi help <topic>".
+type helpTopic struct {
+	name  string
+	short string
+	long  string
+}
+
+var helpTopics = []helpTopic{
+	{
+		name:  "conventions",
+		short: "How providers and commands are discovered",
+		long: `autodi is configured by convention:
+
+  go.mod        module path; required modules replaced by a directory inside
+                the module are scanned under their own module path
+  go.work       the other modules of the workspace are scanned too (not
+                their cmd/), so providers can live in any member module
+  generate.go   //autodi: directives for the whole app
+  <dir>/        a generate.go with //autodi:app below the root is an app of
+  generate.go   its own, with commands in <dir>/cmd/ and output in <dir>/;
+                autodi uses the one nearest the working directory, and no
+                app scans another's directory
+  cmd/<name>/   one command per package: an exported New* returning *T where
+                T has Command() *cobra.Command and handler methods
+                func(*cobra.Command) error (Handle → single command)
+                a New* taking another command's *T nests that command
+                under its own
+                fields tagged flag:"name" [short:"p" usage:"..."] (or
+                //autodi:flag name usage) are registered as its flags
+  everything    every other top-level directory is scanned for exported New*
+  else          constructors; one primary New per package is selected
+                (not testdata/, _-prefixed directories or //go:build ignore
+                files: example code; --verbose lists what was skipped)
+
+Only providers reachable from a command's constructor parameters are wired.
+Dependencies are matched by type: a named type over a basic kind (type Port
+int, type DSN string) is its own dependency, while an alias (type DB = sql.DB)
+is the type it names. Channels and function types are dependencies too: a
+constructor returning chan Event or func(context.Context) error provides it,
+matched by element type, direction and signature (parameter names don't
+count), in a field named after the constructor (NewWorker → Worker).
+A *slog.Logger (text to stderr) or *zap.Logger (production config) that no
+constructor provides is built in the generated file.
+Values with Close/Shutdown/Stop are closed after the command; a ctx
+parameter gets its own context with a 30s deadline
+(//autodi:shutdown-timeout). Values with Start(ctx) error or Run(ctx) error
+run in goroutines alongside the handler, and the first error from either
+cancels the shared context. --verbose lists the lifecycle methods found on
+each provider.
+
+Every generation also writes ` + LockFile + `, a JSON snapshot of the providers,
+bindings and per-command construction order; commit it to review wiring
+changes, and run autodi --diff-lock to summarize them before regenerating.
+autodi --diff previews a regeneration as unified diffs against the files on disk.
+autodi --only admin,worker rewrites just those commands' init functions and
+registrations, between their // autodi:begin and // autodi:end markers, and
+leaves the rest of the generated files as they are.
+autodi --size-report estimates what each command's providers import (packages
+and source size, heaviest modules, what only one constructor pulls in).
+go vet -vettool=$(which autodi) ./... checks //autodi: directives in place:
+unknown kinds (with the closest one as a fix), bind targets, optional types
+and generate.go group paths.
+
+Tests build the same graph with NewTestContainer from ` + TestContainerFile + `
+(go test -tags test), replacing any provider with a With<Field> override:
+
+  c, err := NewTestContainer(WithEntClient(fakeClient))
+  defer c.Close()
+
+When go.mod requires ` + otelModule + `, each command adds autodi.binary,
+autodi.command, autodi.providers, autodi.graph (wiring fingerprint) and
+autodi.profile to OTEL_RESOURCE_ATTRIBUTES before its providers run.`,
+	},
+	{
+		name:  "annotations",
+		short: "Directives accepted on constructors and in generate.go",
+		long: `Constructor directives (doc comment of a New* function):
+
+  //autodi:bind <Interface>     bind the return type to an interface
+  //autodi:bind-param <param> <Type>
+                                give this parameter the value of Type's
+                                provider, whatever its interface is bound
+                                to elsewhere; or inline after the param:
+                                repo Repo /* autodi:bind=postgres.Repo */
+  //autodi:as <Interface>       provide the return type only as the interface
+                                (combine with bind to keep the concrete type)
+  //autodi:as <i|name>=<Interface>
+                                the same for another result, by index (from
+                                0, error excluded) or result name, so each
+                                result of one constructor binds on its own
+  //autodi:ignore               never treat this function as a provider
+  //autodi:factory              on an exported method: provide its results,
+                                called on the constructed receiver
+  //autodi:invoke               call for side effects, result not stored
+  //autodi:optional <Type>      parameter may be left unresolved
+                                (passed nil or its zero value, logged at
+                                startup)
+  //autodi:primary              default binding when several providers
+                                implement the same interface
+  //autodi:replayable [Func]    call Func (default <New>Replay, same signature)
+                                instead when AUTODI_REPLAY=1 (recorded fakes)
+  //autodi:env <VAR,...>        environment variables the constructor reads
+  //autodi:test-replace <Type>  fake constructor used for Type in the
+                                generated test container only
+  //autodi:nostart              don't run this provider's Start(ctx)/Run(ctx)
+                                alongside the command handler
+  //autodi:group <name>         join a group declared in generate.go, in
+                                addition to the group's paths
+  //autodi:adapt <Method>       join a group whose single-method interface
+                                the result doesn't implement, through a
+                                method with the same signature
+  //autodi:field <Name>         Container field name of the first result,
+                                whatever //autodi:naming says
+  //autodi:deprecated [message]  keep generating, but warn with the commands
+                                still constructing it (e.g. use NewV2)
+  //autodi:override             win over any other provider of the same type
+  //autodi:lazy                 with //autodi:runtime lambda, construct it and
+                                its dependents on the first invocation
+                                instead of at cold start
+  //autodi:phase infra|app      infra: construct it and its dependencies
+                                before every app provider (InfraContainer
+                                and AppContainer with layout library)
+  //autodi:migrate              a migrator: constructed in the infra phase,
+                                its Up(ctx) error runs before the app phase
+                                of every command with dependencies
+  //autodi:x-<key> <value>      custom annotation autodi passes through: to
+                                ` + LockFile + ` and, as .X.<key>, to the
+                                //autodi:comment and init-log templates
+  //autodi:route [METHOD] /path register a member of an http.Handler group on
+                                the generated *http.ServeMux, which any
+                                constructor can take
+  //autodi:order <N>            position in group and auto-collected slices
+                                (lower first; then by package path)
+  //autodi:key <name>           key in map[string]Interface parameters
+                                (default: the result's Name() string)
+  //autodi:scope request        construct per Container.NewRequestScope(ctx)
+                                call instead of once per command; gets ctx
+                                and singletons, released by scope.Close()
+  //autodi:when env=VAR[=value] [fallback=Func]
+                                construct only when VAR is set (or equals
+                                value); otherwise call Func (same signature)
+                                or leave the result nil
+  //autodi:stdin <param>        fill the io.Reader parameter with os.Stdin
+  //autodi:stdout <param>       fill the io.Writer parameter with os.Stdout
+  //autodi:stderr <param>       fill the io.Writer parameter with os.Stderr
+  //autodi:args <param>         fill the []string parameter with os.Args
+                                (also on commands; a parameter of a named
+                                type Stdin, Stdout, Stderr or Args is filled
+                                without a directive)
+
+Command directive (doc comment of a cmd/ New* constructor):
+
+  //autodi:cmd buildtag=<expr>  generate the command into main_<tag>_gen.go
+                                behind //go:build <expr> (go build -tags ...)
+  //autodi:cmd profile=<a,b>    generate the command only for --profile a or b
+  //autodi:no-migrate           don't run the //autodi:migrate migrator
+
+Handler directive (doc comment of a handler method, or of Handle):
+
+  //autodi:cmd alias=<a,b>      add cobra Aliases to its subcommand
+  //autodi:cmd group=<id>       list it under a help group of its parent
+                                (declared when the parent doesn't)
+  //autodi:cmd hidden           hide it from help
+
+Type directive (doc comment of a struct type):
+
+  //autodi:in                   parameter object: each exported field is a
+                                dependency, the struct literal is generated
+                                (embedding fx.In works the same way)
+  //autodi:out                  result object: a constructor returning it
+                                provides each exported field; group:"name"
+                                tags add the fields to a group (or fx.Out)
+  //autodi:config [prefix=P]    config struct: provided as T and *T, filled
+                                from env vars and flags (see generate.go)
+  //autodi:flags                flags of the commands whose constructor
+                                takes it: each exported field is a cobra
+                                flag (flag, short, usage, default and env
+                                tags), filled before the command is built
+
+Struct field directive (config struct returned by a provider):
+
+  //autodi:secret <key>         fill this string field from the provider
+                                implementing Secret(ctx, key) (string, error),
+                                constructed first
+  //autodi:env <VAR>            environment variable filling this field
+                                (env:"VAR" struct tags are read too)
+
+Commands whose providers declare env variables warn at startup about unknown
+AUTODI_, APP_ or <APPNAME>_ prefixed variables.
+
+generate.go directives:
+
+  //autodi:app <name> "<short>" "<long>" [commands=a,b] [out=<file.go>]
+                                        repeat for one entrypoint per app
+                                        (default cmd/<name>/main_gen.go)
+  //autodi:group <name> []<Interface> <path>...
+                                        paths are module-relative or import
+                                        paths, which may name go.work members
+                                        (example.com/plugins); an Interface
+                                        with Schedule() string and
+                                        Run(ctx) error is a group of
+                                        jobs: a command taking the slice runs
+                                        them on tickers ("@every 5m") unless
+                                        marked //autodi:nostart, and a
+                                        *cron.Cron (robfig/cron/v3) parameter
+                                        gets them added on their cron specs
+  //autodi:exclude <path/...>
+  //autodi:precedence <rules>|none      resolve providers of the same type, in
+                                        order (default override,annotated,
+                                        internal): //autodi:override wins,
+                                        then //autodi:bind or primary, then
+                                        internal/ over pkg/; --verbose logs
+                                        the winners
+  //autodi:exclude-func <pkg.Func>      skip one constructor (package name,
+                                        module-relative or import path)
+  //autodi:layout single|multi-binary|split|library
+                                        split: main.go plus one
+                                        autodi_<command>_gen.go per command;
+                                        library: wiring/wiring_gen.go with
+                                        BuildContainer(ctx) and a Build<Cmd>
+                                        (ctx) per command, no main; with
+                                        //autodi:phase, also BuildInfra(ctx)
+                                        and BuildApp(ctx, infra)
+  //autodi:cli cobra|kong               kong: a cmd/<name> command is a struct
+                                        with Run(deps...) error; its Run
+                                        parameters are built and bound on
+                                        the parsed kong context
+  //autodi:require-version <constraint>  fail fast under another autodi, e.g.
+                                        >=v0.5 or >=0.5,<0.7; generated
+                                        headers record the version, go
+                                        directive and generate.go hash, which
+                                        --verify-header checks in CI
+  //autodi:runtime lambda <pkg.Func>    main.go constructs the handler returned
+                                        by pkg.Func and passes its Handle
+                                        method to lambda.Start
+  //autodi:output <file.go> [package]   write the entrypoint there instead of
+                                        main.go; a package other than main
+                                        exports Main() for your main to call
+  //autodi:import <module>              load providers from another module's
+                                        ` + ManifestFile + ` (see autodi export)
+  //autodi:provide <path>.<Func> ...    use a dependency's constructor as a
+                                        provider, e.g. github.com/redis/
+                                        go-redis/v9.NewClient
+  //autodi:config <pkg.Type> [prefix=P] provide a plain config struct filled
+                                        from P_FIELD_NAME env vars and
+                                        --p-field-name flags (scalar fields)
+  //autodi:replace old=<Type> new=<Type>
+                                        give every consumer of old the value
+                                        of new's provider (same type or an
+                                        implementation of the interface)
+  //autodi:constructor-prefix Provide,Make
+                                        also treat Provide*/Make* functions
+                                        as constructors (New* always is)
+  //autodi:log-level debug|info|warn|error
+                                        level of the generated default
+                                        *slog.Logger / *zap.Logger (info)
+  //autodi:comment <template>           comment above each constructor call
+                                        in init code, e.g. owner: {{.X.owner}}
+  //autodi:init-log <template>          slog.Info before each constructor
+                                        call, with its x- annotations as
+                                        attributes; templates see .Pkg,
+                                        .Path, .Func, .Type, .Field and .X
+  //autodi:error-wrap func|field|none [log]
+                                        how init returns a constructor's
+                                        error: "db.NewClient: err" (func,
+                                        default), "init EntClient: err"
+                                        (field) or as is; log also calls
+                                        slog.Error with the provider first
+  //autodi:shutdown-timeout <dur>       deadline of the ctx passed to each
+                                        Shutdown(ctx)-style cleanup (30s)
+  //autodi:exit-code-map <path>.<Err>=<code> ...
+                                        exit with code when a command's error
+                                        is the sentinel (errors.Is); else with
+                                        the ExitCode() int of an error in its
+                                        chain, else 1
+  //autodi:scan-cmd-internal            scan cmd/internal/... for providers
+                                        (not commands) like internal/
+  //autodi:parallel-init                run the constructors of each dependency
+                                        level concurrently (errgroup; needs
+                                        golang.org/x/sync in go.mod)
+  //autodi:naming package|short|full-path|<template>
+                                        Container field names: RedisxLocker
+                                        (default), Locker, InternalRedisx-
+                                        Locker, or e.g. {{.Type}}{{.Pkg}}
+  //autodi:metrics otel|prometheus      time each constructor and cleanup
+                                        call by Container field: spans
+                                        autodi.construct/shutdown <Field>,
+                                        or autodi_provider_seconds gauges
+  //autodi:allow-container-injection    constructors may take *Container, or
+                                        an interface of its Lookup/Health-
+                                        Check methods; they run after every
+                                        other provider of the command
+
+Package directives (doc comment above the package clause, e.g. doc.go):
+
+  //autodi:group <name> [[]<Interface>]   add this package to a group
+  //autodi:ignore                         contribute no providers
+  //autodi:layer <name>                   architectural layer label
+  //autodi:profile <a,b>                  only scanned with --profile a|b`,
+	},
+	{
+		name:  "groups",
+		short: "Collecting implementations into slices",
+		long: `A parameter of type []Interface is filled with every reachable provider
+whose return type implements Interface (auto-collect).
+
+Use //autodi:group in generate.go to restrict a slice to providers under
+a specific path; grouped providers are not registered as singletons.
+A constructor anywhere in the tree joins a declared group with its own
+//autodi:group <name> directive; members from both sources are merged.
+
+In a go.work workspace every member module is scanned, so a group can
+collect implementations of a shared interface from several modules:
+
+  //autodi:group plugins []plugins.Plugin internal/plugins example.com/extras
+
+Paths are module-relative (../extras/auth reaches a member by directory) or
+import paths of the module and its members; a member package can also join
+with //autodi:group plugins in its doc comment. Members are ordered by
+//autodi:order, then import path, whichever module they come from.
+
+The Interface may belong to a third-party module no scanned package
+imports; it is loaded for the group when named by import path or by the
+package name of a module required in go.mod:
+
+  //autodi:group handlers []github.com/go-chi/chi/v5.Router internal/http/handlers
+  //autodi:group handlers []chi.Router internal/http/handlers
+
+A parameter of type map[string]Interface collects the same providers into a
+map for keyed lookup, e.g. a registry of payment methods. Each is stored
+under the //autodi:key of its constructor, else under the Name() string
+method of its result; a member with neither, or two with the same key, is
+an error:
+
+  //autodi:key stripe
+  func NewStripe(cfg *Config) *Stripe
+
+  func NewCheckout(methods map[string]payment.Method) *Checkout`,
+	},
+}
@@ -0,0 +1,130 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"time"
+)
+
+// defaultShutdownTimeout bounds Shutdown(ctx)-style cleanup when generate.go
+// has no //autodi:shutdown-timeout.
+const defaultShutdownTimeout = 30 * time.Second
+
+// shutdownTimeout is the deadline of the context passed to cleanup methods
+// taking one.
+func (cfg *Config) shutdownTimeout() time.Duration {
+	if cfg.ShutdownTimeout > 0 {
+		return cfg.ShutdownTimeout
+	}
+	return defaultShutdownTimeout
+}
+
+// durationSource renders a duration as a Go expression, e.g. "10 * time.Second".
+func durationSource(d time.Duration, timeQualifier string) string {
+	for _, unit := range []struct {
+		d    time.Duration
+		name string
+	}{{time.Hour, "Hour"}, {time.Minute, "Minute"}, {time.Second, "Second"}, {time.Millisecond, "Millisecond"}} {
+		if d%unit.d == 0 {
+			return fmt.Sprintf("%d * %s.%s", d/unit.d, timeQualifier, unit.name)
+		}
+	}
+	return fmt.Sprintf("%s.Duration(%d)", timeQualifier, int64(d))
+}
+
+// writeCleanupCall emits the call releasing cl. A method taking a context
+// gets its own shutdown timeout, so one slow cleanup doesn't eat into the
+// next one's.
+func (cg *CodeGen) writeCleanupCall(buf *bytes.Buffer, cl *CloseableField, ctxVar, cancelVar, indent string) {
+	if !cl.HasCtx {
+		fmt.Fprintf(buf, "%s%s.%s()\n", indent, cl.VarName, cl.Method)
+		return
+	}
+	contextQualifier := cg.imports.Add("context", "context")
+	timeQualifier := cg.imports.Add("time", "time")
+	fmt.Fprintf(buf, "%s%s, %s := %s.WithTimeout(%s.Background(), %s)\n", indent, ctxVar, cancelVar,
+		contextQualifier, contextQualifier, durationSource(cg.cfg.shutdownTimeout(), timeQualifier))
+	fmt.Fprintf(buf, "%s%s.%s(%s)\n", indent, cl.VarName, cl.Method, ctxVar)
+	fmt.Fprintf(buf, "%s%s()\n", indent, cancelVar)
+}
+
+// cleanupAppend renders the statement registering cl on the cleanups field of
+// recv, e.g. "c" for the library Container or "scope" for a RequestScope.
+func (cg *CodeGen) cleanupAppend(recv string, cl *CloseableField) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "\t%s.cleanups = append(%s.cleanups, func() {\n", recv, recv)
+	fmt.Fprintf(&buf, "\t\tif %s != nil {\n", cl.VarName)
+	used := map[string]bool{cl.VarName: true}
+	cg.writeCleanupCall(&buf, cl, cg.uniqueLocalVar("shutdownCtx", used), cg.uniqueLocalVar("cancel", used), "\t\t\t")
+	buf.WriteString("\t\t}\n\t})\n")
+	return buf.String()
+}
+
+// CloseableField records a field with a cleanup method.
+type CloseableField struct {
+	VarName string
+	Field   string // Container field of the value, for //autodi:metrics
+	Method  string // "Close", "Shutdown", "Stop"
+	HasCtx  bool   // method takes context.Context as first param
+}
+
+// checkCloseable checks if a type has Close, Shutdown, or Stop methods.
+func checkCloseable(t types.Type, varName string) *CloseableField {
+	mset := types.NewMethodSet(t)
+
+	for _, methodName := range []string{"Close", "Shutdown", "Stop"} {
+		for i := 0; i < mset.Len(); i++ {
+			method := mset.At(i)
+			if method.Obj().Name() != methodName {
+				continue
+			}
+			sig, ok := method.Type().(*types.Signature)
+			if !ok {
+				continue
+			}
+
+			params := sig.Params()
+			hasCtx := false
+
+			switch params.Len() {
+			case 0:
+				// No params — valid cleanup method
+			case 1:
+				if isContextType(params.At(0).Type()) {
+					hasCtx = true
+				} else {
+					continue
+				}
+			default:
+				continue
+			}
+
+			return &CloseableField{
+				VarName: varName,
+				Method:  methodName,
+				HasCtx:  hasCtx,
+			}
+		}
+	}
+	return nil
+}
+
+// isContextType checks if a type is context.Context.
+func isContextType(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "context" && obj.Name() == "Context"
+}
+
+// isNilable checks if a type can be compared to nil.
+func isNilable(t types.Type) bool {
+	switch t.Underlying().(type) {
+	case *types.Pointer, *types.Interface, *types.Map, *types.Slice, *types.Chan:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Handler options: //autodi:cmd on a handler method sets fields of the cobra
+// command it runs, so CLI ergonomics live next to the handler instead of in
+// a hand-written Command():
+//
+//	//autodi:cmd alias=ls,l group=management hidden
+//	func (p *Pool) List(cmd *cobra.Command) error
+//
+// alias= adds Aliases, group= lists the command under a help group of its
+// parent, declaring the group when the parent doesn't, and hidden sets
+// Hidden. On Handle they apply to the command itself.
+
+// hasOptions reports whether the handler has //autodi:cmd options.
+func (h HandlerInfo) hasOptions() bool {
+	return len(h.Aliases) > 0 || h.Group != "" || h.Hidden
+}
+
+// applyHandlerDirectives reads //autodi:cmd on the handler methods of a
+// command's struct.
+func applyHandlerDirectives(pkg *packages.Package, cmd *DiscoveredCommand) error {
+	for i := range cmd.Handlers {
+		h := &cmd.Handlers[i]
+		fn := methodDecl(pkg, cmd.StructName, h.MethodName)
+		if fn == nil {
+			continue
+		}
+		for _, value := range GetAnnotationValues(ParseAnnotations(fn), AnnotCmd) {
+			for _, opt := range strings.Fields(value) {
+				key, val, _ := strings.Cut(opt, "=")
+				switch {
+				case key == "alias" && val != "":
+					h.Aliases = append(h.Aliases, strings.Split(val, ",")...)
+				case key == "group" && val != "":
+					h.Group = val
+				case opt == "hidden":
+					h.Hidden = true
+				default:
+					return fmt.Errorf("%s: %s.%s: //autodi:cmd: unknown handler option %q\n  hint: options are alias=<a,b>, group=<id> and hidden",
+						cmd.Dir, cmd.StructName, h.MethodName, opt)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// methodDecl finds the declaration of a method of a package's type.
+func methodDecl(pkg *packages.Package, typeName, name string) *ast.FuncDecl {
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || len(fn.Recv.List) == 0 || fn.Name.Name != name {
+				continue
+			}
+			recv := fn.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			if ident, ok := recv.(*ast.Ident); ok && ident.Name == typeName {
+				return fn
+			}
+		}
+	}
+	return nil
+}
+
+// groupTitle returns the help heading of a handler option group.
+func groupTitle(id string) string {
+	return strings.ToUpper(id[:1]) + id[1:] + " Commands:"
+}
+
+// writeHandlerOptions emits the //autodi:cmd options of a handler for the
+// cobra command in cmdVar, which must already have its parent.
+func (cg *CodeGen) writeHandlerOptions(buf *bytes.Buffer, indent, cmdVar, cobraQualifier string, h HandlerInfo) {
+	if len(h.Aliases) > 0 {
+		var quoted []string
+		for _, alias := range h.Aliases {
+			quoted = append(quoted, fmt.Sprintf("%q", alias))
+		}
+		fmt.Fprintf(buf, "%s%s.Aliases = append(%s.Aliases, %s)\n", indent, cmdVar, cmdVar, strings.Join(quoted, ", "))
+	}
+	if h.Group != "" {
+		fmt.Fprintf(buf, "%sif !%s.Parent().ContainsGroup(%q) {\n", indent, cmdVar, h.Group)
+		fmt.Fprintf(buf, "%s\t%s.Parent().AddGroup(&%s.Group{ID: %q, Title: %q})\n", indent, cmdVar, cobraQualifier, h.Group, groupTitle(h.Group))
+		fmt.Fprintf(buf, "%s}\n", indent)
+		fmt.Fprintf(buf, "%s%s.GroupID = %q\n", indent, cmdVar, h.Group)
+	}
+	if h.Hidden {
+		fmt.Fprintf(buf, "%s%s.Hidden = true\n", indent, cmdVar)
+	}
+}
+
+// writeHandlerWiring emits the wireRunE call connecting a handler of a
+// multi-subcommand struct, and its options when withOptions is set.
+func (cg *CodeGen) writeHandlerWiring(buf *bytes.Buffer, indent, treeVar, instVar, cobraQualifier string, h HandlerInfo, withOptions bool) {
+	call := fmt.Sprintf("wireRunE(%s, %q, %s.%s)", treeVar, pascalToKebab(h.MethodName), instVar, h.MethodName)
+	if !withOptions || !h.hasOptions() {
+		fmt.Fprintf(buf, "%s%s\n", indent, call)
+		return
+	}
+	fmt.Fprintf(buf, "%sif sub := %s; sub != nil {\n", indent, call)
+	cg.writeHandlerOptions(buf, indent+"\t", "sub", cobraQualifier, h)
+	fmt.Fprintf(buf, "%s}\n", indent)
+}
+
+// handleOptions returns the Handle handler of a single command, carrying its
+// options.
+func handleOptions(cmd *DiscoveredCommand) HandlerInfo {
+	for _, h := range cmd.Handlers {
+		if h.MethodName == "Handle" {
+			return h
+		}
+	}
+	return HandlerInfo{}
+}
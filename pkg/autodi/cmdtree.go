@@ -0,0 +1,131 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ChildCommand is a command nested under the command whose constructor takes
+// its struct, e.g. NewAdmin(users *userscmd.Users, billing *billingcmd.Billing).
+type ChildCommand struct {
+	Index int // position of the parameter in the parent constructor
+	Cmd   *DiscoveredCommand
+}
+
+// linkCommandTree turns constructor parameters that are other commands'
+// structs into parent/child links. Children are registered under their
+// parent's cobra command and constructed with it, so only top-level commands
+// have init functions.
+func linkCommandTree(cfg *Config, commands []*DiscoveredCommand) error {
+	byType := make(map[string]*DiscoveredCommand, len(commands))
+	for _, cmd := range commands {
+		byType["*"+cmd.PkgPath+"."+cmd.StructName] = cmd
+	}
+
+	for _, cmd := range commands {
+		var params []TypeRef
+		for i, param := range cmd.Params {
+			child, ok := byType[param.TypeStr]
+			if !ok {
+				params = append(params, param)
+				continue
+			}
+			if child.Parent != nil {
+				return fmt.Errorf("command %s is composed by both %s and %s\n  hint: a command can have only one parent",
+					child.Name, child.Parent.Name, cmd.Name)
+			}
+			if child.BuildTag != cmd.BuildTag {
+				return fmt.Errorf("command %s: child command %s has a different //autodi:cmd buildtag\n  hint: nested commands share their parent's build constraint",
+					cmd.Name, child.Name)
+			}
+			if cfg.Layout == LayoutMultiBinary {
+				return fmt.Errorf("command %s composes command %s, which the %s layout can't nest\n  hint: use //autodi:layout %s",
+					cmd.Name, child.Name, LayoutMultiBinary, LayoutSingle)
+			}
+			child.Parent = cmd
+			cmd.Children = append(cmd.Children, ChildCommand{Index: i, Cmd: child})
+		}
+		cmd.Params = params
+	}
+
+	for _, cmd := range commands {
+		depth := 0
+		for p := cmd.Parent; p != nil; p = p.Parent {
+			if depth++; depth > len(commands) {
+				return fmt.Errorf("command %s: nested commands form a cycle through their constructor parameters", cmd.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// allParams returns the constructor parameters of a command and of its child
+// commands, depth first: everything its init function has to provide.
+func (dc *DiscoveredCommand) allParams() []TypeRef {
+	params := dc.Params
+	for _, child := range dc.Children {
+		params = append(slices.Clip(params), child.Cmd.allParams()...)
+	}
+	return params
+}
+
+// builtCommand is a constructed child command instance and its own children.
+type builtCommand struct {
+	cmd      *DiscoveredCommand
+	instVar  string
+	children []builtCommand
+}
+
+// commandCall returns the constructor call of a command; args are the
+// arguments of its own parameters, the children's instances are inserted at
+// their parameter positions.
+func (cg *CodeGen) commandCall(cmd *DiscoveredCommand, qualifier string, args []string, children []builtCommand) string {
+	args = slices.Clone(args)
+	for i, child := range cmd.Children {
+		args = slices.Insert(args, child.Index, children[i].instVar)
+	}
+	return fmt.Sprintf("%s(%s)", qualifiedName(qualifier, cmd.FuncName), strings.Join(args, ", "))
+}
+
+// writeChildCommands constructs the child commands of cmd, depth first;
+// argsOf gives the arguments of a command's own parameters.
+func (cg *CodeGen) writeChildCommands(buf *bytes.Buffer, indent string, cmd *DiscoveredCommand, argsOf func(*DiscoveredCommand) []string, usedVars map[string]bool) []builtCommand {
+	var built []builtCommand
+	for _, child := range cmd.Children {
+		children := cg.writeChildCommands(buf, indent, child.Cmd, argsOf, usedVars)
+		v := cg.uniqueLocalVar(localVarName(cmdExportName(child.Cmd.Name)), usedVars)
+		alias := cg.imports.AddWithAlias(child.Cmd.PkgPath, child.Cmd.PkgName+"cmd")
+		fmt.Fprintf(buf, "%s%s := %s\n", indent, v, cg.commandCall(child.Cmd, alias, argsOf(child.Cmd), children))
+		built = append(built, builtCommand{cmd: child.Cmd, instVar: v, children: children})
+	}
+	return built
+}
+
+// writeChildWiring adds the cobra commands of built children under parentVar
+// and connects their handlers; withFlags also registers their flag fields and
+// handler options, for the tree cobra parses. components, nil for that tree,
+// holds the components each child runs alongside its handlers.
+func (cg *CodeGen) writeChildWiring(buf *bytes.Buffer, indent, parentVar string, built []builtCommand, usedVars map[string]bool, cobraQualifier string, withFlags bool, components map[*DiscoveredCommand][]Component) {
+	for _, b := range built {
+		cmdVar := cg.uniqueLocalVar(b.instVar+"Cmd", usedVars)
+		fmt.Fprintf(buf, "%s%s := %s.Command()\n", indent, cmdVar, b.instVar)
+		if withFlags {
+			cg.writeFlagRegistration(buf, indent, cmdVar, b.instVar, b.cmd)
+		}
+		if b.cmd.IsSingle {
+			fmt.Fprintf(buf, "%s%s.RunE = func(c *%s.Command, _ []string) error { return %s.Handle(c) }\n", indent, cmdVar, cobraQualifier, b.instVar)
+		} else {
+			for _, h := range b.cmd.Handlers {
+				cg.writeHandlerWiring(buf, indent, cmdVar, b.instVar, cobraQualifier, h, withFlags)
+			}
+		}
+		cg.writeStartComponents(buf, cmdVar, components[b.cmd])
+		cg.writeChildWiring(buf, indent, cmdVar, b.children, usedVars, cobraQualifier, withFlags, components)
+		fmt.Fprintf(buf, "%s%s.AddCommand(%s)\n", indent, parentVar, cmdVar)
+		if b.cmd.IsSingle && withFlags {
+			cg.writeHandlerOptions(buf, indent, cmdVar, cobraQualifier, handleOptions(b.cmd))
+		}
+	}
+}
@@ -0,0 +1,1505 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/types"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// generatedMarker identifies files written by autodi, which are never
+// scanned, hashed into the cache key or merged as hand-written code.
+const generatedMarker = "Code generated by autodi"
+
+// generatedHeader opens every generated Go file: the marker and the version
+// stamp.
+func (cg *CodeGen) generatedHeader() string {
+	return "// " + generatedMarker + ", DO NOT EDIT.\n" + versionStamp(cg.cfg) + "\n"
+}
+
+// renderFile assembles a generated Go file and formats it: the header, a
+// preamble (build constraint or package doc), the package clause, the
+// imports collected so far, then the non-empty parts separated by blank
+// lines. A format error carries the unformatted source.
+func (cg *CodeGen) renderFile(name, preamble, pkg string, parts ...[]byte) (GeneratedFile, error) {
+	var full bytes.Buffer
+	full.WriteString(cg.generatedHeader())
+	full.WriteString(preamble)
+	fmt.Fprintf(&full, "package %s\n\n", pkg)
+	full.WriteString(cg.imports.FormatBlock())
+	for _, part := range parts {
+		if len(part) == 0 {
+			continue
+		}
+		full.WriteString("\n")
+		full.Write(part)
+	}
+
+	src, err := format.Source(full.Bytes())
+	if err != nil {
+		return GeneratedFile{Name: name, Content: full.Bytes()},
+			fmt.Errorf("format %s: %w\n--- source ---\n%s", name, err, full.String())
+	}
+	return GeneratedFile{Name: name, Content: src}, nil
+}
+
+// buildConstraint returns the //go:build line of a preamble, "" for none.
+func buildConstraint(expr string) string {
+	if expr == "" {
+		return ""
+	}
+	return "//go:build " + expr + "\n\n"
+}
+
+// GeneratedFile represents a file to be written.
+type GeneratedFile struct {
+	Name    string
+	Content []byte
+}
+
+// CodeGen orchestrates code generation.
+type CodeGen struct {
+	cfg        *Config
+	graph      *Graph
+	commands   []*DiscoveredCommand
+	moduleRoot string
+	imports    *ImportManager
+
+	hasContainer bool // current file needs the Container type
+	hasEnvCheck  bool // current file needs checkEnv
+
+	hasResourceAttrs bool // current file needs addResourceAttributes
+	hasInitProfile   bool // current file needs initProfile
+	hasComponents    bool // current file needs runWithComponents
+	hasFlagCopy      bool // current file needs copyFlag
+	hasFlagValue     bool // current file needs flagValue
+	hasFlagEnv       bool // current file needs flagFromEnv
+	hasMetrics       bool // current file needs wiringMetrics
+	hasJobTicker     bool // current file needs jobTicker
+
+	configLoaders map[*Provider]bool // //autodi:config loaders called in the current file
+	routeMux      *Provider          // //autodi:route mux provider called in the current file
+	jobCron       *Provider          // cron of the groups of jobs called in the current file
+	loggers       map[*Provider]bool // generated default loggers called in the current file
+	adapters      map[string]bool    // group interfaces with //autodi:adapt members in the current file
+
+	profVar    string // initProfile variable of the init function being written
+	metricsVar string // wiringMetrics variable of the init function being written
+
+	memberFail *failReturn // how a failing member returns in the library or Lambda builder being written; nil: return nil, err
+	unresolved []string    // parameters buildLocalArgs had no value for, "<type> of <pkg>.<Func>"
+}
+
+// NewCodeGen creates a code generator.
+func NewCodeGen(cfg *Config, graph *Graph, commands []*DiscoveredCommand, moduleRoot string) *CodeGen {
+	return &CodeGen{
+		cfg:        cfg,
+		graph:      graph,
+		commands:   commands,
+		moduleRoot: moduleRoot,
+		imports:    NewImportManager(cfg.Module),
+	}
+}
+
+// Generate produces the main.go file (one entrypoint per //autodi:app when
+// there are several, one main_gen.go per command in the multi-binary layout,
+// or the wiring package in the library layout), an interactive DI diagram,
+// and a package diagram.
+func (cg *CodeGen) Generate() ([]GeneratedFile, error) {
+	var mains []GeneratedFile
+	if cg.cfg.Layout != LayoutSingle && cg.cfg.CLI == CLIKong {
+		return nil, fmt.Errorf("//autodi:cli %s generates one entrypoint file; use //autodi:layout %s", CLIKong, LayoutSingle)
+	}
+	if cg.cfg.Runtime == RuntimeLambda {
+		f, err := cg.generateLambdaMain()
+		if err != nil {
+			return nil, err
+		}
+		mains = append(mains, f)
+	} else if cg.cfg.Layout == LayoutLibrary {
+		f, err := cg.generateLibrary()
+		if err != nil {
+			return nil, err
+		}
+		mains = append(mains, f)
+	} else if cg.cfg.Layout == LayoutMultiBinary {
+		for _, cmd := range cg.commands {
+			f, err := cg.generateBinaryMain(cmd)
+			if err != nil {
+				return nil, err
+			}
+			mains = append(mains, f)
+		}
+	} else {
+		fs, err := cg.generateApps()
+		if err != nil {
+			return nil, err
+		}
+		mains = append(mains, fs...)
+	}
+	diGraph := GeneratedFile{
+		Name:    filepath.FromSlash(cg.cfg.appPath("dependency-graph.html")),
+		Content: renderDIHTML(cg.graph, cg.commands, cg.cfg),
+	}
+
+	pkgContent, err := generatePkgDiagram(cg.cfg, cg.moduleRoot)
+	if err != nil {
+		pkgContent = []byte(fmt.Sprintf(
+			"<html><body><pre>autodi: package diagram failed: %v</pre></body></html>", err))
+	}
+	pkgDiag := GeneratedFile{
+		Name:    filepath.FromSlash(cg.cfg.appPath("package-diagram.html")),
+		Content: pkgContent,
+	}
+
+	tests, err := cg.generateTestContainers()
+	if err != nil {
+		return nil, fmt.Errorf("test container: %w", err)
+	}
+	mains = append(mains, tests...)
+
+	return append(mains, diGraph, pkgDiag), nil
+}
+
+// generateMain generates the complete entrypoint (main.go unless set with
+// //autodi:output) with two-phase DI, followed by one file per build tag of
+// //autodi:cmd buildtag= commands and, in the split layout, one file per DI
+// command.
+func (cg *CodeGen) generateMain() ([]GeneratedFile, error) {
+	cg.imports.Reset()
+	cg.hasContainer = false
+	cg.hasEnvCheck = false
+	cg.hasResourceAttrs = false
+	cg.hasInitProfile = false
+	cg.hasComponents = false
+	cg.hasFlagCopy = false
+	cg.hasFlagValue = false
+	cg.hasFlagEnv = false
+	cg.hasMetrics = false
+	cg.hasJobTicker = false
+	cg.configLoaders = make(map[*Provider]bool)
+	cg.routeMux = nil
+	cg.jobCron = nil
+	cg.loggers = make(map[*Provider]bool)
+	cg.adapters = make(map[string]bool)
+	if cg.cfg.CLI == CLIKong {
+		return cg.generateKongMain()
+	}
+
+	// Commands behind a build constraint go into their own files
+	plain, tagged := splitTaggedCommands(cg.commands)
+	var files []GeneratedFile
+	for _, tag := range sortedKeys(tagged) {
+		f, err := cg.generateTaggedCommands(tag, tagged[tag])
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	// The split layout writes each command's init function to its own file
+	if cg.cfg.Layout == LayoutSplit {
+		for _, cmd := range plain {
+			if !cmd.HasDeps() {
+				continue
+			}
+			f, err := cg.generateCommandFile(cmd)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, f)
+		}
+	}
+	cg.imports.Reset()
+
+	// Pre-register cmd package imports with predictable aliases
+	cmdAliases := make(map[string]string) // pkgPath → alias
+	for _, cmd := range plain {
+		alias := cmd.PkgName + "cmd"
+		cmdAliases[cmd.PkgPath] = cg.imports.AddWithAlias(cmd.PkgPath, alias)
+	}
+
+	// We'll build the main function body and init functions separately,
+	// then combine them. First, generate all init functions to discover imports.
+	var initBuf bytes.Buffer
+	for _, cmd := range plain {
+		if !cmd.HasDeps() || cg.cfg.Layout == LayoutSplit {
+			continue
+		}
+		if err := cg.generateInitFunc(&initBuf, cmd, cmdAliases[cmd.PkgPath]); err != nil {
+			return nil, fmt.Errorf("generate init for %s: %w", cmd.Name, err)
+		}
+		initBuf.WriteString("\n")
+	}
+
+	// Now generate the main function
+	var mainBuf bytes.Buffer
+
+	// main function
+	cg.imports.Add("os", "os")
+	cobraQualifier := cg.imports.Add("github.com/spf13/cobra", "cobra")
+
+	// A wiring package outside main exports Main for the real entrypoint to call
+	if cg.cfg.Package == "main" {
+		mainBuf.WriteString("func main() {\n")
+	} else {
+		mainBuf.WriteString("// Main builds the command tree and runs it. Call it from the program's main.\n")
+		mainBuf.WriteString("func Main() {\n")
+	}
+
+	// Root command
+	fmt.Fprintf(&mainBuf, "\troot := &%s.Command{Use: %q, Short: %q", cobraQualifier, cg.cfg.AppName, cg.cfg.AppShort)
+	if cg.cfg.AppLong != "" {
+		fmt.Fprintf(&mainBuf, ", Long: %q", cg.cfg.AppLong)
+	}
+	mainBuf.WriteString("}\n\n")
+
+	// Init function map (for DI commands)
+	hasDI := false
+	for _, cmd := range cg.commands {
+		if cmd.HasDeps() {
+			hasDI = true
+			break
+		}
+	}
+
+	if hasDI || len(tagged) > 0 {
+		if len(tagged) == 0 {
+			fmt.Fprintf(&mainBuf, "\ttype initFunc func(cmd, top *%s.Command) (func(), error)\n", cobraQualifier)
+		}
+		fmt.Fprintf(&mainBuf, "\tinitFuncs := make(map[*%s.Command]initFunc)\n\n", cobraQualifier)
+	}
+
+	// Register all commands
+	for _, cmd := range plain {
+		cg.writeCommandRegistration(&mainBuf, cmd, cmdAliases[cmd.PkgPath], cobraQualifier)
+	}
+	if len(tagged) > 0 {
+		mainBuf.WriteString("\tfor _, register := range taggedCommands {\n")
+		mainBuf.WriteString("\t\tregister(root, initFuncs)\n")
+		mainBuf.WriteString("\t}\n")
+	}
+	if cg.cfg.Inspect && hasDI {
+		cg.writeInspectCommand(&mainBuf, cobraQualifier)
+	}
+
+	// PersistentPreRunE / PostRunE
+	if hasDI {
+		mainBuf.WriteString("\n\tvar cleanup func()\n")
+		fmt.Fprintf(&mainBuf, "\troot.PersistentPreRunE = func(cmd *%s.Command, args []string) error {\n", cobraQualifier)
+		mainBuf.WriteString("\t\ttop := cmd\n")
+		mainBuf.WriteString("\t\tfor top.HasParent() && top.Parent().HasParent() {\n")
+		mainBuf.WriteString("\t\t\ttop = top.Parent()\n")
+		mainBuf.WriteString("\t\t}\n")
+		mainBuf.WriteString("\t\tif fn, ok := initFuncs[top]; ok {\n")
+		mainBuf.WriteString("\t\t\tvar err error\n")
+		mainBuf.WriteString("\t\t\tcleanup, err = fn(cmd, top)\n")
+		mainBuf.WriteString("\t\t\treturn err\n")
+		mainBuf.WriteString("\t\t}\n")
+		mainBuf.WriteString("\t\treturn nil\n")
+		mainBuf.WriteString("\t}\n")
+		fmt.Fprintf(&mainBuf, "\troot.PersistentPostRunE = func(cmd *%s.Command, args []string) error {\n", cobraQualifier)
+		mainBuf.WriteString("\t\tif cleanup != nil {\n")
+		mainBuf.WriteString("\t\t\tcleanup()\n")
+		mainBuf.WriteString("\t\t}\n")
+		mainBuf.WriteString("\t\treturn nil\n")
+		mainBuf.WriteString("\t}\n")
+	}
+
+	if len(cg.configLoaders) > 0 {
+		mainBuf.WriteString("\n\troot.PersistentFlags().AddFlagSet(configFlags)\n")
+	}
+
+	mainBuf.WriteString("\n\tif err := root.Execute(); err != nil {\n")
+	mainBuf.WriteString("\t\tos.Exit(exitCode(err))\n")
+	mainBuf.WriteString("\t}\n")
+	mainBuf.WriteString("}\n")
+
+	// Generate helper functions
+	var helperBuf bytes.Buffer
+	cg.writeRuntimeHelpers(&helperBuf, cobraQualifier, hasDI)
+	helperBuf.WriteString("\n")
+	cg.writeExitCodeHelper(&helperBuf)
+	if len(tagged) > 0 {
+		helperBuf.WriteString("\n")
+		cg.writeTaggedCommandsVar(&helperBuf, cobraQualifier)
+	}
+	if cg.hasContainer {
+		helperBuf.WriteString("\n")
+		cg.writeContainerType(&helperBuf)
+	}
+	if cg.hasEnvCheck {
+		helperBuf.WriteString("\n")
+		cg.writeEnvCheck(&helperBuf)
+	}
+	if cg.hasResourceAttrs {
+		helperBuf.WriteString("\n")
+		cg.writeResourceAttrsHelper(&helperBuf)
+	}
+	if cg.hasInitProfile {
+		helperBuf.WriteString("\n")
+		cg.writeInitProfileHelper(&helperBuf)
+	}
+	if cg.hasMetrics {
+		helperBuf.WriteString("\n")
+		cg.writeMetricsHelper(&helperBuf)
+	}
+	if cg.hasComponents {
+		helperBuf.WriteString("\n")
+		cg.writeComponentsHelper(&helperBuf, cobraQualifier)
+	}
+	if cg.hasFlagCopy {
+		helperBuf.WriteString("\n")
+		cg.writeFlagCopyHelper(&helperBuf, cobraQualifier)
+	}
+	if cg.hasFlagValue || cg.hasFlagEnv {
+		helperBuf.WriteString("\n")
+		cg.writeFlagStructHelpers(&helperBuf)
+	}
+	if len(cg.configLoaders) > 0 {
+		helperBuf.WriteString("\n")
+		cg.writeConfigLoaders(&helperBuf)
+	}
+	if cg.routeMux != nil {
+		helperBuf.WriteString("\n")
+		if err := cg.writeRouteMux(&helperBuf, cg.routeMux); err != nil {
+			return nil, err
+		}
+	}
+	if err := cg.writeJobs(&helperBuf); err != nil {
+		return nil, err
+	}
+	if len(cg.loggers) > 0 {
+		helperBuf.WriteString("\n")
+		cg.writeDefaultLoggers(&helperBuf)
+	}
+	if len(cg.adapters) > 0 {
+		helperBuf.WriteString("\n")
+		cg.writeAdapters(&helperBuf)
+	}
+
+	f, err := cg.renderFile(filepath.FromSlash(cg.cfg.Output), "", cg.cfg.Package, mainBuf.Bytes(), initBuf.Bytes(), helperBuf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return append([]GeneratedFile{f}, files...), nil
+}
+
+// writeCommandRegistration emits the block that constructs a command's stub
+// and adds its tree to root, registering its init function for DI commands.
+func (cg *CodeGen) writeCommandRegistration(buf *bytes.Buffer, cmd *DiscoveredCommand, alias, cobraQualifier string) {
+	exportName := cmdExportName(cmd.Name)
+
+	// Generate zero-value args for constructor
+	zeroArgsOf := func(c *DiscoveredCommand) []string {
+		var args []string
+		for _, param := range c.Params {
+			if param.Stream == flagsStream {
+				args = append(args, cg.zeroValue(param))
+				continue
+			}
+			args = append(args, zeroValueForType(param.Type))
+		}
+		return args
+	}
+
+	buf.WriteString("\t" + regionBegin(cmd.Name))
+	buf.WriteString("\t{\n")
+	usedVars := map[string]bool{"stub": true, "cmd": true, "tree": true, "root": true}
+	children := cg.writeChildCommands(buf, "\t\t", cmd, zeroArgsOf, usedVars)
+	fmt.Fprintf(buf, "\t\tstub := %s\n", cg.commandCall(cmd, alias, zeroArgsOf(cmd), children))
+
+	if cmd.IsSingle {
+		// Single command: Command() + direct RunE → Handle
+		buf.WriteString("\t\tcmd := stub.Command()\n")
+		cg.writeFlagRegistration(buf, "\t\t", "cmd", "stub", cmd)
+		fmt.Fprintf(buf, "\t\tcmd.RunE = func(c *%s.Command, _ []string) error { return stub.Handle(c) }\n", cobraQualifier)
+		cg.writeChildWiring(buf, "\t\t", "cmd", children, usedVars, cobraQualifier, true, nil)
+		buf.WriteString("\t\troot.AddCommand(cmd)\n")
+		cg.writeHandlerOptions(buf, "\t\t", "cmd", cobraQualifier, handleOptions(cmd))
+		if cmd.HasDeps() {
+			fmt.Fprintf(buf, "\t\tinitFuncs[cmd] = init%s\n", exportName)
+		}
+	} else {
+		// Multi-subcommand: Command() + wireRunE for each handler
+		buf.WriteString("\t\ttree := stub.Command()\n")
+		cg.writeFlagRegistration(buf, "\t\t", "tree", "stub", cmd)
+		for _, h := range cmd.Handlers {
+			cg.writeHandlerWiring(buf, "\t\t", "tree", "stub", cobraQualifier, h, true)
+		}
+		cg.writeChildWiring(buf, "\t\t", "tree", children, usedVars, cobraQualifier, true, nil)
+		buf.WriteString("\t\troot.AddCommand(tree)\n")
+		if cmd.HasDeps() {
+			fmt.Fprintf(buf, "\t\tinitFuncs[tree] = init%s\n", exportName)
+		}
+	}
+
+	buf.WriteString("\t}\n")
+	buf.WriteString("\t" + regionEnd(cmd.Name))
+}
+
+// writeRuntimeHelpers emits wireRunE (always) and swapRunE/relativePath (DI only),
+// the small runtime helpers shared by every generated entry point.
+func (cg *CodeGen) writeRuntimeHelpers(buf *bytes.Buffer, cobraQualifier string, hasDI bool) {
+	// wireRunE — always needed (all commands use it for handler wiring)
+	cg.imports.Add("strings", "strings")
+	buf.WriteString("// wireRunE connects a handler method to a subcommand's RunE by kebab-case name,\n")
+	buf.WriteString("// returning the subcommand, or nil when none matches.\n")
+	buf.WriteString("// For nested commands, the name segments form a path (e.g. \"pool-list\" matches pool→list).\n")
+	fmt.Fprintf(buf, "func wireRunE(parent *%s.Command, name string, handler func(*%s.Command) error) *%s.Command {\n", cobraQualifier, cobraQualifier, cobraQualifier)
+	buf.WriteString("\t// Try exact match first (direct child)\n")
+	buf.WriteString("\tfor _, sub := range parent.Commands() {\n")
+	buf.WriteString("\t\tif sub.Name() == name {\n")
+	buf.WriteString("\t\t\th := handler\n")
+	fmt.Fprintf(buf, "\t\t\tsub.RunE = func(cmd *%s.Command, _ []string) error { return h(cmd) }\n", cobraQualifier)
+	buf.WriteString("\t\t\treturn sub\n")
+	buf.WriteString("\t\t}\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\t// Try path-based match: split name by \"-\" and greedily match child commands\n")
+	buf.WriteString("\tparts := strings.Split(name, \"-\")\n")
+	buf.WriteString("\treturn wireRunEPath(parent, parts, handler)\n")
+	buf.WriteString("}\n\n")
+
+	fmt.Fprintf(buf, "func wireRunEPath(parent *%s.Command, parts []string, handler func(*%s.Command) error) *%s.Command {\n", cobraQualifier, cobraQualifier, cobraQualifier)
+	buf.WriteString("\tif len(parts) == 0 {\n")
+	buf.WriteString("\t\treturn nil\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\t// Try progressively longer prefixes as the child command name\n")
+	buf.WriteString("\tfor i := 1; i <= len(parts); i++ {\n")
+	buf.WriteString("\t\tcandidate := strings.Join(parts[:i], \"-\")\n")
+	buf.WriteString("\t\tfor _, sub := range parent.Commands() {\n")
+	buf.WriteString("\t\t\tif sub.Name() != candidate {\n")
+	buf.WriteString("\t\t\t\tcontinue\n")
+	buf.WriteString("\t\t\t}\n")
+	buf.WriteString("\t\t\tif i == len(parts) {\n")
+	buf.WriteString("\t\t\t\t// Leaf match\n")
+	buf.WriteString("\t\t\t\th := handler\n")
+	fmt.Fprintf(buf, "\t\t\t\tsub.RunE = func(cmd *%s.Command, _ []string) error { return h(cmd) }\n", cobraQualifier)
+	buf.WriteString("\t\t\t\treturn sub\n")
+	buf.WriteString("\t\t\t}\n")
+	buf.WriteString("\t\t\t// Try remaining parts as deeper path\n")
+	buf.WriteString("\t\t\tif leaf := wireRunEPath(sub, parts[i:], handler); leaf != nil {\n")
+	buf.WriteString("\t\t\t\treturn leaf\n")
+	buf.WriteString("\t\t\t}\n")
+	buf.WriteString("\t\t}\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\treturn nil\n")
+	buf.WriteString("}\n")
+
+	// swapRunE + relativePath — only needed for DI commands
+	if hasDI {
+		buf.WriteString("\n// swapRunE replaces the executing command's RunE with the real one from the DI-built tree.\n")
+		fmt.Fprintf(buf, "func swapRunE(executing, stubTop, realTop *%s.Command) {\n", cobraQualifier)
+		buf.WriteString("\tpath := relativePath(executing, stubTop)\n")
+		buf.WriteString("\ttarget := realTop\n")
+		buf.WriteString("\tfor _, name := range path {\n")
+		buf.WriteString("\t\tfor _, sub := range target.Commands() {\n")
+		buf.WriteString("\t\t\tif sub.Name() == name {\n")
+		buf.WriteString("\t\t\t\ttarget = sub\n")
+		buf.WriteString("\t\t\t\tbreak\n")
+		buf.WriteString("\t\t\t}\n")
+		buf.WriteString("\t\t}\n")
+		buf.WriteString("\t}\n")
+		buf.WriteString("\texecuting.RunE = target.RunE\n")
+		buf.WriteString("}\n\n")
+		fmt.Fprintf(buf, "func relativePath(cmd, ancestor *%s.Command) []string {\n", cobraQualifier)
+		buf.WriteString("\tif cmd == ancestor {\n")
+		buf.WriteString("\t\treturn nil\n")
+		buf.WriteString("\t}\n")
+		buf.WriteString("\treturn append(relativePath(cmd.Parent(), ancestor), cmd.Name())\n")
+		buf.WriteString("}\n")
+	}
+}
+
+// autoCollectParam records an auto-collected slice parameter.
+type autoCollectParam struct {
+	idx       int
+	elemType  string      // element type string (e.g., "github.com/.../seed.Seeder")
+	keyed     bool        // a map[string]Interface parameter
+	providers []*Provider // collected providers
+}
+
+// generateInitFunc generates an init<Cmd> function for a DI command.
+func (cg *CodeGen) generateInitFunc(buf *bytes.Buffer, cmd *DiscoveredCommand, cmdAlias string) error {
+	exportName := cmdExportName(cmd.Name)
+
+	// Determine which types this command needs (from NewCommand params and
+	// those of its child commands)
+	params := cmd.allParams()
+	var neededTypes []string
+	var groupParams []struct {
+		idx       int
+		groupName string
+	}
+	var autoParams []autoCollectParam
+
+	seenParams := make(map[string]bool)
+	for i, param := range params {
+		if param.Stream != "" || seenParams[param.TypeStr] {
+			continue
+		}
+		seenParams[param.TypeStr] = true
+		groupName := cg.matchGroup(param.TypeStr)
+		if groupName != "" {
+			groupParams = append(groupParams, struct {
+				idx       int
+				groupName string
+			}{i, groupName})
+			// Include all group providers' dependencies
+			for _, p := range cg.graph.Groups[groupName] {
+				neededTypes = append(neededTypes, memberDeps(p)...)
+			}
+		} else if elemType, keyed, ok := collectedElem(param.TypeStr); ok {
+			// Auto-collect: scan all providers implementing this interface
+			autoProviders := cg.graph.AutoCollect(elemType)
+			if len(autoProviders) > 0 {
+				autoParams = append(autoParams, autoCollectParam{
+					idx:       i,
+					elemType:  elemType,
+					keyed:     keyed,
+					providers: autoProviders,
+				})
+				for _, p := range autoProviders {
+					neededTypes = append(neededTypes, memberDeps(p)...)
+				}
+			} else {
+				neededTypes = append(neededTypes, param.TypeStr)
+			}
+		} else {
+			neededTypes = append(neededTypes, param.TypeStr)
+		}
+	}
+
+	// Request-scoped providers are built per NewRequestScope call from the
+	// command's singletons, which are constructed here
+	scoped, err := cg.graph.scopedProviders()
+	if err != nil {
+		return fmt.Errorf("resolve request scope for %s: %w", cmd.Name, err)
+	}
+	scopeDeps := cg.graph.scopeDeps(scoped)
+	neededTypes = append(neededTypes, scopeDeps...)
+
+	// The //autodi:migrate migrator is constructed to run between the phases
+	migrator := cg.graph.commandMigrator(cmd)
+	if migrator != nil {
+		neededTypes = append(neededTypes, migrator.Returns[0].TypeStr)
+	}
+
+	// Get providers in topological order
+	providers, err := cg.graph.ProvidersForTypes(neededTypes)
+	if err != nil {
+		return fmt.Errorf("resolve deps for %s: %w", cmd.Name, err)
+	}
+
+	// Deep auto-collection: scan resolved providers for []Interface and
+	// map[string]Interface params that aren't handled by groups or
+	// command-level auto-collection.
+	deepAutoMap := make(map[string][]autoCollectParam) // provider typeStr → auto-collected params
+	needsResolve := false
+	for _, p := range providers {
+		for i, param := range p.Params {
+			elemType, keyed, ok := collectedElem(param.TypeStr)
+			if !ok {
+				continue
+			}
+			// Skip if already handled by group
+			if cg.matchGroup(param.TypeStr) != "" {
+				continue
+			}
+			autoProviders := cg.graph.AutoCollect(elemType)
+			if len(autoProviders) > 0 {
+				key := p.PkgPath + "." + p.FuncName
+				deepAutoMap[key] = append(deepAutoMap[key], autoCollectParam{
+					idx:       i,
+					elemType:  elemType,
+					keyed:     keyed,
+					providers: autoProviders,
+				})
+				for _, ap := range autoProviders {
+					neededTypes = append(neededTypes, memberDeps(ap)...)
+				}
+				needsResolve = true
+			}
+		}
+	}
+
+	// Re-resolve if deep auto-collection added new dependencies
+	extraEdges := make(map[string][]string)
+	if needsResolve {
+		// Build extra edges: consuming provider's return type → auto-collected providers' dependency types.
+		// This ensures the topological sort places auto-collected deps before the consuming provider.
+		for _, p := range providers {
+			key := p.PkgPath + "." + p.FuncName
+			aps, ok := deepAutoMap[key]
+			if !ok {
+				continue
+			}
+			for _, ret := range p.Returns {
+				for _, ap := range aps {
+					for _, cp := range ap.providers {
+						extraEdges[ret.TypeStr] = append(extraEdges[ret.TypeStr], memberDeps(cp)...)
+					}
+				}
+			}
+		}
+
+		providers, err = cg.graph.ProvidersForTypesWithExtraEdges(neededTypes, extraEdges)
+		if err != nil {
+			return fmt.Errorf("resolve deps for %s (after auto-collect): %w", cmd.Name, err)
+		}
+	}
+
+	// Providers taking the Container come after every other provider, so
+	// Lookup sees them from the constructor
+	if deps := containerDeps(providers); deps != nil {
+		for p, after := range deps {
+			if len(p.Returns) > 0 {
+				extraEdges[p.Returns[0].TypeStr] = append(extraEdges[p.Returns[0].TypeStr], after...)
+			}
+		}
+		providers, err = cg.graph.ProvidersForTypesWithExtraEdges(neededTypes, extraEdges)
+		if err != nil {
+			return fmt.Errorf("resolve deps for %s (after container injection): %w", cmd.Name, err)
+		}
+	}
+
+	// Build type → local var name mapping
+	varMap := make(map[string]string) // typeStr → local var name
+	usedVars := make(map[string]bool)
+
+	// Register singleton provider imports up front so local variable names can
+	// avoid colliding with import qualifiers that appear later.
+	cg.registerProviderImports(providers)
+
+	// Build set of "consumed" types — types actually referenced as params
+	consumedTypes := make(map[string]bool)
+	for _, p := range providers {
+		for _, param := range p.Params {
+			consumedTypes[param.TypeStr] = true
+			consumedTypes[cg.graph.resolveType(param.TypeStr)] = true
+		}
+	}
+	// Command params are also consumed
+	for _, param := range params {
+		consumedTypes[param.TypeStr] = true
+		consumedTypes[cg.graph.resolveType(param.TypeStr)] = true
+	}
+	// Group provider params are consumed
+	for _, gp := range groupParams {
+		for _, p := range cg.graph.Groups[gp.groupName] {
+			for _, dep := range p.Params {
+				consumedTypes[dep.TypeStr] = true
+				consumedTypes[cg.graph.resolveType(dep.TypeStr)] = true
+			}
+		}
+	}
+	// Auto-collected provider params are consumed (command-level + deep)
+	for _, ap := range autoParams {
+		for _, p := range ap.providers {
+			for _, dep := range p.Params {
+				consumedTypes[dep.TypeStr] = true
+				consumedTypes[cg.graph.resolveType(dep.TypeStr)] = true
+			}
+		}
+	}
+	for _, aps := range deepAutoMap {
+		for _, ap := range aps {
+			for _, p := range ap.providers {
+				for _, dep := range p.Params {
+					consumedTypes[dep.TypeStr] = true
+					consumedTypes[cg.graph.resolveType(dep.TypeStr)] = true
+				}
+			}
+		}
+	}
+	for _, t := range scopeDeps {
+		consumedTypes[t] = true
+		consumedTypes[cg.graph.resolveType(t)] = true
+	}
+	if migrator != nil {
+		consumedTypes[migrator.Returns[0].TypeStr] = true
+	}
+	// Interface bindings: if an interface is consumed, its concrete type is too
+	for ifaceStr, concreteStr := range cg.graph.Bindings {
+		if consumedTypes[ifaceStr] {
+			consumedTypes[concreteStr] = true
+		}
+	}
+	// Container.Lookup can return any provider of the command
+	if cg.cfg.ContainerInjection {
+		for _, p := range providers {
+			for _, ret := range p.Returns {
+				consumedTypes[ret.TypeStr] = true
+			}
+		}
+	}
+
+	// Env allowlist for the startup check
+	envVar, err := cg.writeEnvAllowlist(buf, cmd, exportName)
+	if err != nil {
+		return err
+	}
+	resourceAttrs, err := cg.resourceAttributes(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Generate function signature
+	buf.WriteString(regionBegin(cmd.Name))
+	if cg.cfg.CLI == CLIKong {
+		usedVars["kctx"] = true
+		fmt.Fprintf(buf, "func init%s(kctx *%s.Context) (func(), error) {\n", exportName, cg.imports.Add(kongPath, "kong"))
+	} else {
+		fmt.Fprintf(buf, "func init%s(cmd, top *%s.Command) (func(), error) {\n", exportName, cg.imports.Add("github.com/spf13/cobra", "cobra"))
+	}
+	if envVar != "" {
+		fmt.Fprintf(buf, "\tcheckEnv(%s)\n", envVar)
+	}
+	if resourceAttrs != "" {
+		fmt.Fprintf(buf, "\taddResourceAttributes(%q)\n", resourceAttrs)
+	}
+	cg.beginInitProfile(buf, cmd, usedVars)
+	cg.beginMetrics(buf, cmd, providers, usedVars)
+	cg.beginContainerValues(buf)
+	cg.writeOptionalNotices(buf, providers)
+
+	hasAnyError := false
+	for _, p := range providers {
+		if p.HasError {
+			hasAnyError = true
+			break
+		}
+	}
+	if hasAnyError {
+		cg.addInitErrorImports()
+	}
+
+	// Generate provider calls in topological order, or level by level with
+	// //autodi:parallel-init. For providers with []Interface params (deep
+	// auto-collect), generate the slice just before calling that provider.
+	var closeables []CloseableField
+	var components []Component
+	lookups := make(map[string]bool)
+	writeProvider := func(p *Provider) error {
+		// Check if this provider has deep auto-collected params
+		key := p.PkgPath + "." + p.FuncName
+		if aps, ok := deepAutoMap[key]; ok {
+			for _, ap := range aps {
+				cg.registerProviderImports(ap.providers)
+
+				varName := deriveSliceVarName(ap.elemType)
+				if cg.imports.IsQualifier(varName) {
+					varName = varName + "List"
+				}
+				if usedVars[varName] {
+					varName = varName + "Auto"
+				}
+				varName = cg.uniqueLocalVar(varName, usedVars)
+
+				if err := cg.writeCollection(buf, varName, ap.elemType, ap.keyed, ap.providers, varMap, usedVars); err != nil {
+					return err
+				}
+				buf.WriteString("\n")
+
+				// Register in varMap so the provider call can reference it
+				varMap[p.Params[ap.idx].TypeStr] = varName
+			}
+		}
+
+		if takesContainer(p) {
+			cg.writeContainerValues(buf, providers, varMap, lookups)
+		}
+		cg.writeLocalProviderCall(buf, p, varMap, usedVars, &closeables, &components, consumedTypes)
+		buf.WriteString("\n")
+		return nil
+	}
+	// With //autodi:phase, the infra phase is constructed before the app one
+	phases := [][]*Provider{providers}
+	if cg.graph.phased() {
+		infra, app := cg.graph.splitPhases(providers, extraEdges)
+		phases = [][]*Provider{infra, app}
+	}
+	for i, phase := range phases {
+		if len(phases) > 1 && len(phases[0]) > 0 && len(phases[1]) > 0 {
+			fmt.Fprintf(buf, "\t// Phase %d: %s\n", i+1, []string{PhaseInfra, PhaseApp}[i])
+		}
+		if cg.cfg.ParallelInit {
+			if err := cg.writeParallelInit(buf, phase, deepAutoMap, writeProvider, varMap, usedVars, &closeables, &components, consumedTypes); err != nil {
+				return err
+			}
+		} else {
+			for _, p := range phase {
+				if err := writeProvider(p); err != nil {
+					return err
+				}
+			}
+		}
+		if i == 0 && migrator != nil {
+			cg.writeMigrate(buf, migrator, varMap)
+		}
+	}
+
+	// Write interface bindings
+	for ifaceStr, concreteStr := range cg.graph.Bindings {
+		if concreteVar, ok := varMap[concreteStr]; ok {
+			if _, needed := varMap[ifaceStr]; !needed {
+				// Check if this interface type is needed by any group provider or command param
+				if cg.isTypeNeeded(ifaceStr, neededTypes, cmd) {
+					varMap[ifaceStr] = concreteVar
+				}
+			}
+		}
+	}
+
+	// Build group slices
+	for _, gp := range groupParams {
+		groupName := gp.groupName
+		groupProviders := cg.graph.Groups[groupName]
+		if len(groupProviders) == 0 {
+			continue
+		}
+
+		cg.registerProviderImports(groupProviders)
+
+		groupCfg := cg.cfg.Groups[groupName]
+		groupVarName := localVarName(GroupFieldName(groupName))
+		if cg.imports.IsQualifier(groupVarName) {
+			groupVarName = groupVarName + "List"
+		}
+		if usedVars[groupVarName] {
+			groupVarName = groupVarName + "Group"
+		}
+		groupVarName = cg.uniqueLocalVar(groupVarName, usedVars)
+
+		ifaceType := cg.qualifyType(groupCfg.Interface, "")
+		fmt.Fprintf(buf, "\t%s := make([]%s, 0, %d)\n", groupVarName, ifaceType, len(groupProviders))
+		if err := cg.writeSliceProviderCalls(buf, groupVarName, groupCfg.Interface, groupProviders, varMap, usedVars); err != nil {
+			return err
+		}
+		buf.WriteString("\n")
+
+		// Register the slice in varMap for the NewCommand call
+		varMap[params[gp.idx].TypeStr] = groupVarName
+		cg.writeJobTicker(buf, cmd, groupName, groupVarName, usedVars, &components)
+	}
+
+	// Build auto-collected slices
+	for _, ap := range autoParams {
+		cg.registerProviderImports(ap.providers)
+
+		varName := deriveSliceVarName(ap.elemType)
+		if cg.imports.IsQualifier(varName) {
+			varName = varName + "List"
+		}
+		if usedVars[varName] {
+			varName = varName + "Auto"
+		}
+		varName = cg.uniqueLocalVar(varName, usedVars)
+
+		if err := cg.writeCollection(buf, varName, ap.elemType, ap.keyed, ap.providers, varMap, usedVars); err != nil {
+			return err
+		}
+		buf.WriteString("\n")
+
+		varMap[params[ap.idx].TypeStr] = varName
+	}
+
+	// Build NewCommand args
+	argsOf := func(c *DiscoveredCommand) []string {
+		var args []string
+		for _, param := range c.Params {
+			if param.Stream != "" {
+				args = append(args, cg.streamArg(param))
+			} else if varName, ok := varMap[param.TypeStr]; ok {
+				args = append(args, varName)
+			} else {
+				// Try resolving via bindings
+				resolved := cg.graph.resolveType(param.TypeStr)
+				if varName, ok := varMap[resolved]; ok {
+					args = append(args, varName)
+				} else {
+					args = append(args, "nil /* unresolved: "+toShortTypeName(param.TypeStr)+" */")
+				}
+			}
+		}
+		return args
+	}
+
+	// Register providers, their health checks and the request scope on the Container
+	cg.writeContainerValues(buf, providers, varMap, lookups)
+	cg.writeHealthChecks(buf, providers, varMap)
+	cg.writeRequestScope(buf, scoped, varMap, usedVars)
+
+	// A kong command gets its Run dependencies bound on the parsed context
+	if cg.cfg.CLI == CLIKong {
+		if err := cg.writeKongBindings(buf, cmd, argsOf(cmd), components); err != nil {
+			return fmt.Errorf("command %s: %w", cmd.Name, err)
+		}
+	} else {
+		cobraQualifier := cg.imports.Add("github.com/spf13/cobra", "cobra")
+
+		// Each command of the tree runs the components of its own graph
+		shared := scopeDeps
+		if migrator != nil {
+			shared = append(slices.Clip(shared), migrator.Returns[0].TypeStr)
+		}
+		byCmd, err := cg.commandComponents(cmd, shared, components)
+		if err != nil {
+			return fmt.Errorf("components of %s: %w", cmd.Name, err)
+		}
+
+		// Create real command instance (after its children) and wire handlers
+		for _, v := range []string{"cmd", "top", "real", "realCmd", "tree"} {
+			usedVars[v] = true
+		}
+		children := cg.writeChildCommands(buf, "\t", cmd, argsOf, usedVars)
+		fmt.Fprintf(buf, "\treal := %s\n", cg.commandCall(cmd, cmdAlias, argsOf(cmd), children))
+		cg.writeFlagCopies(buf, cmd, "real", children)
+
+		if cmd.IsSingle {
+			// Single command: Command() + direct RunE → Handle
+			fmt.Fprintf(buf, "\trealCmd := real.Command()\n")
+			fmt.Fprintf(buf, "\trealCmd.RunE = func(c *%s.Command, _ []string) error { return real.Handle(c) }\n", cobraQualifier)
+			cg.writeStartComponents(buf, "realCmd", byCmd[cmd])
+			cg.writeChildWiring(buf, "\t", "realCmd", children, usedVars, cobraQualifier, false, byCmd)
+			fmt.Fprintf(buf, "\tswapRunE(cmd, top, realCmd)\n\n")
+		} else {
+			// Multi-subcommand: Command() + wireRunE for each handler
+			fmt.Fprintf(buf, "\ttree := real.Command()\n")
+			for _, h := range cmd.Handlers {
+				cg.writeHandlerWiring(buf, "\t", "tree", "real", cobraQualifier, h, false)
+			}
+			cg.writeStartComponents(buf, "tree", byCmd[cmd])
+			cg.writeChildWiring(buf, "\t", "tree", children, usedVars, cobraQualifier, false, byCmd)
+			fmt.Fprintf(buf, "\tswapRunE(cmd, top, tree)\n\n")
+		}
+	}
+
+	cg.endInitProfile(buf)
+
+	// Generate cleanup function
+	if len(closeables) > 0 {
+		buf.WriteString("\treturn func() {\n")
+		ctxVar, cancelVar := "", ""
+		for i := len(closeables) - 1; i >= 0; i-- {
+			cl := closeables[i]
+			fmt.Fprintf(buf, "\t\tif %s != nil {\n", cl.VarName)
+			if cg.metricsVar != "" {
+				fmt.Fprintf(buf, "\t\t\t%s.start()\n", cg.metricsVar)
+			}
+			if cl.HasCtx && ctxVar == "" {
+				ctxVar = cg.uniqueLocalVar("shutdownCtx", usedVars)
+				cancelVar = cg.uniqueLocalVar("cancel", usedVars)
+			}
+			cg.writeCleanupCall(buf, &cl, ctxVar, cancelVar, "\t\t\t")
+			if cg.metricsVar != "" {
+				fmt.Fprintf(buf, "\t\t\t%s.shutdown(%q)\n", cg.metricsVar, cl.Field)
+			}
+			buf.WriteString("\t\t}\n")
+		}
+		buf.WriteString("\t}, nil\n")
+	} else {
+		buf.WriteString("\treturn nil, nil\n")
+	}
+	buf.WriteString("}\n")
+	buf.WriteString(regionEnd(cmd.Name))
+	cg.metricsVar = ""
+
+	return nil
+}
+
+// writeLocalProviderCall writes a provider call using local variables.
+func (cg *CodeGen) writeLocalProviderCall(buf *bytes.Buffer, p *Provider, varMap map[string]string, usedVars map[string]bool, closeables *[]CloseableField, components *[]Component, consumedTypes map[string]bool) {
+	qualifier := cg.replaySwitch(buf, p, usedVars)
+	args := cg.buildLocalArgs(p, varMap)
+	lhsNames := cg.providerResultVars(p, varMap, usedVars, closeables, components, consumedTypes)
+	lhsNames, post := cg.resultLHS(p, lhsNames, usedVars)
+	qualifier = cg.whenSwitch(buf, p, qualifier, usedVars)
+	assign, endWhen := cg.beginWhen(buf, p, lhsNames)
+	cg.writeProviderNotes(buf, p, "\t")
+	cg.profileStart(buf)
+	if p.HasError {
+		if len(lhsNames) > 0 {
+			fmt.Fprintf(buf, "\t%s, err %s %s(%s)\n", strings.Join(lhsNames, ", "), assign, qualifier, strings.Join(args, ", "))
+		} else {
+			fmt.Fprintf(buf, "\t_, err := %s(%s)\n", qualifier, strings.Join(args, ", "))
+		}
+		cg.profileDone(buf, p)
+		fmt.Fprintf(buf, "\tif err != nil {\n")
+		cg.writeInitError(buf, p, "\t\t", "nil")
+		fmt.Fprintf(buf, "\t}\n")
+	} else {
+		if len(lhsNames) > 0 {
+			fmt.Fprintf(buf, "\t%s %s %s(%s)\n", strings.Join(lhsNames, ", "), assign, qualifier, strings.Join(args, ", "))
+		} else {
+			fmt.Fprintf(buf, "\t%s(%s)\n", qualifier, strings.Join(args, ", "))
+		}
+		cg.profileDone(buf, p)
+	}
+	buf.WriteString(post)
+	cg.writeSecretResolution(buf, p, secretTarget(p, varMap), varMap, failReturn{results: "nil"})
+	buf.WriteString(endWhen)
+}
+
+// providerResultVars names the local variables a provider's results are
+// assigned to ("_" for unused results), registering them in varMap and
+// collecting closeables and components.
+func (cg *CodeGen) providerResultVars(p *Provider, varMap map[string]string, usedVars map[string]bool, closeables *[]CloseableField, components *[]Component, consumedTypes map[string]bool) []string {
+	var lhsNames []string
+	for i, ret := range p.Returns {
+		// Check if this return type is actually consumed
+		isConsumed := consumedTypes[ret.TypeStr]
+		// Also consumed through an //autodi:as interface
+		for _, ifaceStr := range p.asTypes(i) {
+			isConsumed = isConsumed || consumedTypes[ifaceStr]
+		}
+		if !isConsumed {
+			// Also check via bindings
+			for ifaceStr := range consumedTypes {
+				if cg.graph.resolveType(ifaceStr) == ret.TypeStr {
+					isConsumed = true
+					break
+				}
+			}
+		}
+		// Also check for closeable — we need the var name for cleanup
+		hasClose := isNilable(ret.Type) && checkCloseable(ret.Type, "_") != nil
+		// Health-checkable providers are registered on the Container
+		hasHealth := isNilable(ret.Type) && checkHealthCheck(ret.Type) != ""
+		// Secret fields are assigned after construction; the source is read from
+		hasSecrets := len(p.Secrets) > 0 || (ret.TypeStr == cg.graph.SecretsSource && cg.graph.SecretsSource != "")
+		// Start/Run components are started alongside the handler
+		startMethod := ""
+		if !HasAnnotation(p.Annotations, AnnotNoStart) {
+			startMethod = checkStartable(ret.Type)
+		}
+
+		if !isConsumed && !hasClose && !hasHealth && !hasSecrets && startMethod == "" {
+			lhsNames = append(lhsNames, "_")
+			continue
+		}
+
+		varName := keywordSafe(localVarName(cg.graph.varFieldName(ret.TypeStr)))
+		// Avoid shadowing import qualifiers
+		if cg.imports.IsQualifier(varName) {
+			varName = varName + "Svc"
+		}
+		// Ensure uniqueness
+		origVarName := varName
+		for i := 2; usedVars[varName]; i++ {
+			varName = fmt.Sprintf("%s%d", origVarName, i)
+		}
+		usedVars[varName] = true
+		lhsNames = append(lhsNames, varName)
+		varMap[ret.TypeStr] = varName
+		for _, ifaceStr := range p.asTypes(i) {
+			varMap[ifaceStr] = varName
+		}
+
+		// Check for closeable
+		if isNilable(ret.Type) {
+			if cl := checkCloseable(ret.Type, varName); cl != nil {
+				*closeables = append(*closeables, CloseableField{
+					VarName: varName,
+					Field:   cg.graph.fieldName(ret.TypeStr),
+					Method:  cl.Method,
+					HasCtx:  cl.HasCtx,
+				})
+			}
+		}
+		if startMethod != "" {
+			*components = append(*components, Component{
+				Provider: p,
+				VarName:  varName,
+				Method:   startMethod,
+				MayNil:   p.When != nil && p.When.Fallback == "",
+			})
+		}
+	}
+
+	return lhsNames
+}
+
+// replaySwitch returns the expression to call for a provider. For
+// //autodi:replayable providers it first emits a substitution point that picks
+// the recorded-fake constructor when AUTODI_REPLAY=1 and returns that variable.
+func (cg *CodeGen) replaySwitch(buf *bytes.Buffer, p *Provider, usedVars map[string]bool) string {
+	qualifier := cg.qualifyFunc(p)
+	if p.ReplayFunc == "" {
+		return qualifier
+	}
+	cg.imports.Add("os", "os")
+	fnVar := cg.uniqueLocalVar(localVarName(p.FuncName), usedVars)
+	replay := qualifiedName(cg.imports.Add(p.PkgPath, p.PkgName), p.ReplayFunc)
+	fmt.Fprintf(buf, "\t%s := %s\n", fnVar, qualifier)
+	fmt.Fprintf(buf, "\tif os.Getenv(%q) == \"1\" {\n", replayEnvVar)
+	fmt.Fprintf(buf, "\t\t%s = %s\n", fnVar, replay)
+	buf.WriteString("\t}\n")
+	return fnVar
+}
+
+// registerProviderImports pre-registers provider packages so local variable name
+// generation can avoid import qualifier collisions.
+func (cg *CodeGen) registerProviderImports(providers []*Provider) {
+	for _, p := range providers {
+		_ = cg.qualifyFunc(p)
+	}
+}
+
+// uniqueLocalVar returns a unique local variable name that does not collide with
+// existing locals or import qualifiers.
+func (cg *CodeGen) uniqueLocalVar(base string, usedVars map[string]bool) string {
+	if base == "" {
+		base = "value"
+	}
+
+	name := base
+	for i := 2; usedVars[name] || cg.imports.IsQualifier(name); i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	usedVars[name] = true
+	return name
+}
+
+// matchingSliceReturnIndexes returns provider return indexes that should be
+// added to a []elemTypeStr slice.
+func (cg *CodeGen) matchingSliceReturnIndexes(p *Provider, elemTypeStr string) ([]int, error) {
+	if len(p.Returns) == 0 {
+		return nil, fmt.Errorf("provider %s.%s has no return values", p.PkgName, p.FuncName)
+	}
+
+	resolvedElem := cg.graph.resolveConfigType(elemTypeStr)
+	iface := cg.graph.findIfaceType(resolvedElem)
+
+	var matches []int
+	for i, ret := range p.Returns {
+		if ret.TypeStr == resolvedElem || cg.graph.resolveType(ret.TypeStr) == resolvedElem {
+			matches = append(matches, i)
+			continue
+		}
+		if iface != nil {
+			if cg.graph.impls.implementsIface(ret.Type, ret.TypeStr, iface, resolvedElem) {
+				matches = append(matches, i)
+			}
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("provider %s.%s has no return matching %s", p.PkgName, p.FuncName, toShortTypeName(resolvedElem))
+	}
+	return matches, nil
+}
+
+// writeSliceProviderCalls emits provider calls that append the selected return
+// value into the target slice variable.
+func (cg *CodeGen) writeSliceProviderCalls(buf *bytes.Buffer, sliceVarName, elemTypeStr string, providers []*Provider, varMap map[string]string, usedVars map[string]bool) error {
+	return cg.writeCollectedCalls(buf, elemTypeStr, providers, varMap, usedVars, func(_ *Provider, v string) string {
+		return fmt.Sprintf("%s = append(%s, %s)", sliceVarName, sliceVarName, v)
+	})
+}
+
+// writeCollectedCalls emits the calls of the members collected as
+// elemTypeStr values; store returns the statement storing each one.
+func (cg *CodeGen) writeCollectedCalls(buf *bytes.Buffer, elemTypeStr string, providers []*Provider, varMap map[string]string, usedVars map[string]bool,
+	store func(p *Provider, v string) string) error {
+	// A member that doesn't implement the interface joins through //autodi:adapt
+	adapted := make(map[*Provider]bool)
+	match := func(p *Provider) ([]int, error) {
+		idxs, err := cg.matchingSliceReturnIndexes(p, elemTypeStr)
+		if err != nil && adaptMethod(p) != "" {
+			adapted[p] = true
+			return []int{0}, nil
+		}
+		return idxs, err
+	}
+	return cg.writeMemberCalls(buf, providers, varMap, usedVars, match, func(p *Provider, v string) string {
+		if adapted[p] {
+			v = cg.adaptedValue(p, cg.graph.resolveConfigType(elemTypeStr), v)
+		}
+		return store(p, v)
+	})
+}
+
+// writeMemberCalls emits the calls of group-like members; add returns the
+// statement storing each selected return value.
+func (cg *CodeGen) writeMemberCalls(buf *bytes.Buffer, providers []*Provider, varMap map[string]string, usedVars map[string]bool,
+	match func(*Provider) ([]int, error), add func(p *Provider, v string) string) error {
+	for _, p := range providers {
+		matchIdxs, err := match(p)
+		if err != nil {
+			return err
+		}
+
+		qualifier := cg.whenSwitch(buf, p, cg.replaySwitch(buf, p, usedVars), usedVars)
+		args := cg.buildLocalArgs(p, varMap)
+
+		// A conditional member without a fallback is left out of the slice
+		endWhen := ""
+		if p.When != nil && p.When.Fallback == "" {
+			cg.imports.Add("os", "os")
+			fmt.Fprintf(buf, "\tif %s {\n", p.When.expr())
+			endWhen = "\t}\n"
+		}
+		cg.writeProviderNotes(buf, p, "\t")
+
+		if len(p.Returns) == 1 && !p.HasError && len(p.Secrets) == 0 && len(matchIdxs) == 1 && matchIdxs[0] == 0 && p.Returns[0].OutStruct == nil {
+			cg.profileStart(buf)
+			fmt.Fprintf(buf, "\t%s\n", add(p, fmt.Sprintf("%s(%s)", qualifier, strings.Join(args, ", "))))
+			cg.profileDone(buf, p)
+			buf.WriteString(endWhen)
+			continue
+		}
+
+		selectedVars := make(map[int]string, len(matchIdxs))
+		for _, idx := range matchIdxs {
+			selectedType := p.Returns[idx].TypeStr
+			selectedVar := keywordSafe(localVarName(cg.graph.varFieldName(selectedType)))
+			if cg.imports.IsQualifier(selectedVar) {
+				selectedVar = selectedVar + "Val"
+			}
+			selectedVars[idx] = cg.uniqueLocalVar(selectedVar, usedVars)
+		}
+
+		lhs := make([]string, 0, len(p.Returns)+1)
+		for i := range p.Returns {
+			if selectedVar, ok := selectedVars[i]; ok {
+				lhs = append(lhs, selectedVar)
+				continue
+			}
+			lhs = append(lhs, "_")
+		}
+		lhs, post := cg.resultLHS(p, lhs, usedVars)
+
+		cg.profileStart(buf)
+		if p.HasError {
+			lhs = append(lhs, "err")
+			fmt.Fprintf(buf, "\t%s := %s(%s)\n", strings.Join(lhs, ", "), qualifier, strings.Join(args, ", "))
+			cg.profileDone(buf, p)
+			fmt.Fprintf(buf, "\tif err != nil {\n")
+			cg.writeFailReturn(buf, p, "\t\t", cg.memberFailReturn())
+			fmt.Fprintf(buf, "\t}\n")
+		} else {
+			fmt.Fprintf(buf, "\t%s := %s(%s)\n", strings.Join(lhs, ", "), qualifier, strings.Join(args, ", "))
+			cg.profileDone(buf, p)
+		}
+		buf.WriteString(post)
+		if len(matchIdxs) > 0 {
+			cg.writeSecretResolution(buf, p, selectedVars[matchIdxs[0]], varMap, cg.memberFailReturn())
+		}
+
+		for _, idx := range matchIdxs {
+			fmt.Fprintf(buf, "\t%s\n", add(p, selectedVars[idx]))
+		}
+		buf.WriteString(endWhen)
+	}
+
+	return nil
+}
+
+// memberFailReturn is how a failing group member returns from the function
+// being written.
+func (cg *CodeGen) memberFailReturn() failReturn {
+	if cg.memberFail != nil {
+		return *cg.memberFail
+	}
+	return failReturn{results: "nil"}
+}
+
+// buildLocalArgs constructs the argument list for a provider call using local vars.
+// Fields of an //autodi:in parameter object are gathered into a struct literal.
+func (cg *CodeGen) buildLocalArgs(p *Provider, varMap map[string]string) []string {
+	var args []string
+	var object *TypeRef
+	var fields []string
+	flush := func() {
+		if object == nil {
+			return
+		}
+		typeStr, amp := object.TypeStr, ""
+		if strings.HasPrefix(typeStr, "*") {
+			typeStr, amp = typeStr[1:], "&"
+		}
+		args = append(args, fmt.Sprintf("%s%s{%s}", amp, cg.qualifyType(typeStr, ""), strings.Join(fields, ", ")))
+		object, fields = nil, nil
+	}
+
+	for _, param := range p.Params {
+		var arg string
+		resolved := cg.graph.resolveType(param.TypeStr)
+		if param.Stream != "" {
+			arg = cg.streamArg(param)
+		} else if varName, ok := varMap[resolved]; ok {
+			arg = varName
+		} else if varName, ok := varMap[param.TypeStr]; ok {
+			arg = varName
+		} else if param.Optional {
+			arg = cg.zeroValue(param)
+		} else {
+			cg.unresolved = append(cg.unresolved, fmt.Sprintf("%s of %s.%s", toShortTypeName(param.TypeStr), p.PkgName, p.FuncName))
+			arg = "nil /* missing: " + toShortTypeName(param.TypeStr) + " */"
+		}
+
+		if param.InStruct != object {
+			flush()
+		}
+		if param.InStruct == nil {
+			args = append(args, arg)
+			continue
+		}
+		object = param.InStruct
+		fields = append(fields, param.InField+": "+arg)
+	}
+	flush()
+	return args
+}
+
+// unresolvedError reports the parameters the builder just written had no
+// value for instead of generating it with nil arguments, and clears them.
+func (cg *CodeGen) unresolvedError(builder string) error {
+	if len(cg.unresolved) == 0 {
+		return nil
+	}
+	err := fmt.Errorf("%s: no value for %s", builder, strings.Join(cg.unresolved, ", "))
+	cg.unresolved = nil
+	return err
+}
+
+// matchGroup checks if a type string matches a group definition.
+// Returns the group name, or "" if not a group.
+func (cg *CodeGen) matchGroup(typeStr string) string {
+	if !strings.HasPrefix(typeStr, "[]") {
+		return ""
+	}
+	elemType := typeStr[2:]
+
+	for groupName, groupCfg := range cg.cfg.Groups {
+		groupIfaceFull := cg.graph.resolveConfigType(groupCfg.Interface)
+		if elemType == groupIfaceFull {
+			return groupName
+		}
+	}
+	return ""
+}
+
+// isTypeNeeded checks if a type is needed by the command or its group/auto-collected providers.
+func (cg *CodeGen) isTypeNeeded(typeStr string, neededTypes []string, cmd *DiscoveredCommand) bool {
+	for _, t := range neededTypes {
+		if t == typeStr {
+			return true
+		}
+	}
+	// Check group provider params and auto-collected provider params
+	for _, param := range cmd.allParams() {
+		groupName := cg.matchGroup(param.TypeStr)
+		if groupName != "" {
+			for _, p := range cg.graph.Groups[groupName] {
+				for _, dep := range p.Params {
+					if dep.TypeStr == typeStr {
+						return true
+					}
+				}
+			}
+		} else if elemType, _, ok := collectedElem(param.TypeStr); ok {
+			autoProviders := cg.graph.AutoCollect(elemType)
+			for _, p := range autoProviders {
+				for _, dep := range p.Params {
+					if dep.TypeStr == typeStr {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// qualifyFunc returns the qualified function call like "iam.NewIAM".
+func (cg *CodeGen) qualifyFunc(p *Provider) string {
+	if p.Config != nil && cg.configLoaders != nil {
+		cg.configLoaders[p] = true
+	}
+	if len(p.Routes) > 0 && cg.configLoaders != nil {
+		cg.routeMux = p
+	}
+	if len(p.Jobs) > 0 && cg.configLoaders != nil {
+		// Reserve the qualifier newJobCron uses before locals are named
+		cg.jobCron = p
+		cg.imports.Add(cronPath, "cron")
+	}
+	if p.Logger != "" && cg.loggers != nil {
+		cg.loggers[p] = true
+	}
+	if p.Recv != "" {
+		return cg.factoryFunc(p)
+	}
+	alias := cg.imports.Add(p.PkgPath, p.PkgName)
+	if alias == "" {
+		return p.FuncName
+	}
+	return alias + "." + p.FuncName
+}
+
+// qualifiedName joins an import qualifier and identifier; an empty qualifier
+// refers to the generated file's own package.
+func qualifiedName(qualifier, name string) string {
+	if qualifier == "" {
+		return name
+	}
+	return qualifier + "." + name
+}
+
+// qualifyType converts a type string (possibly short config name) into Go source.
+func (cg *CodeGen) qualifyType(typeStr, _ string) string {
+	resolved := cg.graph.resolveConfigType(typeStr)
+	return cg.shortType(resolved)
+}
+
+// typeSource renders a type as Go source, registering the imports it needs.
+func (cg *CodeGen) typeSource(t types.Type) string {
+	return types.TypeString(t, func(pkg *types.Package) string {
+		return cg.imports.Add(pkg.Path(), pkg.Name())
+	})
+}
+
+// shortType converts a fully qualified type string to its short form,
+// registering imports as needed.
+func (cg *CodeGen) shortType(typeStr string) string {
+	if isFuncOrChanType(typeStr) {
+		return qualifiedNameRe.ReplaceAllStringFunc(typeStr, cg.shortType)
+	}
+	prefix := ""
+	s := typeStr
+
+	if strings.HasPrefix(s, "*") {
+		prefix = "*"
+		s = s[1:]
+	}
+
+	dotIdx := strings.LastIndex(s, ".")
+	if dotIdx < 0 {
+		return typeStr
+	}
+
+	pkgPath := s[:dotIdx]
+	typeName := s[dotIdx+1:]
+
+	if !strings.Contains(pkgPath, "/") {
+		resolved := cg.graph.resolveConfigType(typeStr)
+		if resolved != typeStr {
+			return cg.shortType(resolved)
+		}
+		if fullPkg, ok := cg.graph.pkgNameToPath[pkgPath]; ok {
+			pkgPath = fullPkg
+		}
+	}
+
+	pkgName := pkgShortName(pkgPath)
+	qualifier := cg.imports.Add(pkgPath, pkgName)
+	if qualifier == "" {
+		return prefix + typeName
+	}
+	return prefix + qualifier + "." + typeName
+}
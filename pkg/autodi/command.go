@@ -1,7 +1,7 @@
-package main
+package autodi
 
 import (
-	"fmt"
+	"errors"
 	"go/types"
 	"sort"
 	"strings"
@@ -14,22 +14,33 @@ import (
 type DiscoveredCommand struct {
 	Name       string        // directory name: "admin", "admin_api", "kafka"
 	PkgPath    string        // full import path
+	Dir        string        // module-relative directory: "cmd/admin", "cmd/admin/api"
 	PkgName    string        // Go package name
 	StructName string        // return type name: "Admin", "Worker", "Kafka"
 	FuncName   string        // constructor: "NewAdmin", "NewWorker", "NewKafka"
 	Params     []TypeRef     // constructor parameters (empty for zero-dep)
 	Handlers   []HandlerInfo // exported handler methods on the struct
 	IsSingle   bool          // has Handle method (leaf command, no subcommands)
+	BuildTag   string        // //autodi:cmd buildtag= constraint; "" = always built
+	Parent     *DiscoveredCommand
+	Children   []ChildCommand // commands whose structs the constructor takes (excluded from Params)
+	Flags      []FlagField    // fields bound to flags (flag:"name" tags, //autodi:flag)
+	NoStart    bool           // //autodi:nostart: groups of jobs it takes aren't scheduled
+	NoMigrate  bool           // //autodi:no-migrate: the //autodi:migrate migrator isn't run
 }
 
-// HasDeps returns true if the command constructor has parameters.
+// HasDeps returns true if the command constructor, or one of its child
+// commands' constructors, has parameters.
 func (dc *DiscoveredCommand) HasDeps() bool {
-	return len(dc.Params) > 0
+	return len(dc.allParams()) > 0
 }
 
 // HandlerInfo describes an exported handler method on a command struct.
 type HandlerInfo struct {
-	MethodName string // Go method name: "Create", "List", "Handle"
+	MethodName string   // Go method name: "Create", "List", "Handle"
+	Aliases    []string // //autodi:cmd alias=
+	Group      string   // //autodi:cmd group=: help group ID under the parent
+	Hidden     bool     // //autodi:cmd hidden
 }
 
 // CommandDetector scans cmd/ packages for command definitions.
@@ -43,7 +54,17 @@ func NewCommandDetector(cfg *Config, moduleRoot string) *CommandDetector {
 	return &CommandDetector{cfg: cfg, moduleRoot: moduleRoot}
 }
 
-// Detect loads cmd/ packages and discovers commands.
+// cmdInternalDir holds packages shared by commands; with
+// //autodi:scan-cmd-internal they are scanned for providers instead of
+// detected as commands.
+const cmdInternalDir = "cmd/internal"
+
+// Pattern returns the package pattern covering every command package.
+func (d *CommandDetector) Pattern() string {
+	return d.cfg.Module + "/" + d.cfg.cmdDir() + "/..."
+}
+
+// Detect discovers commands in the cmd/ packages of a loaded set.
 //
 // Detection rules:
 //   - Find exported New* functions returning *T where T has Command() *cobra.Command
@@ -51,28 +72,54 @@ func NewCommandDetector(cfg *Config, moduleRoot string) *CommandDetector {
 //   - If T has a Handle method → single command (leaf)
 //   - If T has other handler methods (Create, List, etc.) → multi-subcommand
 //   - Constructor params determine DI vs zero-dep
-func (d *CommandDetector) Detect() ([]*DiscoveredCommand, error) {
-	pattern := d.cfg.Module + "/cmd/..."
-
-	pkgCfg := &packages.Config{
-		Mode: packages.NeedName | packages.NeedTypes | packages.NeedTypesInfo |
-			packages.NeedSyntax | packages.NeedFiles | packages.NeedImports,
-		Dir: d.moduleRoot,
-	}
-
-	pkgs, err := packages.Load(pkgCfg, pattern)
-	if err != nil {
-		return nil, fmt.Errorf("load cmd packages: %w", err)
-	}
-
-	var commands []*DiscoveredCommand
-	for _, pkg := range pkgs {
+//   - //autodi:cmd on the constructor sets a build tag or limits it to profiles
+//   - //autodi:stdin/stdout/stderr/args fill parameters from the process
+//
+// With //autodi:cli kong, commands are structs with a Run method instead
+// (see analyzeKongPackage).
+func (d *CommandDetector) Detect(set *PackageSet) ([]*DiscoveredCommand, error) {
+	pkgs := set.Match(d.Pattern())
+
+	found := make([]*DiscoveredCommand, len(pkgs))
+	errs := make([]error, len(pkgs))
+	forEachPackage(pkgs, func(i int, pkg *packages.Package) {
 		rel := strings.TrimPrefix(pkg.PkgPath, d.cfg.Module+"/")
-		if rel == "cmd" {
-			continue
+		if rel == d.cfg.cmdDir() || pkg.Types == nil {
+			return
+		}
+		// Shared helpers scanned for providers aren't commands
+		if d.cfg.CmdInternal && matchPattern(d.cfg.appPath(cmdInternalDir)+"/...", rel) {
+			return
 		}
-
 		cmd := d.analyzePackage(pkg, rel)
+		if cmd == nil {
+			return
+		}
+		keep, err := d.applyCommandDirectives(pkg, cmd)
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		if d.cfg.CLI != CLIKong {
+			if cmd.Flags, err = commandFlags(pkg, cmd); err != nil {
+				errs[i] = err
+				return
+			}
+			if err := applyFlagStructs(set, cmd); err != nil {
+				errs[i] = err
+				return
+			}
+		}
+		if keep {
+			found[i] = cmd
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	var commands []*DiscoveredCommand
+	for _, cmd := range found {
 		if cmd != nil {
 			commands = append(commands, cmd)
 		}
@@ -82,6 +129,9 @@ func (d *CommandDetector) Detect() ([]*DiscoveredCommand, error) {
 		return commands[i].Name < commands[j].Name
 	})
 
+	if err := linkCommandTree(d.cfg, commands); err != nil {
+		return nil, err
+	}
 	return commands, nil
 }
 
@@ -89,6 +139,9 @@ func (d *CommandDetector) Detect() ([]*DiscoveredCommand, error) {
 // Finds the first exported New* function that returns *T where T has
 // both Command() *cobra.Command and at least one handler method.
 func (d *CommandDetector) analyzePackage(pkg *packages.Package, relPath string) *DiscoveredCommand {
+	if d.cfg.CLI == CLIKong {
+		return d.analyzeKongPackage(pkg, relPath)
+	}
 	scope := pkg.Types.Scope()
 
 	names := scope.Names()
@@ -137,21 +190,23 @@ func (d *CommandDetector) analyzePackage(pkg *packages.Package, relPath string)
 		params := sig.Params()
 		var paramTypes []TypeRef
 		for i := 0; i < params.Len(); i++ {
-			t := params.At(i).Type()
+			t := resolveAliases(params.At(i).Type())
 			paramTypes = append(paramTypes, TypeRef{
 				Type:    t,
 				TypeStr: types.TypeString(t, nil),
 				PkgPath: typePkgPath(t),
 				IsIface: isInterface(t),
+				Name:    params.At(i).Name(),
 			})
 		}
 
-		dirName := strings.TrimPrefix(relPath, "cmd/")
+		dirName := strings.TrimPrefix(relPath, d.cfg.cmdDir()+"/")
 		dirName = strings.ReplaceAll(dirName, "/", "_")
 
 		return &DiscoveredCommand{
 			Name:       dirName,
 			PkgPath:    pkg.PkgPath,
+			Dir:        relPath,
 			PkgName:    pkg.Name,
 			StructName: namedType.Obj().Name(),
 			FuncName:   name,
@@ -0,0 +1,95 @@
+package autodi
+
+import "time"
+
+// Config holds autodi configuration, populated from conventions and generate.go annotations.
+type Config struct {
+	Module   string
+	AppDir   string // module-relative directory of the generate.go in use; "" = the module root
+	Scan     []string
+	Exclude  []string
+	Output   string                 // generated entrypoint, module-relative (from //autodi:output)
+	Package  string                 // package clause of the generated entrypoint (from //autodi:output)
+	Layout   string                 // from //autodi:layout (LayoutSingle, LayoutMultiBinary or LayoutSplit)
+	CLI      string                 // from //autodi:cli (CLICobra or CLIKong); "" = cobra
+	Runtime  string                 // from //autodi:runtime (RuntimeLambda); "" = a command-line program
+	Handler  string                 // handler constructor of the runtime, pkg.Func (from //autodi:runtime)
+	Bindings map[string][]string    // concrete type → interface list (from //autodi:bind)
+	Groups   map[string]GroupConfig // from //autodi:group (generate.go and package doc.go)
+	Layers   map[string]string      // package path → layer (from doc.go //autodi:layer)
+	Profile  string                 // active profile; doc.go //autodi:profile packages need a match
+	Imports  []string               // provider bundle modules (from //autodi:import)
+
+	ConfigTypes   []ConfigDirective // structs filled from env/flags (from //autodi:config)
+	Substitutions []Substitution    // graph-wide provider swaps (from //autodi:replace)
+	ExcludeFuncs  []string          // constructors skipped by name (from //autodi:exclude-func)
+	LogLevel      string            // level of generated default loggers (from //autodi:log-level)
+	ErrorWrap     string            // ErrorWrapFunc, ErrorWrapField or ErrorWrapNone (from //autodi:error-wrap); "" = ErrorWrapFunc
+	ErrorLog      bool              // slog.Error a failing constructor before returning (from //autodi:error-wrap ... log)
+	Precedence    []string          // duplicate-provider rules in order (from //autodi:precedence); nil = defaultPrecedence
+	Prefixes      []string          // constructor prefixes besides New (from //autodi:constructor-prefix)
+	Provides      []string          // external constructors, "<import path>.<Func>" (from //autodi:provide)
+	ParallelInit  bool              // construct independent providers concurrently (from //autodi:parallel-init)
+	CmdInternal   bool              // scan cmd/internal/... for providers (from //autodi:scan-cmd-internal)
+	Metrics       string            // MetricsOTel or MetricsPrometheus (from //autodi:metrics); "" = none
+	Naming        string            // Container field naming strategy or template (from //autodi:naming); "" = NamingPackage
+	Comment       string            // template of a comment above each constructor call (from //autodi:comment)
+	InitLog       string            // template of a slog.Info before each constructor call (from //autodi:init-log)
+	ExitCodes     []ExitCodeMapping // sentinel errors → process exit codes (from //autodi:exit-code-map)
+
+	// Constructors may take the Container itself (from //autodi:allow-container-injection)
+	ContainerInjection bool
+
+	// Deadline of the context passed to Shutdown(ctx)-style cleanup methods
+	// (from //autodi:shutdown-timeout); 0 = defaultShutdownTimeout
+	ShutdownTimeout time.Duration
+
+	// Recorded in the header of generated files
+	GoVersion      string // go directive of go.mod
+	ConfigHash     string // hash of the generate.go directives
+	RequireVersion string // autodi version constraint (from //autodi:require-version)
+
+	// From go.mod replace directives that point at local directories
+	Replaces  map[string]string // required module → module-relative dir, for replacements inside the module tree
+	LocalMods map[string]string // module → absolute dir, for every filesystem replacement
+
+	// Module paths required in go.mod, sorted
+	Requires []string
+
+	// Directories of the module's other directory-level apps, module-relative
+	OtherApps []string
+
+	// Module-relative Go files of a hermetic run (from --files), analyzed
+	// instead of walking the module; nil otherwise
+	Files []string
+
+	// Other modules of the go.work workspace: module → dir relative to the module root
+	Workspace map[string]string
+
+	// Directories, packages and files left out of the scan as example code:
+	// testdata/, _-prefixed, //go:build ignore ("<path>: <reason>")
+	Skipped []string
+
+	ProfileInit string // --profile-init report destination; "" = no timing code
+	Inspect     bool   // --inspect: timing code plus the hidden di:inspect command
+
+	// From //autodi:app annotation (the first one when there are several)
+	AppName  string
+	AppShort string
+	AppLong  string
+	Apps     []AppConfig // every //autodi:app, in declaration order
+}
+
+// Output layouts selected with //autodi:layout.
+const (
+	LayoutSingle      = "single"       // one root main.go dispatching to every command
+	LayoutMultiBinary = "multi-binary" // one main_gen.go per cmd/<name> package main
+	LayoutSplit       = "split"        // main.go plus one autodi_<command>_gen.go per DI command
+	LayoutLibrary     = "library"      // a wiring package with BuildContainer and Build<Command>, no main
+)
+
+// GroupConfig defines a collection of providers implementing an interface.
+type GroupConfig struct {
+	Interface string
+	Paths     []string
+}
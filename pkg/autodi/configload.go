@@ -0,0 +1,427 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// AnnotConfig marks a plain config struct that autodi fills from environment
+// variables and flags instead of requiring a constructor:
+//
+//	//autodi:config redis.Options prefix=REDIS   (generate.go, any package)
+//	//autodi:config [prefix=SERVER]              (doc comment of a local struct)
+//
+// Exported fields of scalar type (string, bool, integers, floats,
+// time.Duration, []string) are read from <PREFIX>_<FIELD_NAME> or an env:"VAR"
+// tag, and from --<prefix>-<field-name> flags on the root command, which take
+// precedence. Other fields keep their zero value. The prefix defaults to the
+// package name. Both T and *T are provided.
+const AnnotConfig = "config"
+
+// ConfigDirective is a generate.go //autodi:config line.
+type ConfigDirective struct {
+	Type   string // "redis.Options" or a full "<pkgpath>.Name"
+	Prefix string
+}
+
+// ConfigStruct is a struct type populated by a generated loader.
+type ConfigStruct struct {
+	Named  *types.Named
+	Loader string // loader function returning *T
+	Fields []ConfigField
+}
+
+// ConfigField is one populated field of a ConfigStruct.
+type ConfigField struct {
+	Name string     // Go field name
+	Env  string     // environment variable, e.g. REDIS_ADDR
+	Flag string     // flag name, e.g. redis-addr
+	Type types.Type // field type
+}
+
+// parseConfigDirective parses the value of a //autodi:config directive; the
+// type is omitted on a struct's own doc comment.
+func parseConfigDirective(value string, withType bool) (ConfigDirective, error) {
+	var d ConfigDirective
+	fields := strings.Fields(value)
+	if withType {
+		if len(fields) == 0 {
+			return d, fmt.Errorf("//autodi:config needs a type, e.g. //autodi:config redis.Options")
+		}
+		d.Type, fields = fields[0], fields[1:]
+	}
+	for _, opt := range fields {
+		key, val, _ := strings.Cut(opt, "=")
+		if key != "prefix" || val == "" {
+			return d, fmt.Errorf("//autodi:config: unknown option %q (want prefix=<NAME>)", opt)
+		}
+		d.Prefix = strings.TrimSuffix(strings.ToUpper(val), "_")
+	}
+	return d, nil
+}
+
+// configProviders builds the loader providers for every //autodi:config
+// struct: those named in generate.go and local structs annotated directly.
+func (s *Scanner) configProviders(pkgs []*packages.Package) ([]*Provider, error) {
+	var providers []*Provider
+	seen := make(map[*types.Named]bool)
+	add := func(named *types.Named, prefix string, pos token.Position) error {
+		if seen[named] {
+			return nil
+		}
+		seen[named] = true
+		cs, err := newConfigStruct(named, prefix)
+		if err != nil {
+			return fmt.Errorf("%s: %v", pos, err)
+		}
+		providers = append(providers, cs.providers(pos)...)
+		return nil
+	}
+
+	for _, d := range s.cfg.ConfigTypes {
+		named, err := s.lookupConfigType(d.Type)
+		if err != nil {
+			return nil, fmt.Errorf("generate.go: //autodi:config %s: %v", d.Type, err)
+		}
+		if err := add(named, d.Prefix, token.Position{Filename: "generate.go"}); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, pkg := range pkgs {
+		if _, ok := s.imported[pkg.PkgPath]; ok || s.shouldExclude(pkg.PkgPath) {
+			continue
+		}
+		for _, ts := range annotatedTypes(pkg, AnnotConfig) {
+			value := strings.TrimSpace(strings.TrimPrefix(directiveText(ts.doc, AnnotConfig), AnnotConfig))
+			d, err := parseConfigDirective(value, false)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", s.fset.Position(ts.spec.Pos()), err)
+			}
+			named, ok := pkg.TypesInfo.Defs[ts.spec.Name].Type().(*types.Named)
+			if !ok {
+				continue
+			}
+			if err := add(named, d.Prefix, s.fset.Position(ts.spec.Pos())); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return providers, nil
+}
+
+// lookupConfigType resolves "pkg.Name" (by package name) or "<path>.Name".
+func (s *Scanner) lookupConfigType(typeStr string) (*types.Named, error) {
+	dot := strings.LastIndex(typeStr, ".")
+	if dot < 0 {
+		return nil, fmt.Errorf("want <pkg>.<Type>")
+	}
+	pkgPath, name := typeStr[:dot], typeStr[dot+1:]
+	if !strings.Contains(pkgPath, "/") {
+		if full, ok := s.PkgIndex[pkgPath]; ok {
+			pkgPath = full
+		}
+	}
+	pkg, ok := s.pkgsByPath[pkgPath]
+	if !ok || pkg.Types == nil {
+		return nil, fmt.Errorf("package %s is not imported by any scanned package", pkgPath)
+	}
+	tn, ok := pkg.Types.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("%s.%s does not exist", pkg.Name, name)
+	}
+	named, ok := tn.Type().(*types.Named)
+	if !ok {
+		return nil, fmt.Errorf("%s.%s is not a named type", pkg.Name, name)
+	}
+	return named, nil
+}
+
+// newConfigStruct collects the scalar fields of a config struct.
+func newConfigStruct(named *types.Named, prefix string) (*ConfigStruct, error) {
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("//autodi:config %s: not a struct", named.Obj().Name())
+	}
+	if prefix == "" {
+		prefix = strings.ToUpper(named.Obj().Pkg().Name())
+	}
+	cs := &ConfigStruct{
+		Named:  named,
+		Loader: "load" + FieldName(types.TypeString(named, nil)),
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		if !f.Exported() || f.Embedded() || configParseKind(f.Type()) == "" {
+			continue
+		}
+		env := prefix + "_" + upperSnake(f.Name())
+		if tag, ok := reflect.StructTag(st.Tag(i)).Lookup("env"); ok {
+			if name, _, _ := strings.Cut(tag, ","); name != "" {
+				env = name
+			}
+		}
+		cs.Fields = append(cs.Fields, ConfigField{
+			Name: f.Name(),
+			Env:  env,
+			Flag: strings.ToLower(strings.ReplaceAll(prefix+"_"+upperSnake(f.Name()), "_", "-")),
+			Type: f.Type(),
+		})
+	}
+	if len(cs.Fields) == 0 {
+		return nil, fmt.Errorf("//autodi:config %s: no exported scalar fields to populate", named.Obj().Name())
+	}
+	return cs, nil
+}
+
+// providers returns the *T loader provider and the T provider built on it.
+func (cs *ConfigStruct) providers(pos token.Position) []*Provider {
+	ptr := types.NewPointer(cs.Named)
+	var env []string
+	for _, f := range cs.Fields {
+		env = append(env, f.Env)
+	}
+	sort.Strings(env)
+	mk := func(fn string, t types.Type) *Provider {
+		return &Provider{
+			FuncName: fn,
+			PkgName:  cs.Named.Obj().Pkg().Name(),
+			Returns:  []TypeRef{{Type: t, TypeStr: types.TypeString(t, nil), PkgPath: typePkgPath(t)}},
+			HasError: true,
+			Env:      env,
+			Config:   cs,
+			Position: pos,
+		}
+	}
+	return []*Provider{mk(cs.Loader, ptr), mk(cs.Loader+"Value", cs.Named)}
+}
+
+// configParseKind returns how a field type is parsed from a string, or "" if
+// it isn't a supported scalar.
+func configParseKind(t types.Type) string {
+	if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != nil &&
+		named.Obj().Pkg().Path() == "time" && named.Obj().Name() == "Duration" {
+		return "duration"
+	}
+	if sl, ok := t.Underlying().(*types.Slice); ok {
+		if b, ok := sl.Elem().(*types.Basic); ok && b.Kind() == types.String {
+			return "strings"
+		}
+		return ""
+	}
+	b, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return ""
+	}
+	switch {
+	case b.Kind() == types.String:
+		return "string"
+	case b.Kind() == types.Bool:
+		return "bool"
+	case b.Info()&types.IsUnsigned != 0:
+		return "uint"
+	case b.Info()&types.IsInteger != 0:
+		return "int"
+	case b.Info()&types.IsFloat != 0:
+		return "float"
+	}
+	return ""
+}
+
+// bitSize returns the strconv bit size of a basic integer or float type.
+func bitSize(t types.Type) int {
+	b, _ := t.Underlying().(*types.Basic)
+	switch b.Kind() {
+	case types.Int8, types.Uint8:
+		return 8
+	case types.Int16, types.Uint16:
+		return 16
+	case types.Int32, types.Uint32, types.Float32:
+		return 32
+	}
+	return 64
+}
+
+// upperSnake converts a Go identifier to UPPER_SNAKE_CASE, keeping acronyms
+// together: MaxRetries → MAX_RETRIES, PoolFIFO → POOL_FIFO, TLSCert → TLS_CERT.
+func upperSnake(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// annotatedType is a type spec with its doc comment.
+type annotatedType struct {
+	spec *ast.TypeSpec
+	doc  *ast.CommentGroup
+}
+
+// annotatedTypes returns the type declarations of a package carrying a
+// //autodi:<kind> directive.
+func annotatedTypes(pkg *packages.Package, kind string) []annotatedType {
+	var found []annotatedType
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				doc := ts.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				if directiveText(doc, kind) != "" {
+					found = append(found, annotatedType{spec: ts, doc: doc})
+				}
+			}
+		}
+	}
+	return found
+}
+
+// directiveText returns "<kind> ..." of the first //autodi:<kind> line in a
+// doc comment, or "".
+func directiveText(doc *ast.CommentGroup, kind string) string {
+	if doc == nil {
+		return ""
+	}
+	for _, c := range doc.List {
+		text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+		if text == "autodi:"+kind || strings.HasPrefix(text, "autodi:"+kind+" ") {
+			return strings.TrimPrefix(text, "autodi:")
+		}
+	}
+	return ""
+}
+
+// writeConfigLoaders emits the loaders of the //autodi:config structs used in
+// the current file, and the configFlags set main adds to the root command.
+func (cg *CodeGen) writeConfigLoaders(buf *bytes.Buffer) {
+	pflagQualifier := cg.imports.Add("github.com/spf13/pflag", "pflag")
+	cg.imports.Add("fmt", "fmt")
+	cg.imports.Add("os", "os")
+
+	var structs []*ConfigStruct
+	values := make(map[*ConfigStruct]bool)
+	seen := make(map[*ConfigStruct]bool)
+	for p := range cg.configLoaders {
+		if p.Returns[0].TypeStr == types.TypeString(p.Config.Named, nil) {
+			values[p.Config] = true
+		}
+		if !seen[p.Config] {
+			seen[p.Config] = true
+			structs = append(structs, p.Config)
+		}
+	}
+	sort.Slice(structs, func(i, j int) bool { return structs[i].Loader < structs[j].Loader })
+
+	buf.WriteString("// configFlags holds the //autodi:config flags; they override environment variables.\n")
+	fmt.Fprintf(buf, "var configFlags = func() *%s.FlagSet {\n", pflagQualifier)
+	fmt.Fprintf(buf, "\tfs := %s.NewFlagSet(\"config\", %s.ContinueOnError)\n", pflagQualifier, pflagQualifier)
+	for _, cs := range structs {
+		for _, f := range cs.Fields {
+			fmt.Fprintf(buf, "\tfs.String(%q, \"\", %q)\n", f.Flag, fmt.Sprintf("%s.%s (env %s)", cs.Named.Obj().Name(), f.Name, f.Env))
+		}
+	}
+	buf.WriteString("\treturn fs\n")
+	buf.WriteString("}()\n\n")
+
+	buf.WriteString("// configValue returns a config field from its flag, if set, or environment variable.\n")
+	buf.WriteString("func configValue(flag, env string) (string, bool) {\n")
+	buf.WriteString("\tif f := configFlags.Lookup(flag); f != nil && f.Changed {\n")
+	buf.WriteString("\t\treturn f.Value.String(), true\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\treturn os.LookupEnv(env)\n")
+	buf.WriteString("}\n")
+
+	for _, cs := range structs {
+		typ := cg.shortType(types.TypeString(cs.Named, nil))
+		fmt.Fprintf(buf, "\n// %s builds %s from environment variables and flags (//autodi:config).\n", cs.Loader, typ)
+		fmt.Fprintf(buf, "func %s() (*%s, error) {\n", cs.Loader, typ)
+		fmt.Fprintf(buf, "\tv := &%s{}\n", typ)
+		for _, f := range cs.Fields {
+			cg.writeConfigField(buf, f)
+		}
+		buf.WriteString("\treturn v, nil\n")
+		buf.WriteString("}\n")
+
+		if values[cs] {
+			fmt.Fprintf(buf, "\n// %sValue provides %s by value.\n", cs.Loader, typ)
+			fmt.Fprintf(buf, "func %sValue() (%s, error) {\n", cs.Loader, typ)
+			fmt.Fprintf(buf, "\tv, err := %s()\n", cs.Loader)
+			buf.WriteString("\tif err != nil {\n")
+			fmt.Fprintf(buf, "\t\treturn %s{}, err\n", typ)
+			buf.WriteString("\t}\n")
+			buf.WriteString("\treturn *v, nil\n")
+			buf.WriteString("}\n")
+		}
+	}
+}
+
+// writeConfigField emits the lookup and parse of one config field.
+func (cg *CodeGen) writeConfigField(buf *bytes.Buffer, f ConfigField) {
+	fieldType := cg.shortType(types.TypeString(f.Type, nil))
+	convert := func(expr, basic string) string {
+		if fieldType == basic {
+			return expr
+		}
+		return fieldType + "(" + expr + ")"
+	}
+	parseErr := fmt.Sprintf("\t\tif err != nil {\n\t\t\treturn nil, fmt.Errorf(\"%s: %%w\", err)\n\t\t}\n", f.Env)
+
+	fmt.Fprintf(buf, "\tif s, ok := configValue(%q, %q); ok {\n", f.Flag, f.Env)
+	switch configParseKind(f.Type) {
+	case "string":
+		fmt.Fprintf(buf, "\t\tv.%s = %s\n", f.Name, convert("s", "string"))
+	case "strings":
+		cg.imports.Add("strings", "strings")
+		fmt.Fprintf(buf, "\t\tv.%s = %s\n", f.Name, convert(`strings.Split(s, ",")`, "[]string"))
+	case "bool":
+		cg.imports.Add("strconv", "strconv")
+		buf.WriteString("\t\tb, err := strconv.ParseBool(s)\n")
+		buf.WriteString(parseErr)
+		fmt.Fprintf(buf, "\t\tv.%s = %s\n", f.Name, convert("b", "bool"))
+	case "int":
+		cg.imports.Add("strconv", "strconv")
+		fmt.Fprintf(buf, "\t\tn, err := strconv.ParseInt(s, 10, %d)\n", bitSize(f.Type))
+		buf.WriteString(parseErr)
+		fmt.Fprintf(buf, "\t\tv.%s = %s\n", f.Name, convert("n", "int64"))
+	case "uint":
+		cg.imports.Add("strconv", "strconv")
+		fmt.Fprintf(buf, "\t\tn, err := strconv.ParseUint(s, 10, %d)\n", bitSize(f.Type))
+		buf.WriteString(parseErr)
+		fmt.Fprintf(buf, "\t\tv.%s = %s\n", f.Name, convert("n", "uint64"))
+	case "float":
+		cg.imports.Add("strconv", "strconv")
+		fmt.Fprintf(buf, "\t\tx, err := strconv.ParseFloat(s, %d)\n", bitSize(f.Type))
+		buf.WriteString(parseErr)
+		fmt.Fprintf(buf, "\t\tv.%s = %s\n", f.Name, convert("x", "float64"))
+	case "duration":
+		cg.imports.Add("time", "time")
+		buf.WriteString("\t\td, err := time.ParseDuration(s)\n")
+		buf.WriteString(parseErr)
+		fmt.Fprintf(buf, "\t\tv.%s = d\n", f.Name)
+	}
+	buf.WriteString("\t}\n")
+}
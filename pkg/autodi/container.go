@@ -0,0 +1,278 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Container injection: with //autodi:allow-container-injection in generate.go,
+// a provider or command constructor may take the generated Container itself,
+// for plugins and registries that look providers up at runtime:
+//
+//	type Registry interface{ Lookup(field string) (any, bool) }
+//
+//	func NewPluginHost(c Registry) *PluginHost
+//
+// A parameter is filled with the Container when its type is *Container of the
+// generated package, or an interface made of Container methods that no
+// provider returns or implements. Lookup takes a Container field name.
+// Constructors taking the Container run after every other provider of the
+// command, so Lookup already sees them; a provider one of them depends on
+// can't take it.
+//
+// Without the directive, an interface made of HealthCheck alone is still
+// filled with the Container, so a readiness endpoint can report the health
+// checks of the command's providers:
+//
+//	type HealthChecker interface{ HealthCheck(ctx context.Context) map[string]error }
+//
+// The library layout returns them from BuildContainer instead.
+const containerStream = "container"
+
+// containerMethods checks the signature of each method the Container offers
+// with container injection.
+var containerMethods = map[string]func(*types.Signature) bool{
+	// Lookup(field string) (any, bool)
+	"Lookup": func(sig *types.Signature) bool {
+		if sig.Params().Len() != 1 || !isStringType(sig.Params().At(0).Type()) || sig.Results().Len() != 2 {
+			return false
+		}
+		iface, ok := sig.Results().At(0).Type().Underlying().(*types.Interface)
+		basic, isBasic := sig.Results().At(1).Type().Underlying().(*types.Basic)
+		return ok && iface.NumMethods() == 0 && isBasic && basic.Kind() == types.Bool
+	},
+	// HealthCheck(ctx context.Context) map[string]error
+	"HealthCheck": func(sig *types.Signature) bool {
+		if sig.Params().Len() != 1 || !isContextType(sig.Params().At(0).Type()) || sig.Results().Len() != 1 {
+			return false
+		}
+		m, ok := sig.Results().At(0).Type().Underlying().(*types.Map)
+		return ok && isStringType(m.Key()) && isErrorType(m.Elem())
+	},
+}
+
+// markContainerParams sets Stream to containerStream on the provider and
+// command parameters filled with the Container: any Container type with
+// container injection, a HealthChecker without it.
+func markContainerParams(providers []*Provider, commands []*DiscoveredCommand, cfg *Config) {
+	fits := isContainerType
+	if !cfg.ContainerInjection {
+		// The library and Lambda layouts have no Container to pass
+		if cfg.Layout == LayoutLibrary || cfg.Runtime == RuntimeLambda {
+			return
+		}
+		fits = func(t types.Type, _ string) bool { return isHealthCheckerType(t) }
+	}
+	provided := make(map[string]bool)
+	for _, p := range providers {
+		for _, ret := range p.Returns {
+			provided[ret.TypeStr] = true
+		}
+	}
+	for _, ifaces := range cfg.Bindings {
+		for _, iface := range ifaces {
+			provided[iface] = true
+		}
+	}
+
+	outputPkg := cfg.Module
+	if dir := path.Dir(cfg.Output); dir != "." {
+		outputPkg += "/" + dir
+	}
+	impls := newImplMatrix()
+	implemented := func(param TypeRef) bool {
+		iface, ok := param.Type.Underlying().(*types.Interface)
+		if !ok {
+			return false
+		}
+		for _, p := range providers {
+			for _, ret := range p.Returns {
+				if ret.Type != nil && impls.implements(ret.Type, ret.TypeStr, iface, param.TypeStr) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	mark := func(params []TypeRef) {
+		for i, param := range params {
+			if param.Stream == "" && !provided[param.TypeStr] && fits(param.Type, outputPkg) && !implemented(param) {
+				params[i].Stream = containerStream
+			}
+		}
+	}
+	for _, p := range providers {
+		mark(p.Params)
+	}
+	for _, cmd := range commands {
+		mark(cmd.Params)
+	}
+}
+
+// isContainerType reports whether the Container can be passed for a
+// parameter of type t: *Container of the generated package, or an interface
+// made of Container methods.
+func isContainerType(t types.Type, outputPkg string) bool {
+	if t == nil {
+		return false
+	}
+	if ptr, ok := t.(*types.Pointer); ok {
+		named, ok := ptr.Elem().(*types.Named)
+		return ok && named.Obj().Name() == "Container" && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == outputPkg
+	}
+	iface, ok := t.Underlying().(*types.Interface)
+	if !ok || iface.NumMethods() == 0 {
+		return false
+	}
+	for i := 0; i < iface.NumMethods(); i++ {
+		method := iface.Method(i)
+		check, ok := containerMethods[method.Name()]
+		if !ok || !check(method.Type().(*types.Signature)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isHealthCheckerType reports whether t is an interface made of the
+// Container's HealthCheck method alone.
+func isHealthCheckerType(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	iface, ok := t.Underlying().(*types.Interface)
+	if !ok || iface.NumMethods() != 1 || iface.Method(0).Name() != "HealthCheck" {
+		return false
+	}
+	return containerMethods["HealthCheck"](iface.Method(0).Type().(*types.Signature))
+}
+
+// takesContainer reports whether a provider has a parameter filled with the
+// Container.
+func takesContainer(p *Provider) bool {
+	for _, param := range p.Params {
+		if param.Stream == containerStream {
+			return true
+		}
+	}
+	return false
+}
+
+// containerDeps returns, for each provider taking the Container, the types of
+// the other providers it must be constructed after: the first result of
+// every provider that doesn't take it.
+func containerDeps(providers []*Provider) map[*Provider][]string {
+	var takers []*Provider
+	var others []string
+	for _, p := range providers {
+		switch {
+		case takesContainer(p):
+			takers = append(takers, p)
+		case len(p.Returns) > 0:
+			others = append(others, p.Returns[0].TypeStr)
+		}
+	}
+	if len(takers) == 0 {
+		return nil
+	}
+	deps := make(map[*Provider][]string, len(takers))
+	for _, p := range takers {
+		deps[p] = others
+	}
+	return deps
+}
+
+// checkContainerInjection reports providers taking the Container that another
+// provider of the same command depends on: they can't be constructed after it.
+func (g *Graph) checkContainerInjection(commands []*DiscoveredCommand) []error {
+	if !slices.ContainsFunc(g.Providers, takesContainer) {
+		return nil
+	}
+	var errs []error
+	reported := make(map[[2]*Provider]bool)
+	for _, cmd := range commands {
+		providers, err := g.CommandProviders(cmd)
+		if err != nil {
+			continue
+		}
+		for _, p := range providers {
+			if takesContainer(p) {
+				continue
+			}
+			var deps []string
+			for _, param := range p.Params {
+				if param.Stream == "" {
+					deps = append(deps, param.TypeStr)
+				}
+			}
+			deps = append(deps, p.ExtraDeps...)
+			before, err := g.ProvidersForTypes(deps)
+			if err != nil {
+				continue
+			}
+			for _, taker := range before {
+				if !takesContainer(taker) || reported[[2]*Provider{taker, p}] {
+					continue
+				}
+				reported[[2]*Provider{taker, p}] = true
+				errs = append(errs, fmt.Errorf("%s: %s.%s takes the Container, but %s.%s (%s) depends on it and must be constructed before it\n  hint: look %s.%s up through the Container in %s.%s too, or drop the Container parameter",
+					taker.Position, taker.PkgName, taker.FuncName, p.PkgName, p.FuncName, p.Position, taker.PkgName, taker.FuncName, p.PkgName, p.FuncName))
+			}
+		}
+	}
+	return errs
+}
+
+// beginContainerValues starts the Lookup table of the Container in an init
+// function.
+func (cg *CodeGen) beginContainerValues(buf *bytes.Buffer) {
+	if !cg.cfg.ContainerInjection {
+		return
+	}
+	fmt.Fprintf(buf, "\t%s.values = make(map[string]any)\n", containerVar)
+	cg.hasContainer = true
+}
+
+// writeContainerValues adds the providers constructed so far to the Lookup
+// table of the Container, under their Container field names; registered
+// holds the fields already added.
+func (cg *CodeGen) writeContainerValues(buf *bytes.Buffer, providers []*Provider, varMap map[string]string, registered map[string]bool) {
+	if !cg.cfg.ContainerInjection {
+		return
+	}
+	var lines []string
+	for _, p := range providers {
+		for _, ret := range p.Returns {
+			varName, ok := varMap[ret.TypeStr]
+			field := cg.graph.TypeToField[ret.TypeStr]
+			if !ok || field == "" || registered[field] {
+				continue
+			}
+			registered[field] = true
+			lines = append(lines, fmt.Sprintf("\t%s.values[%q] = %s\n", containerVar, field, varName))
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	buf.WriteString(strings.Join(lines, ""))
+	buf.WriteString("\n")
+}
+
+// writeContainerLookup emits the Lookup method of the Container.
+func (cg *CodeGen) writeContainerLookup(buf *bytes.Buffer) {
+	if !cg.cfg.ContainerInjection {
+		return
+	}
+	buf.WriteString("\n// Lookup returns the provider wired for the running command under a\n")
+	buf.WriteString("// Container field name. A constructor taking the Container sees every\n")
+	buf.WriteString("// provider except those taking it too.\n")
+	buf.WriteString("func (c *Container) Lookup(field string) (any, bool) {\n")
+	buf.WriteString("\tv, ok := c.values[field]\n")
+	buf.WriteString("\treturn v, ok\n")
+	buf.WriteString("}\n")
+}
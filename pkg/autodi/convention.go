@@ -0,0 +1,447 @@
+package autodi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// BuildConfig builds a Config from go.mod + generate.go conventions.
+func BuildConfig(moduleRoot string) (*Config, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("getwd: %w", err)
+	}
+	return buildConfigIn(moduleRoot, dir)
+}
+
+// buildConfigIn is BuildConfig run from dir, which selects the
+// directory-level app.
+func buildConfigIn(moduleRoot, dir string) (*Config, error) {
+	gomod, err := parseGoMod(moduleRoot)
+	if err != nil {
+		return nil, err
+	}
+	return buildConfig(moduleRoot, dir, gomod, nil)
+}
+
+// buildConfig builds the config of the module described by gomod, run from
+// dir. files, when not nil, are the module-relative Go files of a hermetic
+// run, analyzed instead of the packages found by walking moduleRoot.
+func buildConfig(moduleRoot, dir string, gomod *GoMod, files []string) (*Config, error) {
+	cfg := &Config{
+		Module:    gomod.Module,
+		GoVersion: gomod.GoVersion,
+		Replaces:  nestedReplacements(moduleRoot, gomod),
+		LocalMods: gomod.Replaces,
+		Requires:  sortedKeys(gomod.Requires),
+		Output:    "main.go",
+		Package:   "main",
+		Layout:    LayoutSingle,
+		Bindings:  make(map[string][]string),
+		Groups:    make(map[string]GroupConfig),
+		Layers:    make(map[string]string),
+		Files:     files,
+	}
+	cfg.AppDir = findAppDir(moduleRoot, dir)
+	if err := parseGenerateFile(filepath.Join(moduleRoot, filepath.FromSlash(cfg.AppDir)), cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Layout == LayoutLibrary {
+		if err := resolveLibraryOutput(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if err := resolveApps(cfg); err != nil {
+		return nil, err
+	}
+	if err := resolveRuntime(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Layout == LayoutMultiBinary && cfg.Output != "main.go" {
+		return nil, fmt.Errorf("generate.go: //autodi:output can't be combined with layout %s\n  hint: each command's main_gen.go is written to its cmd/ directory", LayoutMultiBinary)
+	}
+	if err := resolveAppDir(moduleRoot, cfg); err != nil {
+		return nil, err
+	}
+
+	if files != nil {
+		cfg.Scan = fileScanPaths(files)
+		return cfg, nil
+	}
+
+	gitignore := LoadGitignore(moduleRoot)
+	scan, skipped, err := discoverScanPaths(moduleRoot, gitignore)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Scan = scan
+	cfg.Skipped = skipped
+
+	if work := findWorkFile(moduleRoot); work != "" {
+		members, err := parseWorkspace(work, moduleRoot)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Workspace = members
+	}
+
+	return cfg, nil
+}
+
+// discoverScanPaths enumerates top-level directories in the module root and
+// returns them as scan patterns (e.g. "internal/..."), excluding:
+//   - cmd/       — entry-point packages, handled by EntryDetector
+//   - dot dirs   — hidden (.git, .claude, …)
+//   - vendor/    — vendored dependencies
+//   - nested modules (own go.mod); replaced ones are scanned by module path
+//   - gitignored directories
+//   - testdata/ and _-prefixed directories, returned as skipped
+func discoverScanPaths(root string, gitignore []GitignorePattern) (paths, skipped []string, err error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read module root: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		if name == "cmd" || name == "vendor" {
+			continue
+		}
+		if reason := skippedDirReason(name); reason != "" {
+			skipped = append(skipped, name+": "+reason)
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(root, name, "go.mod")); err == nil {
+			continue
+		}
+		if IsGitignored(name, gitignore) {
+			continue
+		}
+		paths = append(paths, name+"/...")
+	}
+	return paths, skipped, nil
+}
+
+// generateParsers apply the directives of generate.go to a Config, by kind;
+// parts are the directive's fields, the kind first.
+var generateParsers = map[string]func(cfg *Config, directive string, parts []string) error{
+	"app": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:app leaflow "Leaflow Cloud" "Leaflow Cloud Management CLI Tool"
+		// //autodi:app worker "Background jobs" commands=worker,cron
+		app, err := parseAppDirective(directive)
+		if err != nil {
+			return err
+		}
+		for _, other := range cfg.Apps {
+			if other.Name == app.Name {
+				return fmt.Errorf("generate.go: //autodi:app %s declared twice", app.Name)
+			}
+		}
+		cfg.Apps = append(cfg.Apps, app)
+		return nil
+	},
+	"group": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:group user_controllers []apis.Controller internal/apis/user/controllers
+		// //autodi:group plugins []plugins.Plugin internal/plugins example.com/extras/plugins
+		if len(parts) >= 4 {
+			groupName := parts[1]
+			ifaceType := strings.TrimPrefix(parts[2], "[]")
+			cfg.Groups[groupName] = GroupConfig{
+				Interface: ifaceType,
+				Paths:     parts[3:],
+			}
+		}
+		return nil
+	},
+	"replace": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:replace old=internal/db.Store new=internal/fakedb.Store
+		s, err := parseSubstitution(parts[1:])
+		if err != nil {
+			return fmt.Errorf("generate.go: %v", err)
+		}
+		cfg.Substitutions = append(cfg.Substitutions, s)
+		return nil
+	},
+	"exclude": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:exclude ent/...
+		if len(parts) >= 2 {
+			cfg.Exclude = append(cfg.Exclude, parts[1])
+		}
+		return nil
+	},
+	"exclude-func": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:exclude-func iam.NewLegacyIAM
+		if len(parts) < 2 || !strings.Contains(parts[1], ".") {
+			return fmt.Errorf("generate.go: //autodi:exclude-func needs pkg.Func, e.g. //autodi:exclude-func iam.NewLegacyIAM")
+		}
+		cfg.ExcludeFuncs = append(cfg.ExcludeFuncs, parts[1:]...)
+		return nil
+	},
+	"constructor-prefix": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:constructor-prefix Provide,Make
+		if len(parts) != 2 {
+			return fmt.Errorf("generate.go: //autodi:constructor-prefix needs a comma-separated list, e.g. //autodi:constructor-prefix Provide,Make")
+		}
+		for _, prefix := range strings.Split(parts[1], ",") {
+			if prefix == "" || !isExported(prefix) {
+				return fmt.Errorf("generate.go: //autodi:constructor-prefix %s: %q is not an exported identifier prefix", parts[1], prefix)
+			}
+			cfg.Prefixes = append(cfg.Prefixes, prefix)
+		}
+		return nil
+	},
+	"precedence": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:precedence override,annotated,internal
+		if len(parts) != 2 {
+			return fmt.Errorf("generate.go: //autodi:precedence needs a comma-separated list of %s, or none", strings.Join(defaultPrecedence, ", "))
+		}
+		var err error
+		cfg.Precedence, err = parsePrecedence(parts[1])
+		return err
+	},
+	"log-level": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:log-level debug
+		if len(parts) != 2 || !slices.Contains(logLevels, parts[1]) {
+			return fmt.Errorf("generate.go: //autodi:log-level needs one of %s", strings.Join(logLevels, ", "))
+		}
+		cfg.LogLevel = parts[1]
+		return nil
+	},
+	"error-wrap": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:error-wrap field log
+		var err error
+		cfg.ErrorWrap, cfg.ErrorLog, err = parseErrorWrap(parts[1:])
+		return err
+	},
+	"shutdown-timeout": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:shutdown-timeout 10s
+		var d time.Duration
+		var err error
+		if len(parts) == 2 {
+			d, err = time.ParseDuration(parts[1])
+		}
+		if len(parts) != 2 || err != nil || d <= 0 {
+			return fmt.Errorf("generate.go: //autodi:shutdown-timeout needs a positive duration, e.g. //autodi:shutdown-timeout 10s")
+		}
+		cfg.ShutdownTimeout = d
+		return nil
+	},
+	"exit-code-map": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:exit-code-map example.com/app/internal/store.ErrNotFound=3
+		mappings, err := parseExitCodeMap(parts[1:])
+		if err == nil {
+			err = cfg.addExitCodes(mappings)
+		}
+		if err != nil {
+			return fmt.Errorf("generate.go: %v", err)
+		}
+		return nil
+	},
+	"scan-cmd-internal": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:scan-cmd-internal
+		cfg.CmdInternal = true
+		return nil
+	},
+	"parallel-init": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:parallel-init
+		cfg.ParallelInit = true
+		return nil
+	},
+	"allow-container-injection": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:allow-container-injection
+		cfg.ContainerInjection = true
+		return nil
+	},
+	"naming": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:naming short
+		// //autodi:naming {{.Type}}{{.Pkg}}
+		if len(parts) < 2 {
+			return fmt.Errorf("generate.go: //autodi:naming needs %s, %s, %s or a template", NamingPackage, NamingShort, NamingFullPath)
+		}
+		naming := strings.TrimSpace(strings.TrimPrefix(directive, "naming"))
+		if err := parseNaming(naming); err != nil {
+			return fmt.Errorf("generate.go: //autodi:naming: %v", err)
+		}
+		cfg.Naming = naming
+		return nil
+	},
+	"comment": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:comment owner: {{.X.owner}}
+		text := strings.TrimSpace(strings.TrimPrefix(directive, parts[0]))
+		if err := parseProviderTemplate(parts[0], text); err != nil {
+			return err
+		}
+		cfg.Comment = text
+		return nil
+	},
+	"init-log": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:init-log constructing {{.Pkg}}.{{.Func}}
+		text := strings.TrimSpace(strings.TrimPrefix(directive, parts[0]))
+		if err := parseProviderTemplate(parts[0], text); err != nil {
+			return err
+		}
+		cfg.InitLog = text
+		return nil
+	},
+	"metrics": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:metrics otel
+		if len(parts) != 2 || parts[1] != MetricsOTel && parts[1] != MetricsPrometheus {
+			return fmt.Errorf("generate.go: //autodi:metrics needs %s or %s", MetricsOTel, MetricsPrometheus)
+		}
+		cfg.Metrics = parts[1]
+		return nil
+	},
+	"provide": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:provide github.com/redis/go-redis/v9.NewClient
+		if len(parts) < 2 {
+			return fmt.Errorf("generate.go: //autodi:provide needs <import path>.<Func>, e.g. //autodi:provide github.com/redis/go-redis/v9.NewClient")
+		}
+		for _, ref := range parts[1:] {
+			if _, _, ok := splitFuncRef(ref); !ok {
+				return fmt.Errorf("generate.go: //autodi:provide %s: want <import path>.<Func> naming an exported function", ref)
+			}
+			cfg.Provides = append(cfg.Provides, ref)
+		}
+		return nil
+	},
+	"import": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:import github.com/org/shared-providers
+		if len(parts) >= 2 {
+			cfg.Imports = append(cfg.Imports, parts[1])
+		}
+		return nil
+	},
+	"output": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:output cmd/app/main_gen.go
+		// //autodi:output internal/wiring/wiring_gen.go wiring
+		if len(parts) >= 2 {
+			out := filepath.ToSlash(filepath.Clean(parts[1]))
+			if !strings.HasSuffix(out, ".go") || filepath.IsAbs(parts[1]) || out == ".." || strings.HasPrefix(out, "../") {
+				return fmt.Errorf("generate.go: //autodi:output %s: want a .go file inside the module", parts[1])
+			}
+			cfg.Output = out
+			if len(parts) >= 3 {
+				cfg.Package = parts[2]
+			}
+		}
+		return nil
+	},
+	AnnotConfig: func(cfg *Config, directive string, parts []string) error {
+		// //autodi:config redis.Options prefix=REDIS
+		d, err := parseConfigDirective(strings.TrimPrefix(directive, AnnotConfig), true)
+		if err != nil {
+			return fmt.Errorf("generate.go: %v", err)
+		}
+		cfg.ConfigTypes = append(cfg.ConfigTypes, d)
+		return nil
+	},
+	"runtime": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:runtime lambda handler.NewHandler
+		if len(parts) != 3 || parts[1] != RuntimeLambda || !strings.Contains(parts[2], ".") {
+			return fmt.Errorf("generate.go: //autodi:runtime needs %s <pkg.NewHandler>", RuntimeLambda)
+		}
+		cfg.Runtime, cfg.Handler = parts[1], parts[2]
+		return nil
+	},
+	"require-version": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:require-version >=v0.5
+		if len(parts) != 2 {
+			return fmt.Errorf("generate.go: //autodi:require-version needs a constraint like >=v0.5")
+		}
+		if err := checkRequiredVersion(parts[1], toolVersion()); err != nil {
+			return fmt.Errorf("generate.go: //autodi:require-version %s: %w", parts[1], err)
+		}
+		cfg.RequireVersion = parts[1]
+		return nil
+	},
+	"cli": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:cli kong
+		if len(parts) != 2 || parts[1] != CLICobra && parts[1] != CLIKong {
+			return fmt.Errorf("generate.go: //autodi:cli needs %s or %s", CLICobra, CLIKong)
+		}
+		cfg.CLI = parts[1]
+		return nil
+	},
+	"layout": func(cfg *Config, directive string, parts []string) error {
+		// //autodi:layout multi-binary
+		if len(parts) >= 2 {
+			switch parts[1] {
+			case LayoutSingle, LayoutMultiBinary, LayoutSplit, LayoutLibrary:
+				cfg.Layout = parts[1]
+			default:
+				return fmt.Errorf("generate.go: unknown layout %q (want %s, %s, %s or %s)", parts[1], LayoutSingle, LayoutMultiBinary, LayoutSplit, LayoutLibrary)
+			}
+		}
+		return nil
+	},
+}
+
+// generateDirectives are the directive kinds accepted in generate.go.
+var generateDirectives = sortedKeys(generateParsers)
+
+// parseGenerateFile applies //autodi: directives from the generate.go in root
+// (the module root or a directory-level app's) to cfg.
+func parseGenerateFile(root string, cfg *Config) error {
+	path := filepath.Join(root, "generate.go")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read generate.go: %w", err)
+	}
+
+	// The config hash in generated headers covers the directives only
+	h := sha256.New()
+	defer func() { cfg.ConfigHash = hex.EncodeToString(h.Sum(nil))[:configHashLen] }()
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "//autodi:") {
+			continue
+		}
+		fmt.Fprintln(h, line)
+		directive := strings.TrimPrefix(line, "//autodi:")
+		parts := strings.Fields(directive)
+		if len(parts) == 0 {
+			continue
+		}
+
+		parse, ok := generateParsers[parts[0]]
+		if !ok {
+			continue
+		}
+		if err := parse(cfg, directive, parts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseQuotedStrings extracts "quoted strings" from text.
+func parseQuotedStrings(s string) []string {
+	var result []string
+	for {
+		start := strings.Index(s, `"`)
+		if start < 0 {
+			break
+		}
+		end := strings.Index(s[start+1:], `"`)
+		if end < 0 {
+			break
+		}
+		result = append(result, s[start+1:start+1+end])
+		s = s[start+1+end+1:]
+	}
+	return result
+}
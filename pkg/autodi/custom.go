@@ -0,0 +1,119 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Custom annotations: //autodi:x-<key> <value> on a constructor has no
+// meaning to autodi. It is kept in Provider.Annotations, recorded in the lock
+// file and passed to the generate.go templates as .X, so organizations can
+// drive their own generation without forking the tool:
+//
+//	//autodi:x-owner payments
+//	//autodi:x-slo 99.9
+//	func NewLedger(db *sql.DB) *Ledger
+//
+//	//autodi:comment owner: {{.X.owner}}        (generate.go) comment above each constructor call
+//	//autodi:init-log constructing {{.Func}}    (generate.go) slog.Info before each constructor call,
+//	                                            with the x- annotations as attributes
+const customAnnotPrefix = "x-"
+
+// isCustomAnnotation reports whether a directive kind is an x- annotation.
+func isCustomAnnotation(kind string) bool {
+	return strings.HasPrefix(kind, customAnnotPrefix) && len(kind) > len(customAnnotPrefix)
+}
+
+// CustomAnnotations returns the x- annotations of a provider by key, without
+// the prefix; the last one of a key wins.
+func (p *Provider) CustomAnnotations() map[string]string {
+	var x map[string]string
+	for _, a := range p.Annotations {
+		if isCustomAnnotation(a.Kind) {
+			if x == nil {
+				x = make(map[string]string)
+			}
+			x[strings.TrimPrefix(a.Kind, customAnnotPrefix)] = a.Value
+		}
+	}
+	return x
+}
+
+// providerTemplateData is the data of the //autodi:comment and
+// //autodi:init-log templates, executed once per constructor call.
+type providerTemplateData struct {
+	Pkg   string            // package name: store
+	Path  string            // import path: example.com/app/internal/store
+	Func  string            // constructor: NewStore
+	Type  string            // first result: *store.Store
+	Field string            // Container field of the first result, "" for group members and invokes
+	X     map[string]string // x- annotations by key: {{.X.owner}}
+}
+
+// parseProviderTemplate checks the template of a generate.go directive.
+func parseProviderTemplate(directive, value string) error {
+	if value == "" {
+		return fmt.Errorf("generate.go: //autodi:%s needs a template, e.g. //autodi:%s {{.Pkg}}.{{.Func}} owner={{.X.owner}}", directive, directive)
+	}
+	tmpl, err := template.New(directive).Option("missingkey=zero").Parse(value)
+	if err == nil {
+		err = tmpl.Execute(&bytes.Buffer{}, providerTemplateData{Pkg: "store", Func: "NewStore", X: map[string]string{}})
+	}
+	if err != nil {
+		return fmt.Errorf("generate.go: //autodi:%s: %v\n  hint: the template sees .Pkg, .Path, .Func, .Type, .Field and .X.<key>", directive, err)
+	}
+	return nil
+}
+
+// renderProviderTemplate executes a generate.go template for a constructor,
+// flattened to one line.
+func (cg *CodeGen) renderProviderTemplate(text string, p *Provider) string {
+	data := providerTemplateData{Pkg: p.PkgName, Path: p.PkgPath, Func: p.FuncName, X: p.CustomAnnotations()}
+	if data.X == nil {
+		data.X = map[string]string{}
+	}
+	if len(p.Returns) > 0 {
+		data.Type = toShortTypeName(p.Returns[0].TypeStr)
+		if cg.graph.ProviderMap[p.Returns[0].TypeStr] == p {
+			data.Field = cg.graph.TypeToField[p.Returns[0].TypeStr]
+		}
+	}
+	var b strings.Builder
+	if err := template.Must(template.New("provider").Option("missingkey=zero").Parse(text)).Execute(&b, data); err != nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// writeProviderNotes emits the //autodi:comment and //autodi:init-log lines
+// of a constructor before its call; empty renderings are left out.
+func (cg *CodeGen) writeProviderNotes(buf *bytes.Buffer, p *Provider, indent string) {
+	if p.PkgPath == "" {
+		return // generated providers have no annotations
+	}
+	if cg.cfg.Comment != "" {
+		if text := cg.renderProviderTemplate(cg.cfg.Comment, p); text != "" {
+			fmt.Fprintf(buf, "%s// %s\n", indent, text)
+		}
+	}
+	if cg.cfg.InitLog != "" {
+		msg := cg.renderProviderTemplate(cg.cfg.InitLog, p)
+		if msg == "" {
+			return
+		}
+		args := []string{fmt.Sprintf("%q", msg)}
+		x := p.CustomAnnotations()
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			args = append(args, fmt.Sprintf("%q, %q", k, x[k]))
+		}
+		fmt.Fprintf(buf, "%s%s.Info(%s)\n", indent, cg.imports.Add("log/slog", "slog"), strings.Join(args, ", "))
+	}
+}
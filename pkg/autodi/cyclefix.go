@@ -0,0 +1,129 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// cycleEdge is one dependency in a cycle: consumer's constructor takes param,
+// which resolves to the type provided by the next provider in the cycle.
+type cycleEdge struct {
+	consumer *Provider
+	param    TypeRef
+	dep      *Provider
+}
+
+// cycleEdges resolves the parameter edges of a cycle reported as a type trail
+// (t0 → t1 → … → t0). Ordering-only edges (ExtraDeps) have no fix to suggest.
+func (g *Graph) cycleEdges(cycle []string) []cycleEdge {
+	var edges []cycleEdge
+	for i := 0; i+1 < len(cycle); i++ {
+		consumer := g.ProviderMap[cycle[i]]
+		dep := g.ProviderMap[cycle[i+1]]
+		if consumer == nil || dep == nil {
+			continue
+		}
+		for _, param := range consumer.Params {
+			if g.resolveType(param.TypeStr) == cycle[i+1] {
+				edges = append(edges, cycleEdge{consumer: consumer, param: param, dep: dep})
+				break
+			}
+		}
+	}
+	return edges
+}
+
+// formatCycleFixes suggests concrete ways to break a cycle:
+//   - narrow a concrete dependency to an interface, with the stub to declare,
+//     so it can be satisfied by a provider outside the cycle;
+//   - drop a parameter and inject it after construction with a setter.
+func (g *Graph) formatCycleFixes(cycle []string) string {
+	edges := g.cycleEdges(cycle)
+	if len(edges) == 0 {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("suggested fixes:\n")
+	n := 1
+
+	// Interface: the first edge on a concrete named type with exported methods
+	for _, e := range edges {
+		if e.param.Type == nil || isInterface(e.param.Type) {
+			continue
+		}
+		stub := interfaceStub(e.param.Type, e.consumer.PkgPath)
+		if stub == "" {
+			continue
+		}
+		fmt.Fprintf(&buf, "  %d. narrow %s.%s's dependency on %s to an interface declared in package %s,\n",
+			n, e.consumer.PkgName, e.consumer.FuncName, toShortTypeName(e.param.TypeStr), e.consumer.PkgName)
+		buf.WriteString("     keep only the methods it calls, and provide it from a constructor that\n")
+		fmt.Fprintf(&buf, "     doesn't depend back on %s (bind it with //autodi:bind or //autodi:as):\n\n", toShortTypeName(e.consumer.Returns[0].TypeStr))
+		for _, line := range strings.Split(stub, "\n") {
+			buf.WriteString("       " + line + "\n")
+		}
+		buf.WriteString("\n")
+		n++
+		break
+	}
+
+	// Setter: the edge closing the cycle
+	if last := edges[len(edges)-1]; len(last.consumer.Returns) > 0 {
+		recv := strings.TrimPrefix(toShortTypeName(last.consumer.Returns[0].TypeStr), "*")
+		if dot := strings.LastIndex(recv, "."); dot >= 0 {
+			recv = recv[dot+1:]
+		}
+		depName := last.param.TypeStr
+		if dot := strings.LastIndex(depName, "."); dot >= 0 {
+			depName = depName[dot+1:]
+		}
+		fmt.Fprintf(&buf, "  %d. take the dependency after construction: drop the %s parameter of %s.%s\n",
+			n, toShortTypeName(last.param.TypeStr), last.consumer.PkgName, last.consumer.FuncName)
+		fmt.Fprintf(&buf, "     and add a setter called once both exist (e.g. from an //autodi:invoke function):\n\n")
+		fmt.Fprintf(&buf, "       func (x *%s) Set%s(v %s)\n", recv, exportName(depName), toShortTypeName(last.param.TypeStr))
+		n++
+	}
+
+	if n == 1 {
+		return ""
+	}
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// interfaceStub renders an interface declaration with the exported methods of
+// t, qualified relative to the consumer package. Returns "" when t has none.
+func interfaceStub(t types.Type, consumerPkg string) string {
+	named, ok := t.(*types.Named)
+	if ptr, isPtr := t.(*types.Pointer); isPtr {
+		named, ok = ptr.Elem().(*types.Named)
+	}
+	if !ok {
+		return ""
+	}
+	qualifier := func(p *types.Package) string {
+		if p.Path() == consumerPkg {
+			return ""
+		}
+		return p.Name()
+	}
+
+	mset := types.NewMethodSet(types.NewPointer(named))
+	var methods []string
+	for i := 0; i < mset.Len(); i++ {
+		fn, ok := mset.At(i).Obj().(*types.Func)
+		if !ok || !fn.Exported() {
+			continue
+		}
+		sig := fn.Type().(*types.Signature)
+		var sb bytes.Buffer
+		types.WriteSignature(&sb, sig, qualifier)
+		methods = append(methods, "\t"+fn.Name()+strings.TrimPrefix(sb.String(), "func"))
+	}
+	if len(methods) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("type %s interface {\n%s\n}", named.Obj().Name(), strings.Join(methods, "\n"))
+}
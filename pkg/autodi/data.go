@@ -1,4 +1,4 @@
-package main
+package autodi
 
 import (
 	"fmt"
@@ -127,11 +127,11 @@ func (g *pkgDiagramGen) loadAndParse() error {
 		if pkg.PkgPath == g.cfg.Module {
 			continue
 		}
-		if !strings.HasPrefix(pkg.PkgPath, g.cfg.Module+"/") {
+		if !g.cfg.ownsPackage(pkg.PkgPath) || pkg.PkgPath == g.cfg.Module {
 			continue
 		}
 
-		rel := strings.TrimPrefix(pkg.PkgPath, g.cfg.Module+"/")
+		rel := g.cfg.RelPath(pkg.PkgPath)
 		info := &pdPkgInfo{
 			PkgPath:   pkg.PkgPath,
 			RelPath:   rel,
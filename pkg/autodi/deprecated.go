@@ -0,0 +1,47 @@
+package autodi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AnnotDeprecated marks a constructor being retired:
+//
+//	//autodi:deprecated use NewClientV2 instead
+//	func NewClient(cfg *Config) *Client
+//
+// Generation keeps working; each run warns with the commands whose graph
+// still constructs it, so platform teams can track the migration.
+const AnnotDeprecated = "deprecated"
+
+// deprecationWarnings returns a warning for each deprecated provider used by
+// the commands, listing those commands.
+func (g *Graph) deprecationWarnings(commands []*DiscoveredCommand) []string {
+	users := make(map[*Provider][]string)
+	var deprecated []*Provider
+	for _, cmd := range commands {
+		providers, err := g.CommandProviders(cmd)
+		if err != nil {
+			continue
+		}
+		for _, p := range providers {
+			if !HasAnnotation(p.Annotations, AnnotDeprecated) {
+				continue
+			}
+			if users[p] == nil {
+				deprecated = append(deprecated, p)
+			}
+			users[p] = append(users[p], cmd.Name)
+		}
+	}
+
+	var warnings []string
+	for _, p := range deprecated {
+		msg := fmt.Sprintf("%s: %s.%s is deprecated", p.Position, p.PkgName, p.FuncName)
+		if values := GetAnnotationValues(p.Annotations, AnnotDeprecated); len(values) > 0 {
+			msg += ": " + values[0]
+		}
+		warnings = append(warnings, fmt.Sprintf("%s\n  used by: %s", msg, strings.Join(users[p], ", ")))
+	}
+	return warnings
+}
@@ -1,4 +1,4 @@
-package main
+package autodi
 
 import (
 	"bytes"
@@ -115,7 +115,7 @@ func (mg *mermaidGen) render() []byte {
 	if len(ifaceSet) > 0 {
 		buf.WriteString("    %% ── Interfaces ───────────────────────────────────────────────────────\n")
 		buf.WriteString("    subgraph sg_interfaces[\"Interfaces\"]\n")
-		sortedIfaces := sortedStringKeys(ifaceSet)
+		sortedIfaces := sortedKeys(ifaceSet)
 		for _, ifaceTypeStr := range sortedIfaces {
 			id := mg.ifaceNodeID(ifaceTypeStr)
 			label := mermaidEscape(ifaceShortName(ifaceTypeStr))
@@ -198,10 +198,9 @@ func (mg *mermaidGen) collectIfaceTypes() map[string]bool {
 
 	collect := func(params []TypeRef) {
 		for _, param := range params {
-			if strings.HasPrefix(param.TypeStr, "[]") {
-				elemType := param.TypeStr[2:]
+			if elemType, keyed, ok := collectedElem(param.TypeStr); ok {
 				// Check groups
-				if mg.matchGroupByElem(elemType) != "" {
+				if !keyed && mg.matchGroupByElem(elemType) != "" {
 					ifaceSet[elemType] = true
 					continue
 				}
@@ -272,14 +271,13 @@ func (mg *mermaidGen) computeStats(ifaceSet map[string]bool) (providerCounts map
 	// Consumer counts: walk every provider/command's param list.
 	countConsumers := func(params []TypeRef) {
 		for _, param := range params {
-			if strings.HasPrefix(param.TypeStr, "[]") {
-				elemType := param.TypeStr[2:]
+			if elemType, keyed, ok := collectedElem(param.TypeStr); ok {
 				if ifaceSet[elemType] {
 					// Slice of interface → interface consumer count
 					s := ifaceStats[elemType]
 					s[1]++
 					ifaceStats[elemType] = s
-				} else if groupName := mg.matchGroupByElem(elemType); groupName != "" {
+				} else if groupName := mg.matchGroupByElem(elemType); !keyed && groupName != "" {
 					for _, gp := range mg.graph.Groups[groupName] {
 						providerCounts[mg.nodeID(gp)]++
 					}
@@ -358,9 +356,8 @@ func (mg *mermaidGen) writeImplementsEdges(buf *bytes.Buffer, ifaceTypeStr, ifac
 // instead of going directly from concrete to consumer.
 func (mg *mermaidGen) writeParamEdges(buf *bytes.Buffer, consumerID string, params []TypeRef, ifaceSet map[string]bool) {
 	for _, param := range params {
-		if strings.HasPrefix(param.TypeStr, "[]") {
-			elemType := param.TypeStr[2:]
-			sliceLabel := mermaidEscape("[]" + briefTypeName(elemType))
+		if elemType, keyed, ok := collectedElem(param.TypeStr); ok {
+			sliceLabel := mermaidEscape(collectedLabel(param.TypeStr, elemType))
 
 			if ifaceSet[elemType] {
 				// Route through interface node: IFace --> Consumer
@@ -368,7 +365,7 @@ func (mg *mermaidGen) writeParamEdges(buf *bytes.Buffer, consumerID string, para
 				fmt.Fprintf(buf, "    %s -.->|\"%s\"| %s\n", ifaceID, sliceLabel, consumerID)
 			} else {
 				// No interface node — fan out directly
-				if groupName := mg.matchGroupByElem(elemType); groupName != "" {
+				if groupName := mg.matchGroupByElem(elemType); !keyed && groupName != "" {
 					for _, gp := range mg.graph.Groups[groupName] {
 						fmt.Fprintf(buf, "    %s -.->|\"%s\"| %s\n", mg.nodeID(gp), sliceLabel, consumerID)
 					}
@@ -468,6 +465,12 @@ func (mg *mermaidGen) providerLabel(p *Provider, depCount int) string {
 	return mermaidEscape(mainType) + "<br/>" + mermaidEscape(p.FuncName) + suffix
 }
 
+// collectedLabel labels the edge of a []Interface or map[string]Interface
+// parameter with its element type.
+func collectedLabel(typeStr, elemType string) string {
+	return strings.TrimSuffix(typeStr, elemType) + briefTypeName(elemType)
+}
+
 // briefTypeName strips the package path and keeps only the bare type name.
 func briefTypeName(typeStr string) string {
 	if strings.HasPrefix(typeStr, "[]") {
@@ -535,13 +538,3 @@ func mermaidEscape(s string) string {
 	s = strings.ReplaceAll(s, "\"", "#quot;")
 	return s
 }
-
-// sortedStringKeys returns sorted keys from a map[string]bool.
-func sortedStringKeys(m map[string]bool) []string {
-	keys := make([]string, 0, len(m))
-	for k := range m {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-	return keys
-}
@@ -0,0 +1,379 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// runDocsArch renders architecture documents for the named commands (all
+// commands when none are given), to stdout or as <out>/<command>.md.
+func runDocsArch(opts *Options, out string, names []string) error {
+	proj, err := analyzeProject(opts)
+	if err != nil {
+		return err
+	}
+	commands, err := selectCommands(proj.Commands, names)
+	if err != nil {
+		return err
+	}
+
+	if out != "" {
+		if err := os.MkdirAll(out, 0755); err != nil {
+			return fmt.Errorf("create %s: %w", out, err)
+		}
+	}
+	for i, cmd := range commands {
+		doc, err := renderArchDoc(proj, cmd)
+		if err != nil {
+			return err
+		}
+		if out == "" {
+			if i > 0 {
+				fmt.Fprintln(os.Stdout)
+			}
+			os.Stdout.Write(doc)
+			continue
+		}
+		path := filepath.Join(out, cmd.Name+".md")
+		if err := os.WriteFile(path, doc, 0644); err != nil {
+			return fmt.Errorf("write %s: %w", path, err)
+		}
+		if opts.Verbose {
+			fmt.Fprintf(os.Stderr, "autodi: wrote %s\n", path)
+		}
+	}
+	return nil
+}
+
+// selectCommands returns the commands matching names, or all commands when names is empty.
+func selectCommands(commands []*DiscoveredCommand, names []string) ([]*DiscoveredCommand, error) {
+	if len(names) == 0 {
+		return commands, nil
+	}
+	byName := make(map[string]*DiscoveredCommand, len(commands))
+	for _, cmd := range commands {
+		byName[cmd.Name] = cmd
+	}
+	var selected []*DiscoveredCommand
+	for _, name := range names {
+		cmd, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown command %q", name)
+		}
+		selected = append(selected, cmd)
+	}
+	return selected, nil
+}
+
+// externalServiceKinds maps package path segments to the kind of external
+// service a provider talks to. Short keywords must match a whole segment;
+// longer ones may appear inside a segment (e.g. "go-redis").
+var externalServiceKinds = []struct {
+	keyword string
+	kind    string
+}{
+	{"sql", "database"}, {"db", "database"}, {"ent", "database"}, {"gorm", "database"},
+	{"pgx", "database"}, {"mongo", "database"}, {"orm", "database"},
+	{"redis", "cache"}, {"memcache", "cache"}, {"cache", "cache"},
+	{"kafka", "queue"}, {"nats", "queue"}, {"amqp", "queue"}, {"rabbitmq", "queue"},
+	{"sqs", "queue"}, {"pubsub", "queue"}, {"mq", "queue"},
+	{"s3", "storage"}, {"minio", "storage"},
+	{"smtp", "mail"}, {"mailer", "mail"},
+	{"grpc", "rpc"},
+}
+
+// renderArchDoc renders a Markdown architecture document for one command
+// (binary): command tree, container composition, external services touched,
+// and a startup order diagram — generated entirely from the graph.
+func renderArchDoc(proj *Project, cmd *DiscoveredCommand) ([]byte, error) {
+	providers, err := proj.Graph.CommandProviders(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("command %s: %w", cmd.Name, err)
+	}
+
+	var buf bytes.Buffer
+	title := cmd.Name
+	if proj.Cfg.Layout != LayoutMultiBinary && proj.Cfg.AppName != "" {
+		title = proj.Cfg.AppName + " " + cmd.Name
+	}
+	fmt.Fprintf(&buf, "# %s\n\n", title)
+	buf.WriteString("> Generated by autodi from the dependency graph. Do not edit.\n\n")
+
+	// Command tree
+	buf.WriteString("## Command tree\n\n")
+	fmt.Fprintf(&buf, "- `%s` — `%s.%s`\n", cmd.Name, cmd.PkgName, cmd.FuncName)
+	for _, h := range cmd.Handlers {
+		if cmd.IsSingle && h.MethodName == "Handle" {
+			fmt.Fprintf(&buf, "  - runs `%s.%s`\n", cmd.StructName, h.MethodName)
+			continue
+		}
+		fmt.Fprintf(&buf, "  - `%s` → `%s.%s`\n", pascalToKebab(h.MethodName), cmd.StructName, h.MethodName)
+	}
+	buf.WriteString("\n")
+
+	// Container composition
+	buf.WriteString("## Container composition\n\n")
+	if len(providers) == 0 {
+		buf.WriteString("No providers: the command has no dependencies.\n\n")
+	} else {
+		buf.WriteString("| # | Provider | Provides | Layer |\n")
+		buf.WriteString("|---|----------|----------|-------|\n")
+		for i, p := range providers {
+			var provides []string
+			for _, ret := range p.Returns {
+				provides = append(provides, "`"+toShortTypeName(ret.TypeStr)+"`")
+			}
+			fmt.Fprintf(&buf, "| %d | `%s.%s` | %s | %s |\n",
+				i+1, p.PkgName, p.FuncName, strings.Join(provides, ", "), proj.Cfg.Layers[p.PkgPath])
+		}
+		buf.WriteString("\n")
+	}
+
+	// External services
+	buf.WriteString("## External services\n\n")
+	services := externalServices(providers)
+	if len(services) == 0 {
+		buf.WriteString("None detected.\n\n")
+	} else {
+		kinds := make([]string, 0, len(services))
+		for kind := range services {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			fmt.Fprintf(&buf, "- **%s**: %s\n", kind, strings.Join(services[kind], ", "))
+		}
+		buf.WriteString("\n")
+	}
+
+	// Environment
+	if proj.Graph.usesEnv() {
+		env, err := proj.Graph.CommandEnv(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("command %s: %w", cmd.Name, err)
+		}
+		buf.WriteString("## Environment\n\n")
+		for _, name := range env {
+			fmt.Fprintf(&buf, "- `%s`\n", name)
+		}
+		buf.WriteString("\n")
+	}
+
+	// Startup order
+	buf.WriteString("## Startup order\n\n")
+	if len(providers) > 0 {
+		buf.WriteString("```mermaid\nflowchart TD\n")
+		ids := make(map[*Provider]string, len(providers))
+		for i, p := range providers {
+			ids[p] = fmt.Sprintf("p%d", i+1)
+			fmt.Fprintf(&buf, "  %s[\"%d. %s.%s\"]\n", ids[p], i+1, p.PkgName, p.FuncName)
+		}
+		for _, p := range providers {
+			for _, dep := range archDeps(proj.Graph, p) {
+				if id, ok := ids[dep]; ok {
+					fmt.Fprintf(&buf, "  %s --> %s\n", id, ids[p])
+				}
+			}
+		}
+		fmt.Fprintf(&buf, "  cmd[\"%s\"]\n", cmd.FuncName)
+		for _, param := range cmd.Params {
+			for _, dep := range archParamProviders(proj.Graph, param) {
+				if id, ok := ids[dep]; ok {
+					fmt.Fprintf(&buf, "  %s --> cmd\n", id)
+				}
+			}
+		}
+		buf.WriteString("```\n\n")
+	}
+
+	var shutdown []string
+	for i := len(providers) - 1; i >= 0; i-- {
+		p := providers[i]
+		for _, ret := range p.Returns {
+			if !isNilable(ret.Type) {
+				continue
+			}
+			if cl := checkCloseable(ret.Type, ""); cl != nil {
+				shutdown = append(shutdown, fmt.Sprintf("`%s.%s`", toShortTypeName(ret.TypeStr), cl.Method))
+			}
+		}
+	}
+	if len(shutdown) > 0 {
+		fmt.Fprintf(&buf, "Shutdown runs in reverse: %s.\n", strings.Join(shutdown, " → "))
+	}
+
+	return buf.Bytes(), nil
+}
+
+// externalServices classifies providers by the external service they touch,
+// based on the package paths of the provider and its return types.
+// Returns kind → sorted provider labels.
+func externalServices(providers []*Provider) map[string][]string {
+	result := make(map[string][]string)
+	for _, p := range providers {
+		paths := []string{p.PkgPath}
+		for _, ret := range p.Returns {
+			if ret.PkgPath != "" {
+				paths = append(paths, ret.PkgPath)
+			}
+		}
+		kind := serviceKind(paths)
+		if kind == "" {
+			continue
+		}
+		result[kind] = append(result[kind], fmt.Sprintf("`%s.%s`", p.PkgName, p.FuncName))
+	}
+	for _, labels := range result {
+		sort.Strings(labels)
+	}
+	return result
+}
+
+// serviceKind returns the external service kind for the first matching package path.
+func serviceKind(pkgPaths []string) string {
+	for _, pkgPath := range pkgPaths {
+		for _, seg := range strings.Split(pkgPath, "/") {
+			seg = strings.ToLower(seg)
+			for _, k := range externalServiceKinds {
+				if seg == k.keyword || (len(k.keyword) >= 4 && strings.Contains(seg, k.keyword)) {
+					return k.kind
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// archDeps returns the providers a provider's parameters (and synthetic
+// ExtraDeps, e.g. the SecretsSource) resolve to.
+func archDeps(g *Graph, p *Provider) []*Provider {
+	var deps []*Provider
+	for _, param := range p.Params {
+		deps = append(deps, archParamProviders(g, param)...)
+	}
+	for _, typeStr := range p.ExtraDeps {
+		if dep := g.ProviderMap[g.resolveType(typeStr)]; dep != nil {
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
+
+// archParamProviders returns the providers satisfying a single parameter.
+func archParamProviders(g *Graph, param TypeRef) []*Provider {
+	if members := g.SliceMembers(param.TypeStr); members != nil {
+		return members
+	}
+	if dep := g.ProviderMap[g.resolveType(param.TypeStr)]; dep != nil {
+		return []*Provider{dep}
+	}
+	return nil
+}
+
+// renderGraphDoc renders the --doc Markdown document for the whole graph: a
+// dependency tree per command, a table of every provider and the group
+// memberships.
+func renderGraphDoc(proj *Project) []byte {
+	g := proj.Graph
+	var buf bytes.Buffer
+	title := "Architecture"
+	if proj.Cfg.AppName != "" {
+		title = proj.Cfg.AppName + " architecture"
+	}
+	fmt.Fprintf(&buf, "# %s\n\n", title)
+	buf.WriteString("> Generated by autodi from the dependency graph. Do not edit.\n\n")
+
+	// Dependency trees; a provider already expanded in a command's tree is
+	// referenced instead of repeated
+	buf.WriteString("## Commands\n\n")
+	for _, cmd := range proj.Commands {
+		fmt.Fprintf(&buf, "### %s\n\n", cmd.Name)
+		fmt.Fprintf(&buf, "- `%s.%s`\n", cmd.PkgName, cmd.FuncName)
+		expanded := make(map[*Provider]bool)
+		var tree func(param TypeRef, depth int)
+		tree = func(param TypeRef, depth int) {
+			indent := strings.Repeat("  ", depth)
+			deps := archParamProviders(g, param)
+			if len(deps) == 0 {
+				fmt.Fprintf(&buf, "%s- `%s` — unresolved\n", indent, toShortTypeName(param.TypeStr))
+				return
+			}
+			for _, dep := range deps {
+				label := fmt.Sprintf("%s- `%s` — `%s.%s`", indent, toShortTypeName(param.TypeStr), dep.PkgName, dep.FuncName)
+				if expanded[dep] {
+					fmt.Fprintf(&buf, "%s (above)\n", label)
+					continue
+				}
+				expanded[dep] = true
+				buf.WriteString(label + "\n")
+				for _, p := range dep.Params {
+					tree(p, depth+1)
+				}
+			}
+		}
+		for _, param := range cmd.Params {
+			tree(param, 1)
+		}
+		buf.WriteString("\n")
+	}
+
+	// Provider table
+	buf.WriteString("## Providers\n\n")
+	providers := append([]*Provider(nil), g.Providers...)
+	sort.Slice(providers, func(i, j int) bool {
+		if providers[i].PkgPath != providers[j].PkgPath {
+			return providers[i].PkgPath < providers[j].PkgPath
+		}
+		return providers[i].FuncName < providers[j].FuncName
+	})
+	buf.WriteString("| Package | Constructor | Provides | Requires | Lifecycle |\n")
+	buf.WriteString("|---------|-------------|----------|----------|-----------|\n")
+	for _, p := range providers {
+		var provides, requires, lifecycle []string
+		for _, ret := range p.Returns {
+			provides = append(provides, "`"+toShortTypeName(ret.TypeStr)+"`")
+			if !isNilable(ret.Type) {
+				continue
+			}
+			if m := checkStartable(ret.Type); m != "" && !HasAnnotation(p.Annotations, AnnotNoStart) {
+				lifecycle = append(lifecycle, m)
+			}
+			if m := checkHealthCheck(ret.Type); m != "" {
+				lifecycle = append(lifecycle, m)
+			}
+			if cl := checkCloseable(ret.Type, ""); cl != nil {
+				lifecycle = append(lifecycle, cl.Method)
+			}
+		}
+		for _, param := range p.Params {
+			requires = append(requires, "`"+toShortTypeName(param.TypeStr)+"`")
+		}
+		pkg := p.PkgPath
+		if pkg == "" {
+			pkg = "(generated)"
+		}
+		fmt.Fprintf(&buf, "| `%s` | `%s` | %s | %s | %s |\n",
+			pkg, p.FuncName, strings.Join(provides, ", "), strings.Join(requires, ", "), strings.Join(lifecycle, ", "))
+	}
+	buf.WriteString("\n")
+
+	// Groups
+	if len(g.Groups) > 0 {
+		buf.WriteString("## Groups\n\n")
+		for _, name := range sortedKeys(g.Groups) {
+			fmt.Fprintf(&buf, "### %s\n\n", name)
+			if group, ok := proj.Cfg.Groups[name]; ok && group.Interface != "" {
+				fmt.Fprintf(&buf, "Collected as `[]%s`.\n\n", toShortTypeName(group.Interface))
+			}
+			for _, p := range g.Groups[name] {
+				fmt.Fprintf(&buf, "- `%s.%s`\n", p.PkgName, p.FuncName)
+			}
+			buf.WriteString("\n")
+		}
+	}
+	return append(bytes.TrimRight(buf.Bytes(), "\n"), '\n')
+}
@@ -0,0 +1,161 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// envTagKeys are the struct tag keys read as environment variable names
+// (caarlos0/env, sethvargo/go-envconfig).
+var envTagKeys = []string{"env"}
+
+// replayEnvVar switches //autodi:replayable providers to their recorded fakes.
+const replayEnvVar = "AUTODI_REPLAY"
+
+// resolveEnv collects the environment variables a provider reads: names from
+// //autodi:env on the constructor, and from env struct tags or //autodi:env
+// field directives on its struct return types declared in the same package.
+func (s *Scanner) resolveEnv(pkg *packages.Package, p *Provider) {
+	seen := make(map[string]bool)
+	add := func(name string) {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			p.Env = append(p.Env, name)
+		}
+	}
+
+	for _, a := range p.Annotations {
+		if a.Kind == AnnotEnv {
+			for _, name := range strings.Split(a.Value, ",") {
+				add(name)
+			}
+		}
+	}
+
+	for _, ret := range p.Returns {
+		t := ret.Type
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		named, ok := t.(*types.Named)
+		if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != pkg.PkgPath {
+			continue
+		}
+		st, ok := named.Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		for i := 0; i < st.NumFields(); i++ {
+			tag := reflect.StructTag(st.Tag(i))
+			for _, key := range envTagKeys {
+				// env:"DATABASE_URL,required"
+				if value, ok := tag.Lookup(key); ok {
+					name, _, _ := strings.Cut(value, ",")
+					add(name)
+				}
+			}
+		}
+		if spec := findStructSpec(pkg, named.Obj().Name()); spec != nil {
+			for _, field := range spec.Fields.List {
+				add(fieldDirective(field, AnnotEnv))
+			}
+		}
+	}
+	sort.Strings(p.Env)
+}
+
+// usesEnv reports whether any provider declares environment variables. The
+// startup check is only generated for apps that describe their env config.
+func (g *Graph) usesEnv() bool {
+	for _, p := range g.Providers {
+		if len(p.Env) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// CommandEnv returns the sorted environment variables read by a command's
+// provider closure, plus the variables autodi itself reads.
+func (g *Graph) CommandEnv(cmd *DiscoveredCommand) ([]string, error) {
+	providers, err := g.CommandProviders(cmd)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{replayEnvVar: true}
+	for _, p := range providers {
+		for _, name := range p.Env {
+			seen[name] = true
+		}
+	}
+	return sortedKeys(seen), nil
+}
+
+// envPrefixes returns the variable prefixes checked against the allowlist:
+// AUTODI_, APP_ and the upper-cased app name.
+func (cg *CodeGen) envPrefixes() []string {
+	prefixes := []string{"AUTODI_", "APP_"}
+	if cg.cfg.AppName != "" {
+		app := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(cg.cfg.AppName)) + "_"
+		if app != "AUTODI_" && app != "APP_" {
+			prefixes = append(prefixes, app)
+		}
+	}
+	return prefixes
+}
+
+// writeEnvAllowlist emits the command's env allowlist variable and returns its
+// name, or "" when the app declares no env config.
+func (cg *CodeGen) writeEnvAllowlist(buf *bytes.Buffer, cmd *DiscoveredCommand, exportName string) (string, error) {
+	if !cg.graph.usesEnv() {
+		return "", nil
+	}
+	names, err := cg.graph.CommandEnv(cmd)
+	if err != nil {
+		return "", fmt.Errorf("env for %s: %w", cmd.Name, err)
+	}
+
+	varName := "envAllowlist" + exportName
+	fmt.Fprintf(buf, "// %s lists the environment variables the %s command reads.\n", varName, cmd.Name)
+	fmt.Fprintf(buf, "var %s = []string{\n", varName)
+	for _, name := range names {
+		fmt.Fprintf(buf, "\t%q,\n", name)
+	}
+	buf.WriteString("}\n\n")
+	cg.hasEnvCheck = true
+	return varName, nil
+}
+
+// writeEnvCheck emits checkEnv, which warns about prefixed environment
+// variables no provider reads — typically a typo in deployment config.
+func (cg *CodeGen) writeEnvCheck(buf *bytes.Buffer) {
+	cg.imports.Add("fmt", "fmt")
+	cg.imports.Add("os", "os")
+	cg.imports.Add("slices", "slices")
+	cg.imports.Add("strings", "strings")
+
+	var quoted []string
+	for _, prefix := range cg.envPrefixes() {
+		quoted = append(quoted, fmt.Sprintf("%q", prefix))
+	}
+	buf.WriteString("// checkEnv warns about environment variables with an app prefix that are\n")
+	buf.WriteString("// not in the command's allowlist.\n")
+	buf.WriteString("func checkEnv(allowed []string) {\n")
+	buf.WriteString("\tfor _, kv := range os.Environ() {\n")
+	buf.WriteString("\t\tname, _, _ := strings.Cut(kv, \"=\")\n")
+	fmt.Fprintf(buf, "\t\tfor _, prefix := range []string{%s} {\n", strings.Join(quoted, ", "))
+	buf.WriteString("\t\t\tif strings.HasPrefix(name, prefix) && !slices.Contains(allowed, name) {\n")
+	buf.WriteString("\t\t\t\tfmt.Fprintf(os.Stderr, \"warning: unknown environment variable %s\\n\", name)\n")
+	buf.WriteString("\t\t\t\tbreak\n")
+	buf.WriteString("\t\t\t}\n")
+	buf.WriteString("\t\t}\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("}\n")
+}
@@ -0,0 +1,93 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Error-wrapping policies for a failing constructor in generated init code,
+// selected with //autodi:error-wrap in generate.go; "log" also reports the
+// failure with slog.Error before returning it:
+//
+//	//autodi:error-wrap func         fmt.Errorf("db.NewClient: %w", err) (the default)
+//	//autodi:error-wrap field log    fmt.Errorf("init EntClient: %w", err), logged
+//	//autodi:error-wrap none         the constructor's error as is
+const (
+	ErrorWrapFunc  = "func"
+	ErrorWrapField = "field"
+	ErrorWrapNone  = "none"
+)
+
+// errorWrapStyles lists the accepted //autodi:error-wrap styles.
+var errorWrapStyles = []string{ErrorWrapFunc, ErrorWrapField, ErrorWrapNone}
+
+// parseErrorWrap parses the arguments of //autodi:error-wrap.
+func parseErrorWrap(args []string) (style string, log bool, err error) {
+	if len(args) == 0 || len(args) > 2 || !slices.Contains(errorWrapStyles, args[0]) || len(args) == 2 && args[1] != "log" {
+		return "", false, fmt.Errorf("generate.go: //autodi:error-wrap needs one of %s, optionally followed by log", strings.Join(errorWrapStyles, ", "))
+	}
+	return args[0], len(args) == 2, nil
+}
+
+// addInitErrorImports adds the imports the error returns of failing
+// constructors use under the //autodi:error-wrap policy.
+func (cg *CodeGen) addInitErrorImports() {
+	if cg.cfg.ErrorWrap != ErrorWrapNone {
+		cg.imports.Add("fmt", "fmt")
+	}
+	if cg.cfg.ErrorLog {
+		cg.imports.Add("log/slog", "slog")
+	}
+}
+
+// initLabel names a constructor in its wrapped error: the Container field of
+// its first result with //autodi:error-wrap field (group members and invokes
+// have none), otherwise pkg.Func.
+func (cg *CodeGen) initLabel(p *Provider) string {
+	if cg.cfg.ErrorWrap == ErrorWrapField && len(p.Returns) > 0 {
+		if field, ok := cg.graph.TypeToField[p.Returns[0].TypeStr]; ok && cg.graph.ProviderMap[p.Returns[0].TypeStr] == p {
+			return field
+		}
+	}
+	return p.PkgName + "." + p.FuncName
+}
+
+// failReturn is how a generated builder returns the error of a failing
+// constructor or secret lookup.
+type failReturn struct {
+	results string // the results before the error ("" for none)
+	cleanup string // statement releasing what the builder built so far, or ""
+}
+
+// writeFailReturn emits cleanup and the return of a failed constructor's err
+// at the given indent.
+func (cg *CodeGen) writeFailReturn(buf *bytes.Buffer, p *Provider, indent string, fail failReturn) {
+	if fail.cleanup != "" {
+		fmt.Fprintf(buf, "%s%s\n", indent, fail.cleanup)
+	}
+	cg.writeInitError(buf, p, indent, fail.results)
+}
+
+// writeInitError emits the return of a failed constructor's err under the
+// //autodi:error-wrap policy, at the given indent; fail lists the results
+// returned before the error ("" for none).
+func (cg *CodeGen) writeInitError(buf *bytes.Buffer, p *Provider, indent, fail string) {
+	cg.addInitErrorImports()
+	label := cg.initLabel(p)
+	if cg.cfg.ErrorLog {
+		fmt.Fprintf(buf, "%s%s.Error(\"init failed\", \"provider\", %q, \"err\", err)\n", indent, cg.imports.Add("log/slog", "slog"), label)
+	}
+	if fail != "" {
+		fail += ", "
+	}
+	switch cg.cfg.ErrorWrap {
+	case ErrorWrapNone:
+		fmt.Fprintf(buf, "%sreturn %serr\n", indent, fail)
+	case ErrorWrapField:
+		fmt.Fprintf(buf, "%sreturn %sfmt.Errorf(\"init %s: %%w\", err)\n", indent, fail, label)
+	default:
+		fmt.Fprintf(buf, "%sreturn %sfmt.Errorf(\"%s: %%w\", err)\n", indent, fail, label)
+	}
+}
@@ -0,0 +1,152 @@
+package autodi
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// exampleFS holds the sample projects. Files carry a .tmpl suffix so the go
+// tool neither builds them as part of autodi nor treats go.mod as a module
+// boundary.
+//
+//go:embed all:examples
+var exampleFS embed.FS
+
+// exampleModuleRe finds the module path of a template's go.mod.
+var exampleModuleRe = regexp.MustCompile(`(?m)^module\s+(\S+)`)
+
+// exampleTemplates lists the bundled sample projects.
+func exampleTemplates() []string {
+	entries, _ := exampleFS.ReadDir("examples")
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	return names
+}
+
+// writeExample materializes template into dir. module replaces the
+// template's module path when set.
+func writeExample(template, dir, module string) error {
+	root := path.Join("examples", template)
+	gomod, err := exampleFS.ReadFile(path.Join(root, "go.mod.tmpl"))
+	if err != nil {
+		return fmt.Errorf("unknown example %q\n  hint: available: %s", template, strings.Join(exampleTemplates(), ", "))
+	}
+	m := exampleModuleRe.FindSubmatch(gomod)
+	if m == nil {
+		return fmt.Errorf("example %s: go.mod has no module directive", template)
+	}
+	from := string(m[1])
+
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s is not empty\n  hint: pass an empty or new directory", dir)
+	}
+
+	return fs.WalkDir(exampleFS, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := exampleFS.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if module != "" {
+			data = bytes.ReplaceAll(data, []byte(from), []byte(module))
+		}
+		rel := strings.TrimSuffix(strings.TrimPrefix(p, root+"/"), ".tmpl")
+		out := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(out, data, 0644)
+	})
+}
+
+// runExample writes a sample project and prints how to generate and run it.
+func runExample(template, dir, module string) error {
+	if dir == "" {
+		dir = template
+	}
+	if err := writeExample(template, dir, module); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "autodi: wrote example %s to %s\n\n", template, dir)
+	fmt.Fprintf(os.Stderr, "  cd %s\n  go mod tidy\n  go generate ./...\n  go run . --help\n", dir)
+	return nil
+}
+
+// runSelfTest generates and builds every bundled example with this autodi
+// binary, validating a release against projects that exercise groups,
+// bindings, cleanup and commands. Needs the go tool and module downloads.
+func runSelfTest(opts *Options) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	defer os.Chdir(cwd)
+
+	failed := 0
+	for _, template := range exampleTemplates() {
+		start := time.Now()
+		if err := selfTestExample(opts, template); err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "FAIL %s: %v\n", template, err)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "ok   %s (%s)\n", template, time.Since(start).Round(time.Millisecond))
+	}
+	if failed > 0 {
+		return errReported
+	}
+	return nil
+}
+
+// selfTestExample materializes one example in a temporary directory, runs the
+// generator in-process, then builds and vets the result.
+func selfTestExample(opts *Options, template string) error {
+	dir, err := os.MkdirTemp("", "autodi-selftest-"+template+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := writeExample(template, dir, ""); err != nil {
+		return err
+	}
+	if err := goTool(dir, "mod", "tidy"); err != nil {
+		return err
+	}
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+	if err := runGenerate(&Options{Verbose: opts.Verbose, Full: true, Profile: opts.Profile}); err != nil {
+		return fmt.Errorf("generate: %w", err)
+	}
+	if err := goTool(dir, "build", "./..."); err != nil {
+		return err
+	}
+	return goTool(dir, "vet", "./...")
+}
+
+// goTool runs a go subcommand in dir, returning its output on failure.
+func goTool(dir string, args ...string) error {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("go %s: %w\n%s", strings.Join(args, " "), err, out)
+	}
+	return nil
+}
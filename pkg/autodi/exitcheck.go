@@ -0,0 +1,155 @@
+package autodi
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ExitCall is a process-terminating call reachable from a provider constructor.
+type ExitCall struct {
+	Provider *Provider
+	Call     string // "os.Exit", "log.Fatalf", "(*zap.Logger).Fatal"
+	Position token.Position
+}
+
+// fatalMethods are logger methods that terminate the process.
+var fatalMethods = map[string]bool{
+	"Fatal": true, "Fatalf": true, "Fatalln": true, "Fatalw": true,
+}
+
+// FindExitCalls reports calls to os.Exit and Fatal-style loggers made by the
+// providers' constructors, directly or through functions and methods of the
+// same package. Such calls skip the generated cleanup, so dependencies that
+// were already constructed are never closed.
+func (s *Scanner) FindExitCalls(providers []*Provider) []ExitCall {
+	var calls []ExitCall
+	bodiesByPkg := make(map[string]map[*types.Func]*ast.BlockStmt)
+	for _, p := range providers {
+		pkg := s.pkgsByPath[p.PkgPath]
+		if pkg == nil || pkg.Types == nil {
+			continue
+		}
+		var obj types.Object = pkg.Types.Scope().Lookup(p.FuncName)
+		if p.Recv != "" {
+			obj, _, _ = types.LookupFieldOrMethod(p.Params[0].Type, true, pkg.Types, p.FuncName)
+		}
+		fn, ok := obj.(*types.Func)
+		if !ok {
+			continue
+		}
+		bodies, ok := bodiesByPkg[p.PkgPath]
+		if !ok {
+			bodies = funcBodies(pkg)
+			bodiesByPkg[p.PkgPath] = bodies
+		}
+		visited := make(map[*types.Func]bool)
+		var walk func(fn *types.Func)
+		walk = func(fn *types.Func) {
+			if visited[fn] {
+				return
+			}
+			visited[fn] = true
+			body := bodies[fn]
+			if body == nil {
+				return
+			}
+			ast.Inspect(body, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				callee := calledFunc(pkg.TypesInfo, call)
+				if callee == nil {
+					return true
+				}
+				if name := exitCallName(callee); name != "" {
+					calls = append(calls, ExitCall{Provider: p, Call: name, Position: s.fset.Position(call.Pos())})
+					return true
+				}
+				if callee.Pkg() == pkg.Types {
+					walk(callee.Origin())
+				}
+				return true
+			})
+		}
+		walk(fn)
+	}
+
+	sort.SliceStable(calls, func(i, j int) bool {
+		a, b := calls[i].Position, calls[j].Position
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Line < b.Line
+	})
+	return calls
+}
+
+// funcBodies maps the package's functions and methods to their bodies.
+func funcBodies(pkg *packages.Package) map[*types.Func]*ast.BlockStmt {
+	bodies := make(map[*types.Func]*ast.BlockStmt)
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Body == nil {
+				continue
+			}
+			if fn, ok := pkg.TypesInfo.Defs[fd.Name].(*types.Func); ok {
+				bodies[fn] = fd.Body
+			}
+		}
+	}
+	return bodies
+}
+
+// calledFunc returns the function or method a call expression invokes, or nil
+// for calls through function values.
+func calledFunc(info *types.Info, call *ast.CallExpr) *types.Func {
+	var ident *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	case *ast.IndexExpr: // generic instantiation
+		if sel, ok := fun.X.(*ast.SelectorExpr); ok {
+			ident = sel.Sel
+		} else if id, ok := fun.X.(*ast.Ident); ok {
+			ident = id
+		}
+	}
+	if ident == nil {
+		return nil
+	}
+	fn, _ := info.Uses[ident].(*types.Func)
+	return fn
+}
+
+// exitCallName returns a display name when fn terminates the process.
+func exitCallName(fn *types.Func) string {
+	if fn.Pkg() == nil {
+		return ""
+	}
+	sig, _ := fn.Type().(*types.Signature)
+	if sig != nil && sig.Recv() != nil {
+		if !fatalMethods[fn.Name()] {
+			return ""
+		}
+		return "(" + types.TypeString(sig.Recv().Type(), (*types.Package).Name) + ")." + fn.Name()
+	}
+	switch fn.Pkg().Path() {
+	case "os":
+		if fn.Name() == "Exit" {
+			return "os.Exit"
+		}
+	case "log":
+		if fatalMethods[fn.Name()] {
+			return "log." + fn.Name()
+		}
+	}
+	return ""
+}
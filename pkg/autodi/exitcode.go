@@ -0,0 +1,85 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Exit codes: the generated main exits with the code of the error a command
+// returns, so scripts can tell failures apart. Sentinel errors listed with
+//
+//	//autodi:exit-code-map example.com/app/internal/store.ErrNotFound=3 context.Canceled=130
+//
+// in generate.go map to their code when errors.Is matches, in declaration
+// order; otherwise an error in the chain with an ExitCode() int method sets
+// the code; anything else exits 1.
+
+// ExitCodeMapping maps a sentinel error to a process exit code.
+type ExitCodeMapping struct {
+	PkgPath string // import path of the package declaring the error
+	Var     string // its exported variable, e.g. ErrNotFound
+	Code    int
+}
+
+// parseExitCodeMap parses the <import path>.<Var>=<code> entries of an
+// //autodi:exit-code-map directive.
+func parseExitCodeMap(fields []string) ([]ExitCodeMapping, error) {
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("//autodi:exit-code-map needs <import path>.<Err>=<code> entries, e.g. //autodi:exit-code-map io.ErrUnexpectedEOF=3")
+	}
+	var mappings []ExitCodeMapping
+	for _, field := range fields {
+		ref, codeStr, _ := strings.Cut(field, "=")
+		pkgPath, name, ok := splitFuncRef(ref)
+		if !ok {
+			return nil, fmt.Errorf("//autodi:exit-code-map %s: want <import path>.<Err> naming an exported error variable", field)
+		}
+		code, err := strconv.Atoi(codeStr)
+		if err != nil || code < 0 || code > 255 {
+			return nil, fmt.Errorf("//autodi:exit-code-map %s: the exit code must be a number from 0 to 255", field)
+		}
+		mappings = append(mappings, ExitCodeMapping{PkgPath: pkgPath, Var: name, Code: code})
+	}
+	return mappings, nil
+}
+
+// addExitCodes appends mappings to the configured ones, rejecting an error
+// mapped twice.
+func (cfg *Config) addExitCodes(mappings []ExitCodeMapping) error {
+	for _, m := range mappings {
+		for _, prev := range cfg.ExitCodes {
+			if prev.PkgPath == m.PkgPath && prev.Var == m.Var {
+				return fmt.Errorf("//autodi:exit-code-map: %s.%s is mapped to both %d and %d", m.PkgPath, m.Var, prev.Code, m.Code)
+			}
+		}
+		cfg.ExitCodes = append(cfg.ExitCodes, m)
+	}
+	return nil
+}
+
+// writeExitCodeHelper emits exitCode, which maps the error of a command to
+// the exit code of the process.
+func (cg *CodeGen) writeExitCodeHelper(buf *bytes.Buffer) {
+	errorsQualifier := cg.imports.Add("errors", "errors")
+	buf.WriteString("// exitCode maps the error a command returned to the process exit code:\n")
+	buf.WriteString("// a sentinel of //autodi:exit-code-map, else the ExitCode() of an error\n")
+	buf.WriteString("// in its chain, else 1.\n")
+	buf.WriteString("func exitCode(err error) int {\n")
+	if len(cg.cfg.ExitCodes) > 0 {
+		buf.WriteString("\tswitch {\n")
+		for _, m := range cg.cfg.ExitCodes {
+			qualifier := cg.imports.Add(m.PkgPath, pkgShortName(m.PkgPath))
+			fmt.Fprintf(buf, "\tcase %s.Is(err, %s.%s):\n", errorsQualifier, qualifier, m.Var)
+			fmt.Fprintf(buf, "\t\treturn %d\n", m.Code)
+		}
+		buf.WriteString("\t}\n")
+	}
+	buf.WriteString("\tvar coder interface{ ExitCode() int }\n")
+	fmt.Fprintf(buf, "\tif %s.As(err, &coder) {\n", errorsQualifier)
+	buf.WriteString("\t\treturn coder.ExitCode()\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\treturn 1\n")
+	buf.WriteString("}\n")
+}
@@ -0,0 +1,77 @@
+package autodi
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// AnnotFactory opts an exported method in as a provider:
+//
+//	//autodi:factory
+//	func (f *Factory) NewClient(cfg Config) (*Client, error)
+//
+// The receiver becomes the provider's first dependency, so the factory is
+// constructed first, and the generated code calls the method through a method
+// expression: (*pkg.Factory).NewClient(factory, cfg).
+const AnnotFactory = "factory"
+
+// buildFactoryProvider creates a Provider from a //autodi:factory method, or
+// returns nil for any other method.
+func (s *Scanner) buildFactoryProvider(pkg *packages.Package, fn *ast.FuncDecl) *Provider {
+	annotations := ParseAnnotations(fn)
+	if !HasAnnotation(annotations, AnnotFactory) || HasAnnotation(annotations, AnnotIgnore) {
+		return nil
+	}
+	funcObj, ok := pkg.TypesInfo.Defs[fn.Name].(*types.Func)
+	if !ok {
+		return nil
+	}
+	p := s.buildProvider(pkg, fn, annotations)
+	if p == nil {
+		return nil
+	}
+	recv := resolveAliases(funcObj.Type().(*types.Signature).Recv().Type())
+	p.Recv = types.TypeString(recv, nil)
+	p.Params = append([]TypeRef{{
+		Type:    recv,
+		TypeStr: p.Recv,
+		PkgPath: typePkgPath(recv),
+	}}, p.Params...)
+	return p
+}
+
+// resolveFactory checks that the generated code can call a //autodi:factory
+// method: it and its receiver type must be exported, and the method can't be
+// swapped for a replay or fallback function. It runs before resolveReplay.
+func resolveFactory(p *Provider) error {
+	if p.Recv == "" {
+		return nil
+	}
+	recv := p.Params[0].Type
+	if ptr, ok := recv.(*types.Pointer); ok {
+		recv = ptr.Elem()
+	}
+	named, ok := recv.(*types.Named)
+	if !ok || !named.Obj().Exported() || !ast.IsExported(p.FuncName) {
+		return fmt.Errorf("%s: //autodi:factory %s.%s: the method and its receiver type must be exported",
+			p.Position, p.PkgName, p.FuncName)
+	}
+	fallback := false
+	for _, v := range GetAnnotationValues(p.Annotations, AnnotWhen) {
+		fallback = fallback || strings.Contains(v, "fallback=")
+	}
+	if HasAnnotation(p.Annotations, AnnotReplayable) || fallback {
+		return fmt.Errorf("%s: //autodi:factory %s.%s can't use a replay or fallback function",
+			p.Position, p.PkgName, p.FuncName)
+	}
+	return nil
+}
+
+// factoryFunc returns the method expression calling a //autodi:factory method.
+func (cg *CodeGen) factoryFunc(p *Provider) string {
+	return "(" + cg.shortType(p.Recv) + ")." + p.FuncName
+}
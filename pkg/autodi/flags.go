@@ -0,0 +1,145 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// AnnotFlag binds a field of a cobra command struct to a flag of its command:
+//
+//	type Serve struct {
+//		Port int `flag:"port" short:"p" usage:"listen port"`
+//		//autodi:flag verbose log every request
+//		Verbose bool
+//	}
+//
+// The generated wiring registers the flag on the struct's Command(), with the
+// field's value after construction as the default. Commands with
+// dependencies get the parsed values copied onto the DI-built instance.
+// A tree of subcommands registers its flags as persistent flags.
+const AnnotFlag = "flag"
+
+// FlagField is a command struct field bound to a flag.
+type FlagField struct {
+	Field string // Go field name
+	Name  string // flag name
+	Short string // one-letter shorthand, or ""
+	Usage string
+	Kind  string // pflag method suffix: String, Bool, Int, Duration, ...
+
+	// Set for the fields of an //autodi:flags struct
+	Default    string // default tag
+	HasDefault bool
+	Env        string // env tag: variable overriding the default
+}
+
+// flagKinds maps the supported field types to their pflag method suffix.
+var flagKinds = map[string]string{
+	"string":        "String",
+	"bool":          "Bool",
+	"int":           "Int",
+	"int64":         "Int64",
+	"uint":          "Uint",
+	"float64":       "Float64",
+	"time.Duration": "Duration",
+	"[]string":      "StringSlice",
+}
+
+// commandFlags reads the flag-bound fields of a command struct.
+func commandFlags(pkg *packages.Package, cmd *DiscoveredCommand) ([]FlagField, error) {
+	named, ok := pkg.Types.Scope().Lookup(cmd.StructName).Type().(*types.Named)
+	if !ok {
+		return nil, nil
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return nil, nil
+	}
+	spec := findStructSpec(pkg, cmd.StructName)
+	if spec == nil {
+		return nil, nil
+	}
+
+	var flags []FlagField
+	for _, field := range spec.Fields.List {
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			tag = reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
+		}
+		name, short, usage := tag.Get("flag"), tag.Get("short"), tag.Get("usage")
+		if value := fieldDirective(field, AnnotFlag); value != "" {
+			name, usage, _ = strings.Cut(value, " ")
+			usage = strings.TrimSpace(usage)
+		}
+		if name == "" {
+			continue
+		}
+		for _, ident := range field.Names {
+			pos := pkg.Fset.Position(ident.Pos())
+			if !ident.IsExported() {
+				return nil, fmt.Errorf("%s: flag %s: field %s.%s must be exported", pos, name, cmd.StructName, ident.Name)
+			}
+			kind, ok := flagKinds[types.TypeString(fieldType(st, ident.Name), nil)]
+			if !ok {
+				return nil, fmt.Errorf("%s: flag %s: %s.%s has type %s\n  hint: flag fields are string, bool, int, int64, uint, float64, time.Duration or []string",
+					pos, name, cmd.StructName, ident.Name, types.TypeString(fieldType(st, ident.Name), types.RelativeTo(pkg.Types)))
+			}
+			if len(short) > 1 {
+				return nil, fmt.Errorf("%s: flag %s: short:%q must be one letter", pos, name, short)
+			}
+			flags = append(flags, FlagField{Field: ident.Name, Name: name, Short: short, Usage: usage, Kind: kind})
+		}
+	}
+	return flags, nil
+}
+
+// writeFlagRegistration registers a command's flag fields on its cobra
+// command cmdVar, bound to the fields of instVar.
+func (cg *CodeGen) writeFlagRegistration(buf *bytes.Buffer, indent, cmdVar, instVar string, cmd *DiscoveredCommand) {
+	// Persistent under subcommands: their init reads the parent's flags from
+	// the executing command
+	set := "Flags"
+	if !cmd.IsSingle || len(cmd.Children) > 0 {
+		set = "PersistentFlags"
+	}
+	for _, f := range cmd.Flags {
+		field := instVar + "." + f.Field
+		if f.Short != "" {
+			fmt.Fprintf(buf, "%s%s.%s().%sVarP(&%s, %q, %q, %s, %q)\n", indent, cmdVar, set, f.Kind, field, f.Name, f.Short, field, f.Usage)
+		} else {
+			fmt.Fprintf(buf, "%s%s.%s().%sVar(&%s, %q, %s, %q)\n", indent, cmdVar, set, f.Kind, field, f.Name, field, f.Usage)
+		}
+	}
+	cg.writeFlagStructRegistration(buf, indent, cmdVar, set, cmd)
+}
+
+// writeFlagCopies copies the flags given on the command line onto the
+// DI-built instances of a command and its children; cmd is the executing
+// cobra command, whose flag set includes its parents' persistent flags.
+func (cg *CodeGen) writeFlagCopies(buf *bytes.Buffer, cmd *DiscoveredCommand, instVar string, children []builtCommand) {
+	for _, f := range cmd.Flags {
+		fmt.Fprintf(buf, "\tcopyFlag(cmd, %q, &%s.%s, cmd.Flags().Get%s)\n", f.Name, instVar, f.Field, f.Kind)
+		cg.hasFlagCopy = true
+	}
+	for _, b := range children {
+		cg.writeFlagCopies(buf, b.cmd, b.instVar, b.children)
+	}
+}
+
+// writeFlagCopyHelper emits copyFlag.
+func (cg *CodeGen) writeFlagCopyHelper(buf *bytes.Buffer, cobraQualifier string) {
+	buf.WriteString("// copyFlag sets dst to a flag's value when it was given on the command line.\n")
+	fmt.Fprintf(buf, "func copyFlag[T any](cmd *%s.Command, name string, dst *T, get func(string) (T, error)) {\n", cobraQualifier)
+	buf.WriteString("\tif f := cmd.Flags().Lookup(name); f == nil || !f.Changed {\n")
+	buf.WriteString("\t\treturn\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\tif v, err := get(name); err == nil {\n")
+	buf.WriteString("\t\t*dst = v\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("}\n")
+}
@@ -0,0 +1,253 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// AnnotFlags marks a struct type as the flags of the commands taking it:
+//
+//	//autodi:flags
+//	type ServeOptions struct {
+//		Port    int           `default:"8080" usage:"listen port" env:"PORT"`
+//		Timeout time.Duration `flag:"timeout" default:"30s"`
+//		Debug   bool          `flag:"-"`
+//	}
+//
+//	func NewServe(opts ServeOptions, svc *Service) *Serve
+//
+// Each exported field is registered as a flag of the command — named by its
+// flag tag or //autodi:flag directive, else the kebab-case field name — with
+// the default tag as default, overridden by the env variable when it is set.
+// The init function fills the struct from the parsed flags and passes it,
+// or a pointer to it, to the constructor; it is not resolved as a dependency.
+const AnnotFlags = "flags"
+
+// flagsStream marks a command parameter of an //autodi:flags struct type.
+const flagsStream = "flags"
+
+// applyFlagStructs marks the command parameters of an //autodi:flags struct
+// type, declared in one of the loaded packages, and reads their fields.
+func applyFlagStructs(set *PackageSet, cmd *DiscoveredCommand) error {
+	for i, param := range cmd.Params {
+		if param.Stream != "" || param.InStruct != nil {
+			continue
+		}
+		t := param.Type
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		named, ok := t.(*types.Named)
+		if !ok || named.Obj().Pkg() == nil {
+			continue
+		}
+		if _, ok := named.Underlying().(*types.Struct); !ok {
+			continue
+		}
+		pkg := set.pkg(named.Obj().Pkg().Path())
+		if pkg == nil || !typeHasDirective(pkg, named.Obj().Name(), AnnotFlags) {
+			continue
+		}
+		fields, err := structFlags(pkg, named)
+		if err != nil {
+			return err
+		}
+		cmd.Params[i].Stream = flagsStream
+		cmd.Params[i].Flags = fields
+	}
+	return nil
+}
+
+// pkg returns the loaded root package with an import path, nil if none.
+func (ps *PackageSet) pkg(path string) *packages.Package {
+	for _, pkg := range ps.Pkgs {
+		if pkg.PkgPath == path {
+			return pkg
+		}
+	}
+	return nil
+}
+
+// structFlags reads the flags of the exported fields of an //autodi:flags
+// struct.
+func structFlags(pkg *packages.Package, named *types.Named) ([]FlagField, error) {
+	st := named.Underlying().(*types.Struct)
+	spec := findStructSpec(pkg, named.Obj().Name())
+	if spec == nil {
+		return nil, nil
+	}
+	var flags []FlagField
+	for _, field := range spec.Fields.List {
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			tag = reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
+		}
+		name, usage := tag.Get("flag"), tag.Get("usage")
+		if value := fieldDirective(field, AnnotFlag); value != "" {
+			name, usage, _ = strings.Cut(value, " ")
+			usage = strings.TrimSpace(usage)
+		}
+		env, _, _ := strings.Cut(tag.Get("env"), ",")
+		if value := fieldDirective(field, AnnotEnv); value != "" {
+			env = value
+		}
+		if name == "-" {
+			continue
+		}
+		for _, ident := range field.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			pos := pkg.Fset.Position(ident.Pos())
+			flagName := name
+			if flagName == "" {
+				flagName = pascalToKebab(ident.Name)
+			}
+			kind, ok := flagKinds[types.TypeString(fieldType(st, ident.Name), nil)]
+			if !ok {
+				return nil, fmt.Errorf("%s: //autodi:flags %s: %s.%s has type %s\n  hint: flag fields are string, bool, int, int64, uint, float64, time.Duration or []string; exclude others with flag:\"-\"",
+					pos, named.Obj().Name(), named.Obj().Name(), ident.Name, types.TypeString(fieldType(st, ident.Name), types.RelativeTo(pkg.Types)))
+			}
+			short := tag.Get("short")
+			if len(short) > 1 {
+				return nil, fmt.Errorf("%s: flag %s: short:%q must be one letter", pos, flagName, short)
+			}
+			def, hasDefault := tag.Lookup("default")
+			if hasDefault {
+				if _, err := flagDefault(kind, def, "time"); err != nil {
+					return nil, fmt.Errorf("%s: flag %s: default:%q: %v", pos, flagName, def, err)
+				}
+			}
+			flags = append(flags, FlagField{Field: ident.Name, Name: flagName, Short: short, Usage: usage, Kind: kind,
+				Default: def, HasDefault: hasDefault, Env: env})
+		}
+	}
+	return flags, nil
+}
+
+// flagDefault returns the Go source of a default tag for a flag of kind.
+func flagDefault(kind, value, timeQualifier string) (string, error) {
+	var err error
+	switch kind {
+	case "String":
+		return strconv.Quote(value), nil
+	case "Bool":
+		var b bool
+		b, err = strconv.ParseBool(value)
+		return strconv.FormatBool(b), err
+	case "Int", "Int64":
+		_, err = strconv.ParseInt(value, 0, 64)
+	case "Uint":
+		_, err = strconv.ParseUint(value, 0, 64)
+	case "Float64":
+		_, err = strconv.ParseFloat(value, 64)
+	case "Duration":
+		var d time.Duration
+		d, err = time.ParseDuration(value)
+		return durationSource(d, timeQualifier), err
+	case "StringSlice":
+		var quoted []string
+		for _, s := range strings.Split(value, ",") {
+			quoted = append(quoted, strconv.Quote(s))
+		}
+		return "[]string{" + strings.Join(quoted, ", ") + "}", nil
+	}
+	return value, err
+}
+
+// flagZero returns the Go source of the zero default of a flag of kind.
+func flagZero(kind string) string {
+	switch kind {
+	case "String":
+		return `""`
+	case "Bool":
+		return "false"
+	case "StringSlice":
+		return "nil"
+	}
+	return "0"
+}
+
+// writeFlagStructRegistration registers the flags of a command's
+// //autodi:flags parameters on its cobra command cmdVar.
+func (cg *CodeGen) writeFlagStructRegistration(buf *bytes.Buffer, indent, cmdVar, set string, cmd *DiscoveredCommand) {
+	for _, param := range cmd.Params {
+		if param.Stream != flagsStream {
+			continue
+		}
+		for _, f := range param.Flags {
+			def := flagZero(f.Kind)
+			if f.HasDefault {
+				timeQualifier := ""
+				if f.Kind == "Duration" {
+					timeQualifier = cg.imports.Add("time", "time")
+				}
+				def, _ = flagDefault(f.Kind, f.Default, timeQualifier)
+			}
+			if f.Short != "" {
+				fmt.Fprintf(buf, "%s%s.%s().%sP(%q, %q, %s, %q)\n", indent, cmdVar, set, f.Kind, f.Name, f.Short, def, f.Usage)
+			} else {
+				fmt.Fprintf(buf, "%s%s.%s().%s(%q, %s, %q)\n", indent, cmdVar, set, f.Kind, f.Name, def, f.Usage)
+			}
+			if f.Env != "" {
+				fmt.Fprintf(buf, "%sflagFromEnv(%s.%s(), %q, %q)\n", indent, cmdVar, set, f.Name, f.Env)
+				cg.hasFlagEnv = true
+			}
+		}
+	}
+}
+
+// flagStructArg returns the argument of an //autodi:flags parameter: the
+// struct filled from the executing command's parsed flags.
+func (cg *CodeGen) flagStructArg(param TypeRef) string {
+	typeStr, amp := cg.typeSource(param.Type), ""
+	if ptr, ok := param.Type.(*types.Pointer); ok {
+		typeStr, amp = cg.typeSource(ptr.Elem()), "&"
+	}
+	var fields []string
+	for _, f := range param.Flags {
+		fields = append(fields, fmt.Sprintf("%s: flagValue(cmd.Flags().Get%s, %q)", f.Field, f.Kind, f.Name))
+	}
+	cg.hasFlagValue = true
+	return fmt.Sprintf("%s%s{%s}", amp, typeStr, strings.Join(fields, ", "))
+}
+
+// writeFlagStructHelpers emits flagValue and, when a flag has an env
+// variable, flagFromEnv.
+func (cg *CodeGen) writeFlagStructHelpers(buf *bytes.Buffer) {
+	if cg.hasFlagValue {
+		buf.WriteString("// flagValue returns a flag's parsed value, or its zero value when it is missing.\n")
+		buf.WriteString("func flagValue[T any](get func(string) (T, error), name string) T {\n")
+		buf.WriteString("\tv, _ := get(name)\n")
+		buf.WriteString("\treturn v\n")
+		buf.WriteString("}\n")
+	}
+	if cg.hasFlagEnv {
+		if cg.hasFlagValue {
+			buf.WriteString("\n")
+		}
+		pflag := cg.imports.Add("github.com/spf13/pflag", "pflag")
+		osQualifier := cg.imports.Add("os", "os")
+		fmtQualifier := cg.imports.Add("fmt", "fmt")
+		buf.WriteString("// flagFromEnv sets a flag's default from an environment variable when it is set.\n")
+		fmt.Fprintf(buf, "func flagFromEnv(flags *%s.FlagSet, name, env string) {\n", pflag)
+		fmt.Fprintf(buf, "\tv, ok := %s.LookupEnv(env)\n", osQualifier)
+		buf.WriteString("\tif !ok {\n")
+		buf.WriteString("\t\treturn\n")
+		buf.WriteString("\t}\n")
+		buf.WriteString("\tf := flags.Lookup(name)\n")
+		buf.WriteString("\tif err := f.Value.Set(v); err != nil {\n")
+		fmt.Fprintf(buf, "\t\t%s.Fprintf(%s.Stderr, \"warning: %%s=%%q: %%v\\n\", env, v, err)\n", fmtQualifier, osQualifier)
+		buf.WriteString("\t\treturn\n")
+		buf.WriteString("\t}\n")
+		buf.WriteString("\tf.DefValue = f.Value.String()\n")
+		buf.WriteString("}\n")
+	}
+}
@@ -0,0 +1,103 @@
+package autodi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// GoMod is the part of go.mod autodi needs: the module path and the replace
+// directives that point at local directories.
+type GoMod struct {
+	Module    string
+	GoVersion string            // go directive, e.g. "1.22"; "" when absent
+	Requires  map[string]bool   // required module paths
+	Replaces  map[string]string // module path → absolute directory of a local replacement
+}
+
+// parseGoMod parses go.mod in root with modfile, keeping requirements and
+// filesystem replacements. Version-to-version replacements are left to go list.
+func parseGoMod(root string) (*GoMod, error) {
+	path := filepath.Join(root, "go.mod")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("open go.mod: %w", err)
+	}
+	f, err := modfile.Parse(path, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("parse go.mod: %w", err)
+	}
+	if f.Module == nil || f.Module.Mod.Path == "" {
+		return nil, fmt.Errorf("module directive not found in go.mod")
+	}
+
+	gm := &GoMod{
+		Module:   f.Module.Mod.Path,
+		Requires: make(map[string]bool),
+		Replaces: make(map[string]string),
+	}
+	if f.Go != nil {
+		gm.GoVersion = f.Go.Version
+	}
+	for _, r := range f.Require {
+		gm.Requires[r.Mod.Path] = true
+	}
+	for _, r := range f.Replace {
+		if r.New.Version != "" || !modfile.IsDirectoryPath(r.New.Path) {
+			continue
+		}
+		dir := filepath.FromSlash(r.New.Path)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, dir)
+		}
+		gm.Replaces[r.Old.Path] = filepath.Clean(dir)
+	}
+	return gm, nil
+}
+
+// nestedReplacements returns the required modules replaced by a directory
+// inside the module root, keyed by module path with module-relative slash
+// directories. They are scanned under their own import paths.
+func nestedReplacements(root string, gm *GoMod) map[string]string {
+	nested := make(map[string]string)
+	for mod, dir := range gm.Replaces {
+		if !gm.Requires[mod] {
+			continue
+		}
+		rel, err := filepath.Rel(root, dir)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		nested[mod] = filepath.ToSlash(rel)
+	}
+	return nested
+}
+
+// RelPath maps a package path to its directory relative to the module root,
+// resolving nested replaced modules and workspace members through their
+// directories. Packages of other modules are returned unchanged.
+func (cfg *Config) RelPath(pkgPath string) string {
+	mod := cfg.moduleOf(pkgPath)
+	dir, ok := cfg.Replaces[mod]
+	if !ok {
+		dir, ok = cfg.Workspace[mod]
+	}
+	if !ok {
+		return strings.TrimPrefix(pkgPath, cfg.Module+"/")
+	}
+	return dir + strings.TrimPrefix(pkgPath, mod)
+}
+
+// ownsPackage reports whether a package belongs to the module or one of its
+// nested replaced modules; workspace members export their own providers.
+func (cfg *Config) ownsPackage(pkgPath string) bool {
+	mod := cfg.moduleOf(pkgPath)
+	if mod == cfg.Module {
+		return true
+	}
+	_, ok := cfg.Replaces[mod]
+	return ok
+}
@@ -0,0 +1,713 @@
+package autodi
+
+import (
+	"fmt"
+	"go/types"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// implEntry records that a provider's return type implements an interface.
+type implEntry struct {
+	provider   *Provider
+	retTypeStr string
+}
+
+// Graph holds the resolved dependency graph.
+type Graph struct {
+	Providers   []*Provider
+	ProviderMap map[string]*Provider   // typeStr → provider
+	Bindings    map[string]string      // interface typeStr → concrete typeStr
+	BindSource  map[string]string      // interface typeStr → how it was bound (Bind* constants)
+	Groups      map[string][]*Provider // group name → providers
+	TypeToField map[string]string      // typeStr → Container field name
+
+	SecretsSource string               // typeStr of the SecretsSource provider, if //autodi:secret is used
+	TestReplace   map[string]*Provider // typeStr → test container fake (//autodi:test-replace)
+	Shadowed      []Shadowed           // duplicate providers resolved by //autodi:precedence
+
+	cfg            *Config
+	shortToFull    map[string]string           // short type name → full type string
+	pkgNameToPath  map[string]string           // pkg short name → full pkg path
+	ifaceTypes     map[string]*types.Interface // full typeStr → interface type from loaded packages
+	fieldOverrides map[string]string           // typeStr → Container field name from //autodi:field
+
+	// Performance indexes (built once, queried many times)
+	typeIndex    map[string]types.Type  // typeStr → types.Type (Step 3)
+	implIndex    map[string][]implEntry // ifaceTypeStr → implementors (Step 1)
+	impls        *implMatrix            // cached types.Implements results (Step 2)
+	fieldToGroup map[string]string      // fieldName → groupName reverse index (Step 5)
+	sortedTypes  []string               // pre-sorted ProviderMap keys (Step 7)
+}
+
+// BuildGraph constructs the dependency graph from discovered providers.
+func BuildGraph(providers []*Provider, cfg *Config, pkgIndex map[string]string, ifaceTypes map[string]*types.Interface) (*Graph, []error) {
+	g := &Graph{
+		Providers:      providers,
+		ProviderMap:    make(map[string]*Provider),
+		Bindings:       make(map[string]string),
+		BindSource:     make(map[string]string),
+		Groups:         make(map[string][]*Provider),
+		TypeToField:    make(map[string]string),
+		TestReplace:    make(map[string]*Provider),
+		cfg:            cfg,
+		shortToFull:    make(map[string]string),
+		pkgNameToPath:  make(map[string]string),
+		ifaceTypes:     ifaceTypes,
+		typeIndex:      make(map[string]types.Type),
+		impls:          newImplMatrix(),
+		fieldToGroup:   make(map[string]string),
+		fieldOverrides: make(map[string]string),
+	}
+
+	// Seed pkgNameToPath with the full package index from scanner
+	for name, path := range pkgIndex {
+		g.pkgNameToPath[name] = path
+	}
+
+	// Build short-to-full type name mapping and typeStr→Type index
+	g.buildTypeIndex(providers)
+
+	var errs []error
+
+	// Phase 1: Classify providers into groups
+	for _, p := range providers {
+		if err := resultObjectGroups(p); err != nil {
+			errs = append(errs, err)
+		}
+		for _, groupName := range p.Groups {
+			if _, ok := cfg.Groups[groupName]; !ok {
+				errs = append(errs, fmt.Errorf("%s: %s.%s: group:%q is not declared\n  hint: //autodi:group %s []<Interface>",
+					p.Position, p.PkgName, p.FuncName, groupName, groupName))
+			}
+		}
+		// //autodi:group on the constructor joins a group from anywhere
+		for _, groupName := range GetAnnotationValues(p.Annotations, AnnotGroup) {
+			if _, ok := cfg.Groups[groupName]; !ok {
+				errs = append(errs, fmt.Errorf("%s: %s.%s: //autodi:group %s is not declared\n  hint: //autodi:group %s []<Interface> in generate.go",
+					p.Position, p.PkgName, p.FuncName, groupName, groupName))
+				continue
+			}
+			if !slices.Contains(p.Groups, groupName) {
+				p.Groups = append(p.Groups, groupName)
+			}
+		}
+		for groupName, groupCfg := range cfg.Groups {
+			for _, gpath := range groupCfg.Paths {
+				if cfg.inGroupPath(p.PkgPath, gpath) && !slices.Contains(p.Groups, groupName) {
+					p.Groups = append(p.Groups, groupName)
+				}
+			}
+		}
+	}
+
+	// Phase 2: Register each provider's return types in the provider map
+	for _, p := range providers {
+		if p.IsInvoke {
+			continue
+		}
+
+		// //autodi:as replaces return types with interface types
+		errs = append(errs, g.resolveAs(p)...)
+		if err := g.registerFieldOverride(p); err != nil {
+			errs = append(errs, err)
+		}
+		if len(p.Groups) == 0 {
+			for _, ifaceStr := range p.allAs() {
+				if err := g.registerProvider(ifaceStr, p); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+
+		for i, ret := range p.Returns {
+			typeStr := ret.TypeStr
+
+			// Skip grouped providers from the singleton map
+			if len(p.Groups) > 0 {
+				continue
+			}
+			// Concrete type hidden behind //autodi:as
+			if len(p.asTypes(i)) > 0 {
+				continue
+			}
+
+			if err := g.registerProvider(typeStr, p); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	g.dropShadowed()
+
+	// Add grouped providers + build fieldToGroup reverse index (Step 5)
+	for _, p := range providers {
+		for _, groupName := range p.Groups {
+			g.Groups[groupName] = append(g.Groups[groupName], p)
+		}
+	}
+	for name, members := range g.Groups {
+		sortGroupMembers(members)
+		g.fieldToGroup[GroupFieldName(name)] = name
+	}
+	errs = append(errs, g.checkAdapters()...)
+
+	// //autodi:route handlers of http.Handler groups are served by one mux
+	errs = append(errs, g.addRouteMux()...)
+
+	// Groups of jobs are added to the generated cron
+	errs = append(errs, g.addJobCron()...)
+
+	// Build pre-sorted provider keys (Step 7)
+	g.rebuildSortedTypes()
+
+	// Order //autodi:secret consumers after the SecretsSource provider
+	errs = append(errs, g.resolveSecretsSource(providers)...)
+
+	// Build interface→implementors index (Step 1) — after ProviderMap is populated
+	g.buildImplIndex()
+
+	// Per-consumer bindings take their parameters out of interface resolution
+	errs = append(errs, g.applyParamBindings(providers)...)
+
+	// Phase 3: Resolve interface bindings
+	bindErrs := g.resolveBindings(providers)
+	errs = append(errs, bindErrs...)
+
+	// //autodi:replace swaps providers after bindings are settled
+	errs = append(errs, g.applySubstitutions()...)
+
+	// Custom field names may collide where the default names didn't
+	errs = append(errs, g.checkFieldNames()...)
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return g, nil
+}
+
+// resolveAs resolves a provider's //autodi:as targets to full interface type
+// strings and checks that the return type implements each of them. A target
+// applies to the first return unless prefixed with a result index or name:
+//
+//	//autodi:as w=io.Writer
+//	func NewPipe() (r io.Reader, w *PipeWriter, err error)
+func (g *Graph) resolveAs(p *Provider) []error {
+	targets := GetAnnotationValues(p.Annotations, AnnotAs)
+	if len(targets) == 0 || len(p.Returns) == 0 {
+		return nil
+	}
+	var errs []error
+	for _, target := range targets {
+		i := 0
+		if key, iface, ok := strings.Cut(target, "="); ok {
+			if i = p.returnIndex(key); i < 0 {
+				errs = append(errs, fmt.Errorf("%s: //autodi:as %s: %s.%s has no result %s\n  hint: use the result's index (from 0, error excluded) or its name",
+					p.Position, target, p.PkgName, p.FuncName, key))
+				continue
+			}
+			target = iface
+		}
+		ret := p.Returns[i]
+		ifaceStr := g.resolveConfigType(target)
+		iface := g.findIfaceType(ifaceStr)
+		if iface == nil {
+			errs = append(errs, fmt.Errorf("%s: //autodi:as %s: unknown interface type", p.Position, target))
+			continue
+		}
+		if !implementsIface(ret.Type, iface) {
+			errs = append(errs, fmt.Errorf("%s: //autodi:as %s: %s does not implement it",
+				p.Position, target, toShortTypeName(ret.TypeStr)))
+			continue
+		}
+		if i == 0 {
+			p.As = append(p.As, ifaceStr)
+			continue
+		}
+		if p.AsAt == nil {
+			p.AsAt = make(map[int][]string)
+		}
+		p.AsAt[i] = append(p.AsAt[i], ifaceStr)
+	}
+	return errs
+}
+
+// returnIndex resolves a result index or result name to an index into Returns.
+func (p *Provider) returnIndex(key string) int {
+	if i, err := strconv.Atoi(key); err == nil {
+		if i >= 0 && i < len(p.Returns) {
+			return i
+		}
+		return -1
+	}
+	for i, ret := range p.Returns {
+		if ret.Name == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// asTypes returns the //autodi:as interfaces return i is provided as.
+func (p *Provider) asTypes(i int) []string {
+	if i == 0 {
+		return p.As
+	}
+	return p.AsAt[i]
+}
+
+// allAs returns the //autodi:as interfaces of every return, in return order.
+func (p *Provider) allAs() []string {
+	as := slices.Clone(p.As)
+	for i := 1; i < len(p.Returns); i++ {
+		as = append(as, p.AsAt[i]...)
+	}
+	return as
+}
+
+// rebuildSortedTypes rebuilds the pre-sorted ProviderMap keys.
+func (g *Graph) rebuildSortedTypes() {
+	g.sortedTypes = make([]string, 0, len(g.ProviderMap))
+	for typeStr := range g.ProviderMap {
+		g.sortedTypes = append(g.sortedTypes, typeStr)
+	}
+	sort.Strings(g.sortedTypes)
+}
+
+// buildTypeIndex builds lookup maps from all discovered types.
+func (g *Graph) buildTypeIndex(providers []*Provider) {
+	for _, p := range providers {
+		g.pkgNameToPath[p.PkgName] = p.PkgPath
+
+		for _, ret := range p.Returns {
+			short := toShortTypeName(ret.TypeStr)
+			if short != ret.TypeStr {
+				g.shortToFull[short] = ret.TypeStr
+			}
+			if ret.PkgPath != "" {
+				parts := strings.Split(ret.PkgPath, "/")
+				g.pkgNameToPath[parts[len(parts)-1]] = ret.PkgPath
+			}
+			// Step 3: index typeStr → Type
+			g.typeIndex[ret.TypeStr] = ret.Type
+		}
+
+		for _, param := range p.Params {
+			short := toShortTypeName(param.TypeStr)
+			if short != param.TypeStr {
+				g.shortToFull[short] = param.TypeStr
+			}
+			if param.PkgPath != "" {
+				parts := strings.Split(param.PkgPath, "/")
+				g.pkgNameToPath[parts[len(parts)-1]] = param.PkgPath
+			}
+			// Step 3: index typeStr → Type
+			g.typeIndex[param.TypeStr] = param.Type
+			// Also index slice and keyed map element types
+			if elemStr, _, ok := collectedElem(param.TypeStr); ok {
+				if elem := collectedElemType(param.Type); elem != nil {
+					g.typeIndex[elemStr] = elem
+				}
+			}
+		}
+	}
+}
+
+// buildImplIndex pre-computes the interface→implementors index and populates the implCache.
+func (g *Graph) buildImplIndex() {
+	g.implIndex = make(map[string][]implEntry)
+
+	// Collect all known interface types from providers + ifaceTypes
+	allIfaces := make(map[string]*types.Interface)
+	for typeStr, t := range g.typeIndex {
+		if iface, ok := t.Underlying().(*types.Interface); ok {
+			allIfaces[typeStr] = iface
+		}
+	}
+	for typeStr, iface := range g.ifaceTypes {
+		if _, exists := allIfaces[typeStr]; !exists {
+			allIfaces[typeStr] = iface
+		}
+	}
+
+	// For each interface, check which providers implement it
+	for ifaceStr, iface := range allIfaces {
+		for _, p := range g.Providers {
+			if p.IsInvoke || p.Scope != "" {
+				continue
+			}
+			for _, ret := range p.Returns {
+				// Also checks *T
+				if g.impls.implementsIface(ret.Type, ret.TypeStr, iface, ifaceStr) {
+					g.implIndex[ifaceStr] = append(g.implIndex[ifaceStr], implEntry{
+						provider:   p,
+						retTypeStr: ret.TypeStr,
+					})
+					break
+				}
+			}
+		}
+		// Sort entries by //autodi:order, then PkgPath for deterministic output
+		entries := g.implIndex[ifaceStr]
+		sort.SliceStable(entries, func(i, j int) bool {
+			return providerLess(entries[i].provider, entries[j].provider)
+		})
+	}
+}
+
+func isPointer(t types.Type) bool {
+	_, ok := t.(*types.Pointer)
+	return ok
+}
+
+// AllSingletonProviders returns all non-group, non-invoke providers in dependency order.
+func (g *Graph) AllSingletonProviders() ([]*Provider, error) {
+	// Use pre-sorted keys (Step 7)
+	sorted, err := g.TopologicalSort(g.sortedTypes)
+	if err != nil {
+		return nil, err
+	}
+	singletons := sorted[:0]
+	for _, p := range sorted {
+		if p.Scope == "" {
+			singletons = append(singletons, p)
+		}
+	}
+	return singletons, nil
+}
+
+// EntryProviders returns the singleton providers needed for an entry point, in dependency order.
+func (g *Graph) EntryProviders(fieldNames []string) ([]*Provider, error) {
+	// Build reverse map: fieldName → typeStr
+	fieldToType := make(map[string]string)
+	for typeStr, fieldName := range g.TypeToField {
+		fieldToType[fieldName] = typeStr
+	}
+
+	needed := make(map[string]bool)
+
+	for _, fieldName := range fieldNames {
+		// O(1) group lookup via reverse index (Step 5)
+		if groupName, ok := g.fieldToGroup[fieldName]; ok {
+			for _, p := range g.Groups[groupName] {
+				for _, param := range p.Params {
+					needed[param.TypeStr] = true
+				}
+			}
+			continue
+		}
+
+		if typeStr, ok := fieldToType[fieldName]; ok {
+			needed[typeStr] = true
+		}
+	}
+
+	// Use shared expansion helper (Step 6)
+	expanded := g.expandTransitive(needed)
+
+	var targets []string
+	for t := range expanded {
+		targets = append(targets, t)
+	}
+	sort.Strings(targets)
+
+	return g.TopologicalSort(targets)
+}
+
+// expandTransitive expands a set of needed types to include all transitive dependencies
+// and invoke providers whose dependencies are satisfied. Shared helper for Step 6.
+func (g *Graph) expandTransitive(needed map[string]bool) map[string]bool {
+	expanded := make(map[string]bool)
+	var expand func(string)
+	expand = func(typeStr string) {
+		resolved := g.resolveType(typeStr)
+		if expanded[resolved] {
+			return
+		}
+		expanded[resolved] = true
+
+		provider := g.ProviderMap[resolved]
+		if provider == nil {
+			return
+		}
+		for _, param := range provider.Params {
+			if param.Stream == "" {
+				expand(param.TypeStr)
+			}
+		}
+		for _, dep := range provider.ExtraDeps {
+			expand(dep)
+		}
+	}
+
+	for t := range needed {
+		expand(t)
+	}
+
+	// Include invoke providers whose dependencies are all satisfied
+	for _, p := range g.Providers {
+		if !p.IsInvoke {
+			continue
+		}
+		allSatisfied := true
+		for _, param := range p.Params {
+			resolved := g.resolveType(param.TypeStr)
+			if !expanded[resolved] {
+				allSatisfied = false
+				break
+			}
+		}
+		if allSatisfied {
+			for _, ret := range p.Returns {
+				expanded[ret.TypeStr] = true
+			}
+		}
+	}
+
+	return expanded
+}
+
+// ValidateEntry checks that all providers for an entry have their dependencies satisfied.
+func (g *Graph) ValidateEntry(name string, providers []*Provider) []error {
+	provided := make(map[string]bool)
+	for _, p := range providers {
+		for _, ret := range p.Returns {
+			provided[ret.TypeStr] = true
+		}
+		for _, ifaceStr := range p.allAs() {
+			provided[ifaceStr] = true
+		}
+	}
+	for iface, concrete := range g.Bindings {
+		if provided[concrete] {
+			provided[iface] = true
+		}
+	}
+
+	var errs []error
+	for _, p := range providers {
+		for _, param := range p.Params {
+			if param.Optional || param.Stream != "" {
+				continue
+			}
+			resolved := g.resolveType(param.TypeStr)
+			if !provided[resolved] {
+				if elemType, _, ok := collectedElem(param.TypeStr); ok {
+					if autoProviders := g.AutoCollect(elemType); len(autoProviders) > 0 {
+						continue
+					}
+				}
+				if param.Type != nil && isPredeclaredBasic(param.Type) {
+					errs = append(errs, diagf(CodeMissingBasicDep, p.Position,
+						name, p.PkgName, p.FuncName, param.TypeStr))
+					continue
+				}
+				errs = append(errs, diagf(CodeMissingDep, p.Position,
+					name, p.PkgName, p.FuncName, toShortTypeName(param.TypeStr)))
+			}
+		}
+	}
+	return errs
+}
+
+// fieldNameToGroup returns the group name for a Container field name, or "" if not a group.
+// Uses O(1) reverse index lookup (Step 5).
+func (g *Graph) fieldNameToGroup(fieldName string) string {
+	return g.fieldToGroup[fieldName]
+}
+
+// GroupFieldName converts a group config name to a Container field name.
+// "admin_controllers" → "AdminControllers"
+// Uses strings.Builder instead of += (Step 8).
+func GroupFieldName(name string) string {
+	parts := strings.Split(name, "_")
+	var b strings.Builder
+	for _, p := range parts {
+		b.WriteString(exportName(p))
+	}
+	return b.String()
+}
+
+// ProvidersForTypes returns singleton providers needed for the given type strings, in dependency order.
+// Step 6: delegates to ProvidersForTypesWithExtraEdges to eliminate duplication.
+func (g *Graph) ProvidersForTypes(typeStrs []string) ([]*Provider, error) {
+	return g.ProvidersForTypesWithExtraEdges(typeStrs, nil)
+}
+
+// ProvidersForTypesWithExtraEdges is like ProvidersForTypes but accepts extra synthetic
+// dependency edges for the topological sort.
+func (g *Graph) ProvidersForTypesWithExtraEdges(typeStrs []string, extraEdges map[string][]string) ([]*Provider, error) {
+	needed := make(map[string]bool)
+	for _, t := range typeStrs {
+		needed[t] = true
+	}
+	expanded := g.expandTransitive(needed)
+
+	var targets []string
+	for t := range expanded {
+		targets = append(targets, t)
+	}
+	sort.Strings(targets)
+
+	sorted, err := g.TopologicalSortWithExtraEdges(targets, extraEdges)
+	if err != nil {
+		return nil, err
+	}
+	return g.phaseOrder(sorted, extraEdges), nil
+}
+
+// AutoCollect scans all providers and returns those whose return type implements
+// the given interface type string. Uses the pre-built impl index (Step 1).
+func (g *Graph) AutoCollect(elemTypeStr string) []*Provider {
+	// Use impl index for O(1) lookup
+	entries := g.implIndex[elemTypeStr]
+	if len(entries) == 0 {
+		return nil
+	}
+
+	// Filter out invoke providers (already filtered during index build)
+	matches := make([]*Provider, 0, len(entries))
+	for _, e := range entries {
+		matches = append(matches, e.provider)
+	}
+	// Already sorted by (order, PkgPath) during index build
+	return matches
+}
+
+// CommandProviders returns every provider wired for a command: singletons in
+// dependency order, followed by the members of group and auto-collected
+// []Interface parameters.
+func (g *Graph) CommandProviders(cmd *DiscoveredCommand) ([]*Provider, error) {
+	return g.commandProviders(cmd, g.ProvidersForTypes)
+}
+
+// commandProviders is CommandProviders with the singletons resolved by
+// resolve.
+func (g *Graph) commandProviders(cmd *DiscoveredCommand, resolve func([]string) ([]*Provider, error)) ([]*Provider, error) {
+	return g.paramProviders(cmd.allParams(), resolve)
+}
+
+// paramProviders returns the providers constructor parameters need: those
+// resolve returns for them and the members of the collected ones.
+func (g *Graph) paramProviders(params []TypeRef, resolve func([]string) ([]*Provider, error)) ([]*Provider, error) {
+	var neededTypes []string
+	var collected []*Provider
+	for _, param := range params {
+		if param.Stream != "" {
+			continue
+		}
+		members := g.SliceMembers(param.TypeStr)
+		if members == nil {
+			neededTypes = append(neededTypes, param.TypeStr)
+			continue
+		}
+		for _, p := range members {
+			neededTypes = append(neededTypes, memberDeps(p)...)
+		}
+		collected = append(collected, members...)
+	}
+
+	providers, err := resolve(neededTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[*Provider]bool, len(providers))
+	for _, p := range providers {
+		seen[p] = true
+	}
+	for _, p := range collected {
+		if !seen[p] {
+			seen[p] = true
+			providers = append(providers, p)
+		}
+	}
+	return providers, nil
+}
+
+// memberDeps returns the types a member collected into a []Interface or
+// map[string]Interface parameter is built from: its parameters and its
+// ExtraDeps, like the SecretsSource of its //autodi:secret fields.
+func memberDeps(m *Provider) []string {
+	deps := slices.Clone(m.ExtraDeps)
+	for _, param := range m.Params {
+		if param.Stream == "" {
+			deps = append(deps, param.TypeStr)
+		}
+	}
+	return deps
+}
+
+// providersWithCollected is ProvidersForTypes for builders that fill the
+// []Interface and map[string]Interface parameters of their providers in
+// place: the dependencies of the collected members are resolved too, and an
+// extra edge orders each provider after those of its members, like the deep
+// auto-collection of generateInitFunc.
+func (g *Graph) providersWithCollected(typeStrs []string) ([]*Provider, error) {
+	needed := slices.Clone(typeStrs)
+	extraEdges := make(map[string][]string)
+	expanded := make(map[*Provider]bool)
+	for {
+		providers, err := g.ProvidersForTypesWithExtraEdges(needed, extraEdges)
+		if err != nil {
+			return nil, err
+		}
+		grew := false
+		for _, p := range providers {
+			if expanded[p] {
+				continue
+			}
+			expanded[p] = true
+			for _, param := range p.Params {
+				for _, m := range g.SliceMembers(param.TypeStr) {
+					deps := memberDeps(m)
+					for _, ret := range p.Returns {
+						extraEdges[ret.TypeStr] = append(extraEdges[ret.TypeStr], deps...)
+					}
+					needed = append(needed, deps...)
+					grew = grew || len(deps) > 0
+				}
+			}
+		}
+		if !grew {
+			return providers, nil
+		}
+	}
+}
+
+// SliceMembers returns the providers that fill a []Interface parameter —
+// the matching group's members, else auto-collected implementations — or a
+// map[string]Interface one, auto-collected. Returns nil for other types or
+// when nothing matches.
+func (g *Graph) SliceMembers(typeStr string) []*Provider {
+	elemType, keyed, ok := collectedElem(typeStr)
+	if !ok {
+		return nil
+	}
+	if keyed {
+		return g.AutoCollect(elemType)
+	}
+	for _, groupName := range sortedGroupNames(g.cfg.Groups) {
+		if g.resolveConfigType(g.cfg.Groups[groupName].Interface) == elemType {
+			return g.Groups[groupName]
+		}
+	}
+	return g.AutoCollect(elemType)
+}
+
+// providerLess orders slice members: by //autodi:order, then package path.
+func providerLess(a, b *Provider) bool {
+	if a.Order != b.Order {
+		return a.Order < b.Order
+	}
+	return a.PkgPath < b.PkgPath
+}
+
+// sortGroupMembers sorts group members by providerLess, keeping declaration
+// order for members of the same package.
+func sortGroupMembers(members []*Provider) {
+	sort.SliceStable(members, func(i, j int) bool {
+		return providerLess(members[i], members[j])
+	})
+}
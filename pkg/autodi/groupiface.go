@@ -0,0 +1,74 @@
+package autodi
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Group interfaces of other modules: the element type of
+//
+//	//autodi:group handlers []chi.Router internal/http/handlers
+//
+// may be declared in a module no scanned package imports. Its package is
+// loaded with the scan roots — named by a full import path
+// ([]github.com/go-chi/chi/v5.Router), or found among the modules required
+// in go.mod by its qualifier — and its interfaces are indexed, so
+// resolveConfigType and findIfaceType resolve it like a local one.
+
+// groupIfacePackages returns the packages of other modules declaring the
+// element types of the groups in generate.go.
+func (cfg *Config) groupIfacePackages() []string {
+	var pkgs []string
+	for _, name := range sortedGroupNames(cfg.Groups) {
+		pkgPath := typePkgPathFromTypeStr(cfg.Groups[name].Interface)
+		switch {
+		case pkgPath == "":
+			continue
+		case !strings.Contains(pkgPath, "/"):
+			pkgPath = cfg.requiredPackage(pkgPath)
+		case cfg.moduleOf(pkgPath) != "":
+			continue // scanned with the module
+		}
+		if pkgPath != "" && !slices.Contains(pkgs, pkgPath) {
+			pkgs = append(pkgs, pkgPath)
+		}
+	}
+	return pkgs
+}
+
+// requiredPackage returns the required module whose root package is named
+// name, or "" when none or several are.
+func (cfg *Config) requiredPackage(name string) string {
+	found := ""
+	for _, mod := range cfg.Requires {
+		if pkgShortName(mod) != name {
+			continue
+		}
+		if found != "" {
+			return ""
+		}
+		found = mod
+	}
+	return found
+}
+
+// indexGroupIfaces adds the packages of group interfaces from other modules
+// to the package and interface indexes; a package scanned or imported under
+// the same name keeps its entry.
+func (s *Scanner) indexGroupIfaces(set *PackageSet) error {
+	for _, pkgPath := range s.ifacePkgs {
+		pkg := set.pkg(pkgPath)
+		if pkg == nil || pkg.Types == nil {
+			continue // left out by --keep-going
+		}
+		if len(pkg.Errors) > 0 {
+			return fmt.Errorf("generate.go: //autodi:group: load %s: %v\n  hint: require the interface's module in go.mod", pkgPath, pkg.Errors[0])
+		}
+		if _, ok := s.PkgIndex[pkg.Name]; !ok {
+			s.PkgIndex[pkg.Name] = pkg.PkgPath
+		}
+		s.indexIfaces(pkg)
+	}
+	return nil
+}
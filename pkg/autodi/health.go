@@ -0,0 +1,151 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"slices"
+	"strings"
+)
+
+// containerVar is the package-level variable holding the running command's Container.
+const containerVar = "autodiContainer"
+
+// checkHealthCheck returns the name of a HealthCheck(ctx) error or Ping(ctx) error
+// method on t, or "" if it has neither.
+func checkHealthCheck(t types.Type) string {
+	mset := types.NewMethodSet(t)
+	for _, methodName := range []string{"HealthCheck", "Ping"} {
+		for i := 0; i < mset.Len(); i++ {
+			method := mset.At(i)
+			if method.Obj().Name() != methodName {
+				continue
+			}
+			sig, ok := method.Type().(*types.Signature)
+			if !ok {
+				continue
+			}
+			if sig.Params().Len() != 1 || !isContextType(sig.Params().At(0).Type()) {
+				continue
+			}
+			if sig.Results().Len() != 1 || !isErrorType(sig.Results().At(0).Type()) {
+				continue
+			}
+			return methodName
+		}
+	}
+	return ""
+}
+
+// writeHealthChecks registers the health checks of every wired provider on the
+// package-level Container.
+func (cg *CodeGen) writeHealthChecks(buf *bytes.Buffer, providers []*Provider, varMap map[string]string) {
+	var entries []string
+	for _, p := range providers {
+		for _, ret := range p.Returns {
+			if !isNilable(ret.Type) {
+				continue
+			}
+			method := checkHealthCheck(ret.Type)
+			varName, ok := varMap[ret.TypeStr]
+			if method == "" || !ok {
+				continue
+			}
+			// Keyed by full type path: short names collide across packages
+			entries = append(entries, fmt.Sprintf("\t\t%q: %s.%s,\n", strings.TrimPrefix(ret.TypeStr, "*"), varName, method))
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	cg.imports.Add("context", "context")
+	fmt.Fprintf(buf, "\t%s.healthChecks = map[string]func(context.Context) error{\n", containerVar)
+	for _, e := range entries {
+		buf.WriteString(e)
+	}
+	buf.WriteString("\t}\n\n")
+	cg.hasContainer = true
+}
+
+// writeContainerType emits the Container type exposing runtime views over the
+// providers wired for the running command.
+func (cg *CodeGen) writeContainerType(buf *bytes.Buffer) {
+	cg.imports.Add("context", "context")
+	buf.WriteString("// Container exposes runtime views over the providers wired for the running command.\n")
+	buf.WriteString("type Container struct {\n")
+	buf.WriteString("\thealthChecks map[string]func(context.Context) error\n")
+	if cg.graph.hasScoped() {
+		buf.WriteString("\tnewRequestScope func(context.Context) (*RequestScope, error)\n")
+	}
+	if cg.cfg.ContainerInjection {
+		buf.WriteString("\tvalues map[string]any\n")
+	}
+	buf.WriteString("}\n\n")
+	fmt.Fprintf(buf, "// %s is populated by the init function of the command being executed.\n", containerVar)
+	fmt.Fprintf(buf, "var %s Container\n\n", containerVar)
+	buf.WriteString("// HealthCheck runs every provider's HealthCheck/Ping concurrently and returns\n")
+	buf.WriteString("// the result per provider type, keyed by its import path and name, e.g.\n")
+	buf.WriteString("// \"example.com/app/internal/store.Store\"; healthy providers map to nil.\n")
+	buf.WriteString("func (c *Container) HealthCheck(ctx context.Context) map[string]error {\n")
+	cg.writeHealthFanOut(buf, "c.healthChecks")
+	buf.WriteString("}\n")
+	cg.writeContainerLookup(buf)
+	cg.writeRequestScopeType(buf)
+}
+
+// writeHealthFanOut emits the body of a HealthCheck method running the
+// checks of a map[string]func(context.Context) error concurrently.
+func (cg *CodeGen) writeHealthFanOut(buf *bytes.Buffer, checks string) {
+	context := cg.imports.Add("context", "context")
+	sync := cg.imports.Add("sync", "sync")
+	fmt.Fprintf(buf, "\tresults := make(map[string]error, len(%s))\n", checks)
+	fmt.Fprintf(buf, "\tvar mu %s.Mutex\n", sync)
+	fmt.Fprintf(buf, "\tvar wg %s.WaitGroup\n", sync)
+	fmt.Fprintf(buf, "\tfor name, check := range %s {\n", checks)
+	buf.WriteString("\t\twg.Add(1)\n")
+	fmt.Fprintf(buf, "\t\tgo func(name string, check func(%s.Context) error) {\n", context)
+	buf.WriteString("\t\t\tdefer wg.Done()\n")
+	buf.WriteString("\t\t\terr := check(ctx)\n")
+	buf.WriteString("\t\t\tmu.Lock()\n")
+	buf.WriteString("\t\t\tresults[name] = err\n")
+	buf.WriteString("\t\t\tmu.Unlock()\n")
+	buf.WriteString("\t\t}(name, check)\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\twg.Wait()\n")
+	buf.WriteString("\treturn results\n")
+}
+
+// writeLibraryHealthCheck emits the HealthCheck method of the library
+// layout's Container over the providers it holds; those a Build<Command>
+// didn't construct are nil and skipped.
+func (cg *CodeGen) writeLibraryHealthCheck(buf *bytes.Buffer, providers []*Provider) {
+	var entries []string
+	for _, p := range providers {
+		fields := cg.libraryFields(p)
+		for _, ret := range p.Returns {
+			if !isNilable(ret.Type) || !slices.Contains(fields, ret.TypeStr) {
+				continue
+			}
+			if method := checkHealthCheck(ret.Type); method != "" {
+				field := "c." + cg.graph.TypeToField[ret.TypeStr]
+				entries = append(entries, fmt.Sprintf("\tif %s != nil {\n\t\tchecks[%q] = %s.%s\n\t}\n",
+					field, strings.TrimPrefix(ret.TypeStr, "*"), field, method))
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+	context := cg.imports.Add("context", "context")
+	buf.WriteString("\n// HealthCheck runs the HealthCheck/Ping method of every provider the Container\n")
+	buf.WriteString("// holds concurrently and returns the result per provider type, keyed by its\n")
+	buf.WriteString("// import path and name; healthy providers map to nil.\n")
+	fmt.Fprintf(buf, "func (c *Container) HealthCheck(ctx %s.Context) map[string]error {\n", context)
+	fmt.Fprintf(buf, "\tchecks := make(map[string]func(%s.Context) error)\n", context)
+	for _, e := range entries {
+		buf.WriteString(e)
+	}
+	cg.writeHealthFanOut(buf, "checks")
+	buf.WriteString("}\n")
+}
@@ -0,0 +1,185 @@
+package autodi
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Hermetic mode: build systems like Bazel and Please know every source file
+// and the module path from their own dependency graph, and run generators in
+// a sandbox without go.mod, GOPATH or network access. With
+//
+//	autodi --module example.com/app --files @srcs.txt --output-archive gen.tar
+//
+// the module path comes from --module instead of go.mod, the working
+// directory is the module root, and only the packages of the listed files are
+// analyzed instead of the directories found by walking the module. They are
+// loaded by file, so a GOPACKAGESDRIVER such as rules_go's
+// gopackagesdriver answers the load from the build graph.
+
+// fileQueryPrefix turns a file path into a go/packages query for the package
+// containing it.
+const fileQueryPrefix = "file="
+
+// expandFileList reads the --files values: Go file paths, and @-prefixed
+// files listing one path per line, as build systems pass long argument lists.
+func expandFileList(values []string) ([]string, error) {
+	var files []string
+	for _, value := range values {
+		list, ok := strings.CutPrefix(value, "@")
+		if !ok {
+			files = append(files, value)
+			continue
+		}
+		f, err := os.Open(list)
+		if err != nil {
+			return nil, fmt.Errorf("--files: %w", err)
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			if line := strings.TrimSpace(sc.Text()); line != "" {
+				files = append(files, line)
+			}
+		}
+		err = sc.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("--files %s: %w", list, err)
+		}
+	}
+	return files, nil
+}
+
+// checkHermeticFlags validates the flags of hermetic mode.
+func checkHermeticFlags(opts *Options) error {
+	if opts.Module == "" && len(opts.Files) == 0 {
+		if opts.GoVersion != "" {
+			return fmt.Errorf("--go-version only applies with --module; otherwise the go directive of go.mod is recorded")
+		}
+		return nil
+	}
+	if opts.Module == "" || len(opts.Files) == 0 {
+		return fmt.Errorf("--module and --files go together\n  hint: autodi --module example.com/app --files @srcs.txt")
+	}
+	if opts.CacheURL != "" {
+		return fmt.Errorf("--cache-url hashes the module by walking it; it can't be combined with --files\n  hint: the build system caches the outputs of hermetic runs")
+	}
+	return nil
+}
+
+// projectRoot returns the module root: the working directory in hermetic
+// mode, else the directory of the nearest go.mod.
+func projectRoot(opts *Options) (string, error) {
+	if opts.Module == "" {
+		return findModuleRoot()
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("getwd: %w", err)
+	}
+	return dir, nil
+}
+
+// loadConfig builds the config of the module at moduleRoot, from --module and
+// --files in hermetic mode.
+func loadConfig(moduleRoot string, opts *Options) (*Config, error) {
+	if opts.Module == "" {
+		return BuildConfig(moduleRoot)
+	}
+	files, err := expandFileList(opts.Files)
+	if err != nil {
+		return nil, err
+	}
+	return BuildHermeticConfig(moduleRoot, opts.Module, opts.GoVersion, files)
+}
+
+// BuildHermeticConfig builds the config of module from an explicit list of its
+// Go files, relative to the working directory, without reading go.mod or
+// walking moduleRoot.
+func BuildHermeticConfig(moduleRoot, module, goVersion string, files []string) (*Config, error) {
+	var rels []string
+	for _, file := range files {
+		if !strings.HasSuffix(file, ".go") {
+			return nil, fmt.Errorf("--files %s: not a Go file", file)
+		}
+		rel, err := moduleRelPath(moduleRoot, file)
+		if err != nil {
+			return nil, fmt.Errorf("--files: %w", err)
+		}
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+
+	cfg, err := buildConfig(moduleRoot, moduleRoot, &GoMod{Module: module, GoVersion: goVersion}, rels)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Imports) > 0 {
+		return nil, fmt.Errorf("generate.go: //autodi:import %s reads the manifest from the module cache, which --files runs don't use\n  hint: list the bundle's constructors with //autodi:provide", cfg.Imports[0])
+	}
+	return cfg, nil
+}
+
+// fileScanPaths returns the directories of the listed files as scan paths,
+// leaving out the module root, which holds no providers, and hidden and
+// vendored directories, like discoverScanPaths. testdata/ and _-prefixed
+// packages are loaded and then skipped by the scanner.
+func fileScanPaths(files []string) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, file := range files {
+		dir := path.Dir(file)
+		if dir == "." || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		if slices.ContainsFunc(strings.Split(dir, "/"), func(elem string) bool {
+			return strings.HasPrefix(elem, ".") || elem == "vendor"
+		}) {
+			continue
+		}
+		paths = append(paths, dir)
+	}
+	return paths
+}
+
+// fileAppDirs returns the directories of the listed generate.go files
+// declaring an //autodi:app, as findAppDirs does by walking the module.
+func fileAppDirs(moduleRoot string, files []string) []string {
+	var dirs []string
+	for _, file := range files {
+		if path.Base(file) != "generate.go" || path.Dir(file) == "." {
+			continue
+		}
+		if isAppGenerateFile(filepath.Join(moduleRoot, filepath.FromSlash(path.Dir(file)))) {
+			dirs = append(dirs, path.Dir(file))
+		}
+	}
+	return dirs
+}
+
+// filePatterns replaces the module's own package patterns with one file query
+// per listed directory; patterns of other modules, like //autodi:provide
+// packages, are kept as import paths.
+func (cfg *Config) filePatterns(moduleRoot string, patterns []string) []string {
+	var out []string
+	for _, pattern := range patterns {
+		if pattern != cfg.Module && !strings.HasPrefix(pattern, cfg.Module+"/") {
+			out = append(out, pattern)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, file := range cfg.Files {
+		if dir := path.Dir(file); !seen[dir] {
+			seen[dir] = true
+			out = append(out, fileQueryPrefix+filepath.Join(moduleRoot, filepath.FromSlash(file)))
+		}
+	}
+	return out
+}
@@ -0,0 +1,85 @@
+package autodi
+
+import (
+	"go/types"
+	"hash/fnv"
+)
+
+// implMatrix memoizes types.Implements by (concrete typeStr, interface
+// typeStr). Binding detection, auto-collection and reachability ask about
+// every interface × provider result pair, most of which can't match: each
+// type's method names are hashed into a 64-bit mask once, and a pair whose
+// interface needs a name bit the type lacks is rejected without
+// types.Implements.
+type implMatrix struct {
+	results map[implCacheKey]bool
+	masks   map[string]uint64 // typeStr → method name mask
+}
+
+// implCacheKey is the key for caching types.Implements() results.
+type implCacheKey struct {
+	typeStr  string
+	ifaceStr string
+}
+
+func newImplMatrix() *implMatrix {
+	return &implMatrix{
+		results: make(map[implCacheKey]bool),
+		masks:   make(map[string]uint64),
+	}
+}
+
+// implements reports whether t, named tStr, implements iface, named ifaceStr.
+func (m *implMatrix) implements(t types.Type, tStr string, iface *types.Interface, ifaceStr string) bool {
+	key := implCacheKey{typeStr: tStr, ifaceStr: ifaceStr}
+	if result, ok := m.results[key]; ok {
+		return result
+	}
+	result := false
+	if need := m.mask(iface, ifaceStr); need&^m.mask(t, tStr) == 0 {
+		result = types.Implements(t, iface)
+	}
+	m.results[key] = result
+	return result
+}
+
+// implementsIface is implementsIface through the matrix: t or *t
+// implements iface.
+func (m *implMatrix) implementsIface(t types.Type, tStr string, iface *types.Interface, ifaceStr string) bool {
+	if m.implements(t, tStr, iface, ifaceStr) {
+		return true
+	}
+	if _, ok := t.(*types.Pointer); !ok {
+		return m.implements(types.NewPointer(t), "*"+tStr, iface, ifaceStr)
+	}
+	return false
+}
+
+// mask returns the method name mask of t, cached by tStr.
+func (m *implMatrix) mask(t types.Type, tStr string) uint64 {
+	if mask, ok := m.masks[tStr]; ok {
+		return mask
+	}
+	mask := methodMask(t)
+	m.masks[tStr] = mask
+	return mask
+}
+
+// methodMask sets one bit per method name of t. A value type gets the mask
+// of its pointer's method set, a superset, so the mask only ever rules out
+// pairs that types.Implements would reject.
+func methodMask(t types.Type) uint64 {
+	if _, ok := t.Underlying().(*types.Interface); !ok {
+		if _, ok := t.(*types.Pointer); !ok {
+			t = types.NewPointer(t)
+		}
+	}
+	var mask uint64
+	mset := types.NewMethodSet(t)
+	for i := 0; i < mset.Len(); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(mset.At(i).Obj().Name()))
+		mask |= 1 << (h.Sum64() % 64)
+	}
+	return mask
+}
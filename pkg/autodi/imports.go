@@ -1,4 +1,4 @@
-package main
+package autodi
 
 import (
 	"bytes"
@@ -11,18 +11,21 @@ import (
 type ImportManager struct {
 	imports map[string]string // pkgPath → alias (or empty for default)
 	used    map[string]string // pkgName → pkgPath (first use wins)
+	local   string            // module path whose packages are grouped last
+	self    string            // package of the generated file: its names need no import
 }
 
-func NewImportManager() *ImportManager {
+func NewImportManager(local string) *ImportManager {
 	return &ImportManager{
 		imports: make(map[string]string),
 		used:    make(map[string]string),
+		local:   local,
 	}
 }
 
 // Add registers an import and returns the qualifier to use in code.
 func (im *ImportManager) Add(pkgPath, pkgName string) string {
-	if pkgPath == "" {
+	if pkgPath == "" || pkgPath == im.self {
 		return ""
 	}
 
@@ -88,24 +91,57 @@ func (im *ImportManager) FormatBlock() string {
 	if len(im.imports) == 0 {
 		return ""
 	}
+	return formatImportBlock(im.imports, im.local) + "\n"
+}
+
+// Import groups, in the order goimports -local lays them out.
+const (
+	importStd = iota
+	importExternal
+	importLocal
+)
+
+// importGroup returns the group of an import path: the standard library has
+// no dot in its first element, local packages are in the module local.
+func importGroup(pkgPath, local string) int {
+	switch first, _, _ := strings.Cut(pkgPath, "/"); {
+	case !strings.Contains(first, "."):
+		return importStd
+	case local != "" && (pkgPath == local || strings.HasPrefix(pkgPath, local+"/")):
+		return importLocal
+	}
+	return importExternal
+}
 
-	var paths []string
-	for p := range im.imports {
+// formatImportBlock renders imports (path → alias, "" for none) as an import
+// block, sorted within standard library, external and local groups separated
+// by blank lines, so gofmt and goimports leave it as it is.
+func formatImportBlock(imports map[string]string, local string) string {
+	paths := make([]string, 0, len(imports))
+	for p := range imports {
 		paths = append(paths, p)
 	}
-	sort.Strings(paths)
+	sort.Slice(paths, func(i, j int) bool {
+		gi, gj := importGroup(paths[i], local), importGroup(paths[j], local)
+		if gi != gj {
+			return gi < gj
+		}
+		return paths[i] < paths[j]
+	})
 
 	var buf bytes.Buffer
 	buf.WriteString("import (\n")
-	for _, p := range paths {
-		alias := im.imports[p]
-		if alias != "" {
+	for i, p := range paths {
+		if i > 0 && importGroup(p, local) != importGroup(paths[i-1], local) {
+			buf.WriteString("\n")
+		}
+		if alias := imports[p]; alias != "" {
 			fmt.Fprintf(&buf, "\t%s %q\n", alias, p)
 		} else {
 			fmt.Fprintf(&buf, "\t%q\n", p)
 		}
 	}
-	buf.WriteString(")\n")
+	buf.WriteString(")")
 	return buf.String()
 }
 
@@ -119,6 +155,7 @@ func (im *ImportManager) IsQualifier(name string) bool {
 func (im *ImportManager) Reset() {
 	im.imports = make(map[string]string)
 	im.used = make(map[string]string)
+	im.self = ""
 }
 
 // pkgShortName extracts the short package name from a full path.
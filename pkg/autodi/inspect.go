@@ -0,0 +1,79 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// InspectCommand is the hidden subcommand --inspect adds to the generated app.
+const InspectCommand = "di:inspect"
+
+// writeInspectCommand adds the hidden di:inspect command under root. It runs
+// the init function of the named command and prints the constructors in call
+// order; initFuncs is the registry of the single-binary layout.
+func (cg *CodeGen) writeInspectCommand(buf *bytes.Buffer, cobraQualifier string) {
+	cg.imports.Add("fmt", "fmt")
+	fmt.Fprintf(buf, "\troot.AddCommand(&%s.Command{\n", cobraQualifier)
+	fmt.Fprintf(buf, "\t\tUse:    %q,\n", InspectCommand+" <command>")
+	buf.WriteString("\t\tShort:  \"Construct a command's providers and print their order and init durations\",\n")
+	buf.WriteString("\t\tHidden: true,\n")
+	fmt.Fprintf(buf, "\t\tArgs:   %s.ExactArgs(1),\n", cobraQualifier)
+	fmt.Fprintf(buf, "\t\tRunE: func(c *%s.Command, args []string) error {\n", cobraQualifier)
+	buf.WriteString("\t\t\tfor top, fn := range initFuncs {\n")
+	buf.WriteString("\t\t\t\tif top.Name() != args[0] {\n")
+	buf.WriteString("\t\t\t\t\tcontinue\n")
+	buf.WriteString("\t\t\t\t}\n")
+	buf.WriteString("\t\t\t\tcleanup, err := fn(top, top)\n")
+	buf.WriteString("\t\t\t\tif cleanup != nil {\n")
+	buf.WriteString("\t\t\t\t\tdefer cleanup()\n")
+	buf.WriteString("\t\t\t\t}\n")
+	buf.WriteString("\t\t\t\tif err != nil {\n")
+	buf.WriteString("\t\t\t\t\treturn err\n")
+	buf.WriteString("\t\t\t\t}\n")
+	buf.WriteString("\t\t\t\tlastInitProfile.print(c.OutOrStdout())\n")
+	buf.WriteString("\t\t\t\treturn nil\n")
+	buf.WriteString("\t\t\t}\n")
+	fmt.Fprintf(buf, "\t\t\treturn fmt.Errorf(\"%s: no command %%q with dependencies\", args[0])\n", InspectCommand)
+	buf.WriteString("\t\t},\n")
+	buf.WriteString("\t})\n")
+}
+
+// writeBinaryInspectCommand adds di:inspect to a multi-binary main, whose
+// root PersistentPreRunE has already run the init function.
+func (cg *CodeGen) writeBinaryInspectCommand(buf *bytes.Buffer, cobraQualifier string) {
+	fmt.Fprintf(buf, "\troot.AddCommand(&%s.Command{\n", cobraQualifier)
+	fmt.Fprintf(buf, "\t\tUse:    %q,\n", InspectCommand)
+	buf.WriteString("\t\tShort:  \"Print the constructed providers in order with their init durations\",\n")
+	buf.WriteString("\t\tHidden: true,\n")
+	fmt.Fprintf(buf, "\t\tArgs:   %s.NoArgs,\n", cobraQualifier)
+	fmt.Fprintf(buf, "\t\tRunE: func(c *%s.Command, _ []string) error {\n", cobraQualifier)
+	buf.WriteString("\t\t\tlastInitProfile.print(c.OutOrStdout())\n")
+	buf.WriteString("\t\t\treturn nil\n")
+	buf.WriteString("\t\t},\n")
+	buf.WriteString("\t})\n")
+}
+
+// writeInspectHelper emits lastInitProfile, which the init functions set, and
+// its printer.
+func (cg *CodeGen) writeInspectHelper(buf *bytes.Buffer) {
+	cg.imports.Add("fmt", "fmt")
+	cg.imports.Add("io", "io")
+	cg.imports.Add("sort", "sort")
+	buf.WriteString("// lastInitProfile holds the timings of the last completed init function (di:inspect).\n")
+	buf.WriteString("var lastInitProfile *initProfile\n\n")
+	buf.WriteString("// print lists the constructors in call order with their start offset and duration.\n")
+	buf.WriteString("func (p *initProfile) print(w io.Writer) {\n")
+	buf.WriteString("\tcalls := append([]initTiming(nil), p.calls...)\n")
+	buf.WriteString("\tsort.SliceStable(calls, func(i, j int) bool { return calls[i].start.Before(calls[j].start) })\n")
+	buf.WriteString("\tvar total time.Duration\n")
+	buf.WriteString("\tfor _, c := range calls {\n")
+	buf.WriteString("\t\tif end := c.start.Add(c.took).Sub(p.begin); end > total {\n")
+	buf.WriteString("\t\t\ttotal = end\n")
+	buf.WriteString("\t\t}\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\tfmt.Fprintf(w, \"%s: %d constructors in %s\\n\", p.command, len(calls), total.Round(time.Microsecond))\n")
+	buf.WriteString("\tfor i, c := range calls {\n")
+	buf.WriteString("\t\tfmt.Fprintf(w, \"%4d  +%-10s %10s  %s\\n\", i+1, c.start.Sub(p.begin).Round(time.Microsecond), c.took.Round(time.Microsecond), c.name)\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("}\n")
+}
@@ -0,0 +1,286 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+)
+
+// Scheduled jobs: a group whose interface has Schedule() string and
+// Run(ctx) error is a group of jobs,
+//
+//	//autodi:group jobs []jobs.Job internal/jobs   (generate.go)
+//
+//	type Job interface {
+//		Schedule() string   // "@every 5m", or a cron spec with robfig/cron
+//		Run(ctx context.Context) error
+//	}
+//
+// and is scheduled without a hand-written registry:
+//   - a constructor or command taking *cron.Cron (github.com/robfig/cron/v3)
+//     gets a cron with every job added, built by newJobCron; the taker starts
+//     and stops it;
+//   - a command taking the group's slice runs each job on a time.Ticker
+//     alongside its handler, every time.ParseDuration of Schedule() ("@every"
+//     is optional), unless its constructor is marked //autodi:nostart.
+//
+// A failing run is printed to stderr; the next one is still scheduled.
+const (
+	cronPath    = "github.com/robfig/cron/v3"
+	cronType    = "*" + cronPath + ".Cron"
+	jobCronFunc = "newJobCron"
+)
+
+// isJobInterface reports whether an interface has the methods of a scheduled
+// job: Schedule() string and Run(context.Context) error.
+func isJobInterface(iface *types.Interface) bool {
+	found := 0
+	for i := 0; i < iface.NumMethods(); i++ {
+		method := iface.Method(i)
+		sig := method.Type().(*types.Signature)
+		switch method.Name() {
+		case "Schedule":
+			if sig.Params().Len() == 0 && sig.Results().Len() == 1 && isStringType(sig.Results().At(0).Type()) {
+				found++
+			}
+		case "Run":
+			if sig.Params().Len() == 1 && isContextType(sig.Params().At(0).Type()) &&
+				sig.Results().Len() == 1 && isErrorType(sig.Results().At(0).Type()) {
+				found++
+			}
+		}
+	}
+	return found == 2
+}
+
+// isJobGroup reports whether a group's interface is a scheduled job.
+func (g *Graph) isJobGroup(name string) bool {
+	groupCfg, ok := g.cfg.Groups[name]
+	if !ok {
+		return false
+	}
+	typeStr := g.resolveConfigType(groupCfg.Interface)
+	iface := g.ifaceTypes[typeStr]
+	if iface == nil {
+		if t, ok := g.typeIndex[typeStr]; ok {
+			iface, _ = t.Underlying().(*types.Interface)
+		}
+	}
+	return iface != nil && isJobInterface(iface)
+}
+
+// jobCron returns the provider of the generated cron when a constructor or
+// command takes *cron.Cron and no candidate provides it. Its jobs and
+// parameters are filled in by addJobCron once groups are resolved.
+func jobCron(candidates []*Provider, commands []*DiscoveredCommand) []*Provider {
+	for _, p := range candidates {
+		for _, ret := range p.Returns {
+			if ret.TypeStr == cronType {
+				return nil
+			}
+		}
+	}
+	var wanted *TypeRef
+	want := func(params []TypeRef) {
+		for i, param := range params {
+			if wanted == nil && param.Stream == "" && param.TypeStr == cronType {
+				wanted = &params[i]
+			}
+		}
+	}
+	for _, p := range candidates {
+		want(p.Params)
+	}
+	for _, cmd := range commands {
+		want(cmd.Params)
+	}
+	if wanted == nil {
+		return nil
+	}
+	return []*Provider{{
+		FuncName: jobCronFunc,
+		PkgName:  "cron",
+		Returns:  []TypeRef{{Type: wanted.Type, TypeStr: cronType, PkgPath: cronPath}},
+		HasError: true,
+		Position: token.Position{Filename: "generate.go"},
+	}}
+}
+
+// addJobCron adds the members of every group of jobs to the generated cron.
+// Call after group membership is resolved.
+func (g *Graph) addJobCron() []error {
+	cron := g.ProviderMap[cronType]
+	if cron == nil || cron.PkgPath != "" || cron.FuncName != jobCronFunc {
+		return nil
+	}
+	seen := make(map[string]bool)
+	for _, name := range sortedGroupNames(g.cfg.Groups) {
+		if !g.isJobGroup(name) {
+			continue
+		}
+		for _, p := range g.Groups[name] {
+			if slices.Contains(cron.Jobs, p) {
+				continue
+			}
+			cron.Jobs = append(cron.Jobs, p)
+			for _, param := range p.Params {
+				if param.Stream != "" || seen[param.TypeStr] {
+					continue
+				}
+				seen[param.TypeStr] = true
+				cron.Params = append(cron.Params, TypeRef{
+					Type:     param.Type,
+					TypeStr:  param.TypeStr,
+					PkgPath:  param.PkgPath,
+					IsIface:  param.IsIface,
+					Optional: param.Optional,
+				})
+			}
+		}
+	}
+	if len(cron.Jobs) == 0 {
+		return []error{fmt.Errorf("generate.go: *cron.Cron is taken, but no group has Schedule() string and Run(ctx) error to add to it\n  hint: //autodi:group jobs []jobs.Job internal/jobs, or provide *cron.Cron yourself")}
+	}
+	return nil
+}
+
+// writeJobTicker schedules a command's group of jobs on tickers alongside its
+// handler.
+func (cg *CodeGen) writeJobTicker(buf *bytes.Buffer, cmd *DiscoveredCommand, groupName, groupVar string, usedVars map[string]bool, components *[]Component) {
+	if cmd.NoStart || !cg.graph.isJobGroup(groupName) {
+		return
+	}
+	tickerVar := cg.uniqueLocalVar(groupVar+"Ticker", usedVars)
+	fmt.Fprintf(buf, "\t%s := jobTicker[%s](%s)\n\n", tickerVar, cg.qualifyType(cg.cfg.Groups[groupName].Interface, ""), groupVar)
+	*components = append(*components, Component{VarName: tickerVar, Method: "Run", Group: groupName})
+	cg.hasJobTicker = true
+}
+
+// writeJobs emits the job scheduling helpers used in the current file.
+func (cg *CodeGen) writeJobs(buf *bytes.Buffer) error {
+	if cg.jobCron == nil && !cg.hasJobTicker {
+		return nil
+	}
+	context := cg.imports.Add("context", "context")
+	cg.imports.Add("fmt", "fmt")
+	cg.imports.Add("os", "os")
+	buf.WriteString("\n// scheduledJob is a member of a group of jobs.\n")
+	buf.WriteString("type scheduledJob interface {\n")
+	buf.WriteString("\tSchedule() string\n")
+	fmt.Fprintf(buf, "\tRun(ctx %s.Context) error\n", context)
+	buf.WriteString("}\n")
+
+	if cg.hasJobTicker {
+		syncQualifier := cg.imports.Add("sync", "sync")
+		timeQualifier := cg.imports.Add("time", "time")
+		stringsQualifier := cg.imports.Add("strings", "strings")
+		buf.WriteString("\n// jobTicker runs a group of jobs alongside a command handler, each on a\n")
+		buf.WriteString("// ticker at the interval its Schedule method returns.\n")
+		buf.WriteString("type jobTicker[J scheduledJob] []J\n\n")
+		buf.WriteString("// Run starts the tickers and returns once ctx is done. A failing run is\n")
+		buf.WriteString("// printed; the job runs again on the next tick.\n")
+		fmt.Fprintf(buf, "func (jobs jobTicker[J]) Run(ctx %s.Context) error {\n", context)
+		fmt.Fprintf(buf, "\tevery := make([]%s.Duration, len(jobs))\n", timeQualifier)
+		buf.WriteString("\tfor i, job := range jobs {\n")
+		buf.WriteString("\t\tvar err error\n")
+		fmt.Fprintf(buf, "\t\tevery[i], err = %s.ParseDuration(%s.TrimPrefix(job.Schedule(), \"@every \"))\n", timeQualifier, stringsQualifier)
+		buf.WriteString("\t\tif err != nil || every[i] <= 0 {\n")
+		buf.WriteString("\t\t\treturn fmt.Errorf(\"job %T: schedule %q: want an interval like @every 5m\", job, job.Schedule())\n")
+		buf.WriteString("\t\t}\n")
+		buf.WriteString("\t}\n")
+		fmt.Fprintf(buf, "\tvar wg %s.WaitGroup\n", syncQualifier)
+		buf.WriteString("\tfor i, job := range jobs {\n")
+		buf.WriteString("\t\twg.Add(1)\n")
+		buf.WriteString("\t\tgo func() {\n")
+		buf.WriteString("\t\t\tdefer wg.Done()\n")
+		fmt.Fprintf(buf, "\t\t\tticker := %s.NewTicker(every[i])\n", timeQualifier)
+		buf.WriteString("\t\t\tdefer ticker.Stop()\n")
+		buf.WriteString("\t\t\tfor {\n")
+		buf.WriteString("\t\t\t\tselect {\n")
+		buf.WriteString("\t\t\t\tcase <-ctx.Done():\n")
+		buf.WriteString("\t\t\t\t\treturn\n")
+		buf.WriteString("\t\t\t\tcase <-ticker.C:\n")
+		buf.WriteString("\t\t\t\t\tif err := job.Run(ctx); err != nil {\n")
+		buf.WriteString("\t\t\t\t\t\tfmt.Fprintf(os.Stderr, \"job %T: %v\\n\", job, err)\n")
+		buf.WriteString("\t\t\t\t\t}\n")
+		buf.WriteString("\t\t\t\t}\n")
+		buf.WriteString("\t\t\t}\n")
+		buf.WriteString("\t\t}()\n")
+		buf.WriteString("\t}\n")
+		buf.WriteString("\twg.Wait()\n")
+		buf.WriteString("\treturn nil\n")
+		buf.WriteString("}\n")
+	}
+
+	if cg.jobCron != nil {
+		buf.WriteString("\n")
+		if err := cg.writeJobCron(buf, cg.jobCron, context); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeJobCron emits newJobCron: it constructs the jobs from its parameters
+// and adds each to a new cron.Cron, and addCronJob.
+func (cg *CodeGen) writeJobCron(buf *bytes.Buffer, cron *Provider, context string) error {
+	cronQualifier := cg.imports.Add(cronPath, "cron")
+	cg.registerProviderImports(cron.Jobs)
+
+	usedVars := map[string]bool{"c": true, "err": true}
+	varMap := make(map[string]string)
+	var params []string
+	for _, param := range cron.Params {
+		v := cg.uniqueLocalVar(localVarName(FieldName(param.TypeStr)), usedVars)
+		if cg.imports.IsQualifier(v) {
+			v = cg.uniqueLocalVar(v+"Dep", usedVars)
+		}
+		varMap[param.TypeStr] = v
+		params = append(params, v+" "+cg.shortType(param.TypeStr))
+	}
+
+	buf.WriteString("// newJobCron adds the members of the groups of jobs to a new cron.Cron.\n")
+	fmt.Fprintf(buf, "func %s(%s) (*%s.Cron, error) {\n", jobCronFunc, strings.Join(params, ", "), cronQualifier)
+	fmt.Fprintf(buf, "\tc := %s.New()\n", cronQualifier)
+	added := make(map[*Provider]bool)
+	for _, name := range sortedGroupNames(cg.cfg.Groups) {
+		if !cg.graph.isJobGroup(name) {
+			continue
+		}
+		iface := cg.cfg.Groups[name].Interface
+		var members []*Provider
+		for _, p := range cg.graph.Groups[name] {
+			if slices.Contains(cron.Jobs, p) && !added[p] {
+				added[p] = true
+				members = append(members, p)
+			}
+		}
+		match := func(p *Provider) ([]int, error) { return cg.matchingSliceReturnIndexes(p, iface) }
+		err := cg.writeMemberCalls(buf, members, varMap, usedVars, match, func(_ *Provider, v string) string {
+			return fmt.Sprintf("if err := addCronJob(c, %s); err != nil {\n\t\treturn nil, err\n\t}", v)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	buf.WriteString("\treturn c, nil\n")
+	buf.WriteString("}\n\n")
+
+	buf.WriteString("// addCronJob runs job on its Schedule. A failing run is printed; the job\n")
+	buf.WriteString("// runs again on the next schedule.\n")
+	fmt.Fprintf(buf, "func addCronJob(c *%s.Cron, job scheduledJob) error {\n", cronQualifier)
+	buf.WriteString("\t_, err := c.AddFunc(job.Schedule(), func() {\n")
+	fmt.Fprintf(buf, "\t\tif err := job.Run(%s.Background()); err != nil {\n", context)
+	buf.WriteString("\t\t\tfmt.Fprintf(os.Stderr, \"job %T: %v\\n\", job, err)\n")
+	buf.WriteString("\t\t}\n")
+	buf.WriteString("\t})\n")
+	buf.WriteString("\tif err != nil {\n")
+	buf.WriteString("\t\treturn fmt.Errorf(\"job %T: schedule %q: %w\", job, job.Schedule(), err)\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\treturn nil\n")
+	buf.WriteString("}\n")
+	return nil
+}
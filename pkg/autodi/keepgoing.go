@@ -0,0 +1,71 @@
+package autodi
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Keep-going: in a monorepo one team's compile error shouldn't block
+// everyone's codegen. With --keep-going a package that fails to load, and
+// every loaded package importing it, is left out of the scan and the command
+// detection instead of failing the run. The wiring is generated for the
+// healthy rest, and the broken packages are reported together.
+
+// BrokenPackage is a package --keep-going left out of the analysis.
+type BrokenPackage struct {
+	PkgPath string
+	Errors  []string // its load and type errors, empty when only a dependency is broken
+	Via     string   // the broken package it imports, for a dependent
+}
+
+// dropBroken removes the packages with errors, and those importing one,
+// from the set and returns them in import path order.
+func (ps *PackageSet) dropBroken() []BrokenPackage {
+	broken := make(map[string]*BrokenPackage)
+	packages.Visit(ps.Pkgs, nil, func(pkg *packages.Package) {
+		if len(pkg.Errors) > 0 {
+			b := &BrokenPackage{PkgPath: pkg.PkgPath}
+			for _, e := range pkg.Errors {
+				b.Errors = append(b.Errors, e.Error())
+			}
+			broken[pkg.PkgPath] = b
+			return
+		}
+		for _, path := range sortedKeys(pkg.Imports) {
+			if dep, ok := broken[path]; ok {
+				via := dep.PkgPath
+				if dep.Via != "" {
+					via = dep.Via
+				}
+				broken[pkg.PkgPath] = &BrokenPackage{PkgPath: pkg.PkgPath, Via: via}
+				return
+			}
+		}
+	})
+
+	var dropped []BrokenPackage
+	ps.Pkgs = slices.DeleteFunc(ps.Pkgs, func(pkg *packages.Package) bool {
+		b, ok := broken[pkg.PkgPath]
+		if ok {
+			dropped = append(dropped, *b)
+		}
+		return ok
+	})
+	return dropped
+}
+
+// writeBrokenReport prints the packages --keep-going left out.
+func writeBrokenReport(w io.Writer, cfg *Config, broken []BrokenPackage) {
+	fmt.Fprintf(w, "autodi: --keep-going: left out %d broken package(s); their providers and commands aren't wired:\n", len(broken))
+	for _, b := range broken {
+		if b.Via != "" {
+			fmt.Fprintf(w, "  %s: imports broken %s\n", cfg.RelPath(b.PkgPath), cfg.RelPath(b.Via))
+			continue
+		}
+		fmt.Fprintf(w, "  %s:\n    %s\n", cfg.RelPath(b.PkgPath), strings.Join(b.Errors, "\n    "))
+	}
+}
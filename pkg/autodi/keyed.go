@@ -0,0 +1,178 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"go/types"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Keyed collections: a map[string]Interface parameter collects the
+// implementations of Interface like a []Interface one, keyed by the
+// //autodi:key of their constructor, else by their Name() string method:
+//
+//	//autodi:key stripe
+//	func NewStripe(cfg *Config) *Stripe
+//
+//	func NewCheckout(methods map[string]payment.Method) *Checkout
+//
+// Strategy registries then look implementations up by name instead of
+// ranging over a slice.
+
+// AnnotKey sets the key of a provider in the map[string]Interface
+// parameters it is collected into.
+const AnnotKey = "key"
+
+// keyedPrefix starts the type string of a map[string]Interface parameter.
+const keyedPrefix = "map[string]"
+
+// collectedElem returns the element type string of a []Interface or
+// map[string]Interface parameter, and whether it is the map.
+func collectedElem(typeStr string) (elem string, keyed, ok bool) {
+	if elem, ok := strings.CutPrefix(typeStr, "[]"); ok {
+		return elem, false, true
+	}
+	if elem, ok := strings.CutPrefix(typeStr, keyedPrefix); ok {
+		return elem, true, true
+	}
+	return "", false, false
+}
+
+// collectedElemType returns the element type of a slice or string-keyed map
+// type, nil for other types.
+func collectedElemType(t types.Type) types.Type {
+	switch u := t.Underlying().(type) {
+	case *types.Slice:
+		return u.Elem()
+	case *types.Map:
+		if basic, ok := u.Key().(*types.Basic); ok && basic.Kind() == types.String {
+			return u.Elem()
+		}
+	}
+	return nil
+}
+
+// providerKey returns the //autodi:key of p, "" without one.
+func providerKey(p *Provider) string {
+	values := GetAnnotationValues(p.Annotations, AnnotKey)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// hasNameMethod reports whether t, or a pointer to it, has a Name() string
+// method.
+func hasNameMethod(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, "Name")
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Params().Len() != 0 || sig.Results().Len() != 1 {
+		return false
+	}
+	basic, ok := sig.Results().At(0).Type().(*types.Basic)
+	return ok && basic.Kind() == types.String
+}
+
+// keyedByName reports whether the values p contributes to a
+// map[string]elemTypeStr have a Name method to key them by.
+func (g *Graph) keyedByName(p *Provider, elemTypeStr string) bool {
+	if hasNameMethod(g.typeIndex[g.resolveConfigType(elemTypeStr)]) {
+		return true
+	}
+	return slices.ContainsFunc(p.Returns, func(ret TypeRef) bool {
+		return hasNameMethod(ret.Type)
+	})
+}
+
+// checkKeyed validates the map[string]Interface parameters of providers and
+// commands: every member has a key, and no two share a //autodi:key.
+func (g *Graph) checkKeyed(commands []*DiscoveredCommand) []error {
+	var errs []error
+	for _, p := range g.Providers {
+		if HasAnnotation(p.Annotations, AnnotKey) && providerKey(p) == "" {
+			errs = append(errs, fmt.Errorf("%s: %s.%s: //autodi:key needs a key\n  hint: //autodi:key stripe", p.Position, p.PkgName, p.FuncName))
+		}
+	}
+
+	params := make(map[string]bool)
+	for _, p := range g.Providers {
+		for _, param := range p.Params {
+			params[param.TypeStr] = true
+		}
+	}
+	for _, cmd := range commands {
+		for _, param := range cmd.allParams() {
+			params[param.TypeStr] = true
+		}
+	}
+	var keyedTypes []string
+	for typeStr := range params {
+		if _, keyed, _ := collectedElem(typeStr); keyed {
+			keyedTypes = append(keyedTypes, typeStr)
+		}
+	}
+	sort.Strings(keyedTypes)
+
+	for _, typeStr := range keyedTypes {
+		elem := strings.TrimPrefix(typeStr, keyedPrefix)
+		seen := make(map[string]*Provider)
+		for _, m := range g.SliceMembers(typeStr) {
+			key := providerKey(m)
+			if key == "" {
+				if !g.keyedByName(m, elem) {
+					errs = append(errs, fmt.Errorf("%s: %s.%s is collected into %s but has no key\n  hint: add //autodi:key <name> to %s, or a Name() string method to its result",
+						m.Position, m.PkgName, m.FuncName, keyedPrefix+toShortTypeName(elem), m.FuncName))
+				}
+				continue
+			}
+			if prev, ok := seen[key]; ok {
+				errs = append(errs, fmt.Errorf("%s: %s.%s and %s.%s both have //autodi:key %s in %s",
+					m.Position, prev.PkgName, prev.FuncName, m.PkgName, m.FuncName, key, keyedPrefix+toShortTypeName(elem)))
+				continue
+			}
+			seen[key] = m
+		}
+	}
+	return errs
+}
+
+// writeCollection declares the variable of a []Interface or
+// map[string]Interface parameter and fills it from providers.
+func (cg *CodeGen) writeCollection(buf *bytes.Buffer, varName, elemTypeStr string, keyed bool, providers []*Provider, varMap map[string]string, usedVars map[string]bool) error {
+	if !keyed {
+		fmt.Fprintf(buf, "\t%s := make([]%s, 0, %d)\n", varName, cg.shortType(elemTypeStr), len(providers))
+		return cg.writeSliceProviderCalls(buf, varName, elemTypeStr, providers, varMap, usedVars)
+	}
+	fmt.Fprintf(buf, "\t%s := make(map[string]%s, %d)\n", varName, cg.shortType(elemTypeStr), len(providers))
+	return cg.writeKeyedProviderCalls(buf, varName, elemTypeStr, providers, varMap, usedVars)
+}
+
+// writeKeyedProviderCalls emits provider calls that store the selected return
+// value in the target map under its //autodi:key, else under its Name().
+func (cg *CodeGen) writeKeyedProviderCalls(buf *bytes.Buffer, mapVarName, elemTypeStr string, providers []*Provider, varMap map[string]string, usedVars map[string]bool) error {
+	return cg.writeCollectedCalls(buf, elemTypeStr, providers, varMap, usedVars, func(p *Provider, v string) string {
+		if key := providerKey(p); key != "" {
+			return fmt.Sprintf("%s[%q] = %s", mapVarName, key, v)
+		}
+		if token.IsIdentifier(v) {
+			return fmt.Sprintf("%s[%s.Name()] = %s", mapVarName, v, v)
+		}
+		// The value is named so Name is called on the stored instance
+		named := keywordSafe(localVarName(cg.graph.varFieldName(elemTypeStr)))
+		if cg.imports.IsQualifier(named) {
+			named = named + "Val"
+		}
+		named = cg.uniqueLocalVar(named, usedVars)
+		return fmt.Sprintf("%s := %s\n\t%s[%s.Name()] = %s", named, v, mapVarName, named, named)
+	})
+}
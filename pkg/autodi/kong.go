@@ -0,0 +1,249 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// CLI frameworks selected with //autodi:cli.
+const (
+	CLICobra = "cobra" // New* returning a struct with Command() *cobra.Command (default)
+	CLIKong  = "kong"  // a struct with Run(deps...) error, parsed by kong
+)
+
+const kongPath = "github.com/alecthomas/kong"
+
+// analyzeKongPackage finds the kong command of a cmd/ package: an exported
+// struct whose pointer has a Run method returning error. The struct is the
+// command's kong grammar (flags and arguments as tagged fields); Run's
+// parameters are its dependencies, bound on the kong context after parsing.
+// *kong.Context is bound by kong and context.Context by the generated main.
+func (d *CommandDetector) analyzeKongPackage(pkg *packages.Package, relPath string) *DiscoveredCommand {
+	scope := pkg.Types.Scope()
+	names := scope.Names()
+	sort.Strings(names)
+
+	for _, name := range names {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || !tn.Exported() || tn.IsAlias() {
+			continue
+		}
+		named, ok := tn.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		if _, ok := named.Underlying().(*types.Struct); !ok {
+			continue
+		}
+		sel := types.NewMethodSet(types.NewPointer(named)).Lookup(pkg.Types, "Run")
+		if sel == nil {
+			continue
+		}
+		sig := sel.Type().(*types.Signature)
+		if sig.Variadic() || sig.Results().Len() != 1 || !isErrorType(sig.Results().At(0).Type()) {
+			continue
+		}
+
+		var params []TypeRef
+		for i := 0; i < sig.Params().Len(); i++ {
+			v := sig.Params().At(i)
+			t := resolveAliases(v.Type())
+			if isContextType(t) || isKongContext(t) {
+				continue
+			}
+			params = append(params, TypeRef{
+				Type:    t,
+				TypeStr: types.TypeString(t, nil),
+				PkgPath: typePkgPath(t),
+				IsIface: isInterface(t),
+				Name:    v.Name(),
+			})
+		}
+
+		dirName := strings.ReplaceAll(strings.TrimPrefix(relPath, d.cfg.cmdDir()+"/"), "/", "_")
+		return &DiscoveredCommand{
+			Name:       dirName,
+			PkgPath:    pkg.PkgPath,
+			Dir:        relPath,
+			PkgName:    pkg.Name,
+			StructName: name,
+			Params:     params,
+			Handlers:   []HandlerInfo{{MethodName: "Run"}},
+			IsSingle:   true,
+		}
+	}
+	return nil
+}
+
+// isKongContext checks if a type is *kong.Context.
+func isKongContext(t types.Type) bool {
+	ptr, ok := t.(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == kongPath && obj.Name() == "Context"
+}
+
+// kongCommandName is the command name on the kong command line.
+func kongCommandName(cmd *DiscoveredCommand) string {
+	return strings.ReplaceAll(cmd.Name, "_", "-")
+}
+
+// generateKongMain generates the entrypoint of a //autodi:cli kong app: a
+// grammar struct with one field per command, kong.Parse, then the selected
+// command's init function, which binds its Run dependencies, and kctx.Run.
+func (cg *CodeGen) generateKongMain() ([]GeneratedFile, error) {
+	_, tagged := splitTaggedCommands(cg.commands)
+	if len(tagged) > 0 {
+		return nil, fmt.Errorf("//autodi:cli %s doesn't support //autodi:cmd buildtag= commands", CLIKong)
+	}
+	if cg.cfg.Inspect {
+		return nil, fmt.Errorf("--inspect adds a cobra command; it needs //autodi:cli %s", CLICobra)
+	}
+
+	cmdAliases := make(map[string]string)
+	for _, cmd := range cg.commands {
+		cmdAliases[cmd.PkgPath] = cg.imports.AddWithAlias(cmd.PkgPath, cmd.PkgName+"cmd")
+	}
+
+	var initBuf bytes.Buffer
+	hasDI := false
+	for _, cmd := range cg.commands {
+		if !cmd.HasDeps() {
+			continue
+		}
+		hasDI = true
+		if err := cg.generateInitFunc(&initBuf, cmd, cmdAliases[cmd.PkgPath]); err != nil {
+			return nil, fmt.Errorf("generate init for %s: %w", cmd.Name, err)
+		}
+		initBuf.WriteString("\n")
+	}
+	if len(cg.configLoaders) > 0 {
+		return nil, fmt.Errorf("//autodi:config registers flags on the cobra root command; it needs //autodi:cli %s", CLICobra)
+	}
+
+	kongQualifier := cg.imports.Add(kongPath, "kong")
+	contextQualifier := cg.imports.Add("context", "context")
+	osQualifier := cg.imports.Add("os", "os")
+	signalQualifier := cg.imports.Add("os/signal", "signal")
+
+	var mainBuf bytes.Buffer
+	mainBuf.WriteString("// cli is the kong grammar: one command per cmd/ package.\n")
+	mainBuf.WriteString("var cli struct {\n")
+	for _, cmd := range cg.commands {
+		fmt.Fprintf(&mainBuf, "\t%s %s.%s `cmd:\"\" name:%q`\n",
+			cmdExportName(cmd.Name), cmdAliases[cmd.PkgPath], cmd.StructName, kongCommandName(cmd))
+	}
+	mainBuf.WriteString("}\n\n")
+
+	if cg.cfg.Package == "main" {
+		mainBuf.WriteString("func main() {\n")
+	} else {
+		mainBuf.WriteString("// Main parses the command line and runs the selected command. Call it from the program's main.\n")
+		mainBuf.WriteString("func Main() {\n")
+	}
+	fmt.Fprintf(&mainBuf, "\tkctx := %s.Parse(&cli, %s.Name(%q), %s.Description(%q))\n",
+		kongQualifier, kongQualifier, cg.cfg.AppName, kongQualifier, cg.cfg.AppShort)
+	fmt.Fprintf(&mainBuf, "\tctx, stop := %s.NotifyContext(%s.Background(), %s.Interrupt)\n", signalQualifier, contextQualifier, osQualifier)
+	fmt.Fprintf(&mainBuf, "\tkctx.BindTo(ctx, (*%s.Context)(nil))\n", contextQualifier)
+	if hasDI {
+		stringsQualifier := cg.imports.Add("strings", "strings")
+		fmt.Fprintf(&mainBuf, "\n\tinitFuncs := map[string]func(*%s.Context) (func(), error){\n", kongQualifier)
+		for _, cmd := range cg.commands {
+			if cmd.HasDeps() {
+				fmt.Fprintf(&mainBuf, "\t\t%q: init%s,\n", kongCommandName(cmd), cmdExportName(cmd.Name))
+			}
+		}
+		mainBuf.WriteString("\t}\n")
+		mainBuf.WriteString("\tvar cleanup func()\n")
+		fmt.Fprintf(&mainBuf, "\tif fn, ok := initFuncs[%s.Fields(kctx.Command())[0]]; ok {\n", stringsQualifier)
+		mainBuf.WriteString("\t\tvar err error\n")
+		mainBuf.WriteString("\t\tcleanup, err = fn(kctx)\n")
+		mainBuf.WriteString("\t\tkctx.FatalIfErrorf(err)\n")
+		mainBuf.WriteString("\t}\n")
+		mainBuf.WriteString("\terr := kctx.Run()\n")
+		mainBuf.WriteString("\tif cleanup != nil {\n")
+		mainBuf.WriteString("\t\tcleanup()\n")
+		mainBuf.WriteString("\t}\n")
+	} else {
+		mainBuf.WriteString("\terr := kctx.Run()\n")
+	}
+	mainBuf.WriteString("\tstop()\n")
+	mainBuf.WriteString("\tif err != nil {\n")
+	mainBuf.WriteString("\t\tkctx.Errorf(\"%s\", err)\n")
+	mainBuf.WriteString("\t\tkctx.Exit(exitCode(err))\n")
+	mainBuf.WriteString("\t}\n")
+	mainBuf.WriteString("}\n")
+
+	var helperBuf bytes.Buffer
+	helperBuf.WriteString("\n")
+	cg.writeExitCodeHelper(&helperBuf)
+	if cg.hasContainer {
+		helperBuf.WriteString("\n")
+		cg.writeContainerType(&helperBuf)
+	}
+	if cg.hasEnvCheck {
+		helperBuf.WriteString("\n")
+		cg.writeEnvCheck(&helperBuf)
+	}
+	if cg.hasResourceAttrs {
+		helperBuf.WriteString("\n")
+		cg.writeResourceAttrsHelper(&helperBuf)
+	}
+	if cg.hasInitProfile {
+		helperBuf.WriteString("\n")
+		cg.writeInitProfileHelper(&helperBuf)
+	}
+	if cg.hasMetrics {
+		helperBuf.WriteString("\n")
+		cg.writeMetricsHelper(&helperBuf)
+	}
+	if cg.routeMux != nil {
+		helperBuf.WriteString("\n")
+		if err := cg.writeRouteMux(&helperBuf, cg.routeMux); err != nil {
+			return nil, err
+		}
+	}
+	if err := cg.writeJobs(&helperBuf); err != nil {
+		return nil, err
+	}
+	if len(cg.loggers) > 0 {
+		helperBuf.WriteString("\n")
+		cg.writeDefaultLoggers(&helperBuf)
+	}
+	if len(cg.adapters) > 0 {
+		helperBuf.WriteString("\n")
+		cg.writeAdapters(&helperBuf)
+	}
+
+	f, err := cg.renderFile(filepath.FromSlash(cg.cfg.Output), "", cg.cfg.Package, mainBuf.Bytes(), initBuf.Bytes(), helperBuf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return []GeneratedFile{f}, nil
+}
+
+// writeKongBindings binds the constructed Run dependencies of a kong command
+// on the parsed context; args are the values of cmd.Params.
+func (cg *CodeGen) writeKongBindings(buf *bytes.Buffer, cmd *DiscoveredCommand, args []string, components []Component) error {
+	if len(components) > 0 {
+		return fmt.Errorf("%s has a %s(ctx) method, which //autodi:cli %s doesn't run alongside the command\n  hint: mark its constructor //autodi:%s and call it from Run",
+			components[0].VarName, components[0].Method, CLIKong, AnnotNoStart)
+	}
+	for i, param := range cmd.Params {
+		fmt.Fprintf(buf, "\tkctx.BindTo(%s, (*%s)(nil))\n", args[i], cg.shortType(param.TypeStr))
+	}
+	buf.WriteString("\n")
+	return nil
+}
@@ -0,0 +1,298 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"path/filepath"
+	"strings"
+)
+
+// Runtimes selected with //autodi:runtime in generate.go.
+const (
+	RuntimeLambda = "lambda" // main.go calls lambda.Start with the handler's Handle method
+)
+
+// lambdaPath is the AWS Lambda runtime package the generated main imports.
+const lambdaPath = "github.com/aws/aws-lambda-go/lambda"
+
+// AnnotLazy defers a constructor, and everything depending on it, to the
+// first invocation of a serverless handler instead of the cold start:
+//
+//	//autodi:lazy
+//	func NewSearchIndex(cfg *Config) (*Index, error)
+const AnnotLazy = "lazy"
+
+// resolveRuntime checks //autodi:runtime against the other output settings.
+func resolveRuntime(cfg *Config) error {
+	if cfg.Runtime == "" {
+		return nil
+	}
+	if cfg.Layout != LayoutSingle {
+		return fmt.Errorf("generate.go: //autodi:runtime %s can't be combined with layout %s", cfg.Runtime, cfg.Layout)
+	}
+	if cfg.CLI == CLIKong {
+		return fmt.Errorf("generate.go: //autodi:runtime %s can't be combined with //autodi:cli %s", cfg.Runtime, CLIKong)
+	}
+	if len(cfg.Apps) > 1 {
+		return fmt.Errorf("generate.go: //autodi:runtime %s builds one handler; it can't be combined with several //autodi:app definitions", cfg.Runtime)
+	}
+	if cfg.Package != "main" {
+		return fmt.Errorf("generate.go: //autodi:runtime %s writes a main package\n  hint: drop the package from //autodi:output", cfg.Runtime)
+	}
+	return nil
+}
+
+// isLambdaHandler reports whether p is the //autodi:runtime lambda handler
+// constructor: pkg.Func, with the package name, its module-relative path or
+// its import path.
+func (cfg *Config) isLambdaHandler(p *Provider) bool {
+	if cfg.Runtime != RuntimeLambda {
+		return false
+	}
+	i := strings.LastIndex(cfg.Handler, ".")
+	if i < 0 || cfg.Handler[i+1:] != p.FuncName {
+		return false
+	}
+	pkgPart := cfg.Handler[:i]
+	return pkgPart == p.PkgName || pkgPart == p.PkgPath || pkgPart == cfg.RelPath(p.PkgPath)
+}
+
+// lambdaHandler returns the handler provider and its Handle method.
+func (g *Graph) lambdaHandler() (*Provider, *types.Signature, error) {
+	var found []*Provider
+	for _, p := range g.Providers {
+		if g.cfg.isLambdaHandler(p) {
+			found = append(found, p)
+		}
+	}
+	switch {
+	case len(found) == 0:
+		return nil, nil, fmt.Errorf("generate.go: //autodi:runtime %s %s: no such constructor\n  hint: name it pkg.NewHandler, with the package name, its module-relative path or its import path", RuntimeLambda, g.cfg.Handler)
+	case len(found) > 1:
+		return nil, nil, fmt.Errorf("generate.go: //autodi:runtime %s %s names both %s and %s\n  hint: use the module-relative path of the package", RuntimeLambda, g.cfg.Handler, found[0].PkgPath, found[1].PkgPath)
+	}
+	p := found[0]
+	if len(p.Returns) == 0 || p.Returns[0].Type == nil {
+		return nil, nil, fmt.Errorf("%s: %s.%s returns no handler", p.Position, p.PkgName, p.FuncName)
+	}
+	sig := handleMethod(p.Returns[0].Type)
+	if sig == nil {
+		return nil, nil, fmt.Errorf("%s: %s has no Handle method the Lambda runtime can call\n  hint: Handle(ctx context.Context, event In) (Out, error), where ctx, event and Out are optional",
+			p.Position, toShortTypeName(p.Returns[0].TypeStr))
+	}
+	return p, sig, nil
+}
+
+// handleMethod returns the signature of t's Handle method if lambda.Start
+// accepts it: at most a context and an event, and no result, an error, or a
+// value and an error.
+func handleMethod(t types.Type) *types.Signature {
+	mset := types.NewMethodSet(t)
+	for i := 0; i < mset.Len(); i++ {
+		if mset.At(i).Obj().Name() != "Handle" {
+			continue
+		}
+		sig, ok := mset.At(i).Type().(*types.Signature)
+		if !ok || sig.Variadic() {
+			return nil
+		}
+		params, results := sig.Params(), sig.Results()
+		if params.Len() > 2 || params.Len() == 2 && !isContextType(params.At(0).Type()) {
+			return nil
+		}
+		if results.Len() > 2 || results.Len() > 0 && !isErrorType(results.At(results.Len()-1).Type()) {
+			return nil
+		}
+		return sig
+	}
+	return nil
+}
+
+// checkLambdaHandler validates the handler and the providers it needs.
+func (g *Graph) checkLambdaHandler() []error {
+	if g.cfg.Runtime != RuntimeLambda {
+		return nil
+	}
+	p, _, err := g.lambdaHandler()
+	if err != nil {
+		return []error{err}
+	}
+	providers, err := g.ProvidersForTypes([]string{p.Returns[0].TypeStr})
+	if err != nil {
+		return []error{fmt.Errorf("lambda handler: %w", err)}
+	}
+	return g.ValidateEntry("lambda", providers)
+}
+
+// lazyProviders returns the providers deferred to the first invocation: the
+// //autodi:lazy ones and everything depending on them, also through the
+// members collected into a parameter.
+func (g *Graph) lazyProviders(providers []*Provider) map[*Provider]bool {
+	lazy := make(map[*Provider]bool)
+	for _, p := range providers {
+		if HasAnnotation(p.Annotations, AnnotLazy) {
+			lazy[p] = true
+			continue
+		}
+		var deps []string
+		for _, param := range p.Params {
+			deps = append(deps, param.TypeStr)
+			for _, m := range g.SliceMembers(param.TypeStr) {
+				deps = append(deps, memberDeps(m)...)
+			}
+		}
+		for _, dep := range append(deps, p.ExtraDeps...) {
+			if lazy[g.ProviderMap[g.resolveType(dep)]] {
+				lazy[p] = true
+				break
+			}
+		}
+	}
+	return lazy
+}
+
+// generateLambdaMain emits the main.go of //autodi:runtime lambda: the
+// handler's providers are constructed at cold start, the //autodi:lazy ones
+// and their dependents on the first invocation, and lambda.Start serves the
+// handler's Handle method. Cleanup never runs; the runtime freezes and
+// discards the process.
+func (cg *CodeGen) generateLambdaMain() (GeneratedFile, error) {
+	cg.imports.Reset()
+	cg.configLoaders = make(map[*Provider]bool)
+	cg.routeMux = nil
+	cg.jobCron = nil
+	cg.loggers = make(map[*Provider]bool)
+	cg.adapters = make(map[string]bool)
+	cg.unresolved = nil
+
+	handler, sig, err := cg.graph.lambdaHandler()
+	if err != nil {
+		return GeneratedFile{}, err
+	}
+	providers, err := cg.graph.providersWithCollected([]string{handler.Returns[0].TypeStr})
+	if err != nil {
+		return GeneratedFile{}, err
+	}
+	cg.registerProviderImports(providers)
+	context := cg.imports.Add("context", "context")
+	cg.imports.Add("fmt", "fmt")
+	cg.imports.Add("os", "os")
+	lambda := cg.imports.Add(lambdaPath, "lambda")
+
+	lazy := cg.graph.lazyProviders(providers)
+	var eager, deferred []*Provider
+	for _, p := range providers {
+		if lazy[p] {
+			deferred = append(deferred, p)
+		} else {
+			eager = append(eager, p)
+		}
+	}
+	varMap := cg.libraryVarMap(providers)
+
+	var body bytes.Buffer
+	body.WriteString("func main() {\n")
+	body.WriteString("\tc := &Container{}\n")
+	fmt.Fprintf(&body, "\tif err := c.build(%s.Background()); err != nil {\n", context)
+	body.WriteString("\t\tfmt.Fprintln(os.Stderr, err)\n")
+	body.WriteString("\t\tos.Exit(1)\n")
+	body.WriteString("\t}\n")
+	handle := "c." + cg.graph.TypeToField[handler.Returns[0].TypeStr] + ".Handle"
+	if len(deferred) == 0 {
+		fmt.Fprintf(&body, "\t%s.Start(%s)\n", lambda, handle)
+	} else {
+		cg.writeLazyStart(&body, sig, handle, lambda)
+	}
+	body.WriteString("}\n\n")
+
+	cg.writeFieldContainer(&body, providers)
+
+	body.WriteString("\n// build constructs the providers of the handler at cold start.\n")
+	fmt.Fprintf(&body, "func (c *Container) build(ctx %s.Context) error {\n", context)
+	if err := cg.writeLibraryProviders(&body, eager, varMap, map[string]bool{"c": true, "ctx": true, "err": true}, ""); err != nil {
+		return GeneratedFile{}, err
+	}
+	if err := cg.unresolvedError("build"); err != nil {
+		return GeneratedFile{}, err
+	}
+	body.WriteString("\treturn nil\n")
+	body.WriteString("}\n")
+	if len(deferred) > 0 {
+		body.WriteString("\n// buildLazy constructs the //autodi:lazy providers and their dependents on\n")
+		body.WriteString("// the first invocation.\n")
+		fmt.Fprintf(&body, "func (c *Container) buildLazy(ctx %s.Context) error {\n", context)
+		if err := cg.writeLibraryProviders(&body, deferred, varMap, map[string]bool{"c": true, "ctx": true, "err": true}, ""); err != nil {
+			return GeneratedFile{}, err
+		}
+		if err := cg.unresolvedError("buildLazy"); err != nil {
+			return GeneratedFile{}, err
+		}
+		body.WriteString("\treturn nil\n")
+		body.WriteString("}\n")
+	}
+
+	if len(cg.configLoaders) > 0 {
+		body.WriteString("\n")
+		cg.writeConfigLoaders(&body)
+	}
+	if cg.routeMux != nil {
+		body.WriteString("\n")
+		if err := cg.writeRouteMux(&body, cg.routeMux); err != nil {
+			return GeneratedFile{}, err
+		}
+	}
+	if err := cg.writeJobs(&body); err != nil {
+		return GeneratedFile{}, err
+	}
+	if len(cg.loggers) > 0 {
+		body.WriteString("\n")
+		cg.writeDefaultLoggers(&body)
+	}
+	if len(cg.adapters) > 0 {
+		body.WriteString("\n")
+		cg.writeAdapters(&body)
+	}
+
+	return cg.renderFile(filepath.FromSlash(cg.cfg.Output), "", "main", body.Bytes())
+}
+
+// writeLazyStart emits a lambda.Start wrapper with the Handle method's
+// signature that runs buildLazy once before the first call.
+func (cg *CodeGen) writeLazyStart(buf *bytes.Buffer, sig *types.Signature, handle, lambda string) {
+	syncQualifier := cg.imports.Add("sync", "sync")
+	var params, args []string
+	ctxArg := cg.imports.Add("context", "context") + ".Background()"
+	for i := 0; i < sig.Params().Len(); i++ {
+		t := sig.Params().At(i).Type()
+		name := "event"
+		if isContextType(t) {
+			name, ctxArg = "ctx", "ctx"
+		}
+		params = append(params, name+" "+cg.typeSource(t))
+		args = append(args, name)
+	}
+	var results []string
+	for i := 0; i < sig.Results().Len(); i++ {
+		results = append(results, cg.typeSource(sig.Results().At(i).Type()))
+	}
+	resultList := strings.Join(results, ", ")
+	if len(results) > 1 {
+		resultList = "(" + resultList + ")"
+	}
+
+	fmt.Fprintf(buf, "\tvar lazy %s.Once\n", syncQualifier)
+	fmt.Fprintf(buf, "\t%s.Start(func(%s) %s {\n", lambda, strings.Join(params, ", "), resultList)
+	buf.WriteString("\t\tlazy.Do(func() {\n")
+	fmt.Fprintf(buf, "\t\t\tif err := c.buildLazy(%s); err != nil {\n", ctxArg)
+	buf.WriteString("\t\t\t\tfmt.Fprintln(os.Stderr, err)\n")
+	buf.WriteString("\t\t\t\tos.Exit(1)\n")
+	buf.WriteString("\t\t\t}\n")
+	buf.WriteString("\t\t})\n")
+	if len(results) == 0 {
+		fmt.Fprintf(buf, "\t\t%s(%s)\n", handle, strings.Join(args, ", "))
+	} else {
+		fmt.Fprintf(buf, "\t\treturn %s(%s)\n", handle, strings.Join(args, ", "))
+	}
+	buf.WriteString("\t})\n")
+}
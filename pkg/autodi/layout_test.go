@@ -0,0 +1,85 @@
+package autodi
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestLayoutsCompile generates each fixture module in testdata, with
+// directives appended to its generate.go, and vets the result with the test
+// container, so generated code that doesn't compile fails here.
+func TestLayoutsCompile(t *testing.T) {
+	tests := []struct {
+		name       string
+		fixture    string
+		directives string
+		about      string
+	}{
+		{"single", "cobra", "", "cobra tree with child commands, a component one child needs, request scope, secrets, a HealthChecker, Shutdown(ctx)"},
+		{"split", "cobra", "//autodi:layout split", "the same tree with an init file per command"},
+		{"multi-binary", "multibinary", "", "a main per command, a Run component, a HealthChecker, request scope"},
+		{"kong", "kong", "", "kong commands binding Run dependencies, a //autodi:nostart provider"},
+		{"library", "library", "", "wiring package: secret fields on a provider, a keyed member and a //autodi:when provider, a member's factory-built dependency, Shutdown(ctx), Container.HealthCheck"},
+		{"lambda", "lambda", "", "Lambda runtime: the same graph with the member's dependency //autodi:lazy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := generateFixture(t, tt.fixture, tt.directives)
+			if err := goTool(dir, "vet", "-tags", "test", "./..."); err != nil {
+				t.Fatalf("%s: %v", tt.about, err)
+			}
+		})
+	}
+}
+
+// generateFixture copies testdata/<fixture> into a temporary module, appends
+// directives to its generate.go, runs autodi generate on it through Scan,
+// Build and Generate and returns the directory. A fixture's go.mod and go.sum
+// already list what the generated code imports.
+func generateFixture(t *testing.T, fixture, directives string) string {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("needs the go tool")
+	}
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS(filepath.Join("testdata", fixture))); err != nil {
+		t.Fatal(err)
+	}
+	if directives != "" {
+		path := filepath.Join(dir, "generate.go")
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, append(src, "\n"+directives+"\n"...), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := goTool(dir, "mod", "download"); err != nil {
+		t.Skipf("needs module downloads: %v", err)
+	}
+	scan, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proj, err := Build(scan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := Generate(proj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, f.Content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,360 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// libraryOutput is where the library layout writes unless //autodi:output
+// says otherwise.
+const libraryOutput = "wiring/wiring_gen.go"
+
+// resolveLibraryOutput checks the library layout and defaults its output to
+// wiring/wiring_gen.go in package wiring, or the package named after the
+// //autodi:output directory.
+func resolveLibraryOutput(cfg *Config) error {
+	if cfg.Output == "main.go" {
+		cfg.Output = libraryOutput
+	}
+	dir := path.Dir(cfg.Output)
+	if dir == "." {
+		return fmt.Errorf("generate.go: layout %s writes an importable package, not the module root\n  hint: //autodi:output %s", LayoutLibrary, libraryOutput)
+	}
+	if cfg.Package == "main" {
+		cfg.Package = path.Base(dir)
+	}
+	if !token.IsIdentifier(cfg.Package) {
+		return fmt.Errorf("generate.go: layout %s: %q is not a valid package name\n  hint: name it on the output directive, e.g. //autodi:output %s/wiring_gen.go wiring", LayoutLibrary, cfg.Package, dir)
+	}
+	if len(cfg.Apps) > 1 {
+		return fmt.Errorf("generate.go: several //autodi:app definitions can't be combined with layout %s\n  hint: the library has no command tree; each main picks the builders it needs", LayoutLibrary)
+	}
+	if cfg.ContainerInjection {
+		return fmt.Errorf("generate.go: //autodi:allow-container-injection can't be combined with layout %s", LayoutLibrary)
+	}
+	return nil
+}
+
+// generateLibrary emits the wiring package of the library layout: a Container
+// with a field per singleton provider and a HealthCheck over them,
+// BuildContainer constructing all of them, and a Build<Command> function per
+// command constructing only what the command needs. There is no main or
+// command tree; an existing main or a serverless handler calls the builders
+// and runs the command itself. Request scopes, Start/Run components and
+// commands behind a build tag are left to the caller.
+func (cg *CodeGen) generateLibrary() (GeneratedFile, error) {
+	cg.imports.Reset()
+	cg.configLoaders = make(map[*Provider]bool)
+	cg.routeMux = nil
+	cg.jobCron = nil
+	cg.loggers = make(map[*Provider]bool)
+	cg.adapters = make(map[string]bool)
+	cg.unresolved = nil
+	context := cg.imports.Add("context", "context")
+
+	all, err := cg.graph.providersWithCollected(cg.graph.sortedTypes)
+	if err != nil {
+		return GeneratedFile{}, err
+	}
+	all = slices.DeleteFunc(all, func(p *Provider) bool { return p.Scope != "" })
+	cg.registerProviderImports(all)
+
+	var body bytes.Buffer
+	cg.writeFieldContainer(&body, all)
+	cg.writeLibraryHealthCheck(&body, all)
+
+	body.WriteString("\n// BuildContainer constructs every singleton provider of the graph. Call Close\n")
+	body.WriteString("// to release them.\n")
+	fmt.Fprintf(&body, "func BuildContainer(ctx %s.Context) (*Container, error) {\n", context)
+	body.WriteString("\tc := &Container{}\n")
+	usedVars := map[string]bool{"c": true, "ctx": true, "err": true}
+	if err := cg.writeLibraryProviders(&body, all, cg.libraryVarMap(all), usedVars, "nil"); err != nil {
+		return GeneratedFile{}, err
+	}
+	if err := cg.unresolvedError("BuildContainer"); err != nil {
+		return GeneratedFile{}, err
+	}
+	body.WriteString("\treturn c, nil\n")
+	body.WriteString("}\n")
+	if cg.graph.phased() {
+		if err := cg.generatePhaseContainers(&body, all); err != nil {
+			return GeneratedFile{}, err
+		}
+	}
+
+	for _, cmd := range cg.commands {
+		if cmd.Parent != nil || cmd.BuildTag != "" {
+			continue
+		}
+		body.WriteString("\n")
+		if err := cg.writeCommandBuilder(&body, cmd); err != nil {
+			return GeneratedFile{}, fmt.Errorf("command %s: %w", cmd.Name, err)
+		}
+	}
+
+	if len(cg.configLoaders) > 0 {
+		body.WriteString("\n")
+		cg.writeConfigLoaders(&body)
+	}
+	if cg.routeMux != nil {
+		body.WriteString("\n")
+		if err := cg.writeRouteMux(&body, cg.routeMux); err != nil {
+			return GeneratedFile{}, err
+		}
+	}
+	if err := cg.writeJobs(&body); err != nil {
+		return GeneratedFile{}, err
+	}
+	if len(cg.loggers) > 0 {
+		body.WriteString("\n")
+		cg.writeDefaultLoggers(&body)
+	}
+	if len(cg.adapters) > 0 {
+		body.WriteString("\n")
+		cg.writeAdapters(&body)
+	}
+
+	doc := fmt.Sprintf("// Package %s wires the providers of %s for an existing main or a\n", cg.cfg.Package, cg.cfg.Module) +
+		"// serverless handler (//autodi:layout library).\n"
+	return cg.renderFile(filepath.FromSlash(cg.cfg.Output), doc, cg.cfg.Package, body.Bytes())
+}
+
+// writeCommandBuilder emits Build<Command>, which constructs a command with
+// only the providers it needs, including those of members collected further
+// down, and returns the cleanup releasing them.
+func (cg *CodeGen) writeCommandBuilder(buf *bytes.Buffer, cmd *DiscoveredCommand) error {
+	providers, err := cg.graph.commandProviders(cmd, cg.graph.providersWithCollected)
+	if err != nil {
+		return err
+	}
+	providers = slices.DeleteFunc(providers, func(p *Provider) bool { return p.Scope != "" })
+	cg.registerProviderImports(providers)
+	alias := cg.imports.AddWithAlias(cmd.PkgPath, cmd.PkgName+"cmd")
+
+	exportName := cmdExportName(cmd.Name)
+	fmt.Fprintf(buf, "// Build%s constructs the %s command and the providers it needs. Call the\n", exportName, cmd.Name)
+	buf.WriteString("// returned cleanup to release them once the command is done.\n")
+	fmt.Fprintf(buf, "func Build%s(ctx %s.Context) (*%s.%s, func(), error) {\n", exportName, cg.imports.Add("context", "context"), alias, cmd.StructName)
+	buf.WriteString("\tc := &Container{}\n")
+	usedVars := map[string]bool{"c": true, "ctx": true, "err": true}
+	varMap := cg.libraryVarMap(providers)
+	if err := cg.writeLibraryProviders(buf, providers, varMap, usedVars, "nil, nil"); err != nil {
+		return err
+	}
+
+	for _, param := range cmd.allParams() {
+		sliceVar, err := cg.writeLibrarySlice(buf, param, varMap, usedVars, "nil, nil")
+		if err != nil {
+			return err
+		}
+		if sliceVar != "" {
+			varMap = withVar(varMap, param.TypeStr, sliceVar)
+		}
+	}
+	argsOf := func(c *DiscoveredCommand) []string {
+		var args []string
+		for _, param := range c.Params {
+			if param.Stream != "" {
+				args = append(args, cg.streamArg(param))
+			} else if v, ok := varMap[param.TypeStr]; ok {
+				args = append(args, v)
+			} else if v, ok := varMap[cg.graph.resolveType(param.TypeStr)]; ok {
+				args = append(args, v)
+			} else {
+				cg.unresolved = append(cg.unresolved, fmt.Sprintf("%s of %s.%s", toShortTypeName(param.TypeStr), c.PkgName, c.FuncName))
+				args = append(args, "nil")
+			}
+		}
+		return args
+	}
+	children := cg.writeChildCommands(buf, "\t", cmd, argsOf, usedVars)
+	fmt.Fprintf(buf, "\treturn %s, c.Close, nil\n", cg.commandCall(cmd, alias, argsOf(cmd), children))
+	buf.WriteString("}\n")
+	return cg.unresolvedError("Build" + exportName)
+}
+
+// writeFieldContainer emits a Container type with a field per type the
+// providers fill, and its Close method.
+func (cg *CodeGen) writeFieldContainer(buf *bytes.Buffer, providers []*Provider) {
+	cg.writeNamedContainer(buf, "Container", "holds the singleton providers of the graph.", "", providers)
+}
+
+// writeNamedContainer emits a container type with the given name, doc
+// comment (after the name) and leading fields, a field per type the
+// providers fill, and its Close method.
+func (cg *CodeGen) writeNamedContainer(buf *bytes.Buffer, name, doc, head string, providers []*Provider) {
+	fmt.Fprintf(buf, "// %s %s\n", name, doc)
+	fmt.Fprintf(buf, "type %s struct {\n", name)
+	buf.WriteString(head)
+	for _, p := range providers {
+		for _, typeStr := range cg.libraryFields(p) {
+			fmt.Fprintf(buf, "\t%s %s\n", cg.graph.TypeToField[typeStr], cg.qualifyType(typeStr, ""))
+		}
+	}
+	buf.WriteString("\n\tcleanups []func()\n")
+	buf.WriteString("}\n\n")
+
+	fmt.Fprintf(buf, "// Close releases the providers the %s constructed, in reverse order.\n", name)
+	fmt.Fprintf(buf, "func (c *%s) Close() {\n", name)
+	buf.WriteString("\tfor i := len(c.cleanups) - 1; i >= 0; i-- {\n")
+	buf.WriteString("\t\tc.cleanups[i]()\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("}\n")
+}
+
+// libraryFields returns the types a provider fills Container fields for:
+// every type the graph resolves to it.
+func (cg *CodeGen) libraryFields(p *Provider) []string {
+	var typeStrs []string
+	for _, typeStr := range append(p.allAs(), returnTypeStrs(p)...) {
+		if cg.graph.ProviderMap[typeStr] == p && !slices.Contains(typeStrs, typeStr) {
+			typeStrs = append(typeStrs, typeStr)
+		}
+	}
+	return typeStrs
+}
+
+// libraryVarMap points every type the providers fill, and the interfaces
+// bound to them, at its Container field; context.Context is the builder's
+// ctx unless a provider returns one.
+func (cg *CodeGen) libraryVarMap(providers []*Provider) map[string]string {
+	varMap := make(map[string]string)
+	for _, p := range providers {
+		for _, typeStr := range cg.libraryFields(p) {
+			varMap[typeStr] = "c." + cg.graph.TypeToField[typeStr]
+		}
+	}
+	for ifaceStr, concreteStr := range cg.graph.Bindings {
+		if v, ok := varMap[concreteStr]; ok {
+			if _, exists := varMap[ifaceStr]; !exists {
+				varMap[ifaceStr] = v
+			}
+		}
+	}
+	if _, ok := varMap["context.Context"]; !ok {
+		varMap["context.Context"] = "ctx"
+	}
+	return varMap
+}
+
+// writeLibraryProviders emits the provider calls of a builder in order,
+// assigning the Container fields; fail is what the builder returns besides
+// the error when a constructor fails ("" when it only returns the error).
+func (cg *CodeGen) writeLibraryProviders(buf *bytes.Buffer, providers []*Provider, varMap map[string]string, usedVars map[string]bool, fail string) error {
+	for _, p := range providers {
+		if len(cg.libraryFields(p)) == 0 && !p.IsInvoke {
+			continue
+		}
+		if err := cg.writeLibraryProviderCall(buf, p, varMap, usedVars, fail); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeLibrarySlice builds the group or auto-collected slice a []Interface
+// parameter takes, or the keyed map of a map[string]Interface one, returning
+// its variable, or "" for other parameters. A failing member returns fail
+// besides its error, like writeLibraryProviders.
+func (cg *CodeGen) writeLibrarySlice(buf *bytes.Buffer, param TypeRef, varMap map[string]string, usedVars map[string]bool, fail string) (string, error) {
+	members := cg.graph.SliceMembers(param.TypeStr)
+	if members == nil {
+		return "", nil
+	}
+	cg.registerProviderImports(members)
+	for _, m := range members {
+		if m.HasError {
+			cg.addInitErrorImports()
+		}
+	}
+	elemType, keyed, _ := collectedElem(param.TypeStr)
+	sliceVar := cg.uniqueLocalVar(deriveSliceVarName(elemType), usedVars)
+	cg.memberFail = &failReturn{results: fail, cleanup: "c.Close()"}
+	defer func() { cg.memberFail = nil }()
+	if err := cg.writeCollection(buf, sliceVar, elemType, keyed, members, varMap, usedVars); err != nil {
+		return "", err
+	}
+	return sliceVar, nil
+}
+
+// writeLibraryProviderCall emits one provider call of a builder: its results
+// go to Container fields, closeable ones are registered for Close.
+func (cg *CodeGen) writeLibraryProviderCall(buf *bytes.Buffer, p *Provider, varMap map[string]string, usedVars map[string]bool, fail string) error {
+	for _, param := range p.Params {
+		sliceVar, err := cg.writeLibrarySlice(buf, param, varMap, usedVars, fail)
+		if err != nil {
+			return err
+		}
+		if sliceVar != "" {
+			varMap = withVar(varMap, param.TypeStr, sliceVar)
+		}
+	}
+
+	qualifier := cg.replaySwitch(buf, p, usedVars)
+	args := strings.Join(cg.buildLocalArgs(p, varMap), ", ")
+
+	fields := cg.libraryFields(p)
+	var lhs, assigns, cleanups []string
+	for i, ret := range p.Returns {
+		var retFields []string
+		for _, typeStr := range fields {
+			if typeStr == ret.TypeStr || slices.Contains(p.asTypes(i), typeStr) {
+				retFields = append(retFields, cg.graph.TypeToField[typeStr])
+			}
+		}
+		if len(retFields) == 0 {
+			lhs = append(lhs, "_")
+			continue
+		}
+		v := cg.uniqueLocalVar(localVarName(retFields[0]), usedVars)
+		lhs = append(lhs, v)
+		for _, field := range retFields {
+			assigns = append(assigns, fmt.Sprintf("\tc.%s = %s\n", field, v))
+		}
+		if isNilable(ret.Type) {
+			if cl := checkCloseable(ret.Type, v); cl != nil {
+				cleanups = append(cleanups, cg.cleanupAppend("c", cl))
+			}
+		}
+	}
+
+	lhs, post := cg.resultLHS(p, lhs, usedVars)
+	qualifier = cg.whenSwitch(buf, p, qualifier, usedVars)
+	assign, endWhen := cg.beginWhen(buf, p, lhs)
+	cg.writeProviderNotes(buf, p, "\t")
+	declares := slices.ContainsFunc(lhs, func(name string) bool { return name != "_" })
+	switch {
+	case p.HasError && declares:
+		cg.addInitErrorImports()
+		fmt.Fprintf(buf, "\t%s, err %s %s(%s)\n", strings.Join(lhs, ", "), assign, qualifier, args)
+		buf.WriteString("\tif err != nil {\n")
+	case p.HasError:
+		// Nothing to keep: the error is scoped to the if statement
+		cg.addInitErrorImports()
+		fmt.Fprintf(buf, "\tif %s := %s(%s); err != nil {\n", strings.Join(append(lhs, "err"), ", "), qualifier, args)
+	case len(lhs) > 0:
+		fmt.Fprintf(buf, "\t%s %s %s(%s)\n", strings.Join(lhs, ", "), assign, qualifier, args)
+	default:
+		fmt.Fprintf(buf, "\t%s(%s)\n", qualifier, args)
+	}
+	if p.HasError {
+		cg.writeFailReturn(buf, p, "\t\t", failReturn{results: fail, cleanup: "c.Close()"})
+		buf.WriteString("\t}\n")
+	}
+	buf.WriteString(post)
+	for _, a := range assigns {
+		buf.WriteString(a)
+	}
+	cg.writeSecretResolution(buf, p, secretTarget(p, varMap), varMap, failReturn{results: fail, cleanup: "c.Close()"})
+	buf.WriteString(endWhen)
+	for _, cl := range cleanups {
+		buf.WriteString(cl)
+	}
+	buf.WriteString("\n")
+	return nil
+}
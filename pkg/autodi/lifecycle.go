@@ -0,0 +1,187 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"slices"
+	"strings"
+)
+
+// AnnotNoStart keeps a provider with a Start/Run method out of the generated
+// startup section, e.g. when the command handler calls Run itself.
+const AnnotNoStart = "nostart"
+
+// Component records a constructed value with a long-running lifecycle method.
+type Component struct {
+	Provider *Provider // nil for a job ticker
+	Group    string    // the job group a ticker runs
+	VarName  string
+	Method   string // "Start" or "Run"
+	MayNil   bool   // //autodi:when without fallback: the value can be nil
+}
+
+// checkStartable returns "Start" or "Run" if the type has a method of that
+// name with signature func(context.Context) error, preferring Start.
+func checkStartable(t types.Type) string {
+	mset := types.NewMethodSet(t)
+	for _, name := range []string{"Start", "Run"} {
+		for i := 0; i < mset.Len(); i++ {
+			method := mset.At(i)
+			if method.Obj().Name() != name {
+				continue
+			}
+			sig, ok := method.Type().(*types.Signature)
+			if !ok || sig.Params().Len() != 1 || sig.Results().Len() != 1 {
+				continue
+			}
+			if isContextType(sig.Params().At(0).Type()) && isErrorType(sig.Results().At(0).Type()) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// describeLifecycle lists the lifecycle methods the generated code calls on a
+// provider's results, for --verbose.
+func describeLifecycle(p *Provider, cfg *Config) []string {
+	var shapes []string
+	for _, ret := range p.Returns {
+		if ret.Type == nil {
+			continue
+		}
+		if method := checkStartable(ret.Type); method != "" && !HasAnnotation(p.Annotations, AnnotNoStart) {
+			shapes = append(shapes, fmt.Sprintf("%s(ctx) alongside the command", method))
+		}
+		if !isNilable(ret.Type) {
+			continue
+		}
+		if cl := checkCloseable(ret.Type, "_"); cl != nil {
+			if cl.HasCtx {
+				shapes = append(shapes, fmt.Sprintf("%s(ctx) on cleanup, %s deadline", cl.Method, cfg.shutdownTimeout()))
+			} else {
+				shapes = append(shapes, fmt.Sprintf("%s() on cleanup", cl.Method))
+			}
+		}
+	}
+	return shapes
+}
+
+// commandComponents splits the components of an init function between cmd
+// and its child commands: each one runs those of its own graph, what its
+// parameters depend on and shared, the types constructed for every command
+// (the request scope's dependencies, the migrator). A command depending on a
+// provider that takes the Container may look up anything, so it runs them
+// all.
+func (cg *CodeGen) commandComponents(cmd *DiscoveredCommand, shared []string, components []Component) (map[*DiscoveredCommand][]Component, error) {
+	sharedProviders, err := cg.graph.ProvidersForTypes(shared)
+	if err != nil {
+		return nil, err
+	}
+	byCmd := make(map[*DiscoveredCommand][]Component)
+	var walk func(c *DiscoveredCommand) error
+	walk = func(c *DiscoveredCommand) error {
+		own, err := cg.graph.paramProviders(c.Params, cg.graph.providersWithCollected)
+		if err != nil {
+			return fmt.Errorf("command %s: %w", c.Name, err)
+		}
+		inGraph := make(map[*Provider]bool)
+		lookups := false
+		for _, p := range slices.Concat(own, sharedProviders) {
+			inGraph[p] = true
+			lookups = lookups || takesContainer(p)
+		}
+		groups := make(map[string]bool)
+		for _, param := range c.Params {
+			if name := cg.matchGroup(param.TypeStr); name != "" {
+				groups[name] = true
+			}
+		}
+		for _, comp := range components {
+			if lookups || inGraph[comp.Provider] || groups[comp.Group] {
+				byCmd[c] = append(byCmd[c], comp)
+			}
+		}
+		for _, child := range c.Children {
+			if err := walk(child.Cmd); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(cmd); err != nil {
+		return nil, err
+	}
+	return byCmd, nil
+}
+
+// writeStartComponents wraps a command and its handler subcommands so the
+// components run while a handler does. Called before the command's children
+// are added, it leaves them to their own call.
+func (cg *CodeGen) writeStartComponents(buf *bytes.Buffer, treeVar string, components []Component) {
+	if len(components) == 0 {
+		return
+	}
+	cg.imports.Add("context", "context")
+	cg.hasComponents = true
+
+	var funcs []string
+	for _, c := range components {
+		if c.MayNil {
+			funcs = append(funcs, fmt.Sprintf("func(ctx context.Context) error {\n\t\tif %s == nil {\n\t\t\treturn nil\n\t\t}\n\t\treturn %s.%s(ctx)\n\t}",
+				c.VarName, c.VarName, c.Method))
+			continue
+		}
+		funcs = append(funcs, c.VarName+"."+c.Method)
+	}
+	fmt.Fprintf(buf, "\trunWithComponents(%s, []func(context.Context) error{\n\t\t%s,\n\t})\n", treeVar, strings.Join(funcs, ",\n\t\t"))
+}
+
+// writeComponentsHelper emits runWithComponents, the generated replacement for
+// fx.Lifecycle OnStart hooks.
+func (cg *CodeGen) writeComponentsHelper(buf *bytes.Buffer, cobraQualifier string) {
+	cg.imports.Add("context", "context")
+	cg.imports.Add("sync", "sync")
+	buf.WriteString("// runWithComponents wraps every RunE under root so far: the components'\n")
+	buf.WriteString("// Start/Run methods run in goroutines alongside the handler on a shared\n")
+	buf.WriteString("// context. The first error, from a component or the handler, cancels that\n")
+	buf.WriteString("// context, and RunE returns it once every component has stopped.\n")
+	fmt.Fprintf(buf, "func runWithComponents(root *%s.Command, components []func(context.Context) error) {\n", cobraQualifier)
+	fmt.Fprintf(buf, "\tvar wrap func(c *%s.Command)\n", cobraQualifier)
+	fmt.Fprintf(buf, "\twrap = func(c *%s.Command) {\n", cobraQualifier)
+	buf.WriteString("\t\tfor _, sub := range c.Commands() {\n")
+	buf.WriteString("\t\t\twrap(sub)\n")
+	buf.WriteString("\t\t}\n")
+	buf.WriteString("\t\tif c.RunE == nil {\n")
+	buf.WriteString("\t\t\treturn\n")
+	buf.WriteString("\t\t}\n")
+	buf.WriteString("\t\trun := c.RunE\n")
+	fmt.Fprintf(buf, "\t\tc.RunE = func(cmd *%s.Command, args []string) error {\n", cobraQualifier)
+	buf.WriteString("\t\t\tctx, cancel := context.WithCancel(cmd.Context())\n")
+	buf.WriteString("\t\t\tdefer cancel()\n")
+	buf.WriteString("\t\t\terrc := make(chan error, len(components)+1)\n")
+	buf.WriteString("\t\t\tvar wg sync.WaitGroup\n")
+	buf.WriteString("\t\t\tfor _, start := range components {\n")
+	buf.WriteString("\t\t\t\twg.Add(1)\n")
+	buf.WriteString("\t\t\t\tgo func(start func(context.Context) error) {\n")
+	buf.WriteString("\t\t\t\t\tdefer wg.Done()\n")
+	buf.WriteString("\t\t\t\t\tif err := start(ctx); err != nil && ctx.Err() == nil {\n")
+	buf.WriteString("\t\t\t\t\t\terrc <- err\n")
+	buf.WriteString("\t\t\t\t\t\tcancel()\n")
+	buf.WriteString("\t\t\t\t\t}\n")
+	buf.WriteString("\t\t\t\t}(start)\n")
+	buf.WriteString("\t\t\t}\n")
+	buf.WriteString("\t\t\tcmd.SetContext(ctx)\n")
+	buf.WriteString("\t\t\tif err := run(cmd, args); err != nil {\n")
+	buf.WriteString("\t\t\t\terrc <- err\n")
+	buf.WriteString("\t\t\t}\n")
+	buf.WriteString("\t\t\tcancel()\n")
+	buf.WriteString("\t\t\twg.Wait()\n")
+	buf.WriteString("\t\t\tclose(errc)\n")
+	buf.WriteString("\t\t\treturn <-errc\n")
+	buf.WriteString("\t\t}\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\twrap(root)\n")
+	buf.WriteString("}\n")
+}
@@ -0,0 +1,129 @@
+package autodi
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// loadMode is the go/packages mode shared by provider scanning and command detection.
+const loadMode = packages.NeedName | packages.NeedTypes | packages.NeedTypesInfo |
+	packages.NeedSyntax | packages.NeedFiles | packages.NeedImports
+
+// PackageSet is the result of a single packages.Load over every scan root,
+// imported provider bundle and cmd/ package.
+//
+// All roots are loaded in one call rather than one call per root: go/packages
+// already type-checks independent packages concurrently, shared dependencies
+// are checked once instead of once per load, and providers and commands end up
+// in one type universe so types.Identical/Implements hold across them.
+type PackageSet struct {
+	Fset *token.FileSet
+	Pkgs []*packages.Package // root packages sorted by import path
+}
+
+// LoadPackages loads the given patterns with full type info.
+func LoadPackages(moduleRoot string, patterns []string) (*PackageSet, error) {
+	fset := token.NewFileSet()
+	cfg := &packages.Config{
+		Mode: loadMode,
+		Dir:  moduleRoot,
+		Fset: fset,
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("load packages: %w", err)
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].PkgPath < pkgs[j].PkgPath
+	})
+	for _, pkg := range pkgs {
+		dropGeneratedFiles(fset, pkg)
+	}
+	return &PackageSet{Fset: fset, Pkgs: pkgs}, nil
+}
+
+// dropGeneratedFiles removes the files of a previous autodi run from a
+// package's syntax, and the errors reported in them, so autodi's own output
+// never feeds back into discovery: an //autodi:output file inside a scanned
+// directory, or a stale main_gen.go that no longer compiles.
+func dropGeneratedFiles(fset *token.FileSet, pkg *packages.Package) {
+	var generated []string
+	pkg.Syntax = slices.DeleteFunc(pkg.Syntax, func(f *ast.File) bool {
+		if !isGeneratedFile(f) {
+			return false
+		}
+		generated = append(generated, fset.Position(f.Package).Filename)
+		return true
+	})
+	if len(generated) == 0 {
+		return
+	}
+	pkg.Errors = slices.DeleteFunc(pkg.Errors, func(e packages.Error) bool {
+		return slices.ContainsFunc(generated, func(name string) bool {
+			return strings.HasPrefix(e.Pos, name+":")
+		})
+	})
+}
+
+// isGeneratedFile reports whether a file carries autodi's generation header
+// above its package clause.
+func isGeneratedFile(f *ast.File) bool {
+	for _, group := range f.Comments {
+		if group.Pos() > f.Package {
+			break
+		}
+		for _, c := range group.List {
+			if strings.HasPrefix(c.Text, "// "+generatedMarker) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Match returns the loaded packages matched by any of the patterns.
+// Patterns are import paths, optionally ending in "/...".
+func (ps *PackageSet) Match(patterns ...string) []*packages.Package {
+	var matched []*packages.Package
+	for _, pkg := range ps.Pkgs {
+		for _, pattern := range patterns {
+			if matchPattern(pattern, pkg.PkgPath) {
+				matched = append(matched, pkg)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// matchPattern reports whether pkgPath matches a package pattern ("a/b" or "a/b/...").
+func matchPattern(pattern, pkgPath string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/..."); ok {
+		return pkgPath == prefix || strings.HasPrefix(pkgPath, prefix+"/")
+	}
+	return pkgPath == pattern
+}
+
+// forEachPackage runs fn for every package on a bounded pool of GOMAXPROCS
+// workers. fn receives the package index so callers can collect results in
+// load order and keep output deterministic.
+func forEachPackage(pkgs []*packages.Package, fn func(i int, pkg *packages.Package)) {
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for i, pkg := range pkgs {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			fn(i, pkg)
+		})
+	}
+	wg.Wait()
+}
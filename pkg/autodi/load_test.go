@@ -0,0 +1,85 @@
+package autodi
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestGenerateIdempotent generates an example project twice, the first output
+// landing in a package the second run scans, and expects the loader to skip
+// that file and the second run to write the same files.
+func TestGenerateIdempotent(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("needs the go tool")
+	}
+	dir := t.TempDir()
+	if err := writeExample("cli", dir, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := goTool(dir, "mod", "tidy"); err != nil {
+		t.Skipf("needs module downloads: %v", err)
+	}
+	genPath := filepath.Join(dir, "generate.go")
+	gen, err := os.ReadFile(genPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen = append(gen, "\n//autodi:output internal/app/main_gen.go app\n"...)
+	if err := os.WriteFile(genPath, gen, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	generate := func() (*ScanResult, []GeneratedFile) {
+		t.Helper()
+		scan, err := Scan(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proj, err := Build(scan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files, err := Generate(proj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return scan, files
+	}
+
+	_, first := generate()
+	for _, f := range first {
+		path := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, f.Content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "internal", "app", "main_gen.go")); err != nil {
+		t.Fatalf("first run did not write into internal/app: %v", err)
+	}
+
+	scan, second := generate()
+	pkgs := scan.Set.Match("example.com/cli/internal/app")
+	if len(pkgs) == 0 {
+		t.Fatal("second run did not load internal/app")
+	}
+	if len(pkgs[0].Syntax) > 0 {
+		t.Errorf("second run scanned %s", scan.Set.Fset.Position(pkgs[0].Syntax[0].Package).Filename)
+	}
+	if len(second) != len(first) {
+		t.Fatalf("second run generated %d files, first %d", len(second), len(first))
+	}
+	for i, f := range second {
+		if f.Name != first[i].Name {
+			t.Fatalf("file %d: second run generated %s, first %s", i, f.Name, first[i].Name)
+		}
+		if !bytes.Equal(f.Content, first[i].Content) {
+			t.Errorf("%s differs between runs:\n%s", f.Name, unifiedDiff("a/"+f.Name, "b/"+f.Name, string(first[i].Content), string(f.Content)))
+		}
+	}
+}
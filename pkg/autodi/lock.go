@@ -0,0 +1,267 @@
+package autodi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// LockFile is the snapshot of the resolved graph written next to go.mod, or
+// a directory-level app's generate.go, on every generation. Committed, it
+// shows wiring changes in review; --diff-lock summarizes them against the
+// current tree.
+const LockFile = ".autodi.lock"
+
+// GraphLock is the content of LockFile.
+type GraphLock struct {
+	Module    string         `json:"module"`
+	Providers []LockProvider `json:"providers"`
+	Bindings  []LockBinding  `json:"bindings,omitempty"`
+	Commands  []LockCommand  `json:"commands,omitempty"`
+}
+
+// LockProvider is a wired constructor with the types it provides and takes.
+type LockProvider struct {
+	Func     string   `json:"func"` // module-relative package path and name
+	Provides []string `json:"provides"`
+	Deps     []string `json:"deps,omitempty"`
+	Phase    string   `json:"phase,omitempty"` // infra when constructed in the //autodi:phase infra phase
+
+	X map[string]string `json:"x,omitempty"` // //autodi:x-<key> annotations
+}
+
+// LockBinding records the implementation chosen for an interface.
+type LockBinding struct {
+	Interface string `json:"interface"`
+	Concrete  string `json:"concrete"`
+	Source    string `json:"source,omitempty"`
+}
+
+// LockCommand lists the constructors a top-level command calls, in order.
+type LockCommand struct {
+	Name  string   `json:"name"`
+	Order []string `json:"order"`
+}
+
+// BuildGraphLock snapshots the analyzed project.
+func BuildGraphLock(proj *Project) (*GraphLock, error) {
+	g := proj.Graph
+	lock := &GraphLock{Module: proj.Cfg.Module}
+
+	var infra map[*Provider]bool
+	if g.phased() {
+		all, err := g.ProvidersForTypes(g.sortedTypes)
+		if err != nil {
+			return nil, err
+		}
+		infra = g.infraProviders(all, nil)
+	}
+	for _, p := range g.Providers {
+		lp := LockProvider{Func: lockRef(proj.Cfg, p), X: p.CustomAnnotations()}
+		if infra[p] {
+			lp.Phase = PhaseInfra
+		}
+		for _, ret := range p.Returns {
+			lp.Provides = append(lp.Provides, ret.TypeStr)
+		}
+		lp.Provides = append(lp.Provides, p.allAs()...)
+		for _, param := range p.Params {
+			if param.Stream == "" {
+				lp.Deps = append(lp.Deps, param.TypeStr)
+			}
+		}
+		lock.Providers = append(lock.Providers, lp)
+	}
+	sort.Slice(lock.Providers, func(i, j int) bool { return lock.Providers[i].Func < lock.Providers[j].Func })
+
+	for iface, concrete := range g.Bindings {
+		lock.Bindings = append(lock.Bindings, LockBinding{Interface: iface, Concrete: concrete, Source: g.BindSource[iface]})
+	}
+	sort.Slice(lock.Bindings, func(i, j int) bool { return lock.Bindings[i].Interface < lock.Bindings[j].Interface })
+
+	for _, cmd := range proj.Commands {
+		if cmd.Parent != nil || !cmd.HasDeps() {
+			continue
+		}
+		providers, err := g.CommandProviders(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("command %s: %w", cmd.Name, err)
+		}
+		lc := LockCommand{Name: cmd.Name, Order: []string{}}
+		for _, p := range providers {
+			lc.Order = append(lc.Order, lockRef(proj.Cfg, p))
+		}
+		lock.Commands = append(lock.Commands, lc)
+	}
+	sort.Slice(lock.Commands, func(i, j int) bool { return lock.Commands[i].Name < lock.Commands[j].Name })
+	return lock, nil
+}
+
+// lockRef names a provider in the lock: "internal/db.NewStore",
+// "internal/db.(*Factory).Store", or the bare name of a generated function.
+func lockRef(cfg *Config, p *Provider) string {
+	name := p.FuncName
+	if p.Recv != "" {
+		name = "(" + toShortTypeName(p.Recv) + ")." + name
+	}
+	if p.PkgPath == "" {
+		return name
+	}
+	return cfg.RelPath(p.PkgPath) + "." + name
+}
+
+// Marshal renders the lock as indented JSON.
+func (l *GraphLock) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// parseGraphLock reads a lock file; empty data is an empty lock.
+func parseGraphLock(data []byte) (*GraphLock, error) {
+	var l GraphLock
+	if len(data) == 0 {
+		return &l, nil
+	}
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", LockFile, err)
+	}
+	return &l, nil
+}
+
+// writeLockDiff prints the providers, dependencies, bindings and command
+// orders that differ between two locks and returns the number of changes.
+func writeLockDiff(w io.Writer, old, cur *GraphLock) int {
+	n := 0
+	line := func(format string, args ...any) {
+		fmt.Fprintf(w, format+"\n", args...)
+		n++
+	}
+
+	oldProviders := make(map[string]LockProvider, len(old.Providers))
+	for _, p := range old.Providers {
+		oldProviders[p.Func] = p
+	}
+	curProviders := make(map[string]bool, len(cur.Providers))
+	for _, p := range cur.Providers {
+		curProviders[p.Func] = true
+		prev, ok := oldProviders[p.Func]
+		if !ok {
+			line("+ provider %s → %s", p.Func, shortTypeList(p.Provides))
+			continue
+		}
+		for _, dep := range p.Deps {
+			if !slices.Contains(prev.Deps, dep) {
+				line("~ %s: + dep %s", p.Func, toShortTypeName(dep))
+			}
+		}
+		for _, dep := range prev.Deps {
+			if !slices.Contains(p.Deps, dep) {
+				line("~ %s: - dep %s", p.Func, toShortTypeName(dep))
+			}
+		}
+		if !slices.Equal(prev.Provides, p.Provides) {
+			line("~ %s: provides %s (was %s)", p.Func, shortTypeList(p.Provides), shortTypeList(prev.Provides))
+		}
+	}
+	for _, p := range old.Providers {
+		if !curProviders[p.Func] {
+			line("- provider %s", p.Func)
+		}
+	}
+
+	oldBindings := make(map[string]LockBinding, len(old.Bindings))
+	for _, b := range old.Bindings {
+		oldBindings[b.Interface] = b
+	}
+	curBindings := make(map[string]bool, len(cur.Bindings))
+	for _, b := range cur.Bindings {
+		curBindings[b.Interface] = true
+		prev, ok := oldBindings[b.Interface]
+		switch {
+		case !ok:
+			line("+ binding %s → %s", toShortTypeName(b.Interface), toShortTypeName(b.Concrete))
+		case prev.Concrete != b.Concrete:
+			line("~ binding %s → %s (was %s)", toShortTypeName(b.Interface), toShortTypeName(b.Concrete), toShortTypeName(prev.Concrete))
+		}
+	}
+	for _, b := range old.Bindings {
+		if !curBindings[b.Interface] {
+			line("- binding %s → %s", toShortTypeName(b.Interface), toShortTypeName(b.Concrete))
+		}
+	}
+
+	oldCommands := make(map[string]LockCommand, len(old.Commands))
+	for _, c := range old.Commands {
+		oldCommands[c.Name] = c
+	}
+	curCommands := make(map[string]bool, len(cur.Commands))
+	for _, c := range cur.Commands {
+		curCommands[c.Name] = true
+		prev, ok := oldCommands[c.Name]
+		switch {
+		case !ok:
+			line("+ command %s (%d providers)", c.Name, len(c.Order))
+		case !slices.Equal(prev.Order, c.Order):
+			line("~ command %s: construction order changed (%d → %d providers)", c.Name, len(prev.Order), len(c.Order))
+		}
+	}
+	for _, c := range old.Commands {
+		if !curCommands[c.Name] {
+			line("- command %s", c.Name)
+		}
+	}
+	return n
+}
+
+// shortTypeList joins type strings in their short form.
+func shortTypeList(typeStrs []string) string {
+	short := make([]string, len(typeStrs))
+	for i, t := range typeStrs {
+		short[i] = toShortTypeName(t)
+	}
+	return strings.Join(short, ", ")
+}
+
+// diffLock compares the lock among the generated files with the one on disk.
+func diffLock(w io.Writer, moduleRoot string, files []GeneratedFile) error {
+	var cur *GraphLock
+	var name string
+	for _, f := range files {
+		if filepath.Base(f.Name) == LockFile {
+			l, err := parseGraphLock(f.Content)
+			if err != nil {
+				return err
+			}
+			cur, name = l, f.Name
+		}
+	}
+	if cur == nil {
+		return fmt.Errorf("%s: not generated", LockFile)
+	}
+
+	data, err := os.ReadFile(filepath.Join(moduleRoot, name))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if data == nil {
+		fmt.Fprintf(w, "no %s yet; the whole graph is new\n", LockFile)
+	}
+	old, err := parseGraphLock(data)
+	if err != nil {
+		return err
+	}
+	if writeLockDiff(w, old, cur) == 0 {
+		fmt.Fprintf(w, "no wiring changes since %s\n", LockFile)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// Default loggers: a *slog.Logger or *zap.Logger parameter that no
+// constructor provides is filled by a logger built in the generated file, so
+// projects don't need a trivial logger constructor to satisfy the graph.
+//
+//	//autodi:log-level debug   (generate.go; debug, info, warn or error)
+//
+// slog writes text to stderr; zap uses its production config (JSON to
+// stderr). The level defaults to info.
+const (
+	slogLoggerType = "*log/slog.Logger"
+	zapLoggerType  = "*go.uber.org/zap.Logger"
+)
+
+// logLevels lists the accepted //autodi:log-level values.
+var logLevels = []string{"debug", "info", "warn", "error"}
+
+// defaultLoggers returns a generated provider for each logger type some
+// constructor or command takes and no candidate provides.
+func defaultLoggers(candidates []*Provider, commands []*DiscoveredCommand) []*Provider {
+	provided := make(map[string]bool)
+	for _, p := range candidates {
+		for _, ret := range p.Returns {
+			provided[ret.TypeStr] = true
+		}
+	}
+
+	wanted := make(map[string]TypeRef)
+	want := func(params []TypeRef) {
+		for _, param := range params {
+			if param.Stream != "" || provided[param.TypeStr] {
+				continue
+			}
+			if param.TypeStr == slogLoggerType || param.TypeStr == zapLoggerType {
+				wanted[param.TypeStr] = param
+			}
+		}
+	}
+	for _, p := range candidates {
+		want(p.Params)
+	}
+	for _, cmd := range commands {
+		want(cmd.Params)
+	}
+
+	var providers []*Provider
+	for typeStr, param := range wanted {
+		kind := "slog"
+		if typeStr == zapLoggerType {
+			kind = "zap"
+		}
+		providers = append(providers, &Provider{
+			FuncName: "new" + strings.ToUpper(kind[:1]) + kind[1:] + "Logger",
+			PkgName:  kind,
+			Returns:  []TypeRef{{Type: param.Type, TypeStr: typeStr, PkgPath: param.PkgPath}},
+			HasError: kind == "zap",
+			Logger:   kind,
+			Position: token.Position{Filename: "generate.go"},
+		})
+	}
+	sort.Slice(providers, func(i, j int) bool { return providers[i].FuncName < providers[j].FuncName })
+	return providers
+}
+
+// writeDefaultLoggers emits the constructors of the default loggers called in
+// the current file.
+func (cg *CodeGen) writeDefaultLoggers(buf *bytes.Buffer) {
+	var loggers []*Provider
+	for p := range cg.loggers {
+		loggers = append(loggers, p)
+	}
+	sort.Slice(loggers, func(i, j int) bool { return loggers[i].FuncName < loggers[j].FuncName })
+
+	level := cg.cfg.LogLevel
+	if level == "" {
+		level = "info"
+	}
+	levelName := strings.ToUpper(level[:1]) + level[1:]
+
+	for i, p := range loggers {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		switch p.Logger {
+		case "slog":
+			slogQualifier := cg.imports.Add("log/slog", "slog")
+			osQualifier := cg.imports.Add("os", "os")
+			fmt.Fprintf(buf, "// %s is the *slog.Logger used when no constructor provides one.\n", p.FuncName)
+			fmt.Fprintf(buf, "func %s() *%s.Logger {\n", p.FuncName, slogQualifier)
+			fmt.Fprintf(buf, "\treturn %s.New(%s.NewTextHandler(%s.Stderr, &%s.HandlerOptions{Level: %s.Level%s}))\n",
+				slogQualifier, slogQualifier, osQualifier, slogQualifier, slogQualifier, levelName)
+			buf.WriteString("}\n")
+		case "zap":
+			zapQualifier := cg.imports.Add("go.uber.org/zap", "zap")
+			fmt.Fprintf(buf, "// %s is the *zap.Logger used when no constructor provides one.\n", p.FuncName)
+			fmt.Fprintf(buf, "func %s() (*%s.Logger, error) {\n", p.FuncName, zapQualifier)
+			fmt.Fprintf(buf, "\tcfg := %s.NewProductionConfig()\n", zapQualifier)
+			fmt.Fprintf(buf, "\tcfg.Level = %s.NewAtomicLevelAt(%s.%sLevel)\n", zapQualifier, zapQualifier, levelName)
+			buf.WriteString("\treturn cfg.Build()\n")
+			buf.WriteString("}\n")
+		}
+	}
+}
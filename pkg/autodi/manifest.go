@@ -0,0 +1,193 @@
+package autodi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ManifestFile is the provider manifest a module publishes with `autodi export`
+// and consumers load with //autodi:import.
+const ManifestFile = "autodi.manifest.json"
+
+// ProviderManifest lists the constructors a module exports as a reusable
+// provider bundle (logging, tracing, DB, …).
+type ProviderManifest struct {
+	Module    string             `json:"module"`
+	Providers []ManifestProvider `json:"providers"`
+}
+
+// ManifestProvider identifies one exported constructor.
+type ManifestProvider struct {
+	Package string `json:"package"` // full import path
+	Func    string `json:"func"`    // constructor name, e.g. "NewLogger"
+}
+
+// runExport scans the module and writes its provider manifest, publishing every
+// local provider candidate for consumers that //autodi:import this module.
+func runExport(opts *Options) error {
+	moduleRoot, err := findModuleRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := BuildConfig(moduleRoot)
+	if err != nil {
+		return err
+	}
+	cfg.Profile = opts.Profile
+
+	scanner := NewScanner(cfg, moduleRoot, LoadGitignore(moduleRoot))
+	patterns, err := scanner.Patterns()
+	if err != nil {
+		return err
+	}
+	set, err := LoadPackages(moduleRoot, patterns)
+	if err != nil {
+		return err
+	}
+	candidates, err := scanner.Scan(set)
+	if err != nil {
+		return fmt.Errorf("scan: %w", err)
+	}
+
+	var local []*Provider
+	for _, p := range candidates {
+		if cfg.ownsPackage(p.PkgPath) && p.Recv == "" {
+			local = append(local, p)
+		}
+	}
+	data, err := BuildManifest(cfg.Module, local).Marshal()
+	if err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		os.Stdout.Write(data)
+		return nil
+	}
+	path := filepath.Join(moduleRoot, ManifestFile)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	fmt.Fprintf(os.Stderr, "autodi: exported %d providers to %s\n", len(local), path)
+	return nil
+}
+
+// BuildManifest builds the manifest for the given provider candidates.
+func BuildManifest(module string, providers []*Provider) *ProviderManifest {
+	m := &ProviderManifest{Module: module}
+	for _, p := range providers {
+		m.Providers = append(m.Providers, ManifestProvider{Package: p.PkgPath, Func: p.FuncName})
+	}
+	sort.Slice(m.Providers, func(i, j int) bool {
+		if m.Providers[i].Package != m.Providers[j].Package {
+			return m.Providers[i].Package < m.Providers[j].Package
+		}
+		return m.Providers[i].Func < m.Providers[j].Func
+	})
+	return m
+}
+
+// Marshal renders the manifest as indented JSON.
+func (m *ProviderManifest) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// loadImportManifests reads the manifest of every //autodi:import module.
+// Modules are resolved with `go list -m`, so they must be required in go.mod;
+// modules replaced by a local directory are read from that directory.
+func loadImportManifests(moduleRoot string, imports []string, localMods map[string]string) ([]*ProviderManifest, error) {
+	var manifests []*ProviderManifest
+	for _, mod := range imports {
+		dir, err := moduleDir(moduleRoot, mod, localMods)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+		if err != nil {
+			return nil, fmt.Errorf("import %s: %w\n  hint: run `autodi export` in %s and publish %s", mod, err, mod, ManifestFile)
+		}
+		var m ProviderManifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("import %s: parse %s: %w", mod, ManifestFile, err)
+		}
+		manifests = append(manifests, &m)
+	}
+	return manifests, nil
+}
+
+// moduleDir returns the on-disk directory of a dependency module: the
+// replacement directory when go.mod replaces it locally, otherwise go list -m.
+func moduleDir(moduleRoot, mod string, localMods map[string]string) (string, error) {
+	if dir, ok := localMods[mod]; ok {
+		return dir, nil
+	}
+	cmd := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", mod)
+	cmd.Dir = moduleRoot
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("import %s: go list: %v: %s\n  hint: go get %s", mod, err, strings.TrimSpace(stderr.String()), mod)
+	}
+	dir := strings.TrimSpace(string(out))
+	if dir == "" {
+		return "", fmt.Errorf("import %s: module not downloaded\n  hint: go mod download %s", mod, mod)
+	}
+	return dir, nil
+}
+
+// manifestFuncs indexes manifests by package path → exported constructor names.
+func manifestFuncs(manifests []*ProviderManifest) map[string]map[string]bool {
+	index := make(map[string]map[string]bool)
+	for _, m := range manifests {
+		for _, mp := range m.Providers {
+			if index[mp.Package] == nil {
+				index[mp.Package] = make(map[string]bool)
+			}
+			index[mp.Package][mp.Func] = true
+		}
+	}
+	return index
+}
+
+// splitFuncRef splits "<import path>.<Func>" at the dot after the last slash,
+// so dots in the module's domain or version don't count.
+func splitFuncRef(ref string) (pkgPath, name string, ok bool) {
+	i := strings.LastIndex(ref, ".")
+	if i <= strings.LastIndex(ref, "/") || !isExported(ref[i+1:]) {
+		return "", "", false
+	}
+	return ref[:i], ref[i+1:], true
+}
+
+// extractManifestProviders builds providers for the constructors a manifest
+// exports from an imported package. The one-New-per-package heuristics don't
+// apply: the publishing module already selected them.
+func (s *Scanner) extractManifestProviders(pkg *packages.Package, funcs map[string]bool) []*Provider {
+	var providers []*Provider
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || !funcs[fn.Name.Name] {
+				continue
+			}
+			if p := s.buildProvider(pkg, fn, ParseAnnotations(fn)); p != nil {
+				providers = append(providers, p)
+			}
+		}
+	}
+	return providers
+}
@@ -0,0 +1,151 @@
+package autodi
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic codes identify a graph error independently of the language it is
+// reported in. They are stable: SARIF rules and CI filters refer to them.
+const (
+	CodeGeneral           = "ADI000" // any other analysis error
+	CodeDuplicateProvider = "ADI001"
+	CodeCycle             = "ADI002"
+	CodeMissingDep        = "ADI003"
+	CodeMissingBasicDep   = "ADI004"
+	CodeMultiplePrimary   = "ADI005"
+	CodeMisplacedError    = "ADI006"
+	CodeBadBinding        = "ADI007"
+)
+
+// codeTitles describes each code for SARIF rule metadata.
+var codeTitles = map[string]string{
+	CodeGeneral:           "Analysis error",
+	CodeDuplicateProvider: "Type has multiple providers",
+	CodeCycle:             "Dependency cycle",
+	CodeMissingDep:        "Missing dependency",
+	CodeMissingBasicDep:   "Missing dependency of a plain basic type",
+	CodeMultiplePrimary:   "Interface has multiple //autodi:primary providers",
+	CodeMisplacedError:    "Constructor returns error before its last result",
+	CodeBadBinding:        "//autodi:bind names an interface the provided type doesn't implement",
+}
+
+// Languages selectable with --lang.
+const (
+	LangEnglish = "en"
+	LangChinese = "zh"
+)
+
+// messages holds the format string of every diagnostic code per language.
+// English is the fallback for codes missing from another catalog.
+var messages = map[string]map[string]string{
+	LangEnglish: {
+		CodeDuplicateProvider: "type %s has multiple providers:\n  1. %s\n  2. %s\n  hint: mark one with //autodi:ignore, or the one to keep with //autodi:override",
+		CodeCycle:             "cycle dependency detected:\n  %s\nproviders involved:\n%s",
+		CodeMissingDep:        "entry %q: %s.%s missing dependency %s",
+		CodeMissingBasicDep:   "entry %q: %s.%s missing dependency %s\n  hint: declare a named type (type DSN %[4]s) and provide that; plain %[4]s can't tell values apart",
+		CodeMultiplePrimary:   "interface %s has multiple //autodi:primary providers:\n%s\n  hint: keep //autodi:primary on exactly one",
+		CodeMisplacedError:    "%s: %s.%s returns error as result %d of %d\n  hint: make error the last result: func %[3]s(...) (..., error)",
+		CodeBadBinding:        "%s: //autodi:bind %s: %s does not implement it:\n%s\n  hint: add the methods or bind the interface on another constructor",
+	},
+	LangChinese: {
+		CodeDuplicateProvider: "类型 %s 有多个提供者:\n  1. %s\n  2. %s\n  提示: 用 //autodi:ignore 标记其中一个, 或用 //autodi:override 标记要保留的那个",
+		CodeCycle:             "检测到循环依赖:\n  %s\n涉及的提供者:\n%s",
+		CodeMissingDep:        "入口 %q: %s.%s 缺少依赖 %s",
+		CodeMissingBasicDep:   "入口 %q: %s.%s 缺少依赖 %s\n  提示: 声明命名类型 (type DSN %[4]s) 并提供它; 单纯的 %[4]s 无法区分不同的值",
+		CodeMultiplePrimary:   "接口 %s 有多个 //autodi:primary 提供者:\n%s\n  提示: 只在其中一个上保留 //autodi:primary",
+		CodeMisplacedError:    "%s: %s.%s 的第 %d 个返回值 (共 %d 个) 是 error\n  提示: 把 error 放在最后: func %[3]s(...) (..., error)",
+		CodeBadBinding:        "%s: //autodi:bind %s: %s 没有实现该接口:\n%s\n  提示: 补上这些方法, 或在其他构造函数上绑定该接口",
+	},
+}
+
+// lang is the language diagnostics are reported in, set by --lang.
+var lang = defaultLang()
+
+// defaultLang picks the diagnostics language from the locale environment
+// (LC_ALL, LC_MESSAGES, LANG), falling back to English.
+func defaultLang() string {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		v := os.Getenv(key)
+		if v == "" {
+			continue
+		}
+		if strings.HasPrefix(v, LangChinese) {
+			return LangChinese
+		}
+		return LangEnglish
+	}
+	return LangEnglish
+}
+
+// setLang validates and selects a --lang value; "" keeps the locale default.
+func setLang(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, ok := messages[name]; !ok {
+		return fmt.Errorf("unknown --lang %q\n  hint: use %s or %s", name, LangEnglish, LangChinese)
+	}
+	lang = name
+	return nil
+}
+
+// Diagnostic is a graph error with a stable code; its message is rendered in
+// the selected language. Pos is the offending constructor, if known.
+type Diagnostic struct {
+	Code    string
+	Message string
+	Pos     token.Position
+}
+
+func (d *Diagnostic) Error() string {
+	return d.Message
+}
+
+// diagf formats the message of a diagnostic code in the selected language.
+func diagf(code string, pos token.Position, args ...any) *Diagnostic {
+	format, ok := messages[lang][code]
+	if !ok {
+		format = messages[LangEnglish][code]
+	}
+	return &Diagnostic{Code: code, Message: fmt.Sprintf(format, args...), Pos: pos}
+}
+
+// reported collects the diagnostics printed during this run for --sarif.
+var reported []*Diagnostic
+
+// positionPrefix matches the "file.go:line:col: " position of uncoded errors,
+// which may follow a "scan: " style context prefix.
+var positionPrefix = regexp.MustCompile(`(\S+\.go):(\d+):(\d+): `)
+
+// reportErrors prints analysis errors to stderr, prefixed with their code, and
+// records them for --sarif.
+func reportErrors(errs []error) {
+	for _, e := range errs {
+		var d *Diagnostic
+		if !errors.As(e, &d) {
+			d = uncodedDiagnostic(e)
+			fmt.Fprintf(os.Stderr, "autodi: %v\n", e)
+		} else {
+			fmt.Fprintf(os.Stderr, "autodi: %s: %v\n", d.Code, e)
+		}
+		reported = append(reported, d)
+	}
+}
+
+// uncodedDiagnostic wraps an error without a code as ADI000, positioned by its
+// "file:line:col:" prefix when it has one.
+func uncodedDiagnostic(err error) *Diagnostic {
+	d := &Diagnostic{Code: CodeGeneral, Message: err.Error()}
+	if m := positionPrefix.FindStringSubmatch(d.Message); m != nil {
+		line, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		d.Pos = token.Position{Filename: m[1], Line: line, Column: col}
+	}
+	return d
+}
@@ -0,0 +1,84 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Wiring metrics: with //autodi:metrics otel|prometheus in generate.go, the
+// generated init functions time each constructor and each cleanup call, and
+// record them under the Container field of the provider — as spans
+// "autodi.construct <Field>" and "autodi.shutdown <Field>", or as the gauge
+// autodi_provider_seconds{command,field,phase} — so startup and shutdown
+// regressions show up in production traces and dashboards.
+const (
+	MetricsOTel       = "otel"
+	MetricsPrometheus = "prometheus"
+)
+
+// Metric phases, the span name suffix or the phase label.
+const (
+	metricsConstruct = "construct"
+	metricsShutdown  = "shutdown"
+)
+
+// beginMetrics declares the wiringMetrics of an init function constructing
+// providers.
+func (cg *CodeGen) beginMetrics(buf *bytes.Buffer, cmd *DiscoveredCommand, providers []*Provider, usedVars map[string]bool) {
+	if cg.cfg.Metrics == "" || len(providers) == 0 {
+		return
+	}
+	cg.metricsVar = cg.uniqueLocalVar("wiring", usedVars)
+	cg.hasMetrics = true
+	fmt.Fprintf(buf, "\t%s := newWiringMetrics(%q)\n", cg.metricsVar, cmd.Name)
+}
+
+// metricsField names a provider in metrics: the Container field of its first
+// result, or the constructor for providers without results.
+func (g *Graph) metricsField(p *Provider) string {
+	if len(p.Returns) > 0 {
+		return g.fieldName(p.Returns[0].TypeStr)
+	}
+	return p.PkgName + "." + p.FuncName
+}
+
+// writeMetricsHelper emits the wiringMetrics type the init functions use.
+func (cg *CodeGen) writeMetricsHelper(buf *bytes.Buffer) {
+	cg.imports.Add("time", "time")
+	fmt.Fprintf(buf, "// wiringMetrics times the construction and shutdown of a command's providers (//autodi:metrics %s).\n", cg.cfg.Metrics)
+	buf.WriteString("type wiringMetrics struct {\n")
+	buf.WriteString("\tcommand string\n")
+	buf.WriteString("\tlast    time.Time\n")
+	buf.WriteString("}\n\n")
+	buf.WriteString("func newWiringMetrics(command string) *wiringMetrics {\n")
+	buf.WriteString("\treturn &wiringMetrics{command: command}\n")
+	buf.WriteString("}\n\n")
+	buf.WriteString("func (m *wiringMetrics) start() { m.last = time.Now() }\n\n")
+	buf.WriteString("func (m *wiringMetrics) constructed(field string) { m.record(field, \"" + metricsConstruct + "\") }\n\n")
+	buf.WriteString("func (m *wiringMetrics) shutdown(field string) { m.record(field, \"" + metricsShutdown + "\") }\n\n")
+
+	if cg.cfg.Metrics == MetricsPrometheus {
+		prometheus := cg.imports.Add("github.com/prometheus/client_golang/prometheus", "prometheus")
+		promauto := cg.imports.Add("github.com/prometheus/client_golang/prometheus/promauto", "promauto")
+		fmt.Fprintf(buf, "var wiringSeconds = %s.NewGaugeVec(%s.GaugeOpts{\n", promauto, prometheus)
+		buf.WriteString("\tName: \"autodi_provider_seconds\",\n")
+		buf.WriteString("\tHelp: \"Time taken to construct or shut down a provider, by Container field.\",\n")
+		buf.WriteString("}, []string{\"command\", \"field\", \"phase\"})\n\n")
+		buf.WriteString("// record sets the gauge of a provider to the time since start.\n")
+		buf.WriteString("func (m *wiringMetrics) record(field, phase string) {\n")
+		buf.WriteString("\twiringSeconds.WithLabelValues(m.command, field, phase).Set(time.Since(m.last).Seconds())\n")
+		buf.WriteString("}\n")
+		return
+	}
+
+	context := cg.imports.Add("context", "context")
+	otel := cg.imports.Add("go.opentelemetry.io/otel", "otel")
+	attribute := cg.imports.Add("go.opentelemetry.io/otel/attribute", "attribute")
+	trace := cg.imports.Add("go.opentelemetry.io/otel/trace", "trace")
+	buf.WriteString("// record records a span autodi.<phase> <field> from start until now.\n")
+	buf.WriteString("func (m *wiringMetrics) record(field, phase string) {\n")
+	fmt.Fprintf(buf, "\t_, span := %s.Tracer(\"autodi\").Start(%s.Background(), \"autodi.\"+phase+\" \"+field,\n", otel, context)
+	fmt.Fprintf(buf, "\t\t%s.WithTimestamp(m.last), %s.WithAttributes(%s.String(\"autodi.command\", m.command)))\n", trace, trace, attribute)
+	buf.WriteString("\tspan.End()\n")
+	buf.WriteString("}\n")
+}
@@ -0,0 +1,116 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+)
+
+// AnnotMigrate marks the constructor of a migrator, whose result has an
+// Up(ctx context.Context) error method:
+//
+//	//autodi:migrate
+//	func NewMigrator(db *sql.DB) *Migrator
+//
+// It belongs to the //autodi:phase infra phase, and every command with
+// dependencies constructs it and runs Up between the infra and app phases,
+// unless its constructor has //autodi:no-migrate. The library layout adds
+// InfraContainer.Migrate instead.
+const AnnotMigrate = "migrate"
+
+// AnnotNoMigrate on a command constructor skips the migrations.
+const AnnotNoMigrate = "no-migrate"
+
+// migrator returns the //autodi:migrate provider, nil without one.
+func (g *Graph) migrator() *Provider {
+	for _, p := range g.Providers {
+		if HasAnnotation(p.Annotations, AnnotMigrate) {
+			return p
+		}
+	}
+	return nil
+}
+
+// hasUpMethod reports whether t has an Up(ctx context.Context) error method.
+func hasUpMethod(t types.Type) bool {
+	mset := types.NewMethodSet(t)
+	for i := 0; i < mset.Len(); i++ {
+		if mset.At(i).Obj().Name() != "Up" {
+			continue
+		}
+		sig, ok := mset.At(i).Type().(*types.Signature)
+		return ok && sig.Params().Len() == 1 && isContextType(sig.Params().At(0).Type()) &&
+			sig.Results().Len() == 1 && isErrorType(sig.Results().At(0).Type())
+	}
+	return false
+}
+
+// checkMigrate validates //autodi:migrate: one migrator, in the infra phase,
+// whose result has Up(ctx).
+func (g *Graph) checkMigrate() []error {
+	var found []*Provider
+	for _, p := range g.Providers {
+		if HasAnnotation(p.Annotations, AnnotMigrate) {
+			found = append(found, p)
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+	var errs []error
+	if len(found) > 1 {
+		errs = append(errs, fmt.Errorf("%s: %s.%s and %s.%s both have //autodi:migrate\n  hint: keep one migrator and run the other migrations from its Up",
+			found[1].Position, found[0].PkgName, found[0].FuncName, found[1].PkgName, found[1].FuncName))
+	}
+	p := found[0]
+	switch {
+	case providerPhase(p) == PhaseApp:
+		errs = append(errs, fmt.Errorf("%s: %s.%s: //autodi:migrate runs before the app phase; it can't have //autodi:phase %s", p.Position, p.PkgName, p.FuncName, PhaseApp))
+	case p.Scope != "":
+		errs = append(errs, fmt.Errorf("%s: %s.%s: //autodi:migrate can't be combined with //autodi:scope %s", p.Position, p.PkgName, p.FuncName, p.Scope))
+	case p.IsInvoke || len(p.Returns) == 0 || p.Returns[0].Type == nil || !hasUpMethod(p.Returns[0].Type):
+		errs = append(errs, fmt.Errorf("%s: %s.%s has //autodi:migrate but returns no Up method\n  hint: Up(ctx context.Context) error on its first result", p.Position, p.PkgName, p.FuncName))
+	}
+	return errs
+}
+
+// commandMigrator returns the migrator a command runs, nil when there is
+// none or the command has //autodi:no-migrate.
+func (g *Graph) commandMigrator(cmd *DiscoveredCommand) *Provider {
+	if cmd.NoMigrate {
+		return nil
+	}
+	return g.migrator()
+}
+
+// writeMigrate emits the Up call of the migrator between the infra and app
+// phases of a command's init function, failing the command under the
+// //autodi:error-wrap policy.
+func (cg *CodeGen) writeMigrate(buf *bytes.Buffer, migrator *Provider, varMap map[string]string) {
+	ctx := "cmd.Context()"
+	if cg.cfg.CLI == CLIKong {
+		ctx = cg.imports.Add("context", "context") + ".Background()"
+	}
+	cg.addInitErrorImports()
+	buf.WriteString("\t// Migrations run before the app phase\n")
+	fmt.Fprintf(buf, "\tif err := %s.Up(%s); err != nil {\n", varMap[migrator.Returns[0].TypeStr], ctx)
+	if cg.cfg.ErrorLog {
+		fmt.Fprintf(buf, "\t\t%s.Error(\"migrate failed\", \"err\", err)\n", cg.imports.Add("log/slog", "slog"))
+	}
+	if cg.cfg.ErrorWrap == ErrorWrapNone {
+		buf.WriteString("\t\treturn nil, err\n")
+	} else {
+		buf.WriteString("\t\treturn nil, fmt.Errorf(\"migrate: %w\", err)\n")
+	}
+	buf.WriteString("\t}\n\n")
+}
+
+// writeInfraMigrate emits InfraContainer.Migrate for the library layout.
+func (cg *CodeGen) writeInfraMigrate(buf *bytes.Buffer, migrator *Provider) {
+	field := cg.graph.TypeToField[migrator.Returns[0].TypeStr]
+	buf.WriteString("\n// Migrate runs the //autodi:migrate migrator; call it between BuildInfra and\n")
+	buf.WriteString("// BuildApp.\n")
+	fmt.Fprintf(buf, "func (c *InfraContainer) Migrate(ctx %s.Context) error {\n", cg.imports.Add("context", "context"))
+	fmt.Fprintf(buf, "\treturn c.%s.Up(ctx)\n", field)
+	buf.WriteString("}\n")
+}
@@ -0,0 +1,158 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// generateBinaryMain generates cmd/<name>/main_gen.go for the multi-binary layout.
+// The command package must itself be package main: its constructor is called
+// unqualified, the command's tree becomes the root command, and only the
+// providers this command needs are wired — so `go build ./cmd/worker` doesn't
+// link the dependencies of every other command.
+func (cg *CodeGen) generateBinaryMain(cmd *DiscoveredCommand) (GeneratedFile, error) {
+	name := filepath.Join(filepath.FromSlash(cmd.Dir), "main_gen.go")
+	if cmd.PkgName != "main" {
+		return GeneratedFile{}, fmt.Errorf("layout %s: %s is package %s; each command must be package main",
+			LayoutMultiBinary, cmd.Dir, cmd.PkgName)
+	}
+
+	cg.imports.Reset()
+	cg.imports.self = cmd.PkgPath
+	cg.hasContainer = false
+	cg.hasEnvCheck = false
+	cg.hasResourceAttrs = false
+	cg.hasInitProfile = false
+	cg.hasComponents = false
+	cg.hasFlagCopy = false
+	cg.hasFlagValue = false
+	cg.hasFlagEnv = false
+	cg.hasMetrics = false
+	cg.hasJobTicker = false
+	cg.configLoaders = make(map[*Provider]bool)
+	cg.routeMux = nil
+	cg.jobCron = nil
+	cg.loggers = make(map[*Provider]bool)
+	cg.adapters = make(map[string]bool)
+	cobraQualifier := cg.imports.Add("github.com/spf13/cobra", "cobra")
+	cg.imports.Add("os", "os")
+
+	var initBuf bytes.Buffer
+	if cmd.HasDeps() {
+		if err := cg.generateInitFunc(&initBuf, cmd, ""); err != nil {
+			return GeneratedFile{}, fmt.Errorf("generate init for %s: %w", cmd.Name, err)
+		}
+		initBuf.WriteString("\n")
+	}
+
+	var mainBuf bytes.Buffer
+	mainBuf.WriteString("func main() {\n")
+
+	var zeroArgs []string
+	for _, param := range cmd.Params {
+		if param.Stream == flagsStream {
+			zeroArgs = append(zeroArgs, cg.zeroValue(param))
+			continue
+		}
+		zeroArgs = append(zeroArgs, zeroValueForType(param.Type))
+	}
+	fmt.Fprintf(&mainBuf, "\tstub := %s(%s)\n", cmd.FuncName, strings.Join(zeroArgs, ", "))
+	mainBuf.WriteString("\troot := stub.Command()\n")
+	cg.writeFlagRegistration(&mainBuf, "\t", "root", "stub", cmd)
+	if cmd.IsSingle {
+		fmt.Fprintf(&mainBuf, "\troot.RunE = func(c *%s.Command, _ []string) error { return stub.Handle(c) }\n", cobraQualifier)
+	} else {
+		for _, h := range cmd.Handlers {
+			cg.writeHandlerWiring(&mainBuf, "\t", "root", "stub", cobraQualifier, h, true)
+		}
+	}
+
+	if cmd.HasDeps() {
+		mainBuf.WriteString("\n\tvar cleanup func()\n")
+		fmt.Fprintf(&mainBuf, "\troot.PersistentPreRunE = func(cmd *%s.Command, args []string) error {\n", cobraQualifier)
+		mainBuf.WriteString("\t\tvar err error\n")
+		fmt.Fprintf(&mainBuf, "\t\tcleanup, err = init%s(cmd, root)\n", cmdExportName(cmd.Name))
+		mainBuf.WriteString("\t\treturn err\n")
+		mainBuf.WriteString("\t}\n")
+		fmt.Fprintf(&mainBuf, "\troot.PersistentPostRunE = func(cmd *%s.Command, args []string) error {\n", cobraQualifier)
+		mainBuf.WriteString("\t\tif cleanup != nil {\n")
+		mainBuf.WriteString("\t\t\tcleanup()\n")
+		mainBuf.WriteString("\t\t}\n")
+		mainBuf.WriteString("\t\treturn nil\n")
+		mainBuf.WriteString("\t}\n")
+		if cg.cfg.Inspect {
+			cg.writeBinaryInspectCommand(&mainBuf, cobraQualifier)
+		}
+	}
+
+	if len(cg.configLoaders) > 0 {
+		mainBuf.WriteString("\n\troot.PersistentFlags().AddFlagSet(configFlags)\n")
+	}
+
+	mainBuf.WriteString("\n\tif err := root.Execute(); err != nil {\n")
+	mainBuf.WriteString("\t\tos.Exit(exitCode(err))\n")
+	mainBuf.WriteString("\t}\n")
+	mainBuf.WriteString("}\n")
+
+	var helperBuf bytes.Buffer
+	cg.writeRuntimeHelpers(&helperBuf, cobraQualifier, cmd.HasDeps())
+	helperBuf.WriteString("\n")
+	cg.writeExitCodeHelper(&helperBuf)
+	if cg.hasContainer {
+		helperBuf.WriteString("\n")
+		cg.writeContainerType(&helperBuf)
+	}
+	if cg.hasEnvCheck {
+		helperBuf.WriteString("\n")
+		cg.writeEnvCheck(&helperBuf)
+	}
+	if cg.hasResourceAttrs {
+		helperBuf.WriteString("\n")
+		cg.writeResourceAttrsHelper(&helperBuf)
+	}
+	if cg.hasInitProfile {
+		helperBuf.WriteString("\n")
+		cg.writeInitProfileHelper(&helperBuf)
+	}
+	if cg.hasMetrics {
+		helperBuf.WriteString("\n")
+		cg.writeMetricsHelper(&helperBuf)
+	}
+	if cg.hasComponents {
+		helperBuf.WriteString("\n")
+		cg.writeComponentsHelper(&helperBuf, cobraQualifier)
+	}
+	if cg.hasFlagCopy {
+		helperBuf.WriteString("\n")
+		cg.writeFlagCopyHelper(&helperBuf, cobraQualifier)
+	}
+	if cg.hasFlagValue || cg.hasFlagEnv {
+		helperBuf.WriteString("\n")
+		cg.writeFlagStructHelpers(&helperBuf)
+	}
+	if len(cg.configLoaders) > 0 {
+		helperBuf.WriteString("\n")
+		cg.writeConfigLoaders(&helperBuf)
+	}
+	if cg.routeMux != nil {
+		helperBuf.WriteString("\n")
+		if err := cg.writeRouteMux(&helperBuf, cg.routeMux); err != nil {
+			return GeneratedFile{}, err
+		}
+	}
+	if err := cg.writeJobs(&helperBuf); err != nil {
+		return GeneratedFile{}, err
+	}
+	if len(cg.loggers) > 0 {
+		helperBuf.WriteString("\n")
+		cg.writeDefaultLoggers(&helperBuf)
+	}
+	if len(cg.adapters) > 0 {
+		helperBuf.WriteString("\n")
+		cg.writeAdapters(&helperBuf)
+	}
+
+	return cg.renderFile(name, buildConstraint(cmd.BuildTag), "main", mainBuf.Bytes(), initBuf.Bytes(), helperBuf.Bytes())
+}
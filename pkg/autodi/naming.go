@@ -0,0 +1,199 @@
+package autodi
+
+import (
+	"fmt"
+	"go/token"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Container field naming strategies, selected with //autodi:naming in
+// generate.go. A value containing "{{" is a text/template instead, executed
+// with .Pkg (package name), .Type (type name) and .Path (the package path in
+// the full-path style), e.g. //autodi:naming {{.Type}}{{.Pkg}}.
+const (
+	NamingPackage  = "package"   // package name + type, unless the type starts with it: RedisxLocker, IAM (default)
+	NamingShort    = "short"     // type name only: Locker
+	NamingFullPath = "full-path" // module-relative package path + type: InternalRedisxLocker
+)
+
+// AnnotField names the Container field of a constructor's first result,
+// whatever the naming strategy:
+//
+//	//autodi:field Locks
+//	func NewLocker(client *redis.Client) *redisx.Locker
+const AnnotField = "field"
+
+// namingData is the data of a //autodi:naming template.
+type namingData struct {
+	Pkg  string
+	Type string
+	Path string
+}
+
+// parseNaming checks a //autodi:naming value.
+func parseNaming(value string) error {
+	switch value {
+	case NamingPackage, NamingShort, NamingFullPath:
+		return nil
+	}
+	if !strings.Contains(value, "{{") {
+		return fmt.Errorf("unknown naming %q (want %s, %s, %s or a {{.Pkg}}{{.Type}} template)", value, NamingPackage, NamingShort, NamingFullPath)
+	}
+	tmpl, err := template.New("naming").Option("missingkey=error").Parse(value)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, namingData{Pkg: "Redisx", Type: "Locker", Path: "InternalRedisx"}); err != nil {
+		return err
+	}
+	if !isExportedIdent(b.String()) {
+		return fmt.Errorf("naming template gives %q, not an exported identifier", b.String())
+	}
+	return nil
+}
+
+// isExportedIdent reports whether s is an exported Go identifier.
+func isExportedIdent(s string) bool {
+	return token.IsIdentifier(s) && token.IsExported(s)
+}
+
+// containerField returns the Container field name of a type under the
+// configured naming strategy.
+func (cfg *Config) containerField(typeStr string) string {
+	pkgPath, pkg, typeName := fieldParts(typeStr)
+	if pkg == "" {
+		return exportName(typeName)
+	}
+	switch cfg.Naming {
+	case "", NamingPackage:
+		return FieldName(typeStr)
+	case NamingShort:
+		return exportName(typeName)
+	case NamingFullPath:
+		return pathFieldPrefix(cfg.RelPath(pkgPath)) + exportName(typeName)
+	}
+	tmpl := template.Must(template.New("naming").Parse(cfg.Naming))
+	var b strings.Builder
+	if err := tmpl.Execute(&b, namingData{Pkg: exportName(pkg), Type: exportName(typeName), Path: pathFieldPrefix(cfg.RelPath(pkgPath))}); err != nil {
+		return FieldName(typeStr)
+	}
+	return b.String()
+}
+
+// pathFieldPrefix turns a package path into a field name prefix:
+// internal/redis-x → InternalRedisX.
+func pathFieldPrefix(path string) string {
+	var b strings.Builder
+	for _, elem := range strings.FieldsFunc(path, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		b.WriteString(exportName(elem))
+	}
+	return b.String()
+}
+
+// varFieldName returns the field name local variables of a type are derived
+// from: the default one, or its constructor's for a function or channel type.
+func (g *Graph) varFieldName(typeStr string) string {
+	if named, ok := g.constructorNamed(typeStr); ok {
+		return FieldName(named)
+	}
+	return FieldName(typeStr)
+}
+
+// constructorNamed returns pkgpath.Subject for a function or channel type,
+// which is named after its constructor: NewWorker returning
+// func(context.Context) error gives bus.Worker.
+func (g *Graph) constructorNamed(typeStr string) (string, bool) {
+	p, ok := g.ProviderMap[typeStr]
+	if !ok || !isFuncOrChanType(typeStr) || p.PkgPath == "" {
+		return "", false
+	}
+	subject := g.cfg.constructorSubject(p.FuncName)
+	return p.PkgPath + "." + subject, subject != ""
+}
+
+// constructorSubject returns a constructor's name without its New or
+// //autodi:constructor-prefix prefix: NewWorker → Worker.
+func (cfg *Config) constructorSubject(funcName string) string {
+	for _, prefix := range append([]string{"New"}, cfg.Prefixes...) {
+		if rest, ok := strings.CutPrefix(funcName, prefix); ok && rest != "" && unicode.IsUpper([]rune(rest)[0]) {
+			return rest
+		}
+	}
+	return ""
+}
+
+// fieldName returns the Container field name of a type: the //autodi:field
+// of its provider, its constructor for a function or channel type, or the
+// configured naming strategy.
+func (g *Graph) fieldName(typeStr string) string {
+	if name, ok := g.fieldOverrides[typeStr]; ok {
+		return name
+	}
+	if named, ok := g.constructorNamed(typeStr); ok {
+		return g.cfg.containerField(named)
+	}
+	return g.cfg.containerField(typeStr)
+}
+
+// registerFieldOverride records a provider's //autodi:field for its first
+// result and the interfaces it is provided as.
+func (g *Graph) registerFieldOverride(p *Provider) error {
+	values := GetAnnotationValues(p.Annotations, AnnotField)
+	if len(values) == 0 || len(p.Returns) == 0 {
+		return nil
+	}
+	name := values[0]
+	if !isExportedIdent(name) {
+		return fmt.Errorf("%s: //autodi:field %s: not an exported identifier", p.Position, name)
+	}
+	g.fieldOverrides[p.Returns[0].TypeStr] = name
+	for _, ifaceStr := range p.asTypes(0) {
+		g.fieldOverrides[ifaceStr] = name
+	}
+	return nil
+}
+
+// checkFieldNames reports types sharing a Container field name under a
+// custom naming strategy or //autodi:field.
+func (g *Graph) checkFieldNames() []error {
+	if g.cfg.Naming == "" && len(g.fieldOverrides) == 0 {
+		return nil
+	}
+	// T and *T (config structs) share a field by design
+	byField := make(map[string][]string)
+	for typeStr, field := range g.TypeToField {
+		if !slices.Contains(byField[field], strings.TrimPrefix(typeStr, "*")) {
+			byField[field] = append(byField[field], strings.TrimPrefix(typeStr, "*"))
+		}
+	}
+	var errs []error
+	for _, field := range sortedKeys(byField) {
+		typeStrs := byField[field]
+		if len(typeStrs) < 2 {
+			continue
+		}
+		sort.Strings(typeStrs)
+		var lines []string
+		for _, typeStr := range typeStrs {
+			line := "  " + typeStr
+			p := g.ProviderMap[typeStr]
+			if p == nil {
+				p = g.ProviderMap["*"+typeStr]
+			}
+			if p != nil {
+				line += fmt.Sprintf(" (%s.%s, %s)", p.PkgName, p.FuncName, p.Position)
+			}
+			lines = append(lines, line)
+		}
+		errs = append(errs, fmt.Errorf("%d types share the Container field %s:\n%s\n  hint: rename one with //autodi:field <Name> on its constructor",
+			len(typeStrs), field, strings.Join(lines, "\n")))
+	}
+	return errs
+}
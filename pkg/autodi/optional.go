@@ -0,0 +1,86 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+)
+
+// Optional dependencies: a parameter marked //autodi:optional (or an
+// optional:"true" parameter-object field) whose type no provider supplies
+// gets its zero value — a typed nil for pointers, maps, slices, channels and
+// funcs, nil for interfaces — and the generated init logs the omission to
+// stderr, so a missing optional client is visible at startup.
+
+// optionalOmission is an optional parameter left without a provider.
+type optionalOmission struct {
+	provider *Provider
+	param    TypeRef
+}
+
+// isOmitted reports whether an optional parameter has no provider.
+func (g *Graph) isOmitted(param TypeRef) bool {
+	if !param.Optional || param.Stream != "" || g.SliceMembers(param.TypeStr) != nil {
+		return false
+	}
+	_, ok := g.ProviderMap[g.resolveType(param.TypeStr)]
+	return !ok
+}
+
+// optionalOmissions lists the omitted optional parameters of providers.
+func (g *Graph) optionalOmissions(providers []*Provider) []optionalOmission {
+	var omitted []optionalOmission
+	for _, p := range providers {
+		for _, param := range p.Params {
+			if g.isOmitted(param) {
+				omitted = append(omitted, optionalOmission{provider: p, param: param})
+			}
+		}
+	}
+	return omitted
+}
+
+// zeroValue is the Go expression passed for an omitted optional parameter.
+func (cg *CodeGen) zeroValue(param TypeRef) string {
+	if param.Type == nil {
+		return "nil"
+	}
+	typeStr := cg.typeSource(param.Type)
+	switch u := param.Type.Underlying().(type) {
+	case *types.Interface:
+		return "nil"
+	case *types.Pointer, *types.Map, *types.Slice, *types.Chan, *types.Signature:
+		return "(" + typeStr + ")(nil)"
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return "false"
+		case u.Info()&types.IsString != 0:
+			return `""`
+		default:
+			return "0"
+		}
+	}
+	return typeStr + "{}"
+}
+
+// writeOptionalNotices logs each omitted optional parameter at the start of
+// an init function.
+func (cg *CodeGen) writeOptionalNotices(buf *bytes.Buffer, providers []*Provider) {
+	for _, o := range cg.graph.optionalOmissions(providers) {
+		fmtQualifier := cg.imports.Add("fmt", "fmt")
+		osQualifier := cg.imports.Add("os", "os")
+		fmt.Fprintf(buf, "\t%s.Fprintln(%s.Stderr, %q)\n", fmtQualifier, osQualifier,
+			fmt.Sprintf("autodi: optional %s has no provider; %s.%s gets %s",
+				toShortTypeName(o.param.TypeStr), o.provider.PkgName, o.provider.FuncName, zeroValueLabel(o.param)))
+	}
+}
+
+// zeroValueLabel describes the zero value of an omitted parameter in the
+// startup notice.
+func zeroValueLabel(param TypeRef) string {
+	if param.Type != nil && isNilable(param.Type) {
+		return "nil"
+	}
+	return "the zero value"
+}
@@ -0,0 +1,83 @@
+package autodi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// otelModule is the module whose presence in go.mod enables wiring attributes.
+const otelModule = "go.opentelemetry.io/otel"
+
+// usesOTel reports whether the module depends on OpenTelemetry.
+func (cg *CodeGen) usesOTel() bool {
+	data, err := os.ReadFile(filepath.Join(cg.moduleRoot, "go.mod"))
+	return err == nil && bytes.Contains(data, []byte(otelModule))
+}
+
+// graphFingerprint hashes a command's wiring: every provider in dependency
+// order with the types it provides. Equal fingerprints mean equal wiring.
+func graphFingerprint(providers []*Provider) string {
+	h := sha256.New()
+	for _, p := range providers {
+		fmt.Fprintf(h, "%s.%s", p.PkgPath, p.FuncName)
+		for _, ret := range p.Returns {
+			fmt.Fprintf(h, " %s", ret.TypeStr)
+		}
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// resourceAttributes returns the OTEL_RESOURCE_ATTRIBUTES entries describing
+// a command's wiring, or "" when the module doesn't use OpenTelemetry.
+func (cg *CodeGen) resourceAttributes(cmd *DiscoveredCommand) (string, error) {
+	if !cg.usesOTel() {
+		return "", nil
+	}
+	providers, err := cg.graph.CommandProviders(cmd)
+	if err != nil {
+		return "", fmt.Errorf("resource attributes for %s: %w", cmd.Name, err)
+	}
+
+	binary := cg.cfg.AppName
+	if cg.cfg.Layout == LayoutMultiBinary || binary == "" {
+		binary = cmd.Name
+	}
+	attrs := [][2]string{
+		{"autodi.binary", binary},
+		{"autodi.command", cmd.Name},
+		{"autodi.providers", fmt.Sprint(len(providers))},
+		{"autodi.graph", graphFingerprint(providers)},
+	}
+	if cg.cfg.Profile != "" {
+		attrs = append(attrs, [2]string{"autodi.profile", cg.cfg.Profile})
+	}
+
+	var parts []string
+	for _, kv := range attrs {
+		parts = append(parts, kv[0]+"="+url.PathEscape(kv[1]))
+	}
+	cg.hasResourceAttrs = true
+	return strings.Join(parts, ","), nil
+}
+
+// writeResourceAttrsHelper emits addResourceAttributes. The OpenTelemetry SDK's
+// default resource reads OTEL_RESOURCE_ATTRIBUTES, so traces and metrics carry
+// the wiring facts without the generated code importing OpenTelemetry.
+func (cg *CodeGen) writeResourceAttrsHelper(buf *bytes.Buffer) {
+	cg.imports.Add("os", "os")
+	buf.WriteString("// addResourceAttributes adds wiring facts to OTEL_RESOURCE_ATTRIBUTES before\n")
+	buf.WriteString("// any provider sets up OpenTelemetry. Attributes set by the environment win.\n")
+	buf.WriteString("func addResourceAttributes(attrs string) {\n")
+	buf.WriteString("\tif cur := os.Getenv(\"OTEL_RESOURCE_ATTRIBUTES\"); cur != \"\" {\n")
+	buf.WriteString("\t\tattrs += \",\" + cur\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\tos.Setenv(\"OTEL_RESOURCE_ATTRIBUTES\", attrs)\n")
+	buf.WriteString("}\n")
+}
@@ -0,0 +1,145 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Parallel init: with //autodi:parallel-init in generate.go, the generated
+// init functions construct providers level by level — a provider's level is
+// one more than that of its deepest dependency — and run the constructors of
+// a level concurrently in an errgroup.Group, so slow network clients (DB,
+// Redis, Kafka, ...) dial at the same time. Constructors that need ordered,
+// inline code (//autodi:when, //autodi:replayable, //autodi:invoke, result
+// objects, secrets, auto-collected slices, --profile-init, //autodi:metrics)
+// run after their level's group, one at a time.
+const errgroupPath = "golang.org/x/sync/errgroup"
+
+// initLevels groups providers, given in topological order, by dependency
+// depth. extraDeps adds dependencies that aren't parameters.
+func (g *Graph) initLevels(providers []*Provider, extraDeps map[*Provider][]string) [][]*Provider {
+	level := make(map[*Provider]int, len(providers))
+	var levels [][]*Provider
+	for _, p := range providers {
+		var deps []string
+		for _, param := range p.Params {
+			deps = append(deps, param.TypeStr)
+		}
+		deps = append(deps, p.ExtraDeps...)
+		deps = append(deps, extraDeps[p]...)
+
+		l := 0
+		for _, dep := range deps {
+			if d, ok := level[g.ProviderMap[g.resolveType(dep)]]; ok && d+1 > l {
+				l = d + 1
+			}
+		}
+		level[p] = l
+		for len(levels) <= l {
+			levels = append(levels, nil)
+		}
+		levels[l] = append(levels[l], p)
+	}
+	return levels
+}
+
+// parallelizable reports whether a provider's call can run in an errgroup
+// goroutine: a plain call whose results are assigned to declared variables.
+func (cg *CodeGen) parallelizable(p *Provider) bool {
+	if p.When != nil || p.ReplayFunc != "" || len(p.Secrets) > 0 || cg.cfg.ProfileInit != "" || cg.cfg.Inspect || cg.cfg.Metrics != "" ||
+		HasAnnotation(p.Annotations, AnnotInvoke) || takesContainer(p) {
+		return false
+	}
+	for _, ret := range p.Returns {
+		if ret.Type == nil || ret.OutStruct != nil || ret.TypeStr == cg.graph.SecretsSource {
+			return false
+		}
+	}
+	return true
+}
+
+// writeParallelInit writes the provider calls of an init function level by
+// level; writeProvider writes one call inline.
+func (cg *CodeGen) writeParallelInit(buf *bytes.Buffer, providers []*Provider, deepAutoMap map[string][]autoCollectParam, writeProvider func(*Provider) error,
+	varMap map[string]string, usedVars map[string]bool, closeables *[]CloseableField, components *[]Component, consumedTypes map[string]bool) error {
+	extraDeps := make(map[*Provider][]string)
+	for _, p := range providers {
+		for _, ap := range deepAutoMap[p.PkgPath+"."+p.FuncName] {
+			for _, member := range ap.providers {
+				extraDeps[p] = append(extraDeps[p], memberDeps(member)...)
+			}
+		}
+	}
+	for p, after := range containerDeps(providers) {
+		extraDeps[p] = append(extraDeps[p], after...)
+	}
+
+	for _, level := range cg.graph.initLevels(providers, extraDeps) {
+		var parallel, serial []*Provider
+		for _, p := range level {
+			if _, auto := deepAutoMap[p.PkgPath+"."+p.FuncName]; !auto && cg.parallelizable(p) {
+				parallel = append(parallel, p)
+			} else {
+				serial = append(serial, p)
+			}
+		}
+		if len(parallel) > 1 {
+			cg.writeParallelProviderCalls(buf, parallel, varMap, usedVars, closeables, components, consumedTypes)
+		} else {
+			serial = append(parallel, serial...)
+		}
+		for _, p := range serial {
+			if err := writeProvider(p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeParallelProviderCalls constructs independent providers concurrently:
+// their results are declared first, then assigned in errgroup goroutines.
+func (cg *CodeGen) writeParallelProviderCalls(buf *bytes.Buffer, providers []*Provider, varMap map[string]string, usedVars map[string]bool,
+	closeables *[]CloseableField, components *[]Component, consumedTypes map[string]bool) {
+	errgroupQualifier := cg.imports.Add(errgroupPath, "errgroup")
+	groupVar := cg.uniqueLocalVar("initGroup", usedVars)
+
+	var calls bytes.Buffer
+	for _, p := range providers {
+		qualifier := cg.qualifyFunc(p)
+		args := strings.Join(cg.buildLocalArgs(p, varMap), ", ")
+		lhsNames := cg.providerResultVars(p, varMap, usedVars, closeables, components, consumedTypes)
+		for i, name := range lhsNames {
+			if name != "_" {
+				fmt.Fprintf(buf, "\tvar %s %s\n", name, cg.typeSource(p.Returns[i].Type))
+			}
+		}
+
+		fmt.Fprintf(&calls, "\t%s.Go(func() error {\n", groupVar)
+		switch {
+		case p.HasError && len(lhsNames) > 0:
+			calls.WriteString("\t\tvar err error\n")
+			fmt.Fprintf(&calls, "\t\t%s, err = %s(%s)\n", strings.Join(lhsNames, ", "), qualifier, args)
+			calls.WriteString("\t\tif err != nil {\n")
+			cg.writeInitError(&calls, p, "\t\t\t", "")
+			calls.WriteString("\t\t}\n")
+		case p.HasError:
+			fmt.Fprintf(&calls, "\t\tif err := %s(%s); err != nil {\n", qualifier, args)
+			cg.writeInitError(&calls, p, "\t\t\t", "")
+			calls.WriteString("\t\t}\n")
+		case len(lhsNames) > 0:
+			fmt.Fprintf(&calls, "\t\t%s = %s(%s)\n", strings.Join(lhsNames, ", "), qualifier, args)
+		default:
+			fmt.Fprintf(&calls, "\t\t%s(%s)\n", qualifier, args)
+		}
+		calls.WriteString("\t\treturn nil\n")
+		calls.WriteString("\t})\n")
+	}
+
+	fmt.Fprintf(buf, "\tvar %s %s.Group\n", groupVar, errgroupQualifier)
+	buf.Write(calls.Bytes())
+	fmt.Fprintf(buf, "\tif err := %s.Wait(); err != nil {\n", groupVar)
+	buf.WriteString("\t\treturn nil, err\n")
+	buf.WriteString("\t}\n\n")
+}
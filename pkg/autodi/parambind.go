@@ -0,0 +1,172 @@
+package autodi
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// AnnotBindParam binds one parameter of a constructor to an implementation,
+// overriding the interface's graph-wide binding for that consumer only:
+//
+//	//autodi:bind-param repo postgres.Repo
+//	func NewSvc(repo Repository, log *slog.Logger) *Svc
+//
+// An inline comment after the parameter does the same, keeping the choice
+// next to the parameter it applies to:
+//
+//	func NewSvc(repo Repository /* autodi:bind=postgres.Repo */, log *slog.Logger) *Svc
+const AnnotBindParam = "bind-param"
+
+// inlineBindPrefix starts an inline parameter binding comment.
+const inlineBindPrefix = "autodi:bind="
+
+// resolveParamBindings reads the parameter bindings of a provider.
+func (s *Scanner) resolveParamBindings(pkg *packages.Package, p *Provider) error {
+	return applyParamBindings(pkg, declAt(pkg, p.Position), p.Params, p.Annotations,
+		fmt.Sprintf("%s: %s.%s", p.Position, p.PkgName, p.FuncName))
+}
+
+// applyParamBindings sets BindTo on the parameters named by //autodi:bind-param
+// and on those followed by an inline autodi:bind= comment in fn; where
+// prefixes errors.
+func applyParamBindings(pkg *packages.Package, fn *ast.FuncDecl, params []TypeRef, annotations []Annotation, where string) error {
+	bind := func(name, target string) error {
+		i := paramIndex(params, name)
+		if i < 0 {
+			return fmt.Errorf("%s: //autodi:bind-param %s: no parameter named %s", where, name, name)
+		}
+		if params[i].Stream != "" {
+			return fmt.Errorf("%s: parameter %s is filled with %s and can't be bound", where, name, params[i].Stream)
+		}
+		if params[i].BindTo != "" && params[i].BindTo != target {
+			return fmt.Errorf("%s: parameter %s is bound to both %s and %s", where, name, params[i].BindTo, target)
+		}
+		params[i].BindTo = target
+		return nil
+	}
+	for _, value := range GetAnnotationValues(annotations, AnnotBindParam) {
+		fields := strings.Fields(value)
+		if len(fields) != 2 {
+			return fmt.Errorf("%s: //autodi:bind-param %s: want <param> <Type>\n  hint: //autodi:bind-param repo postgres.Repo", where, value)
+		}
+		if err := bind(fields[0], fields[1]); err != nil {
+			return err
+		}
+	}
+	if fn == nil {
+		return nil
+	}
+	for name, target := range inlineBindings(pkg, fn) {
+		if target == "" {
+			return fmt.Errorf("%s: parameter %s: autodi:bind= needs a type\n  hint: /* autodi:bind=postgres.Repo */", where, name)
+		}
+		if err := bind(name, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inlineBindings returns the autodi:bind= comments inside the parameter list
+// of fn by the name of the parameter each one follows.
+func inlineBindings(pkg *packages.Package, fn *ast.FuncDecl) map[string]string {
+	list := fn.Type.Params
+	if list == nil || len(list.List) == 0 {
+		return nil
+	}
+	var file *ast.File
+	for _, f := range pkg.Syntax {
+		if f.Pos() <= fn.Pos() && fn.End() <= f.End() {
+			file = f
+			break
+		}
+	}
+	if file == nil {
+		return nil
+	}
+	bindings := make(map[string]string)
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			if c.Pos() < list.Opening || c.Pos() > list.Closing {
+				continue
+			}
+			text := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(c.Text, "//"), "/*"), "*/")
+			target, ok := strings.CutPrefix(strings.TrimSpace(text), inlineBindPrefix)
+			if !ok {
+				continue
+			}
+			// The parameter the comment follows
+			var field *ast.Field
+			for _, f := range list.List {
+				if f.End() <= c.Pos() {
+					field = f
+				}
+			}
+			if field == nil {
+				continue
+			}
+			for _, ident := range field.Names {
+				bindings[ident.Name] = strings.TrimSpace(target)
+			}
+		}
+	}
+	return bindings
+}
+
+// declAt finds the function or method declared at pos in pkg.
+func declAt(pkg *packages.Package, pos token.Position) *ast.FuncDecl {
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && pkg.Fset.Position(fn.Pos()) == pos {
+				return fn
+			}
+		}
+	}
+	return nil
+}
+
+// applyParamBindings points the bound parameters of providers at the result
+// of their implementation's provider, so that consumer gets it whatever the
+// interface is bound to elsewhere.
+func (g *Graph) applyParamBindings(providers []*Provider) []error {
+	var errs []error
+	for _, p := range providers {
+		errs = append(errs, g.bindParams(p.Params, fmt.Sprintf("%s: %s.%s", p.Position, p.PkgName, p.FuncName))...)
+	}
+	return errs
+}
+
+// bindParams rewrites the bound parameters of params to their
+// implementation's type; where prefixes errors.
+func (g *Graph) bindParams(params []TypeRef, where string) []error {
+	var errs []error
+	for i, param := range params {
+		if param.BindTo == "" {
+			continue
+		}
+		targetStr := g.resolveConfigType(param.BindTo)
+		p, ok := g.ProviderMap[targetStr]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: parameter %s is bound to %s, which no constructor provides", where, param.Name, param.BindTo))
+			continue
+		}
+		ret := p.Returns[0]
+		for _, r := range p.Returns {
+			if r.TypeStr == targetStr {
+				ret = r
+			}
+		}
+		if param.Type != nil && ret.Type != nil && !types.AssignableTo(ret.Type, param.Type) {
+			errs = append(errs, fmt.Errorf("%s: parameter %s is bound to %s, which can't be used as %s",
+				where, param.Name, toShortTypeName(ret.TypeStr), toShortTypeName(param.TypeStr)))
+			continue
+		}
+		params[i].Type, params[i].TypeStr, params[i].PkgPath, params[i].IsIface = ret.Type, ret.TypeStr, ret.PkgPath, ret.IsIface
+	}
+	return errs
+}
@@ -0,0 +1,147 @@
+package autodi
+
+import (
+	"go/ast"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// AnnotIn marks a struct type as a parameter object:
+//
+//	//autodi:in
+//	type Params struct {
+//		DB     *ent.Client
+//		Cache  cache.Cache `optional:"true"`
+//	}
+//
+//	func NewService(p Params) *Service
+//
+// Each exported field is resolved from the graph as if it were a separate
+// constructor parameter, and the generated code builds the struct literal.
+// Embedding fx.In (or an In type from a package named autodi) has the same
+// effect, which keeps fx-style constructors working unchanged.
+const AnnotIn = "in"
+
+// indexPackages records every loaded package and its transitive imports, so
+// type declarations outside the scanned packages can be inspected.
+func (s *Scanner) indexPackages(pkgs []*packages.Package) {
+	s.pkgsByPath = make(map[string]*packages.Package)
+	var visit func(pkg *packages.Package)
+	visit = func(pkg *packages.Package) {
+		if _, ok := s.pkgsByPath[pkg.PkgPath]; ok {
+			return
+		}
+		s.pkgsByPath[pkg.PkgPath] = pkg
+		for _, imp := range pkg.Imports {
+			visit(imp)
+		}
+	}
+	for _, pkg := range pkgs {
+		visit(pkg)
+	}
+}
+
+// paramObject returns the struct of a parameter object type (T or *T), or nil.
+func (s *Scanner) paramObject(t types.Type) *types.Struct {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return nil
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		if f := st.Field(i); f.Embedded() && isMarker(f.Type(), "In") {
+			return st
+		}
+	}
+	if pkg := s.pkgsByPath[named.Obj().Pkg().Path()]; pkg != nil && typeHasDirective(pkg, named.Obj().Name(), AnnotIn) {
+		return st
+	}
+	return nil
+}
+
+// isMarker reports whether t is an embeddable In/Out marker: fx.In/fx.Out
+// (dig.In/dig.Out), or an In/Out type declared in a package named autodi.
+func isMarker(t types.Type, name string) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Name() != name || named.Obj().Pkg() == nil {
+		return false
+	}
+	path := named.Obj().Pkg().Path()
+	return path == "go.uber.org/fx" || path == "go.uber.org/dig" ||
+		path == "autodi" || strings.HasSuffix(path, "/autodi")
+}
+
+// typeHasDirective reports whether the declaration of type name in pkg has an
+// //autodi:<kind> line in its doc comment.
+func typeHasDirective(pkg *packages.Package, name, kind string) bool {
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok || ts.Name.Name != name {
+					continue
+				}
+				doc := ts.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				if doc == nil {
+					return false
+				}
+				for _, c := range doc.List {
+					text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+					if text == "autodi:"+kind || strings.HasPrefix(text, "autodi:"+kind+" ") {
+						return true
+					}
+				}
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// paramObjectFields flattens a parameter object into one TypeRef per exported
+// field. Fields tagged optional:"true", or matching //autodi:optional, are optional.
+func paramObjectFields(object TypeRef, st *types.Struct, optionalTypes []string) []TypeRef {
+	var refs []TypeRef
+	obj := &object
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		if !f.Exported() || (f.Embedded() && isMarker(f.Type(), "In")) {
+			continue
+		}
+		t := resolveAliases(f.Type())
+		typeStr := types.TypeString(t, nil)
+		optional := reflect.StructTag(st.Tag(i)).Get("optional") == "true"
+		for _, opt := range optionalTypes {
+			if strings.HasSuffix(typeStr, opt) {
+				optional = true
+				break
+			}
+		}
+		refs = append(refs, TypeRef{
+			Type:     t,
+			TypeStr:  typeStr,
+			PkgPath:  typePkgPath(t),
+			IsIface:  isInterface(t),
+			Optional: optional,
+			InField:  f.Name(),
+			InStruct: obj,
+		})
+	}
+	return refs
+}
@@ -0,0 +1,207 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// AnnotPhase assigns a constructor to a construction phase:
+//
+//	//autodi:phase infra
+//	func NewDB(cfg *Config) (*sql.DB, error)
+//
+// Infra providers, and every provider they depend on, are constructed before
+// all others, so a command fails on an unreachable database before building
+// its business services. The library layout also generates InfraContainer
+// and AppContainer, built by BuildInfra and BuildApp(ctx, infra), so a main
+// can run migrations between the two.
+const AnnotPhase = "phase"
+
+// Construction phases of //autodi:phase.
+const (
+	PhaseInfra = "infra" // constructed first, with its dependencies
+	PhaseApp   = "app"   // the default; an infra provider can't depend on it
+)
+
+// providerPhase returns the //autodi:phase of p, "" when it has none.
+func providerPhase(p *Provider) string {
+	values := GetAnnotationValues(p.Annotations, AnnotPhase)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// infraRoot reports whether p starts the infra phase: it has //autodi:phase
+// infra, or //autodi:migrate.
+func infraRoot(p *Provider) bool {
+	return providerPhase(p) == PhaseInfra || HasAnnotation(p.Annotations, AnnotMigrate)
+}
+
+// phased reports whether any provider starts the infra phase.
+func (g *Graph) phased() bool {
+	return slices.ContainsFunc(g.Providers, infraRoot)
+}
+
+// phaseDeps returns the providers p needs constructed before it: those of its
+// parameters, of its ExtraDeps, of the members of its []Interface parameters
+// and of the extra edges from its results.
+func (g *Graph) phaseDeps(p *Provider, extraEdges map[string][]string) []*Provider {
+	var types []string
+	for _, param := range p.Params {
+		if param.Stream != "" {
+			continue
+		}
+		types = append(types, param.TypeStr)
+		for _, m := range g.SliceMembers(param.TypeStr) {
+			types = append(types, memberDeps(m)...)
+		}
+	}
+	types = append(types, p.ExtraDeps...)
+	for _, ret := range p.Returns {
+		types = append(types, extraEdges[ret.TypeStr]...)
+	}
+	var deps []*Provider
+	for _, typeStr := range types {
+		if dep, ok := g.ProviderMap[g.resolveType(typeStr)]; ok && dep != p {
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
+
+// infraProviders returns the infra phase of providers, given in topological
+// order: the //autodi:phase infra ones, the migrator, and everything they
+// depend on.
+func (g *Graph) infraProviders(providers []*Provider, extraEdges map[string][]string) map[*Provider]bool {
+	infra := make(map[*Provider]bool)
+	for i := len(providers) - 1; i >= 0; i-- {
+		p := providers[i]
+		if !infra[p] && !infraRoot(p) {
+			continue
+		}
+		infra[p] = true
+		for _, dep := range g.phaseDeps(p, extraEdges) {
+			infra[dep] = true
+		}
+	}
+	return infra
+}
+
+// phaseOrder moves the infra phase of providers in topological order ahead
+// of the rest, keeping the order within each phase; without //autodi:phase
+// infra the order is unchanged.
+func (g *Graph) phaseOrder(providers []*Provider, extraEdges map[string][]string) []*Provider {
+	if !g.phased() {
+		return providers
+	}
+	infra := g.infraProviders(providers, extraEdges)
+	ordered := make([]*Provider, 0, len(providers))
+	for _, p := range providers {
+		if infra[p] {
+			ordered = append(ordered, p)
+		}
+	}
+	for _, p := range providers {
+		if !infra[p] {
+			ordered = append(ordered, p)
+		}
+	}
+	return ordered
+}
+
+// splitPhases splits providers in phase order into the infra and app phases.
+func (g *Graph) splitPhases(providers []*Provider, extraEdges map[string][]string) (infra, app []*Provider) {
+	inInfra := g.infraProviders(providers, extraEdges)
+	for _, p := range providers {
+		if inInfra[p] {
+			infra = append(infra, p)
+		} else {
+			app = append(app, p)
+		}
+	}
+	return infra, app
+}
+
+// checkPhases validates //autodi:phase: a known phase, no request scope, and
+// no infra provider depending on one marked app.
+func (g *Graph) checkPhases() []error {
+	var errs []error
+	for _, p := range g.Providers {
+		switch phase := providerPhase(p); {
+		case phase == "" && HasAnnotation(p.Annotations, AnnotPhase):
+			errs = append(errs, fmt.Errorf("%s: %s.%s: //autodi:phase needs %s or %s", p.Position, p.PkgName, p.FuncName, PhaseInfra, PhaseApp))
+		case phase != "" && phase != PhaseInfra && phase != PhaseApp:
+			errs = append(errs, fmt.Errorf("%s: %s.%s: unknown //autodi:phase %q\n  hint: %s or %s", p.Position, p.PkgName, p.FuncName, phase, PhaseInfra, PhaseApp))
+		case phase != "" && p.Scope != "":
+			errs = append(errs, fmt.Errorf("%s: %s.%s: //autodi:phase can't be combined with //autodi:scope %s", p.Position, p.PkgName, p.FuncName, p.Scope))
+		}
+	}
+	if len(errs) > 0 || !g.phased() {
+		return errs
+	}
+	all, err := g.ProvidersForTypes(g.sortedTypes)
+	if err != nil {
+		return nil // reported by the command validation
+	}
+	infra := g.infraProviders(all, nil)
+	for _, p := range all {
+		if !infra[p] || providerPhase(p) == PhaseApp {
+			continue
+		}
+		for _, dep := range g.phaseDeps(p, nil) {
+			if providerPhase(dep) == PhaseApp {
+				errs = append(errs, fmt.Errorf("%s: %s.%s is constructed in the infra phase but depends on %s.%s, marked //autodi:phase app\n  hint: mark %s.%s //autodi:phase infra, or drop the dependency",
+					p.Position, p.PkgName, p.FuncName, dep.PkgName, dep.FuncName, dep.PkgName, dep.FuncName))
+			}
+		}
+	}
+	return errs
+}
+
+// generatePhaseContainers emits the two-phase containers of the library
+// layout: InfraContainer with BuildInfra, and AppContainer with BuildApp,
+// which takes the InfraContainer and constructs everything else.
+func (cg *CodeGen) generatePhaseContainers(buf *bytes.Buffer, all []*Provider) error {
+	context := cg.imports.Add("context", "context")
+	infra, app := cg.graph.splitPhases(all, nil)
+
+	buf.WriteString("\n")
+	cg.writeNamedContainer(buf, "InfraContainer", "holds the providers of the infra phase: those marked\n// //autodi:phase infra and everything they depend on.", "", infra)
+	buf.WriteString("\n")
+	cg.writeNamedContainer(buf, "AppContainer", "holds the providers of the app phase, constructed from an\n// InfraContainer. Close releases only them; close Infra separately.", "\tInfra *InfraContainer\n\n", app)
+
+	buf.WriteString("\n// BuildInfra constructs the infra phase. Call Close to release it.\n")
+	fmt.Fprintf(buf, "func BuildInfra(ctx %s.Context) (*InfraContainer, error) {\n", context)
+	buf.WriteString("\tc := &InfraContainer{}\n")
+	usedVars := map[string]bool{"c": true, "ctx": true, "err": true}
+	if err := cg.writeLibraryProviders(buf, infra, cg.libraryVarMap(infra), usedVars, "nil"); err != nil {
+		return err
+	}
+	buf.WriteString("\treturn c, nil\n")
+	buf.WriteString("}\n")
+	if migrator := cg.graph.migrator(); migrator != nil {
+		cg.writeInfraMigrate(buf, migrator)
+	}
+
+	// The app phase reads the infra providers from the InfraContainer
+	varMap := cg.libraryVarMap(app)
+	for typeStr, v := range cg.libraryVarMap(infra) {
+		if field, ok := strings.CutPrefix(v, "c."); ok {
+			varMap[typeStr] = "infra." + field
+		}
+	}
+	buf.WriteString("\n// BuildApp constructs the app phase from the infra phase, e.g. once migrations\n")
+	buf.WriteString("// have run. Call Close to release it.\n")
+	fmt.Fprintf(buf, "func BuildApp(ctx %s.Context, infra *InfraContainer) (*AppContainer, error) {\n", context)
+	buf.WriteString("\tc := &AppContainer{Infra: infra}\n")
+	usedVars = map[string]bool{"c": true, "ctx": true, "err": true, "infra": true}
+	if err := cg.writeLibraryProviders(buf, app, varMap, usedVars, "nil"); err != nil {
+		return err
+	}
+	buf.WriteString("\treturn c, nil\n")
+	buf.WriteString("}\n")
+	return nil
+}
@@ -0,0 +1,149 @@
+package autodi
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// AnnotOverride makes a constructor win over any other provider of its
+// results:
+//
+//	//autodi:override
+//	func NewCachedStore(db *sql.DB) *Store
+const AnnotOverride = "override"
+
+// Duplicate-provider precedence rules, applied in the order of
+// //autodi:precedence in generate.go until one picks a provider:
+//
+//	//autodi:precedence override,annotated,internal   (the default)
+//	//autodi:precedence none                          (always report duplicates)
+const (
+	PrecedenceOverride  = "override"  // //autodi:override wins outright
+	PrecedenceAnnotated = "annotated" // //autodi:bind or //autodi:primary beats a plain constructor
+	PrecedenceInternal  = "internal"  // a provider under internal/ beats one under pkg/
+)
+
+// defaultPrecedence applies when generate.go has no //autodi:precedence.
+var defaultPrecedence = []string{PrecedenceOverride, PrecedenceAnnotated, PrecedenceInternal}
+
+// parsePrecedence parses the value of //autodi:precedence.
+func parsePrecedence(value string) ([]string, error) {
+	if value == "none" {
+		return []string{}, nil
+	}
+	var rules []string
+	for _, rule := range strings.Split(value, ",") {
+		if !slices.Contains(defaultPrecedence, rule) || slices.Contains(rules, rule) {
+			return nil, fmt.Errorf("generate.go: //autodi:precedence %s: unknown or repeated rule %q\n  hint: a comma-separated list of %s, or none",
+				value, rule, strings.Join(defaultPrecedence, ", "))
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Shadowed records a duplicate provider resolved by a precedence rule.
+type Shadowed struct {
+	TypeStr string
+	Winner  *Provider
+	Loser   *Provider
+	Rule    string
+}
+
+// precede picks the provider of a type two constructors return, with the
+// rule that decided; a nil winner means no rule tells them apart.
+func (cfg *Config) precede(a, b *Provider) (*Provider, string) {
+	rules := cfg.Precedence
+	if rules == nil {
+		rules = defaultPrecedence
+	}
+	for _, rule := range rules {
+		var winsA, winsB bool
+		switch rule {
+		case PrecedenceOverride:
+			winsA, winsB = HasAnnotation(a.Annotations, AnnotOverride), HasAnnotation(b.Annotations, AnnotOverride)
+			if winsA && winsB {
+				return nil, ""
+			}
+		case PrecedenceAnnotated:
+			annotated := func(p *Provider) bool {
+				return HasAnnotation(p.Annotations, AnnotBind) || HasAnnotation(p.Annotations, AnnotPrimary)
+			}
+			winsA, winsB = annotated(a), annotated(b)
+		case PrecedenceInternal:
+			under := func(p *Provider, dir string) bool {
+				rel := p.RelPath(cfg.Module)
+				return rel == dir || strings.HasPrefix(rel, dir+"/")
+			}
+			winsA = under(a, "internal") && under(b, "pkg")
+			winsB = under(b, "internal") && under(a, "pkg")
+		}
+		switch {
+		case winsA && !winsB:
+			return a, rule
+		case winsB && !winsA:
+			return b, rule
+		}
+	}
+	return nil, ""
+}
+
+// registerProvider maps a type to its provider. When another provider already
+// returns it, the precedence rules pick one or the duplicate is reported.
+func (g *Graph) registerProvider(typeStr string, p *Provider) error {
+	existing, ok := g.ProviderMap[typeStr]
+	if !ok {
+		g.ProviderMap[typeStr] = p
+		g.TypeToField[typeStr] = g.fieldName(typeStr)
+		return nil
+	}
+	winner, rule := g.cfg.precede(existing, p)
+	if winner == nil {
+		if HasAnnotation(existing.Annotations, AnnotOverride) && HasAnnotation(p.Annotations, AnnotOverride) {
+			return fmt.Errorf("%s: %s and %s both have //autodi:override for %s\n  hint: keep //autodi:override on exactly one",
+				p.Position, g.cfg.providerRef(existing), g.cfg.providerRef(p), typeStr)
+		}
+		return diagf(CodeDuplicateProvider, p.Position, typeStr, g.cfg.providerRef(existing), g.cfg.providerRef(p))
+	}
+	loser := p
+	if winner == p {
+		loser = existing
+	}
+	g.ProviderMap[typeStr] = winner
+	g.Shadowed = append(g.Shadowed, Shadowed{TypeStr: typeStr, Winner: winner, Loser: loser, Rule: rule})
+	return nil
+}
+
+// dropShadowed removes the providers that lost every type they return, so
+// they aren't constructed or bound to interfaces.
+func (g *Graph) dropShadowed() {
+	if len(g.Shadowed) == 0 {
+		return
+	}
+	registered := make(map[*Provider]bool)
+	for _, p := range g.ProviderMap {
+		registered[p] = true
+	}
+	lost := make(map[*Provider]bool)
+	for _, s := range g.Shadowed {
+		lost[s.Loser] = !registered[s.Loser]
+	}
+	kept := make([]*Provider, 0, len(g.Providers))
+	for _, p := range g.Providers {
+		if !lost[p] {
+			kept = append(kept, p)
+		}
+	}
+	g.Providers = kept
+}
+
+// describe explains a resolved duplicate for --verbose.
+func (s Shadowed) describe(cfg *Config) string {
+	reason := map[string]string{
+		PrecedenceOverride:  "it has //autodi:override",
+		PrecedenceAnnotated: "it has //autodi:bind or //autodi:primary",
+		PrecedenceInternal:  "internal/ beats pkg/",
+	}[s.Rule]
+	return fmt.Sprintf("%s: %s wins over %s (%s)", toShortTypeName(s.TypeStr), cfg.providerRef(s.Winner), cfg.providerRef(s.Loser), reason)
+}
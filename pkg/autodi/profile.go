@@ -0,0 +1,132 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Startup report destinations for --profile-init.
+const (
+	ProfileInitStderr = "stderr" // slowest constructors and total init time on stderr
+	ProfileInitOTel   = "otel"   // one span per constructor under an autodi.init span
+)
+
+// validateProfileInit checks the --profile-init value.
+func validateProfileInit(mode string) error {
+	switch mode {
+	case "", ProfileInitStderr, ProfileInitOTel:
+		return nil
+	}
+	return fmt.Errorf("--profile-init=%s: want %s or %s", mode, ProfileInitStderr, ProfileInitOTel)
+}
+
+// beginInitProfile starts timing an init function when --profile-init or
+// --inspect is set.
+func (cg *CodeGen) beginInitProfile(buf *bytes.Buffer, cmd *DiscoveredCommand, usedVars map[string]bool) {
+	if cg.cfg.ProfileInit == "" && !cg.cfg.Inspect {
+		return
+	}
+	cg.profVar = cg.uniqueLocalVar("initProf", usedVars)
+	cg.hasInitProfile = true
+	fmt.Fprintf(buf, "\t%s := newInitProfile(%q)\n", cg.profVar, cmd.Name)
+}
+
+// endInitProfile reports the timings once every provider is constructed.
+func (cg *CodeGen) endInitProfile(buf *bytes.Buffer) {
+	if cg.profVar == "" {
+		return
+	}
+	if cg.cfg.ProfileInit != "" {
+		fmt.Fprintf(buf, "\t%s.report()\n", cg.profVar)
+	}
+	if cg.cfg.Inspect {
+		fmt.Fprintf(buf, "\tlastInitProfile = %s\n", cg.profVar)
+	}
+	cg.profVar = ""
+}
+
+// profileStart and profileDone bracket a constructor call, for
+// --profile-init and //autodi:metrics.
+func (cg *CodeGen) profileStart(buf *bytes.Buffer) {
+	if cg.profVar != "" {
+		fmt.Fprintf(buf, "\t%s.start()\n", cg.profVar)
+	}
+	if cg.metricsVar != "" {
+		fmt.Fprintf(buf, "\t%s.start()\n", cg.metricsVar)
+	}
+}
+
+func (cg *CodeGen) profileDone(buf *bytes.Buffer, p *Provider) {
+	if cg.profVar != "" {
+		fmt.Fprintf(buf, "\t%s.done(%q)\n", cg.profVar, p.PkgName+"."+p.FuncName)
+	}
+	if cg.metricsVar != "" {
+		fmt.Fprintf(buf, "\t%s.constructed(%q)\n", cg.metricsVar, cg.graph.metricsField(p))
+	}
+}
+
+// writeInitProfileHelper emits the initProfile type the init functions use.
+func (cg *CodeGen) writeInitProfileHelper(buf *bytes.Buffer) {
+	cg.imports.Add("time", "time")
+	buf.WriteString("// initProfile times constructor calls of one init function (autodi --profile-init, --inspect).\n")
+	buf.WriteString("type initProfile struct {\n")
+	buf.WriteString("\tcommand string\n")
+	buf.WriteString("\tbegin   time.Time\n")
+	buf.WriteString("\tlast    time.Time\n")
+	buf.WriteString("\tcalls   []initTiming\n")
+	buf.WriteString("}\n\n")
+	buf.WriteString("type initTiming struct {\n")
+	buf.WriteString("\tname  string\n")
+	buf.WriteString("\tstart time.Time\n")
+	buf.WriteString("\ttook  time.Duration\n")
+	buf.WriteString("}\n\n")
+	buf.WriteString("func newInitProfile(command string) *initProfile {\n")
+	buf.WriteString("\treturn &initProfile{command: command, begin: time.Now()}\n")
+	buf.WriteString("}\n\n")
+	buf.WriteString("func (p *initProfile) start() { p.last = time.Now() }\n\n")
+	buf.WriteString("func (p *initProfile) done(name string) {\n")
+	buf.WriteString("\tp.calls = append(p.calls, initTiming{name: name, start: p.last, took: time.Since(p.last)})\n")
+	buf.WriteString("}\n")
+
+	if cg.cfg.Inspect {
+		buf.WriteString("\n")
+		cg.writeInspectHelper(buf)
+	}
+	if cg.cfg.ProfileInit == "" {
+		return
+	}
+	buf.WriteString("\n")
+
+	if cg.cfg.ProfileInit == ProfileInitOTel {
+		cg.imports.Add("context", "context")
+		otel := cg.imports.Add("go.opentelemetry.io/otel", "otel")
+		trace := cg.imports.Add("go.opentelemetry.io/otel/trace", "trace")
+		buf.WriteString("// report records an autodi.init span with one child span per constructor.\n")
+		buf.WriteString("func (p *initProfile) report() {\n")
+		fmt.Fprintf(buf, "\ttracer := %s.Tracer(\"autodi\")\n", otel)
+		fmt.Fprintf(buf, "\tctx, span := tracer.Start(context.Background(), \"autodi.init \"+p.command, %s.WithTimestamp(p.begin))\n", trace)
+		buf.WriteString("\tfor _, c := range p.calls {\n")
+		fmt.Fprintf(buf, "\t\t_, s := tracer.Start(ctx, c.name, %s.WithTimestamp(c.start))\n", trace)
+		fmt.Fprintf(buf, "\t\ts.End(%s.WithTimestamp(c.start.Add(c.took)))\n", trace)
+		buf.WriteString("\t}\n")
+		buf.WriteString("\tspan.End()\n")
+		buf.WriteString("}\n")
+		return
+	}
+
+	cg.imports.Add("fmt", "fmt")
+	cg.imports.Add("os", "os")
+	cg.imports.Add("sort", "sort")
+	buf.WriteString("// report prints the total init time and the slowest constructors to stderr.\n")
+	buf.WriteString("func (p *initProfile) report() {\n")
+	buf.WriteString("\ttotal := time.Since(p.begin)\n")
+	buf.WriteString("\tsort.SliceStable(p.calls, func(i, j int) bool { return p.calls[i].took > p.calls[j].took })\n")
+	buf.WriteString("\tfmt.Fprintf(os.Stderr, \"autodi: %s init %s (%d constructors)\\n\", p.command, total.Round(time.Microsecond), len(p.calls))\n")
+	buf.WriteString("\tfor i, c := range p.calls {\n")
+	buf.WriteString("\t\tif i == 10 {\n")
+	buf.WriteString("\t\t\tbreak\n")
+	buf.WriteString("\t\t}\n")
+	buf.WriteString("\t\tfmt.Fprintf(os.Stderr, \"  %10s  %s\\n\", c.took.Round(time.Microsecond), c.name)\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("}\n")
+}
@@ -1,4 +1,4 @@
-package main
+package autodi
 
 import (
 	"go/token"
@@ -11,24 +11,55 @@ type Provider struct {
 	FuncName    string         // e.g., "NewIAM"
 	PkgPath     string         // e.g., "github.com/LeaflowNET/cloud/internal/services/iam"
 	PkgName     string         // e.g., "iam"
+	Module      string         // owning module (differs from the main one for go.work members)
 	Params      []TypeRef      // input parameters (dependencies)
 	Returns     []TypeRef      // return values (provided types)
 	HasError    bool           // last return is error
 	IsInvoke    bool           // call-only, no stored result
 	Annotations []Annotation   // parsed //autodi: directives
+	ReplayFunc  string         // recorded-fake constructor (from //autodi:replayable)
+	Secrets     []SecretField  // //autodi:secret fields of the returned struct
+	ExtraDeps   []string       // types constructed first but not passed as arguments
+	As          []string       // interface types the first return is provided as (//autodi:as)
+	Env         []string       // environment variables read (env tags, //autodi:env)
+	When        *WhenCond      // construction condition (from //autodi:when)
+	Order       int            // position among group/slice members (from //autodi:order)
+	Scope       string         // "" = singleton, ScopeRequest (from //autodi:scope)
+	Config      *ConfigStruct  // generated env/flag loader (//autodi:config); PkgPath is ""
+	Recv        string         // receiver type of a //autodi:factory method, also Params[0]
+	Routes      []*Provider    // handlers registered on the generated route mux (//autodi:route); PkgPath is ""
+	Jobs        []*Provider    // jobs added to the generated cron (groups of jobs); PkgPath is ""
+	Logger      string         // "slog" or "zap": generated default logger; PkgPath is ""
 	Position    token.Position // source location for errors
 
 	// Resolved during graph building
-	Groups []string // group memberships
+	Groups []string         // group memberships
+	AsAt   map[int][]string // interfaces later returns are provided as (//autodi:as <index|result>=<Interface>)
 }
 
 // TypeRef describes a single type in a provider's signature.
 type TypeRef struct {
 	Type     types.Type
-	TypeStr  string // qualified string like "*ent.Client", "iam.AuthN"
-	PkgPath  string // package path for this type
-	IsIface  bool   // whether this is an interface type
-	Optional bool   // from //autodi:optional
+	TypeStr  string      // qualified string like "*ent.Client", "iam.AuthN"
+	PkgPath  string      // package path for this type
+	IsIface  bool        // whether this is an interface type
+	Optional bool        // from //autodi:optional
+	Name     string      // parameter name, for directives that refer to it
+	Stream   string      // stdin, stdout, stderr or args: filled from the process, not a provider
+	Flags    []FlagField // fields of an //autodi:flags struct, with Stream flagsStream
+	BindTo   string      // implementation from //autodi:bind-param or an inline autodi:bind=
+
+	// Set for fields of an //autodi:in parameter object, which are flattened
+	// into Params; consecutive fields sharing InStruct form one argument.
+	InField  string
+	InStruct *TypeRef
+
+	// Set for fields of an //autodi:out result object, which are flattened
+	// into Returns; the call assigns the struct and fields are read from it.
+	OutField  string
+	OutStruct *TypeRef
+	OutGroup  string // group:"name" tag
+	OutName   string // name:"..." tag (rejected)
 }
 
 // RelPath returns the relative package path within the module.
@@ -39,20 +70,55 @@ func (p *Provider) RelPath(module string) string {
 // FieldName generates a Container field name for this provider's return type.
 // Uses the package short name + type name to produce unique, readable names.
 func FieldName(typeStr string) string {
-	s := typeStr
-	s = strings.TrimPrefix(s, "*")
+	if isFuncOrChanType(typeStr) {
+		return funcOrChanFieldName(typeStr)
+	}
+	_, pkg, typeName := fieldParts(typeStr)
+	if pkg == "" {
+		return exportName(typeName)
+	}
+
+	// If type name already incorporates the package name, skip prefix
+	// e.g., pkg="iam", name="IAM" → just "IAM"
+	// e.g., pkg="redisx", name="Locker" → "RedisxLocker"
+	// e.g., pkg="ent", name="Client" → "EntClient"
+	if strings.EqualFold(pkg, typeName) {
+		return exportName(typeName)
+	}
+	if len(typeName) > len(pkg) && strings.EqualFold(typeName[:len(pkg)], pkg) {
+		return exportName(typeName)
+	}
+	return exportName(pkg) + exportName(typeName)
+}
+
+// funcOrChanFieldName names a function or channel type without a provider to
+// name it after: BusEventChan, BusEventRecvChan, BusEventSendChan, Func.
+func funcOrChanFieldName(typeStr string) string {
+	for _, c := range []struct{ prefix, suffix string }{{"chan ", "Chan"}, {"<-chan ", "RecvChan"}, {"chan<- ", "SendChan"}} {
+		if elem, ok := strings.CutPrefix(typeStr, c.prefix); ok {
+			return FieldName(elem) + c.suffix
+		}
+	}
+	return "Func"
+}
+
+// fieldParts splits a type string into the parts field names are built
+// from: the package path, its short name ("" for unqualified types) and the
+// type name.
+func fieldParts(typeStr string) (pkgPath, pkg, typeName string) {
+	s := strings.TrimPrefix(typeStr, "*")
 
 	// Split into package path and type name at the last dot
 	dotIdx := strings.LastIndex(s, ".")
 	if dotIdx < 0 {
-		return exportName(s)
+		return "", "", s
 	}
 
-	pkgPath := s[:dotIdx]
-	typeName := s[dotIdx+1:]
+	pkgPath = s[:dotIdx]
+	typeName = s[dotIdx+1:]
 
 	// Get short package name
-	pkg := pkgPath
+	pkg = pkgPath
 	if idx := strings.LastIndex(pkg, "/"); idx >= 0 {
 		pkg = pkg[idx+1:]
 	}
@@ -66,18 +132,7 @@ func FieldName(typeStr string) string {
 			}
 		}
 	}
-
-	// If type name already incorporates the package name, skip prefix
-	// e.g., pkg="iam", name="IAM" → just "IAM"
-	// e.g., pkg="redisx", name="Locker" → "RedisxLocker"
-	// e.g., pkg="ent", name="Client" → "EntClient"
-	if strings.EqualFold(pkg, typeName) {
-		return exportName(typeName)
-	}
-	if len(typeName) > len(pkg) && strings.EqualFold(typeName[:len(pkg)], pkg) {
-		return exportName(typeName)
-	}
-	return exportName(pkg) + exportName(typeName)
+	return pkgPath, pkg, typeName
 }
 
 // exportName ensures first letter is uppercase.
@@ -138,3 +193,15 @@ func typePkgPath(t types.Type) string {
 	}
 	return ""
 }
+
+// misplacedError returns the index of an error result that isn't the last
+// one, or -1.
+func misplacedError(sig *types.Signature) int {
+	results := sig.Results()
+	for i := 0; i < results.Len()-1; i++ {
+		if isErrorType(results.At(i).Type()) {
+			return i
+		}
+	}
+	return -1
+}
@@ -1,16 +1,16 @@
-package main
+package autodi
 
 import (
 	"fmt"
 	"go/types"
 	"os"
-	"strings"
 )
 
 // FilterReachable returns only providers reachable from command entry points.
 // A provider is reachable if its return type is consumed (directly or transitively)
 // as a parameter by a command or another reachable provider.
-// Pinned: //autodi:bind, //autodi:invoke, and group-path providers are always included.
+// Pinned: //autodi:bind, //autodi:invoke, //autodi:scope, //autodi:group, //autodi:migrate and group-path providers are always included,
+// SecretsSource providers once a reachable provider has //autodi:secret fields.
 func FilterReachable(
 	candidates []*Provider,
 	commands []*DiscoveredCommand,
@@ -20,6 +20,7 @@ func FilterReachable(
 ) []*Provider {
 	// Pre-build type index from candidates for O(1) interface lookup
 	candidateTypeIndex := make(map[string]*types.Interface)
+	impls := newImplMatrix()
 	for _, p := range candidates {
 		for _, param := range p.Params {
 			if param.IsIface {
@@ -27,10 +28,10 @@ func FilterReachable(
 					candidateTypeIndex[param.TypeStr] = iface
 				}
 			}
-			if strings.HasPrefix(param.TypeStr, "[]") {
-				if sl, ok := param.Type.Underlying().(*types.Slice); ok {
-					if iface, ok := sl.Elem().Underlying().(*types.Interface); ok {
-						candidateTypeIndex[param.TypeStr[2:]] = iface
+			if elemStr, _, ok := collectedElem(param.TypeStr); ok {
+				if elem := collectedElemType(param.Type); elem != nil {
+					if iface, ok := elem.Underlying().(*types.Interface); ok {
+						candidateTypeIndex[elemStr] = iface
 					}
 				}
 			}
@@ -55,26 +56,54 @@ func FilterReachable(
 	reachable := make(map[*Provider]bool)       // result set
 	var queue []string                          // BFS queue of needed typeStrs
 
-	for _, p := range candidates {
-		// Pin annotated providers
-		if HasAnnotation(p.Annotations, AnnotBind) || HasAnnotation(p.Annotations, AnnotInvoke) {
-			if !reachable[p] {
-				reachable[p] = true
-				for _, param := range p.Params {
-					queue = append(queue, param.TypeStr)
+	// mark adds p to the result and queues its parameters. The first
+	// reachable provider with //autodi:secret fields pins the SecretsSource
+	// providers, which nothing consumes directly.
+	secretsPinned := false
+	var mark func(p *Provider)
+	mark = func(p *Provider) {
+		if reachable[p] {
+			return
+		}
+		reachable[p] = true
+		for _, param := range p.Params {
+			queue = append(queue, param.TypeStr)
+		}
+		if len(p.Secrets) == 0 || secretsPinned {
+			return
+		}
+		secretsPinned = true
+		for _, src := range candidates {
+			for _, ret := range src.Returns {
+				if isSecretsSource(ret.Type) {
+					mark(src)
+					break
 				}
 			}
 		}
+	}
+
+	for _, p := range candidates {
+		// Pin annotated providers
+		if HasAnnotation(p.Annotations, AnnotBind) || HasAnnotation(p.Annotations, AnnotInvoke) || HasAnnotation(p.Annotations, AnnotScope) ||
+			HasAnnotation(p.Annotations, AnnotGroup) || HasAnnotation(p.Annotations, AnnotMigrate) || cfg.isLambdaHandler(p) {
+			mark(p)
+		}
 
 		// Pin group-path providers
-		rel := p.RelPath(cfg.Module)
 		for _, groupCfg := range cfg.Groups {
 			for _, gpath := range groupCfg.Paths {
-				if strings.HasPrefix(rel, gpath) && !reachable[p] {
-					reachable[p] = true
-					for _, param := range p.Params {
-						queue = append(queue, param.TypeStr)
-					}
+				if cfg.inGroupPath(p.PkgPath, gpath) {
+					mark(p)
+				}
+			}
+		}
+
+		// Pin //autodi:replace replacements, which nothing consumes directly
+		for _, s := range cfg.Substitutions {
+			for _, ret := range p.Returns {
+				if namesType(s.New, ret.TypeStr) {
+					mark(p)
 				}
 			}
 		}
@@ -105,12 +134,7 @@ func FilterReachable(
 		// A) Direct concrete match
 		if providers, ok := returnIndex[typeStr]; ok {
 			for _, p := range providers {
-				if !reachable[p] {
-					reachable[p] = true
-					for _, param := range p.Params {
-						queue = append(queue, param.TypeStr)
-					}
-				}
+				mark(p)
 			}
 			continue
 		}
@@ -119,11 +143,8 @@ func FilterReachable(
 		if iface, ok := candidateTypeIndex[typeStr]; ok {
 			for _, p := range candidates {
 				for _, ret := range p.Returns {
-					if implementsIface(ret.Type, iface) && !reachable[p] {
-						reachable[p] = true
-						for _, param := range p.Params {
-							queue = append(queue, param.TypeStr)
-						}
+					if !reachable[p] && impls.implementsIface(ret.Type, ret.TypeStr, iface, typeStr) {
+						mark(p)
 						break
 					}
 				}
@@ -131,17 +152,13 @@ func FilterReachable(
 			continue
 		}
 
-		// C) Slice-of-interface ([]SomeIface) → include ALL implementors
-		if strings.HasPrefix(typeStr, "[]") {
-			elemStr := typeStr[2:]
+		// C) Slice-of-interface ([]SomeIface) or keyed map → include ALL implementors
+		if elemStr, _, ok := collectedElem(typeStr); ok {
 			if iface, ok := candidateTypeIndex[elemStr]; ok {
 				for _, p := range candidates {
 					for _, ret := range p.Returns {
-						if implementsIface(ret.Type, iface) && !reachable[p] {
-							reachable[p] = true
-							for _, param := range p.Params {
-								queue = append(queue, param.TypeStr)
-							}
+						if !reachable[p] && impls.implementsIface(ret.Type, ret.TypeStr, iface, elemStr) {
+							mark(p)
 							break
 						}
 					}
@@ -188,9 +205,9 @@ func findIfaceFromCandidates(typeStr string, candidates []*Provider, ifaceTypes
 					return iface
 				}
 			}
-			if strings.HasPrefix(param.TypeStr, "[]") && param.TypeStr[2:] == typeStr {
-				if sl, ok := param.Type.Underlying().(*types.Slice); ok {
-					if iface, ok := sl.Elem().Underlying().(*types.Interface); ok {
+			if elemStr, _, ok := collectedElem(param.TypeStr); ok && elemStr == typeStr {
+				if elem := collectedElemType(param.Type); elem != nil {
+					if iface, ok := elem.Underlying().(*types.Interface); ok {
 						return iface
 					}
 				}
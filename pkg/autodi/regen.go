@@ -0,0 +1,412 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// genSection is a top-level span of a generated Go file (import block or declaration).
+type genSection struct {
+	Key        string // "import", "func initAPI", "var x", …
+	Start, End int    // byte offsets, Start includes the doc comment
+}
+
+// mergeGenerated splices freshly generated Go source into the previously
+// generated file, rewriting only the top-level sections whose content changed.
+// Unchanged sections keep their original bytes, so committed generated code
+// produces minimal diffs when only one command's wiring changes.
+//
+// Returns the merged source and the keys of rewritten sections. Falls back to
+// the fresh source when the old file can't be parsed or when the set of
+// top-level declarations differs (added, removed or reordered).
+func mergeGenerated(old, fresh []byte) ([]byte, []string) {
+	oldSecs, ok := splitSections(old)
+	if !ok {
+		return fresh, []string{"*"}
+	}
+	newSecs, ok := splitSections(fresh)
+	if !ok || len(oldSecs) != len(newSecs) || len(oldSecs) == 0 {
+		return fresh, []string{"*"}
+	}
+	for i := range oldSecs {
+		if oldSecs[i].Key != newSecs[i].Key {
+			return fresh, []string{"*"}
+		}
+	}
+
+	var out bytes.Buffer
+	var changed []string
+
+	// File header: generated comment + package clause
+	if bytes.Equal(old[:oldSecs[0].Start], fresh[:newSecs[0].Start]) {
+		out.Write(old[:oldSecs[0].Start])
+	} else {
+		out.Write(fresh[:newSecs[0].Start])
+		changed = append(changed, "header")
+	}
+
+	// Comments between declarations, like the end markers of command
+	// regions, are taken from the fresh source when they differ
+	commented := false
+	gap := func(oldText, newText []byte) {
+		if bytes.Equal(oldText, newText) {
+			out.Write(oldText)
+			return
+		}
+		out.Write(newText)
+		if !commented {
+			changed = append(changed, "comments")
+			commented = true
+		}
+	}
+
+	for i := range oldSecs {
+		if i > 0 {
+			gap(old[oldSecs[i-1].End:oldSecs[i].Start], fresh[newSecs[i-1].End:newSecs[i].Start])
+		}
+		oldSec, newSec := oldSecs[i], newSecs[i]
+		oldText := old[oldSec.Start:oldSec.End]
+		newText := fresh[newSec.Start:newSec.End]
+		if sameSection(oldText, newText) {
+			out.Write(oldText)
+			continue
+		}
+		out.Write(newText)
+		changed = append(changed, oldSec.Key)
+	}
+
+	gap(old[oldSecs[len(oldSecs)-1].End:], fresh[newSecs[len(newSecs)-1].End:])
+	return out.Bytes(), changed
+}
+
+// splitSections parses src and returns its top-level sections in source order.
+func splitSections(src []byte) ([]genSection, bool) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, false
+	}
+	file := fset.File(f.Pos())
+
+	var secs []genSection
+	for _, decl := range f.Decls {
+		start := decl.Pos()
+		var key string
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			key = "func " + d.Name.Name
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				key = "method " + recvTypeName(d.Recv.List[0].Type) + "." + d.Name.Name
+			}
+		case *ast.GenDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			key = d.Tok.String()
+			if d.Tok != token.IMPORT && len(d.Specs) > 0 {
+				key += " " + specName(d.Specs[0])
+			}
+		}
+		secs = append(secs, genSection{
+			Key:   key,
+			Start: file.Offset(start),
+			End:   file.Offset(decl.End()),
+		})
+	}
+	return secs, true
+}
+
+// sameSection reports whether two declaration snippets are identical once
+// normalized by gofmt, so purely cosmetic differences don't force a rewrite.
+func sameSection(a, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	fa, errA := format.Source(a)
+	fb, errB := format.Source(b)
+	return errA == nil && errB == nil && bytes.Equal(fa, fb)
+}
+
+// specName returns the first declared name of a type/var/const spec.
+func specName(spec ast.Spec) string {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return s.Name.Name
+	case *ast.ValueSpec:
+		if len(s.Names) > 0 {
+			return s.Names[0].Name
+		}
+	}
+	return ""
+}
+
+// recvTypeName renders a receiver type expression ("*T" or "T").
+func recvTypeName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + recvTypeName(e.X)
+	case *ast.Ident:
+		return e.Name
+	case *ast.IndexExpr:
+		return recvTypeName(e.X)
+	}
+	return "?"
+}
+
+// Generated code of one command (its init function and its registration in
+// the entrypoint) is delimited by marker comments, so --only can regenerate
+// it alone:
+//
+//	// autodi:begin serve
+//	func initServe(cmd, top *cobra.Command) (func(), error) {
+//	…
+//	// autodi:end serve
+var regionRe = regexp.MustCompile(`(?m)^[ \t]*// autodi:(begin|end) (\S+)[ \t]*$\n?`)
+
+// regionBegin and regionEnd return the marker lines of a command's region.
+func regionBegin(name string) string { return "// autodi:begin " + name + "\n" }
+func regionEnd(name string) string   { return "// autodi:end " + name + "\n" }
+
+// span is a byte range of a source file.
+type span struct{ Start, End int }
+
+// commandRegions returns the regions of each command in src, from the start
+// of the begin marker line to the end of the end marker line.
+func commandRegions(src []byte) (map[string][]span, error) {
+	regions := make(map[string][]span)
+	open, start := "", 0
+	for _, m := range regionRe.FindAllSubmatchIndex(src, -1) {
+		kind, name := string(src[m[2]:m[3]]), string(src[m[4]:m[5]])
+		switch {
+		case kind == "begin" && open == "":
+			open, start = name, m[0]
+		case kind == "end" && open == name:
+			regions[name] = append(regions[name], span{start, m[1]})
+			open = ""
+		default:
+			return nil, fmt.Errorf("unbalanced // autodi:%s %s marker", kind, name)
+		}
+	}
+	if open != "" {
+		return nil, fmt.Errorf("// autodi:begin %s has no end marker", open)
+	}
+	return regions, nil
+}
+
+// onlyCommands narrows generated files to the code of the listed commands
+// (--only): Go files with a region of one of them get those regions from the
+// fresh source and keep everything else as it is on disk; other files are
+// left alone. local is the module path, read from go.mod when empty.
+func onlyCommands(moduleRoot, local string, files []GeneratedFile, only []string) ([]GeneratedFile, error) {
+	if local == "" {
+		if gm, err := parseGoMod(moduleRoot); err == nil {
+			local = gm.Module
+		}
+	}
+	found := make(map[string]bool)
+	var kept []GeneratedFile
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name, ".go") {
+			continue
+		}
+		freshRegions, err := commandRegions(f.Content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		old, readErr := os.ReadFile(filepath.Join(moduleRoot, f.Name))
+		oldRegions := make(map[string][]span)
+		if readErr == nil {
+			if oldRegions, err = commandRegions(old); err != nil {
+				return nil, fmt.Errorf("%s: %w\n  hint: run autodi without --only to rewrite the file", f.Name, err)
+			}
+		}
+		listed := false
+		for _, name := range only {
+			if len(freshRegions[name]) > 0 || len(oldRegions[name]) > 0 {
+				listed, found[name] = true, true
+			}
+		}
+		if !listed {
+			continue
+		}
+
+		// A new file is written when it only holds the listed commands
+		if readErr != nil {
+			for name := range freshRegions {
+				if !slices.Contains(only, name) {
+					return nil, fmt.Errorf("--only: %s does not exist yet and also holds command %s\n  hint: run autodi without --only", f.Name, name)
+				}
+			}
+			kept = append(kept, f)
+			continue
+		}
+
+		content, shared, err := mergeOnly(old, f.Content, oldRegions, freshRegions, only, local)
+		if err != nil {
+			return nil, fmt.Errorf("--only: %s: %w", f.Name, err)
+		}
+		if shared {
+			fmt.Fprintf(os.Stderr, "autodi: warning: %s: generated code shared with other commands than %s changed and was left as it is; run autodi without --only to update it\n",
+				f.Name, strings.Join(only, ", "))
+		}
+		kept = append(kept, GeneratedFile{Name: f.Name, Content: content})
+	}
+	for _, name := range only {
+		if !found[name] {
+			return nil, fmt.Errorf("--only %s: no generated code belongs to this command\n  hint: name commands like their cmd/<name> package; a new command needs a run without --only", name)
+		}
+	}
+	return kept, nil
+}
+
+// mergeOnly replaces the regions of the listed commands in old with those of
+// fresh and keeps the imports either version needs. It also reports whether
+// the code shared by every command differs between the two.
+func mergeOnly(old, fresh []byte, oldRegions, freshRegions map[string][]span, only []string, local string) ([]byte, bool, error) {
+	type replacement struct {
+		old  span
+		text []byte
+	}
+	var reps []replacement
+	for _, name := range only {
+		o, f := oldRegions[name], freshRegions[name]
+		switch {
+		case len(f) == 0:
+			// The command is gone: drop its code
+			for _, s := range o {
+				reps = append(reps, replacement{old: s})
+			}
+		case len(o) != len(f):
+			return nil, false, fmt.Errorf("the code of command %s moved or is new (%d regions on disk, %d generated)\n  hint: run autodi without --only", name, len(o), len(f))
+		default:
+			for i := range o {
+				reps = append(reps, replacement{old: o[i], text: fresh[f[i].Start:f[i].End]})
+			}
+		}
+	}
+	slices.SortFunc(reps, func(a, b replacement) int { return a.old.Start - b.old.Start })
+
+	var merged bytes.Buffer
+	pos := 0
+	for _, r := range reps {
+		merged.Write(old[pos:r.old.Start])
+		merged.Write(r.text)
+		pos = r.old.End
+	}
+	merged.Write(old[pos:])
+
+	out, err := mergeImports(merged.Bytes(), old, fresh, local)
+	if err != nil {
+		return nil, false, err
+	}
+	shared := !bytes.Equal(withoutRegions(old, oldRegions), withoutRegions(fresh, freshRegions))
+	return out, shared, nil
+}
+
+// withoutRegions returns src without its command regions, header comment and
+// import block: the code every command shares.
+func withoutRegions(src []byte, regions map[string][]span) []byte {
+	var cut []span
+	for _, spans := range regions {
+		cut = append(cut, spans...)
+	}
+	if f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ImportsOnly); err == nil {
+		end := int(f.Package) - 1
+		if len(f.Decls) > 0 {
+			end = int(f.Decls[len(f.Decls)-1].End()) - 1
+		}
+		cut = append(cut, span{0, end})
+	}
+	slices.SortFunc(cut, func(a, b span) int { return a.Start - b.Start })
+	var out bytes.Buffer
+	pos := 0
+	for _, s := range cut {
+		if s.Start > pos {
+			out.Write(src[pos:s.Start])
+		}
+		pos = max(pos, s.End)
+	}
+	out.Write(src[pos:])
+	return out.Bytes()
+}
+
+// mergeImports rewrites the import block of merged with the imports of old
+// and fresh that it still uses, grouped like FormatBlock with local as the
+// module, and formats the result.
+func mergeImports(merged, old, fresh []byte, local string) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", merged, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("merged source: %w\n  hint: run autodi without --only", err)
+	}
+	used := make(map[string]bool)
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				used[id.Name] = true
+			}
+		}
+		return true
+	})
+
+	// path → alias ("" for none); an import both versions have is kept even
+	// when its package name doesn't match its path
+	imports := make(map[string]string)
+	inBoth := make(map[string]bool)
+	for i, src := range [][]byte{old, fresh} {
+		sf, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+		for _, spec := range sf.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			alias := ""
+			if spec.Name != nil {
+				alias = spec.Name.Name
+			}
+			if _, ok := imports[path]; ok && i == 1 {
+				inBoth[path] = true
+			}
+			imports[path] = alias
+		}
+	}
+	for path, alias := range imports {
+		name := alias
+		if name == "" {
+			name = pkgShortName(path)
+		}
+		if !used[name] && !inBoth[path] {
+			delete(imports, path)
+		}
+	}
+	block := formatImportBlock(imports, local)
+
+	var out bytes.Buffer
+	start, end := len(merged), len(merged)
+	for _, decl := range f.Decls {
+		if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.IMPORT {
+			start, end = fset.Position(gd.Pos()).Offset, fset.Position(gd.End()).Offset
+			break
+		}
+	}
+	if start == len(merged) {
+		return format.Source(merged)
+	}
+	out.Write(merged[:start])
+	out.WriteString(block)
+	out.Write(merged[end:])
+	return format.Source(out.Bytes())
+}
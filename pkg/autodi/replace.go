@@ -0,0 +1,103 @@
+package autodi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Substitution is a generate.go //autodi:replace directive:
+//
+//	//autodi:replace old=github.com/org/app/internal/db.Store new=github.com/org/app/internal/fakedb.Store
+//
+// Every consumer of Old gets the value of New's provider instead, without
+// editing either constructor. New must be Old or implement it.
+type Substitution struct {
+	Old string
+	New string
+}
+
+// parseSubstitution parses the options of a //autodi:replace directive.
+func parseSubstitution(fields []string) (Substitution, error) {
+	var s Substitution
+	for _, opt := range fields {
+		key, val, _ := strings.Cut(opt, "=")
+		switch {
+		case key == "old" && val != "":
+			s.Old = val
+		case key == "new" && val != "":
+			s.New = val
+		default:
+			return s, fmt.Errorf("//autodi:replace: unknown option %q (want old=<Type> new=<Type>)", opt)
+		}
+	}
+	if s.Old == "" || s.New == "" {
+		return s, fmt.Errorf("//autodi:replace needs old=<Type> and new=<Type>")
+	}
+	return s, nil
+}
+
+// namesType reports whether typeStr is the type a directive names, in full or
+// package-qualified short form.
+func namesType(name, typeStr string) bool {
+	return typeStr == name || toShortTypeName(typeStr) == name
+}
+
+// applySubstitutions points each replaced type at the provider of its
+// replacement. Runs after interface bindings are resolved.
+func (g *Graph) applySubstitutions() []error {
+	var errs []error
+	for _, s := range g.cfg.Substitutions {
+		oldStr := g.resolveConfigType(s.Old)
+		newStr := g.resolveConfigType(s.New)
+		_, provided := g.ProviderMap[oldStr]
+		_, bound := g.Bindings[oldStr]
+		if !provided && !bound {
+			errs = append(errs, fmt.Errorf("generate.go: //autodi:replace old=%s: no provider supplies that type", s.Old))
+			continue
+		}
+		p := g.ProviderMap[g.resolveType(newStr)]
+		if p == nil {
+			errs = append(errs, fmt.Errorf("generate.go: //autodi:replace new=%s: no provider supplies that type", s.New))
+			continue
+		}
+		if newStr != oldStr {
+			iface := g.findIfaceType(oldStr)
+			ret := p.Returns[0]
+			for _, r := range p.Returns {
+				if r.TypeStr == g.resolveType(newStr) {
+					ret = r
+				}
+			}
+			if iface == nil || !implementsIface(ret.Type, iface) {
+				errs = append(errs, fmt.Errorf("generate.go: //autodi:replace: %s doesn't implement %s\n  hint: the replacement must be the same type or implement an interface the consumers take",
+					toShortTypeName(ret.TypeStr), toShortTypeName(oldStr)))
+				continue
+			}
+		}
+
+		g.ProviderMap[oldStr] = p
+		g.TypeToField[oldStr] = g.fieldName(oldStr)
+		delete(g.Bindings, oldStr)
+		g.BindSource[oldStr] = BindReplace
+		if !p.providesAs(oldStr) {
+			p.As = append(p.As, oldStr)
+		}
+	}
+	g.rebuildSortedTypes()
+	return errs
+}
+
+// providesAs reports whether a provider returns typeStr or is registered as it.
+func (p *Provider) providesAs(typeStr string) bool {
+	for _, ret := range p.Returns {
+		if ret.TypeStr == typeStr {
+			return true
+		}
+	}
+	for _, as := range p.allAs() {
+		if as == typeStr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package autodi
+
+import (
+	"fmt"
+	"go/types"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// AnnotOut marks a struct type as a result object:
+//
+//	//autodi:out
+//	type Clients struct {
+//		Reader *db.Reader
+//		Writer *db.Writer
+//	}
+//
+//	func NewClients(cfg *Config) (Clients, error)
+//
+// A constructor returning a result object provides each exported field as a
+// separate type. A `group:"name"` tag contributes the field to a group instead;
+// all fields of such a struct must then carry a group tag. Embedding fx.Out (or
+// an Out type from a package named autodi) has the same effect.
+const AnnotOut = "out"
+
+// resultObject returns the struct of a result object type, or nil.
+func (s *Scanner) resultObject(t types.Type) *types.Struct {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return nil
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		if f := st.Field(i); f.Embedded() && isMarker(f.Type(), "Out") {
+			return st
+		}
+	}
+	if pkg := s.pkgsByPath[named.Obj().Pkg().Path()]; pkg != nil && typeHasDirective(pkg, named.Obj().Name(), AnnotOut) {
+		return st
+	}
+	return nil
+}
+
+// resultObjectFields flattens a result object into one TypeRef per exported field.
+func resultObjectFields(object TypeRef, st *types.Struct) []TypeRef {
+	var refs []TypeRef
+	obj := &object
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		if !f.Exported() || (f.Embedded() && isMarker(f.Type(), "Out")) {
+			continue
+		}
+		tag := reflect.StructTag(st.Tag(i))
+		t := resolveAliases(f.Type())
+		refs = append(refs, TypeRef{
+			Type:      t,
+			TypeStr:   types.TypeString(t, nil),
+			PkgPath:   typePkgPath(t),
+			IsIface:   isInterface(t),
+			OutField:  f.Name(),
+			OutStruct: obj,
+			OutGroup:  tag.Get("group"),
+			OutName:   tag.Get("name"),
+		})
+	}
+	return refs
+}
+
+// resultObjectGroups applies group tags of a provider's result object: the
+// provider joins every tagged group. Mixing grouped and ungrouped fields, and
+// named values, are not supported.
+func resultObjectGroups(p *Provider) error {
+	grouped := 0
+	for _, ret := range p.Returns {
+		if ret.OutStruct == nil {
+			return nil
+		}
+		if ret.OutName != "" {
+			return fmt.Errorf("%s: %s.%s: result field %s: named values (name:%q) are not supported\n  hint: give each value its own type",
+				p.Position, p.PkgName, p.FuncName, ret.OutField, ret.OutName)
+		}
+		if ret.OutGroup == "" {
+			continue
+		}
+		grouped++
+		if !slices.Contains(p.Groups, ret.OutGroup) {
+			p.Groups = append(p.Groups, ret.OutGroup)
+		}
+	}
+	if grouped > 0 && grouped < len(p.Returns) {
+		return fmt.Errorf("%s: %s.%s: result struct mixes group-tagged and plain fields\n  hint: split it into two constructors",
+			p.Position, p.PkgName, p.FuncName)
+	}
+	return nil
+}
+
+// resultLHS adapts the per-return variables of a call to a result object
+// provider: the call assigns the struct, and post copies the used fields out.
+func (cg *CodeGen) resultLHS(p *Provider, lhs []string, usedVars map[string]bool) ([]string, string) {
+	if len(p.Returns) == 0 || p.Returns[0].OutStruct == nil {
+		return lhs, ""
+	}
+	outVar := cg.uniqueLocalVar(localVarName(p.FuncName)+"Out", usedVars)
+	var post strings.Builder
+	for i, name := range lhs {
+		if name != "_" {
+			fmt.Fprintf(&post, "\t%s := %s.%s\n", name, outVar, p.Returns[i].OutField)
+		}
+	}
+	if post.Len() == 0 {
+		return []string{"_"}, ""
+	}
+	return []string{outVar}, post.String()
+}
@@ -0,0 +1,207 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"go/types"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// AnnotRoute registers a member of an http.Handler group on the generated
+// *http.ServeMux:
+//
+//	//autodi:group api []http.Handler internal/api   (generate.go)
+//
+//	//autodi:route GET /users/{id}
+//	func NewUserHandler(db *ent.Client) *UserHandler
+//
+// The value is a ServeMux pattern. Constructors taking *http.ServeMux get a
+// mux with every route registered, built by newRouteMux in the generated file.
+const AnnotRoute = "route"
+
+const (
+	httpHandlerType = "net/http.Handler"
+	serveMuxType    = "*net/http.ServeMux"
+	routeMuxFunc    = "newRouteMux"
+)
+
+// addRouteMux registers the provider of the route mux when a group of
+// http.Handler has members. Call after group membership is resolved.
+func (g *Graph) addRouteMux() []error {
+	var groups []string
+	for name, groupCfg := range g.cfg.Groups {
+		iface := g.resolveConfigType(groupCfg.Interface)
+		if (iface == httpHandlerType || iface == "http.Handler") && len(g.Groups[name]) > 0 {
+			groups = append(groups, name)
+		}
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	sort.Strings(groups)
+
+	var errs []error
+	var members []*Provider
+	var muxType types.Type
+	patterns := make(map[string]*Provider)
+	for _, name := range groups {
+		for _, p := range g.Groups[name] {
+			if slices.Contains(members, p) {
+				continue
+			}
+			pattern, err := routePattern(p)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if other, ok := patterns[pattern]; ok {
+				errs = append(errs, fmt.Errorf("%s: //autodi:route %s is also registered by %s.%s",
+					p.Position, pattern, other.PkgName, other.FuncName))
+				continue
+			}
+			patterns[pattern] = p
+			members = append(members, p)
+			if muxType == nil && len(p.Returns) > 0 {
+				muxType = serveMuxOf(p.Returns[0].Type)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	if muxType == nil {
+		return []error{fmt.Errorf("%s: //autodi:route: %s.%s doesn't implement http.Handler",
+			members[0].Position, members[0].PkgName, members[0].FuncName)}
+	}
+	if existing, ok := g.ProviderMap[serveMuxType]; ok {
+		return []error{fmt.Errorf("%s: %s.%s provides *http.ServeMux, which //autodi:route handlers are registered on\n  hint: remove it and take the generated mux, or drop the //autodi:route groups",
+			existing.Position, existing.PkgName, existing.FuncName)}
+	}
+
+	mux := &Provider{
+		FuncName: routeMuxFunc,
+		PkgName:  "http",
+		Returns:  []TypeRef{{Type: muxType, TypeStr: serveMuxType, PkgPath: "net/http"}},
+		Routes:   members,
+		Position: token.Position{Filename: "generate.go"},
+	}
+	seen := make(map[string]bool)
+	for _, p := range members {
+		mux.HasError = mux.HasError || p.HasError
+		for _, param := range p.Params {
+			if param.Stream != "" || seen[param.TypeStr] {
+				continue
+			}
+			seen[param.TypeStr] = true
+			mux.Params = append(mux.Params, TypeRef{
+				Type:     param.Type,
+				TypeStr:  param.TypeStr,
+				PkgPath:  param.PkgPath,
+				IsIface:  param.IsIface,
+				Optional: param.Optional,
+			})
+		}
+	}
+	g.Providers = append(g.Providers, mux)
+	g.ProviderMap[serveMuxType] = mux
+	g.TypeToField[serveMuxType] = g.fieldName(serveMuxType)
+	g.typeIndex[serveMuxType] = muxType
+	return nil
+}
+
+// routePattern returns the //autodi:route pattern of a group member.
+func routePattern(p *Provider) (string, error) {
+	values := GetAnnotationValues(p.Annotations, AnnotRoute)
+	if len(values) != 1 {
+		return "", fmt.Errorf("%s: %s.%s is in an http.Handler group and needs exactly one //autodi:route\n  hint: //autodi:route GET /path",
+			p.Position, p.PkgName, p.FuncName)
+	}
+	fields := strings.Fields(values[0])
+	if len(fields) > 2 || !strings.Contains(fields[len(fields)-1], "/") {
+		return "", fmt.Errorf("%s: //autodi:route %s: want [METHOD] /path", p.Position, values[0])
+	}
+	if len(p.Returns) != 1 {
+		return "", fmt.Errorf("%s: //autodi:route %s.%s must return a single handler", p.Position, p.PkgName, p.FuncName)
+	}
+	return strings.Join(fields, " "), nil
+}
+
+// serveMuxOf finds *http.ServeMux through the *http.Request parameter of a
+// handler's ServeHTTP method, so net/http needn't be loaded separately.
+func serveMuxOf(t types.Type) types.Type {
+	if _, ok := t.Underlying().(*types.Interface); !ok {
+		if _, ok := t.(*types.Pointer); !ok {
+			t = types.NewPointer(t)
+		}
+	}
+	mset := types.NewMethodSet(t)
+	sel := mset.Lookup(nil, "ServeHTTP")
+	if sel == nil {
+		return nil
+	}
+	sig := sel.Type().(*types.Signature)
+	if sig.Params().Len() != 2 {
+		return nil
+	}
+	ptr, ok := sig.Params().At(1).Type().(*types.Pointer)
+	if !ok {
+		return nil
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return nil
+	}
+	obj := named.Obj().Pkg().Scope().Lookup("ServeMux")
+	if obj == nil {
+		return nil
+	}
+	return types.NewPointer(obj.Type())
+}
+
+// writeRouteMux emits newRouteMux: it constructs the route handlers from its
+// parameters and registers each on a new ServeMux.
+func (cg *CodeGen) writeRouteMux(buf *bytes.Buffer, mux *Provider) error {
+	httpQualifier := cg.imports.Add("net/http", "http")
+	cg.registerProviderImports(mux.Routes)
+
+	usedVars := map[string]bool{"mux": true, "err": true}
+	varMap := make(map[string]string)
+	var params []string
+	for _, param := range mux.Params {
+		v := cg.uniqueLocalVar(localVarName(FieldName(param.TypeStr)), usedVars)
+		if cg.imports.IsQualifier(v) {
+			v = cg.uniqueLocalVar(v+"Dep", usedVars)
+		}
+		varMap[param.TypeStr] = v
+		params = append(params, v+" "+cg.shortType(param.TypeStr))
+	}
+
+	results := "*" + httpQualifier + ".ServeMux"
+	if mux.HasError {
+		cg.imports.Add("fmt", "fmt")
+		results = "(" + results + ", error)"
+	}
+	buf.WriteString("// newRouteMux registers the //autodi:route handlers on a new ServeMux.\n")
+	fmt.Fprintf(buf, "func %s(%s) %s {\n", routeMuxFunc, strings.Join(params, ", "), results)
+	fmt.Fprintf(buf, "\tmux := %s.NewServeMux()\n", httpQualifier)
+	for _, p := range mux.Routes {
+		pattern, _ := routePattern(p)
+		first := func(*Provider) ([]int, error) { return []int{0}, nil }
+		err := cg.writeMemberCalls(buf, []*Provider{p}, varMap, usedVars, first, func(_ *Provider, v string) string {
+			return fmt.Sprintf("mux.Handle(%q, %s)", pattern, v)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if mux.HasError {
+		buf.WriteString("\treturn mux, nil\n")
+	} else {
+		buf.WriteString("\treturn mux\n")
+	}
+	buf.WriteString("}\n")
+	return nil
+}
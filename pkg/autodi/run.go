@@ -0,0 +1,632 @@
+package autodi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/go/analysis/unitchecker"
+)
+
+// Main runs the autodi command line on os.Args, exiting non-zero when it
+// fails. It is the autodi binary's main function.
+func Main() {
+	if isVetInvocation(os.Args[1:]) {
+		unitchecker.Main(directiveAnalyzer)
+	}
+	root := newRootCommand()
+	root.SetArgs(normalizeLegacyFlags(os.Args[1:]))
+	if err := root.Execute(); err != nil {
+		if !errors.Is(err, errReported) {
+			fmt.Fprintf(os.Stderr, "autodi: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// Project is the analyzed module: configuration, discovered commands and the
+// validated dependency graph. It is the input to every output generator.
+type Project struct {
+	Root     string
+	Cfg      *Config
+	Commands []*DiscoveredCommand
+	Graph    *Graph
+
+	// Warnings of the analysis: exiting and deprecated constructors, stale
+	// annotations. The CLI also prints them as they are found.
+	Warnings []string
+}
+
+// analyzeProject runs the scan → detect → reachability → graph → validation
+// passes for the module containing the working directory.
+func analyzeProject(opts *Options) (_ *Project, err error) {
+	// Resolve module root: walk up from cwd to find go.mod
+	moduleRoot, err := projectRoot(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.SARIF != "" {
+		defer func() {
+			// Errors returned instead of reported, e.g. from the scan
+			if err != nil && !errors.Is(err, errReported) {
+				reported = append(reported, uncodedDiagnostic(err))
+			}
+			if err := writeSARIF(opts.SARIF, moduleRoot, reported); err != nil {
+				fmt.Fprintf(os.Stderr, "autodi: sarif: %v\n", err)
+			}
+		}()
+	}
+
+	// Build config from conventions (go.mod + generate.go)
+	cfg, err := loadConfig(moduleRoot, opts)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Profile = opts.Profile
+	cfg.ProfileInit = opts.ProfileInit
+	cfg.Inspect = opts.Inspect
+	if err := validateProfileInit(opts.ProfileInit); err != nil {
+		return nil, err
+	}
+
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "autodi: module=%s root=%s\n", cfg.Module, moduleRoot)
+		fmt.Fprintf(os.Stderr, "autodi: app=%s\n", cfg.AppName)
+	}
+
+	scan, err := scanProject(moduleRoot, cfg, opts)
+	if err != nil {
+		return nil, err
+	}
+	var warnings []string
+	proj, err := buildProject(scan, opts, reportErrors, func(w string) {
+		fmt.Fprintf(os.Stderr, "autodi: warning: %s\n", w)
+		warnings = append(warnings, w)
+	})
+	if err != nil {
+		return nil, err
+	}
+	proj.Warnings = warnings
+	return proj, nil
+}
+
+// ScanResult is a loaded and scanned module: the provider candidates and the
+// commands the graph is built for.
+type ScanResult struct {
+	Root       string
+	Cfg        *Config
+	Set        *PackageSet
+	Candidates []*Provider
+	Commands   []*DiscoveredCommand
+
+	scanner *Scanner
+}
+
+// scanProject loads the packages of the module at moduleRoot, scans them for
+// provider candidates and detects the commands.
+func scanProject(moduleRoot string, cfg *Config, opts *Options) (*ScanResult, error) {
+	// Load gitignore patterns
+	gitignorePatterns := LoadGitignore(moduleRoot)
+
+	// ── Pass 0: Load scan roots and cmd/ packages in one shared load ──
+
+	tl := time.Now()
+	scanner := NewScanner(cfg, moduleRoot, gitignorePatterns)
+	detector := NewCommandDetector(cfg, moduleRoot)
+	patterns, err := scanner.Patterns()
+	if err != nil {
+		return nil, err
+	}
+	patterns = append(patterns, detector.Pattern())
+	// Hermetic runs load the listed files instead of the module's directories
+	if cfg.Files != nil {
+		patterns = cfg.filePatterns(moduleRoot, patterns)
+	}
+	set, err := LoadPackages(moduleRoot, patterns)
+	if err != nil {
+		return nil, err
+	}
+	// --keep-going wires the packages that load and reports the others
+	if opts.KeepGoing {
+		if broken := set.dropBroken(); len(broken) > 0 {
+			writeBrokenReport(os.Stderr, cfg, broken)
+		}
+	}
+
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "autodi: [%s] load: %d packages\n", time.Since(tl), len(set.Pkgs))
+	}
+
+	// ── Pass 1: Scan provider candidates ──
+
+	t0 := time.Now()
+	candidates, err := scanner.Scan(set)
+	if err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "autodi: [%s] scan: discovered %d candidates\n", time.Since(t0), len(candidates))
+		for _, skipped := range cfg.Skipped {
+			fmt.Fprintf(os.Stderr, "  skipped %s\n", skipped)
+		}
+	}
+
+	// ── Pass 2: Discover commands from cmd/ packages ──
+
+	t1 := time.Now()
+	commands, err := detector.Detect(set)
+	if err != nil {
+		return nil, fmt.Errorf("detect commands: %w", err)
+	}
+	if err := validateApps(cfg, commands); err != nil {
+		return nil, err
+	}
+
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "autodi: [%s] detect: discovered %d commands\n", time.Since(t1), len(commands))
+		for _, cmd := range commands {
+			var paramTypes []string
+			for _, p := range cmd.Params {
+				paramTypes = append(paramTypes, toShortTypeName(p.TypeStr))
+			}
+			kind := "multi"
+			if cmd.IsSingle {
+				kind = "single"
+			}
+			if !cmd.HasDeps() {
+				kind += "/zero-dep"
+			}
+			var handlers []string
+			for _, h := range cmd.Handlers {
+				handlers = append(handlers, h.MethodName)
+			}
+			fmt.Fprintf(os.Stderr, "  [%s] %s: %s.%s(%s) → [%s]\n",
+				kind, cmd.Name, cmd.StructName, cmd.FuncName,
+				strings.Join(paramTypes, ", "), strings.Join(handlers, ", "))
+		}
+	}
+
+	return &ScanResult{Root: moduleRoot, Cfg: cfg, Set: set, Candidates: candidates, Commands: commands, scanner: scanner}, nil
+}
+
+// buildProject runs the reachability → graph → validation passes on a scan,
+// passing the errors found to report and the warnings to warn. It completes
+// the scan's commands, so a scan is built once.
+func buildProject(scan *ScanResult, opts *Options, report func([]error), warn func(string)) (*Project, error) {
+	cfg, scanner, commands := scan.Cfg, scan.scanner, scan.Commands
+	candidates := slices.Clone(scan.Candidates)
+
+	// Parameters taking the Container itself aren't provider dependencies
+	markContainerParams(candidates, commands, cfg)
+
+	// *slog.Logger and *zap.Logger fall back to generated defaults
+	candidates = append(candidates, defaultLoggers(candidates, commands)...)
+
+	// *cron.Cron is filled with the groups of jobs
+	candidates = append(candidates, jobCron(candidates, commands)...)
+
+	// ── Pass 3: Filter to reachable providers only ──
+
+	t2 := time.Now()
+	providers := FilterReachable(candidates, commands, cfg, scanner.IfaceTypes, opts.Verbose)
+
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "autodi: [%s] reachable: %d candidates → %d providers\n",
+			time.Since(t2), len(candidates), len(providers))
+	}
+
+	// Constructors that terminate the process skip the generated cleanup
+	exits := scanner.FindExitCalls(providers)
+	for _, c := range exits {
+		warn(fmt.Sprintf("%s: %s.%s calls %s\n  hint: return an error instead so already constructed providers are closed",
+			c.Position, c.Provider.PkgName, c.Provider.FuncName, c.Call))
+	}
+	if opts.Strict && len(exits) > 0 {
+		return nil, errReported
+	}
+
+	// ── Pass 4: Build dependency graph ──
+
+	t3 := time.Now()
+	graph, errs := BuildGraph(providers, cfg, scanner.PkgIndex, scanner.IfaceTypes)
+	if len(errs) > 0 {
+		report(errs)
+		return nil, errReported
+	}
+
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "autodi: [%s] build graph\n", time.Since(t3))
+		for _, s := range graph.Shadowed {
+			fmt.Fprintf(os.Stderr, "autodi: duplicate %s\n", s.describe(cfg))
+		}
+	}
+
+	t4 := time.Now()
+	if errs := graph.VerifyAcyclic(); len(errs) > 0 {
+		report(errs)
+		return nil, errReported
+	}
+
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "autodi: [%s] verify acyclic\n", time.Since(t4))
+	}
+
+	// Resolve interface bindings for command parameters
+	t5 := time.Now()
+	if errs := graph.BindCommandInterfaces(commands); len(errs) > 0 {
+		report(errs)
+		return nil, errReported
+	}
+
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "autodi: [%s] bind command interfaces\n", time.Since(t5))
+	}
+
+	// Singletons and commands can't depend on request-scoped providers
+	if errs := graph.checkScopes(commands); len(errs) > 0 {
+		report(errs)
+		return nil, errReported
+	}
+
+	// Validate //autodi:test-replace fakes for the test container
+	if errs := graph.resolveTestReplacements(scanner.TestReplacements); len(errs) > 0 {
+		report(errs)
+		return nil, errReported
+	}
+
+	// Validate per-command dependencies
+	t6 := time.Now()
+	hasValidationErr := false
+	for _, cmd := range commands {
+		if !cmd.HasDeps() {
+			continue
+		}
+		var neededTypes []string
+		for _, param := range cmd.Params {
+			if param.Stream == "" {
+				neededTypes = append(neededTypes, param.TypeStr)
+			}
+		}
+		pp, err := graph.ProvidersForTypes(neededTypes)
+		if err != nil {
+			report([]error{fmt.Errorf("command %s: %w", cmd.Name, err)})
+			hasValidationErr = true
+			continue
+		}
+		if errs := graph.ValidateEntry(cmd.Name, pp); len(errs) > 0 {
+			report(errs)
+			hasValidationErr = true
+		}
+		if opts.Verbose {
+			fmt.Fprintf(os.Stderr, "autodi: command %s: %d providers\n", cmd.Name, len(pp))
+		}
+		if opts.Tree {
+			graph.writeProviderTree(os.Stderr, cmd)
+		}
+	}
+	// A serverless handler is validated like a command
+	if errs := graph.checkLambdaHandler(); len(errs) > 0 {
+		report(errs)
+		hasValidationErr = true
+	}
+	// Providers taking the Container are constructed after the others
+	if errs := graph.checkContainerInjection(commands); len(errs) > 0 {
+		report(errs)
+		hasValidationErr = true
+	}
+	// Infra providers are constructed before, and without, the app ones
+	if errs := graph.checkPhases(); len(errs) > 0 {
+		report(errs)
+		hasValidationErr = true
+	}
+	if errs := graph.checkMigrate(); len(errs) > 0 {
+		report(errs)
+		hasValidationErr = true
+	}
+	// Every member of a map[string]Interface parameter needs its own key
+	if errs := graph.checkKeyed(commands); len(errs) > 0 {
+		report(errs)
+		hasValidationErr = true
+	}
+	// The bindings report lists what stays unresolved instead
+	if hasValidationErr && !opts.Bindings {
+		return nil, errReported
+	}
+
+	// Deprecated constructors still build; each run names their users
+	for _, w := range graph.deprecationWarnings(commands) {
+		warn(w)
+	}
+	// Bindings, groups and optionals that no longer match anything
+	for _, w := range graph.staleWarnings(candidates, commands) {
+		warn(w)
+	}
+
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "autodi: [%s] validate commands\n", time.Since(t6))
+		for _, p := range graph.Providers {
+			if shapes := describeLifecycle(p, cfg); len(shapes) > 0 {
+				fmt.Fprintf(os.Stderr, "autodi: lifecycle %s.%s: %s\n", p.PkgName, p.FuncName, strings.Join(shapes, "; "))
+			}
+		}
+	}
+
+	return &Project{Root: scan.Root, Cfg: cfg, Commands: commands, Graph: graph}, nil
+}
+
+// runGenerate runs the full scan → graph → codegen pipeline for the module
+// containing the working directory.
+func runGenerate(opts *Options) error {
+	totalStart := time.Now()
+
+	if opts.RequireVersion != "" {
+		if err := checkRequiredVersion(opts.RequireVersion, toolVersion()); err != nil {
+			return fmt.Errorf("--require-version %s: %w", opts.RequireVersion, err)
+		}
+	}
+
+	if opts.OutputArchive != "" {
+		if err := checkArchivePath(opts.OutputArchive); err != nil {
+			return err
+		}
+		if opts.DryRun || opts.Diff || opts.Check || opts.DiffLock {
+			return fmt.Errorf("--output-archive writes files; it can't be combined with --dry-run, --diff, --check or --diff-lock")
+		}
+	}
+
+	if err := checkHermeticFlags(opts); err != nil {
+		return err
+	}
+
+	moduleRoot, err := projectRoot(opts)
+	if err != nil {
+		return err
+	}
+
+	// --verify-header only reads the stamps of the files on disk
+	if opts.VerifyHeader {
+		cfg, err := loadConfig(moduleRoot, opts)
+		if err != nil {
+			return err
+		}
+		mismatched, err := verifyHeaders(os.Stdout, moduleRoot, cfg)
+		if err != nil {
+			return err
+		}
+		if mismatched > 0 {
+			fmt.Fprintf(os.Stderr, "autodi: %d generated files have another header\n", mismatched)
+			return errStale
+		}
+		return nil
+	}
+
+	// A remote cache hit for the same inputs skips analysis entirely
+	var files []GeneratedFile
+	var key string
+	cache := newRemoteCache(opts.CacheURL)
+	switch {
+	case opts.KeepGoing:
+		cache = nil // a hit would skip the broken package report, a put share a partial wiring
+	case opts.Strict || opts.SARIF != "" || opts.Tree || opts.Bindings:
+		cache = nil // a hit would skip the checks and reports these ask for
+	}
+	if cache != nil {
+		if key, err = cacheKey(moduleRoot, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "autodi: cache: %v\n", err)
+		} else if entry, err := cache.Get(key); err != nil {
+			fmt.Fprintf(os.Stderr, "autodi: cache: %v\n", err)
+		} else if entry != nil {
+			if opts.Verbose {
+				fmt.Fprintf(os.Stderr, "autodi: cache hit %s\n", key)
+			}
+			// The analysis warnings are replayed like a run without the cache
+			for _, w := range entry.Warnings {
+				fmt.Fprintf(os.Stderr, "autodi: warning: %s\n", w)
+			}
+			files = entry.Files
+		}
+	}
+
+	if files == nil {
+		proj, err := analyzeProject(opts)
+		if err != nil {
+			return err
+		}
+
+		// ── Generate code ──
+
+		t7 := time.Now()
+		if files, err = generateFiles(proj); err != nil {
+			return err
+		}
+
+		if opts.Verbose {
+			fmt.Fprintf(os.Stderr, "autodi: [%s] generate code\n", time.Since(t7))
+		}
+
+		if opts.Doc != "" {
+			name, err := moduleRelPath(moduleRoot, opts.Doc)
+			if err != nil {
+				return fmt.Errorf("--doc: %w", err)
+			}
+			files = append(files, GeneratedFile{Name: name, Content: renderGraphDoc(proj)})
+		}
+
+		if cache != nil && key != "" {
+			if err := cache.Put(key, files, proj.Warnings); err != nil {
+				fmt.Fprintf(os.Stderr, "autodi: cache: %v\n", err)
+			}
+		}
+	}
+
+	// --diff-lock summarizes the wiring changes instead of writing
+	if opts.DiffLock {
+		return diffLock(os.Stdout, moduleRoot, files)
+	}
+
+	// --only keeps the generated code of other commands as it is on disk
+	if len(opts.Only) > 0 {
+		if files, err = onlyCommands(moduleRoot, opts.Module, files, opts.Only); err != nil {
+			return err
+		}
+	}
+
+	// --output-archive leaves the source tree untouched
+	if opts.OutputArchive != "" {
+		if err := writeArchive(opts.OutputArchive, files); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "autodi: archived %d files in %s\n", len(files), time.Since(totalStart))
+		return nil
+	}
+
+	// Refuse to mix output formats before anything is written
+	rewrite := make(map[string]bool)
+	for _, f := range files {
+		if opts.DryRun && !opts.Diff || !strings.HasSuffix(f.Name, ".go") {
+			continue
+		}
+		old, err := os.ReadFile(filepath.Join(moduleRoot, f.Name))
+		if err != nil {
+			continue
+		}
+		full, err := checkOutputSkew(f.Name, old, opts.Migrate)
+		if err != nil {
+			return err
+		}
+		if full {
+			rewrite[f.Name] = true
+			fmt.Fprintf(os.Stderr, "autodi: migrating %s to output format %d\n", f.Name, outputFormat)
+		}
+	}
+
+	// --diff shows what a run would change, in the format of --check
+	if opts.Diff {
+		if changed := checkGenerated(os.Stdout, moduleRoot, files, opts, rewrite); changed == 0 {
+			fmt.Fprintf(os.Stderr, "autodi: %d generated files up to date\n", len(files))
+		}
+		return nil
+	}
+
+	// --check reports stale files instead of writing them
+	if opts.Check {
+		if stale := checkGenerated(os.Stdout, moduleRoot, files, opts, rewrite); stale > 0 {
+			fmt.Fprintf(os.Stderr, "autodi: %d of %d generated files differ\n", stale, len(files))
+			return errStale
+		}
+		if opts.Verbose {
+			fmt.Fprintf(os.Stderr, "autodi: %d generated files up to date in %s\n", len(files), time.Since(totalStart))
+		}
+		return nil
+	}
+
+	// Write or print generated files
+	t8 := time.Now()
+	for _, f := range files {
+		if opts.DryRun {
+			fmt.Fprintf(os.Stdout, "// === %s ===\n%s\n", f.Name, f.Content)
+			continue
+		}
+		path := filepath.Join(moduleRoot, f.Name)
+		content := f.Content
+		if !opts.Full && !rewrite[f.Name] && strings.HasSuffix(f.Name, ".go") {
+			if old, err := os.ReadFile(path); err == nil {
+				var changed []string
+				content, changed = mergeGenerated(old, content)
+				if len(changed) == 0 {
+					if opts.Verbose {
+						fmt.Fprintf(os.Stderr, "autodi: %s unchanged\n", path)
+					}
+					continue
+				}
+				if opts.Verbose {
+					fmt.Fprintf(os.Stderr, "autodi: updating %s [%s]\n", path, strings.Join(changed, ", "))
+				}
+			}
+		}
+		if opts.Verbose {
+			fmt.Fprintf(os.Stderr, "autodi: writing %s\n", path)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("write %s: %w", path, err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return fmt.Errorf("write %s: %w", path, err)
+		}
+	}
+
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "autodi: [%s] write files\n", time.Since(t8))
+	}
+
+	if !opts.DryRun {
+		fmt.Fprintf(os.Stderr, "autodi: generated %d files in %s\n", len(files), time.Since(totalStart))
+	}
+	return nil
+}
+
+// generateFiles renders the generated code of a project and its lock file.
+func generateFiles(proj *Project) ([]GeneratedFile, error) {
+	gen := NewCodeGen(proj.Cfg, proj.Graph, proj.Commands, proj.Root)
+	files, err := gen.Generate()
+	if err != nil {
+		return nil, fmt.Errorf("generate: %w", err)
+	}
+
+	lock, err := BuildGraphLock(proj)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", LockFile, err)
+	}
+	data, err := lock.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", LockFile, err)
+	}
+	return append(files, GeneratedFile{Name: filepath.FromSlash(proj.Cfg.appPath(LockFile)), Content: data}), nil
+}
+
+// findModuleRoot walks up from cwd to find the directory containing go.mod.
+func findModuleRoot() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("getwd: %w", err)
+	}
+	return moduleRootOf(dir)
+}
+
+// moduleRootOf walks up from dir to find the directory containing go.mod.
+func moduleRootOf(dir string) (string, error) {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return "", fmt.Errorf("go.mod not found in any parent directory")
+}
+
+// moduleRelPath resolves a path given on the command line (relative to the
+// working directory) to a slash path relative to the module root.
+func moduleRelPath(moduleRoot, path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(moduleRoot, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the module", path)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func joinStrings(ss []string, sep string) string {
+	return strings.Join(ss, sep)
+}
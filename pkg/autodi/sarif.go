@@ -0,0 +1,115 @@
+package autodi
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// SARIF 2.1.0, the subset GitHub code scanning and editors read.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+	Region           sarifRegion   `json:"region"`
+}
+
+type sarifArtifact struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// writeSARIF writes the diagnostics as a SARIF log. Locations are relative to
+// the module root; a run without diagnostics writes an empty result list so
+// uploads clear earlier alerts.
+func writeSARIF(path, moduleRoot string, diags []*Diagnostic) error {
+	codes := make([]string, 0, len(codeTitles))
+	for code := range codeTitles {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	var rules []sarifRule
+	for _, code := range codes {
+		rules = append(rules, sarifRule{ID: code, ShortDescription: sarifMessage{Text: codeTitles[code]}})
+	}
+
+	results := []sarifResult{}
+	for _, d := range diags {
+		r := sarifResult{RuleID: d.Code, Level: "error", Message: sarifMessage{Text: d.Message}}
+		if d.Pos.Filename != "" && d.Pos.Line > 0 {
+			uri := d.Pos.Filename
+			if rel, err := filepath.Rel(moduleRoot, uri); err == nil && filepath.IsAbs(uri) {
+				uri = rel
+			}
+			r.Locations = []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifact{URI: filepath.ToSlash(uri)},
+				Region:           sarifRegion{StartLine: d.Pos.Line, StartColumn: d.Pos.Column},
+			}}}
+		}
+		results = append(results, r)
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "autodi",
+				Version:        toolVersion(),
+				InformationURI: "https://github.com/iVampireSP/autodi",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
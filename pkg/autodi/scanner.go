@@ -0,0 +1,814 @@
+package autodi
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Scanner discovers providers by loading and analyzing Go packages.
+type Scanner struct {
+	cfg        *Config
+	moduleRoot string
+	gitignore  []GitignorePattern
+	fset       *token.FileSet
+
+	// PkgIndex maps package short name → full package path for all loaded packages.
+	PkgIndex map[string]string
+
+	// IfaceTypes maps full type string → *types.Interface for all exported interface
+	// types discovered in loaded packages. Used by AutoCollect to find interface types
+	// that aren't directly referenced in any provider's params/returns.
+	IfaceTypes map[string]*types.Interface
+
+	// TestReplacements are the //autodi:test-replace fakes, kept out of the
+	// provider candidates.
+	TestReplacements []*Provider
+
+	// imported maps //autodi:import package paths → exported constructor names.
+	imported map[string]map[string]bool
+
+	// ifacePkgs are the packages of other modules declaring group interfaces.
+	ifacePkgs []string
+
+	// pkgsByPath holds every loaded package including transitive imports.
+	pkgsByPath map[string]*packages.Package
+}
+
+// NewScanner creates a scanner.
+func NewScanner(cfg *Config, moduleRoot string, gitignore []GitignorePattern) *Scanner {
+	return &Scanner{
+		cfg:        cfg,
+		moduleRoot: moduleRoot,
+		gitignore:  gitignore,
+	}
+}
+
+// Patterns returns the package patterns the scanner needs loaded: the scan
+// roots plus the packages exported by //autodi:import provider bundles. They
+// are loaded together with local packages so both share one type universe.
+func (s *Scanner) Patterns() ([]string, error) {
+	patterns := s.buildPatterns()
+
+	manifests, err := loadImportManifests(s.moduleRoot, s.cfg.Imports, s.cfg.LocalMods)
+	if err != nil {
+		return nil, err
+	}
+	s.imported = manifestFuncs(manifests)
+
+	// //autodi:provide constructors load just their package, like a manifest's
+	for _, ref := range s.cfg.Provides {
+		pkgPath, name, _ := splitFuncRef(ref)
+		for _, pattern := range patterns {
+			if matchPattern(pattern, pkgPath) {
+				return nil, fmt.Errorf("generate.go: //autodi:provide %s: %s is already scanned\n  hint: //autodi:provide is for packages outside the scanned directories", ref, pkgPath)
+			}
+		}
+		if s.imported[pkgPath] == nil {
+			s.imported[pkgPath] = make(map[string]bool)
+		}
+		s.imported[pkgPath][name] = true
+	}
+	for _, pkgPath := range sortedKeys(s.imported) {
+		patterns = append(patterns, pkgPath)
+	}
+
+	// Group interfaces of other modules load just their package
+	s.ifacePkgs = s.cfg.groupIfacePackages()
+	patterns = append(patterns, s.ifacePkgs...)
+	return patterns, nil
+}
+
+// Scan extracts providers from the scanner's packages in a loaded set.
+// Patterns must have been called to build the set.
+func (s *Scanner) Scan(set *PackageSet) ([]*Provider, error) {
+	patterns := s.buildPatterns()
+	for pkgPath := range s.imported {
+		patterns = append(patterns, pkgPath)
+	}
+	pkgs := s.dropSkippedPackages(set.Match(patterns...))
+
+	// Check for package loading errors
+	var loadErrs []string
+	for _, pkg := range pkgs {
+		for _, e := range pkg.Errors {
+			loadErrs = append(loadErrs, e.Error())
+		}
+	}
+	if len(loadErrs) > 0 {
+		return nil, fmt.Errorf("package errors:\n  %s", strings.Join(loadErrs, "\n  "))
+	}
+
+	s.fset = set.Fset
+	s.indexPackages(set.Pkgs)
+
+	// Build package index from all loaded packages and their imports
+	s.PkgIndex = make(map[string]string)
+	for _, pkg := range pkgs {
+		s.PkgIndex[pkg.Name] = pkg.PkgPath
+		for _, imp := range pkg.Imports {
+			s.PkgIndex[imp.Name] = imp.PkgPath
+		}
+	}
+
+	// Extract interface types from all loaded packages (and their in-module imports)
+	s.buildIfaceTypes(pkgs)
+	if err := s.indexGroupIfaces(set); err != nil {
+		return nil, err
+	}
+
+	// Merge package-level //autodi: directives (doc.go) into the config.
+	// This mutates the config, so it runs before the concurrent extraction.
+	skipped := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if _, ok := s.imported[pkg.PkgPath]; ok {
+			continue
+		}
+		skip, err := s.applyPackageDirectives(pkg)
+		if err != nil {
+			return nil, err
+		}
+		skipped[pkg.PkgPath] = skip
+	}
+
+	// Extract providers from each package on a worker pool; results are
+	// merged in load order.
+	found := make([][]*Provider, len(pkgs))
+	fakes := make([][]*Provider, len(pkgs))
+	errs := make([]error, len(pkgs))
+	forEachPackage(pkgs, func(i int, pkg *packages.Package) {
+		if funcs, ok := s.imported[pkg.PkgPath]; ok {
+			found[i] = s.extractManifestProviders(pkg, funcs)
+			return
+		}
+		if skipped[pkg.PkgPath] || s.shouldExclude(pkg.PkgPath) {
+			return
+		}
+		if err := s.checkErrorReturns(pkg); err != nil {
+			errs[i] = err
+			return
+		}
+		found[i] = s.extractProviders(pkg)
+		fakes[i] = s.extractTestReplacements(pkg)
+		for _, p := range found[i] {
+			if err := resolveFactory(p); err != nil {
+				errs[i] = err
+				return
+			}
+			if err := s.resolveReplay(pkg, p); err != nil {
+				errs[i] = err
+				return
+			}
+			if err := s.resolveSecrets(pkg, p); err != nil {
+				errs[i] = err
+				return
+			}
+			s.resolveEnv(pkg, p)
+			if err := s.resolveWhen(pkg, p); err != nil {
+				errs[i] = err
+				return
+			}
+			if err := resolveOrder(p); err != nil {
+				errs[i] = err
+				return
+			}
+			if err := resolveScope(p); err != nil {
+				errs[i] = err
+				return
+			}
+			if err := resolveStreams(p); err != nil {
+				errs[i] = err
+				return
+			}
+			if err := s.resolveParamBindings(pkg, p); err != nil {
+				errs[i] = err
+				return
+			}
+		}
+	})
+
+	var providers []*Provider
+	for i := range pkgs {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		providers = append(providers, found[i]...)
+		s.TestReplacements = append(s.TestReplacements, fakes[i]...)
+	}
+
+	// Every //autodi:provide must name a usable constructor
+	for _, ref := range s.cfg.Provides {
+		pkgPath, name, _ := splitFuncRef(ref)
+		if !slices.ContainsFunc(providers, func(p *Provider) bool { return p.PkgPath == pkgPath && p.FuncName == name }) {
+			return nil, fmt.Errorf("generate.go: //autodi:provide %s: no such constructor\n  hint: name an exported function of %s returning the value to provide", ref, pkgPath)
+		}
+	}
+
+	// //autodi:config structs are provided by generated loaders
+	loaders, err := s.configProviders(pkgs)
+	if err != nil {
+		return nil, err
+	}
+	providers = append(providers, loaders...)
+
+	return providers, nil
+}
+
+// applyPackageDirectives merges //autodi: directives from a package's doc
+// comment into the central config, so configuration can live next to the code
+// it affects. Returns true when the package must not contribute providers
+// (//autodi:ignore, or an //autodi:profile that isn't active).
+func (s *Scanner) applyPackageDirectives(pkg *packages.Package) (bool, error) {
+	rel := s.cfg.RelPath(pkg.PkgPath)
+	skip := false
+
+	for _, d := range ParsePackageDirectives(pkg.Syntax) {
+		switch d.Kind {
+		case PkgDirectiveIgnore:
+			skip = true
+
+		case PkgDirectiveProfile:
+			// //autodi:profile dev,test
+			if !profileActive(d.Value, s.cfg.Profile) {
+				skip = true
+			}
+
+		case PkgDirectiveLayer:
+			// //autodi:layer infra
+			s.cfg.Layers[pkg.PkgPath] = d.Value
+
+		case PkgDirectiveGroup:
+			// //autodi:group notifiers            (group declared in generate.go)
+			// //autodi:group notifiers []notify.Notifier
+			fields := strings.Fields(d.Value)
+			if len(fields) == 0 {
+				return false, fmt.Errorf("%s: //autodi:group needs a group name", rel)
+			}
+			name := fields[0]
+			group, exists := s.cfg.Groups[name]
+			if len(fields) >= 2 {
+				iface := strings.TrimPrefix(fields[1], "[]")
+				if exists && group.Interface != iface {
+					return false, fmt.Errorf("%s: group %s declared with interface %s, package says %s",
+						rel, name, group.Interface, iface)
+				}
+				group.Interface = iface
+			} else if !exists {
+				return false, fmt.Errorf("%s: group %s is not declared in generate.go\n  hint: //autodi:group %s []<Interface>", rel, name, name)
+			}
+			group.Paths = append(group.Paths, rel)
+			s.cfg.Groups[name] = group
+		}
+	}
+	return skip, nil
+}
+
+// profileActive reports whether a comma-separated profile list contains the active profile.
+func profileActive(list, active string) bool {
+	if active == "" {
+		return false
+	}
+	for _, p := range strings.Split(list, ",") {
+		if strings.TrimSpace(p) == active {
+			return true
+		}
+	}
+	return false
+}
+
+// buildIfaceTypes extracts all exported interface types from loaded packages
+// and their in-module imports. This allows AutoCollect to find interface types
+// that aren't directly used in any provider's signature.
+func (s *Scanner) buildIfaceTypes(pkgs []*packages.Package) {
+	s.IfaceTypes = make(map[string]*types.Interface)
+	visited := make(map[string]bool)
+
+	var extract func(pkg *packages.Package)
+	extract = func(pkg *packages.Package) {
+		if pkg.Types == nil || visited[pkg.PkgPath] {
+			return
+		}
+		visited[pkg.PkgPath] = true
+
+		s.indexIfaces(pkg)
+		// Also process imports within the same module
+		for _, imp := range pkg.Imports {
+			if strings.HasPrefix(imp.PkgPath, s.cfg.Module) {
+				extract(imp)
+			}
+		}
+	}
+
+	for _, pkg := range pkgs {
+		extract(pkg)
+	}
+}
+
+// indexIfaces adds the exported interface types of pkg to IfaceTypes.
+func (s *Scanner) indexIfaces(pkg *packages.Package) {
+	scope := pkg.Types.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		if iface, ok := obj.Type().Underlying().(*types.Interface); ok {
+			typeStr := types.TypeString(resolveAliases(obj.Type()), nil)
+			s.IfaceTypes[typeStr] = iface
+		}
+	}
+}
+
+// buildPatterns converts scan config paths to Go package patterns.
+// Skips cmd/ paths — those are matched by CommandDetector from the shared load.
+func (s *Scanner) buildPatterns() []string {
+	var patterns []string
+	for _, scan := range s.cfg.Scan {
+		p := strings.TrimPrefix(scan, "./")
+		// Skip cmd/ packages — they don't have providers, only entry points
+		// (cmd/internal/... is added below when enabled)
+		if strings.HasPrefix(p, "cmd/") || p == "cmd/..." || p == "cmd" {
+			continue
+		}
+		patterns = append(patterns, s.cfg.Module+"/"+p)
+	}
+	// Command-only helpers, opted in with //autodi:scan-cmd-internal
+	if s.cfg.CmdInternal {
+		patterns = append(patterns, s.cfg.Module+"/"+s.cfg.appPath(cmdInternalDir)+"/...")
+	}
+	// Required modules replaced by a directory inside the tree are loaded
+	// under their own module path; the go command won't match them by dir.
+	for _, mod := range sortedKeys(s.cfg.Replaces) {
+		if IsGitignored(s.cfg.Replaces[mod], s.gitignore) {
+			continue
+		}
+		patterns = append(patterns, mod+"/...")
+	}
+	// Every other go.work module is scanned as a whole; the go command
+	// resolves the imports through the workspace.
+	for _, mod := range sortedKeys(s.cfg.Workspace) {
+		patterns = append(patterns, mod+"/...")
+	}
+	return patterns
+}
+
+// shouldExclude checks if a package path should be excluded.
+func (s *Scanner) shouldExclude(pkgPath string) bool {
+	// Check explicit excludes
+	for _, exc := range s.cfg.Exclude {
+		excPath := strings.TrimPrefix(exc, "./")
+		excPath = strings.TrimSuffix(excPath, "/...")
+		full := s.cfg.Module + "/" + excPath
+		if strings.HasPrefix(pkgPath, full) || strings.HasPrefix(s.cfg.RelPath(pkgPath), excPath) {
+			return true
+		}
+	}
+
+	// Entry points of workspace members are not providers
+	if mod := s.cfg.moduleOf(pkgPath); s.cfg.Workspace[mod] != "" {
+		if rel := strings.TrimPrefix(pkgPath, mod); rel == "/cmd" || strings.HasPrefix(rel, "/cmd/") {
+			return true
+		}
+	}
+
+	// Other apps' packages, and this app's entrypoint and commands
+	rel := s.cfg.RelPath(pkgPath)
+	if s.cfg.outsideApp(rel) {
+		return true
+	}
+
+	// Check gitignore
+	return IsGitignored(rel, s.gitignore)
+}
+
+// excludesFunc reports whether a //autodi:exclude-func pattern names a
+// function of the package: pkg.Func, with the package name, its
+// module-relative path or its import path.
+func (s *Scanner) excludesFunc(pkg *packages.Package, name string) bool {
+	for _, pattern := range s.cfg.ExcludeFuncs {
+		i := strings.LastIndex(pattern, ".")
+		if i < 0 || pattern[i+1:] != name {
+			continue
+		}
+		pkgPart := pattern[:i]
+		if pkgPart == pkg.Name || pkgPart == pkg.PkgPath || pkgPart == s.cfg.RelPath(pkg.PkgPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractProviders finds the PRIMARY exported New* function in a package.
+// Following the project convention: one exported New per package.
+// Selection priority:
+//  1. Functions with //autodi:bind, //autodi:invoke or //autodi:group annotations (always included)
+//  2. "New" + PkgName (e.g., NewIAM in package iam) — canonical form
+//  3. "New" + exported struct name matching package (e.g., NewService in user pkg)
+//  4. Bare "New" function (e.g., redisx.New)
+//
+// Functions with "WithConfig", "WithXxx" suffixes are skipped as variants.
+// //autodi:constructor-prefix prefixes rank like New.
+func (s *Scanner) extractProviders(pkg *packages.Package) []*Provider {
+	type candidate struct {
+		fn          *ast.FuncDecl
+		annotations []Annotation
+		priority    int // lower is better
+	}
+	var candidates []candidate
+	var alwaysInclude []*Provider // annotated functions always included
+	replays := replayFuncNames(pkg)
+	fallbacks := whenFallbackNames(pkg)
+
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			// Methods only provide when opted in; they skip the one-New selection
+			if fn.Recv != nil {
+				if p := s.buildFactoryProvider(pkg, fn); p != nil {
+					alwaysInclude = append(alwaysInclude, p)
+				}
+				continue
+			}
+			if !fn.Name.IsExported() || !s.isConstructorName(fn.Name.Name) {
+				continue
+			}
+
+			annotations := ParseAnnotations(fn)
+			if HasAnnotation(annotations, AnnotIgnore) || HasAnnotation(annotations, AnnotTestReplace) ||
+				replays[fn.Name.Name] || fallbacks[fn.Name.Name] || s.excludesFunc(pkg, fn.Name.Name) {
+				continue
+			}
+
+			// Skip variant constructors (NewXxxWithConfig, NewXxxFromYyy, etc.)
+			name := fn.Name.Name
+			if strings.Contains(name, "With") || strings.Contains(name, "From") {
+				continue
+			}
+
+			obj := pkg.TypesInfo.Defs[fn.Name]
+			if obj == nil {
+				continue
+			}
+			funcObj, ok := obj.(*types.Func)
+			if !ok {
+				continue
+			}
+			sig := funcObj.Type().(*types.Signature)
+
+			returns, hasError := s.extractReturns(sig)
+			if len(returns) == 0 {
+				continue
+			}
+
+			params := s.extractParams(sig, annotations)
+
+			provider := &Provider{
+				FuncName:    fn.Name.Name,
+				PkgPath:     pkg.PkgPath,
+				PkgName:     pkg.Name,
+				Module:      s.cfg.moduleOf(pkg.PkgPath),
+				Params:      params,
+				Returns:     returns,
+				HasError:    hasError,
+				IsInvoke:    HasAnnotation(annotations, AnnotInvoke),
+				Annotations: annotations,
+				Position:    s.fset.Position(fn.Pos()),
+			}
+
+			// Annotated functions are always included (they opted in explicitly)
+			if HasAnnotation(annotations, AnnotBind) || HasAnnotation(annotations, AnnotInvoke) || HasAnnotation(annotations, AnnotGroup) {
+				alwaysInclude = append(alwaysInclude, provider)
+				continue
+			}
+
+			// Determine priority for "one New per package" selection
+			priority := s.funcPriority(pkg.Name, name)
+			candidates = append(candidates, candidate{fn: fn, annotations: annotations, priority: priority})
+		}
+	}
+
+	// Select providers, deduplicating by return type.
+	// Sort candidates by priority (best first), then include each candidate
+	// only if it provides types not already covered by a better candidate.
+	// This handles:
+	//   - Multi-return functions (redisx.New → UniversalClient + Locker)
+	//   - Packages with multiple service constructors (mq: Queue, Router, Consumer, etc.)
+	//   - Deduplication (NewLocker skipped when New already provides *Locker)
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].priority < candidates[j].priority
+	})
+
+	var providers []*Provider
+	providers = append(providers, alwaysInclude...)
+
+	providedTypes := make(map[string]bool)
+	// Mark types from always-included providers
+	for _, p := range alwaysInclude {
+		for _, ret := range p.Returns {
+			providedTypes[ret.TypeStr] = true
+		}
+	}
+
+	for _, c := range candidates {
+		p := s.buildProvider(pkg, c.fn, c.annotations)
+		if p == nil {
+			continue
+		}
+
+		// Check if any return type is already provided
+		overlap := false
+		for _, ret := range p.Returns {
+			if providedTypes[ret.TypeStr] {
+				overlap = true
+				break
+			}
+		}
+		if overlap {
+			continue
+		}
+
+		// Include this provider and mark its return types
+		providers = append(providers, p)
+		for _, ret := range p.Returns {
+			providedTypes[ret.TypeStr] = true
+		}
+	}
+
+	return providers
+}
+
+// replayFuncNames returns the recorded-fake constructors referenced by
+// //autodi:replayable in a package; they are substitutes, never providers.
+func replayFuncNames(pkg *packages.Package) map[string]bool {
+	names := make(map[string]bool)
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil {
+				continue
+			}
+			annotations := ParseAnnotations(fn)
+			if HasAnnotation(annotations, AnnotReplayable) {
+				names[replayFuncName(fn.Name.Name, annotations)] = true
+			}
+		}
+	}
+	return names
+}
+
+// replayFuncName returns the replay constructor for a //autodi:replayable
+// provider: the annotation value, defaulting to <FuncName>Replay.
+func replayFuncName(funcName string, annotations []Annotation) string {
+	if values := GetAnnotationValues(annotations, AnnotReplayable); len(values) > 0 {
+		return values[0]
+	}
+	return funcName + "Replay"
+}
+
+// resolveReplay validates a //autodi:replayable provider's replay constructor:
+// it must live in the same package and have an identical signature, so the
+// generated code can swap one function value for the other.
+func (s *Scanner) resolveReplay(pkg *packages.Package, p *Provider) error {
+	if !HasAnnotation(p.Annotations, AnnotReplayable) {
+		return nil
+	}
+	name := replayFuncName(p.FuncName, p.Annotations)
+	orig, _ := pkg.Types.Scope().Lookup(p.FuncName).(*types.Func)
+	replay, ok := pkg.Types.Scope().Lookup(name).(*types.Func)
+	if !ok {
+		return fmt.Errorf("%s: %s.%s is //autodi:replayable but %s.%s does not exist",
+			p.Position, p.PkgName, p.FuncName, p.PkgName, name)
+	}
+	if orig == nil || !types.Identical(orig.Type(), replay.Type()) {
+		return fmt.Errorf("%s: replay constructor %s.%s must have the same signature as %s",
+			p.Position, p.PkgName, name, p.FuncName)
+	}
+	p.ReplayFunc = name
+	return nil
+}
+
+// resolveOrder reads //autodi:order N, the provider's position among the
+// members of a group or auto-collected slice. Lower comes first; unordered
+// providers are 0 and sort by package path.
+func resolveOrder(p *Provider) error {
+	values := GetAnnotationValues(p.Annotations, AnnotOrder)
+	if len(values) == 0 {
+		return nil
+	}
+	n, err := strconv.Atoi(values[0])
+	if err != nil {
+		return fmt.Errorf("%s: %s.%s: //autodi:order %s: want an integer", p.Position, p.PkgName, p.FuncName, values[0])
+	}
+	p.Order = n
+	return nil
+}
+
+// constructorPrefix returns the prefix that makes funcName a constructor:
+// New, or one of the //autodi:constructor-prefix prefixes followed by an
+// upper-case letter or nothing (Provide, ProvideStore but not Provider).
+func (s *Scanner) constructorPrefix(funcName string) (string, bool) {
+	if strings.HasPrefix(funcName, "New") {
+		return "New", true
+	}
+	for _, prefix := range s.cfg.Prefixes {
+		rest, ok := strings.CutPrefix(funcName, prefix)
+		if ok && (rest == "" || unicode.IsUpper([]rune(rest)[0])) {
+			return prefix, true
+		}
+	}
+	return "", false
+}
+
+// isConstructorName reports whether funcName has a constructor prefix.
+func (s *Scanner) isConstructorName(funcName string) bool {
+	_, ok := s.constructorPrefix(funcName)
+	return ok
+}
+
+// funcPriority determines how well a function name matches the "primary New" convention.
+func (s *Scanner) funcPriority(pkgName, funcName string) int {
+	prefix, _ := s.constructorPrefix(funcName)
+	suffix := strings.TrimPrefix(funcName, prefix)
+
+	// "New" + exact PkgName (case-insensitive) → highest priority
+	if strings.EqualFold(suffix, pkgName) {
+		return 0
+	}
+	// Bare "New" → second
+	if suffix == "" {
+		return 1
+	}
+	// "NewService" → common pattern
+	if suffix == "Service" {
+		return 2
+	}
+	// Any other New* → lowest
+	return 3
+}
+
+// isGroupPackage checks if this package path falls under a group definition.
+func (s *Scanner) isGroupPackage(pkgPath string) bool {
+	for _, group := range s.cfg.Groups {
+		for _, gpath := range group.Paths {
+			if s.cfg.inGroupPath(pkgPath, gpath) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// buildProvider creates a Provider from a function declaration.
+func (s *Scanner) buildProvider(pkg *packages.Package, fn *ast.FuncDecl, annotations []Annotation) *Provider {
+	obj := pkg.TypesInfo.Defs[fn.Name]
+	if obj == nil {
+		return nil
+	}
+	funcObj, ok := obj.(*types.Func)
+	if !ok {
+		return nil
+	}
+	sig := funcObj.Type().(*types.Signature)
+	returns, hasError := s.extractReturns(sig)
+	if len(returns) == 0 {
+		return nil
+	}
+	params := s.extractParams(sig, annotations)
+
+	return &Provider{
+		FuncName:    fn.Name.Name,
+		PkgPath:     pkg.PkgPath,
+		PkgName:     pkg.Name,
+		Module:      s.cfg.moduleOf(pkg.PkgPath),
+		Params:      params,
+		Returns:     returns,
+		HasError:    hasError,
+		IsInvoke:    HasAnnotation(annotations, AnnotInvoke),
+		Annotations: annotations,
+		Position:    s.fset.Position(fn.Pos()),
+	}
+}
+
+// checkErrorReturns reports a constructor of the package whose error result
+// isn't last, e.g. func New() (error, *T), which would otherwise provide error.
+func (s *Scanner) checkErrorReturns(pkg *packages.Package) error {
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || !fn.Name.IsExported() {
+				continue
+			}
+			annotations := ParseAnnotations(fn)
+			if HasAnnotation(annotations, AnnotIgnore) {
+				continue
+			}
+			if fn.Recv != nil && !HasAnnotation(annotations, AnnotFactory) ||
+				fn.Recv == nil && !s.isConstructorName(fn.Name.Name) {
+				continue
+			}
+			funcObj, ok := pkg.TypesInfo.Defs[fn.Name].(*types.Func)
+			if !ok {
+				continue
+			}
+			sig := funcObj.Type().(*types.Signature)
+			if i := misplacedError(sig); i >= 0 {
+				pos := s.fset.Position(fn.Pos())
+				return diagf(CodeMisplacedError, pos, pos, pkg.Name, fn.Name.Name, i+1, sig.Results().Len())
+			}
+		}
+	}
+	return nil
+}
+
+// extractReturns parses return types, separating error from provided types.
+// A signature with error before the last result provides nothing;
+// checkErrorReturns reports it.
+func (s *Scanner) extractReturns(sig *types.Signature) ([]TypeRef, bool) {
+	results := sig.Results()
+	if results.Len() == 0 || misplacedError(sig) >= 0 {
+		return nil, false
+	}
+
+	var refs []TypeRef
+	hasError := false
+
+	// A single result object provides each of its fields
+	if n := results.Len(); n == 1 || (n == 2 && isErrorType(results.At(1).Type())) {
+		t := resolveAliases(results.At(0).Type())
+		if st := s.resultObject(t); st != nil {
+			object := TypeRef{Type: t, TypeStr: types.TypeString(t, nil), PkgPath: typePkgPath(t)}
+			if fields := resultObjectFields(object, st); len(fields) > 0 {
+				return fields, n == 2
+			}
+		}
+	}
+
+	for i := 0; i < results.Len(); i++ {
+		t := resolveAliases(results.At(i).Type())
+
+		// Check if this is the error type (only valid as last return)
+		if i == results.Len()-1 && isErrorType(t) {
+			hasError = true
+			continue
+		}
+
+		refs = append(refs, TypeRef{
+			Type:    t,
+			TypeStr: types.TypeString(t, nil),
+			PkgPath: typePkgPath(t),
+			IsIface: isInterface(t),
+			Name:    results.At(i).Name(),
+		})
+	}
+
+	return refs, hasError
+}
+
+// extractParams parses parameter types as dependencies.
+func (s *Scanner) extractParams(sig *types.Signature, annotations []Annotation) []TypeRef {
+	params := sig.Params()
+	optionalTypes := GetAnnotationValues(annotations, AnnotOptional)
+
+	var refs []TypeRef
+	for i := 0; i < params.Len(); i++ {
+		t := resolveAliases(params.At(i).Type())
+		typeStr := types.TypeString(t, nil)
+
+		if st := s.paramObject(t); st != nil {
+			object := TypeRef{Type: t, TypeStr: typeStr, PkgPath: typePkgPath(t)}
+			if fields := paramObjectFields(object, st, optionalTypes); len(fields) > 0 {
+				refs = append(refs, fields...)
+				continue
+			}
+		}
+
+		optional := false
+		for _, opt := range optionalTypes {
+			if strings.HasSuffix(typeStr, opt) {
+				optional = true
+				break
+			}
+		}
+
+		refs = append(refs, TypeRef{
+			Type:     t,
+			TypeStr:  typeStr,
+			PkgPath:  typePkgPath(t),
+			IsIface:  isInterface(t),
+			Optional: optional,
+			Name:     params.At(i).Name(),
+		})
+	}
+	return refs
+}
@@ -0,0 +1,245 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// AnnotScope marks a provider constructed per unit of work instead of once:
+//
+//	//autodi:scope request
+//
+// Scoped providers are not built by the init functions. Container.NewRequestScope(ctx)
+// constructs them on every call, passing ctx for context.Context parameters and
+// the command's singletons for everything else; RequestScope.Close releases
+// them. Singletons can't depend on scoped providers.
+const AnnotScope = "scope"
+
+// ScopeRequest is the only scope besides the default singleton.
+const ScopeRequest = "request"
+
+// resolveScope applies //autodi:scope to a provider.
+func resolveScope(p *Provider) error {
+	values := GetAnnotationValues(p.Annotations, AnnotScope)
+	if len(values) == 0 {
+		return nil
+	}
+	if len(values) > 1 || values[0] != ScopeRequest {
+		return fmt.Errorf("%s: %s.%s: want //autodi:scope %s", p.Position, p.PkgName, p.FuncName, ScopeRequest)
+	}
+	if p.IsInvoke {
+		return fmt.Errorf("%s: %s.%s: //autodi:scope can't be combined with //autodi:invoke", p.Position, p.PkgName, p.FuncName)
+	}
+	p.Scope = ScopeRequest
+	return nil
+}
+
+// scopedProviders returns the request-scoped providers in dependency order.
+func (g *Graph) scopedProviders() ([]*Provider, error) {
+	var typeStrs []string
+	for _, p := range g.Providers {
+		if p.Scope != "" {
+			typeStrs = append(typeStrs, returnTypeStrs(p)...)
+		}
+	}
+	if len(typeStrs) == 0 {
+		return nil, nil
+	}
+	all, err := g.ProvidersForTypes(typeStrs)
+	if err != nil {
+		return nil, err
+	}
+	var scoped []*Provider
+	for _, p := range all {
+		if p.Scope != "" {
+			scoped = append(scoped, p)
+		}
+	}
+	return scoped, nil
+}
+
+// hasScoped reports whether any provider is request-scoped.
+func (g *Graph) hasScoped() bool {
+	for _, p := range g.Providers {
+		if p.Scope != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkScopes reports singletons and commands that depend on request-scoped
+// providers, and scoped providers in groups.
+func (g *Graph) checkScopes(commands []*DiscoveredCommand) []error {
+	scopedType := func(typeStr string) *Provider {
+		if p, ok := g.ProviderMap[g.resolveType(typeStr)]; ok && p.Scope != "" {
+			return p
+		}
+		return nil
+	}
+
+	var errs []error
+	for _, p := range g.Providers {
+		if p.Scope != "" {
+			if len(p.Groups) > 0 {
+				errs = append(errs, fmt.Errorf("%s: %s.%s: request-scoped providers can't be group members",
+					p.Position, p.PkgName, p.FuncName))
+			}
+			for _, param := range p.Params {
+				if param.Optional || param.Stream != "" || isContextTypeStr(param.TypeStr) {
+					continue
+				}
+				if _, _, ok := collectedElem(param.TypeStr); ok {
+					errs = append(errs, fmt.Errorf("%s: request-scoped %s.%s: collection parameter %s isn't supported\n  hint: take a singleton holding the collection instead",
+						p.Position, p.PkgName, p.FuncName, toShortTypeName(param.TypeStr)))
+					continue
+				}
+				if _, ok := g.ProviderMap[g.resolveType(param.TypeStr)]; !ok {
+					errs = append(errs, fmt.Errorf("%s: request-scoped %s.%s: missing dependency %s",
+						p.Position, p.PkgName, p.FuncName, toShortTypeName(param.TypeStr)))
+				}
+			}
+			continue
+		}
+		for _, param := range p.Params {
+			if sp := scopedType(param.TypeStr); sp != nil {
+				errs = append(errs, fmt.Errorf("%s: singleton %s.%s depends on request-scoped %s (%s.%s)\n  hint: mark %s.%s //autodi:scope %s too, or take the value per call from Container.NewRequestScope",
+					p.Position, p.PkgName, p.FuncName, toShortTypeName(param.TypeStr), sp.PkgName, sp.FuncName, p.PkgName, p.FuncName, ScopeRequest))
+			}
+		}
+	}
+	for _, cmd := range commands {
+		for _, param := range cmd.Params {
+			if sp := scopedType(param.TypeStr); sp != nil {
+				errs = append(errs, fmt.Errorf("command %s: %s is request-scoped (%s.%s)\n  hint: build it per request with Container.NewRequestScope",
+					cmd.Name, toShortTypeName(param.TypeStr), sp.PkgName, sp.FuncName))
+			}
+		}
+	}
+	return errs
+}
+
+// scopeDeps returns the singleton types request-scoped providers consume; the
+// init functions construct them so the scope closure can capture them.
+func (g *Graph) scopeDeps(scoped []*Provider) []string {
+	var deps []string
+	for _, p := range scoped {
+		for _, param := range p.Params {
+			if param.Stream != "" || isContextTypeStr(param.TypeStr) {
+				continue
+			}
+			if dep, ok := g.ProviderMap[g.resolveType(param.TypeStr)]; ok && dep.Scope != "" {
+				continue
+			}
+			deps = append(deps, param.TypeStr)
+		}
+	}
+	return deps
+}
+
+// isContextTypeStr reports whether a type string is context.Context.
+func isContextTypeStr(typeStr string) bool {
+	return typeStr == "context.Context"
+}
+
+// writeRequestScope emits the closure NewRequestScope calls: it constructs the
+// scoped providers from ctx and the singletons built so far.
+func (cg *CodeGen) writeRequestScope(buf *bytes.Buffer, scoped []*Provider, varMap map[string]string, usedVars map[string]bool) {
+	if len(scoped) == 0 {
+		return
+	}
+	cg.imports.Add("context", "context")
+	cg.registerProviderImports(scoped)
+	cg.hasContainer = true
+
+	// Singletons are captured; interfaces resolve through their bindings
+	scopeVars := withVar(varMap, "context.Context", "ctx")
+	for ifaceStr, concreteStr := range cg.graph.Bindings {
+		if v, ok := scopeVars[concreteStr]; ok {
+			if _, exists := scopeVars[ifaceStr]; !exists {
+				scopeVars[ifaceStr] = v
+			}
+		}
+	}
+	consumed := make(map[string]bool)
+	for _, p := range scoped {
+		for _, t := range returnTypeStrs(p) {
+			consumed[t] = true
+		}
+	}
+	usedVars["ctx"], usedVars["scope"], usedVars["built"] = true, true, true
+
+	// Timing and startup only cover the init function itself
+	profVar, metricsVar := cg.profVar, cg.metricsVar
+	cg.profVar, cg.metricsVar = "", ""
+	defer func() { cg.profVar, cg.metricsVar = profVar, metricsVar }()
+
+	fmt.Fprintf(buf, "\t%s.newRequestScope = func(ctx context.Context) (*RequestScope, error) {\n", containerVar)
+	buf.WriteString("\tscope := &RequestScope{}\n")
+	buf.WriteString("\tbuilt := false\n")
+	buf.WriteString("\tdefer func() {\n")
+	buf.WriteString("\t\tif !built {\n")
+	buf.WriteString("\t\t\tscope.Close()\n")
+	buf.WriteString("\t\t}\n")
+	buf.WriteString("\t}()\n")
+	var closeables []CloseableField
+	var components []Component
+	for _, p := range scoped {
+		if p.HasError {
+			cg.addInitErrorImports()
+		}
+		// Cleanups are registered as soon as a value exists, so a failing
+		// constructor releases what the scope built before it
+		n := len(closeables)
+		cg.writeLocalProviderCall(buf, p, scopeVars, usedVars, &closeables, &components, consumed)
+		for _, ret := range p.Returns {
+			if v, ok := scopeVars[ret.TypeStr]; ok {
+				fmt.Fprintf(buf, "\tscope.%s = %s\n", cg.graph.fieldName(ret.TypeStr), v)
+			}
+		}
+		for _, cl := range closeables[n:] {
+			buf.WriteString(cg.cleanupAppend("scope", &cl))
+		}
+	}
+	buf.WriteString("\tbuilt = true\n")
+	buf.WriteString("\treturn scope, nil\n")
+	buf.WriteString("\t}\n\n")
+}
+
+// writeRequestScopeType emits RequestScope and Container.NewRequestScope.
+func (cg *CodeGen) writeRequestScopeType(buf *bytes.Buffer) {
+	scoped, err := cg.graph.scopedProviders()
+	if err != nil || len(scoped) == 0 {
+		return
+	}
+	var fields []string
+	for _, p := range scoped {
+		for _, ret := range p.Returns {
+			fields = append(fields, fmt.Sprintf("\t%s %s\n", cg.graph.fieldName(ret.TypeStr), cg.shortType(ret.TypeStr)))
+		}
+	}
+
+	cg.imports.Add("context", "context")
+	cg.imports.Add("errors", "errors")
+	buf.WriteString("\n// RequestScope holds the request-scoped providers (//autodi:scope request) of\n")
+	buf.WriteString("// one unit of work. Close releases them.\n")
+	buf.WriteString("type RequestScope struct {\n")
+	buf.WriteString(strings.Join(fields, ""))
+	buf.WriteString("\n\tcleanups []func()\n")
+	buf.WriteString("}\n\n")
+	buf.WriteString("// NewRequestScope constructs the request-scoped providers for ctx, using the\n")
+	buf.WriteString("// singletons of the running command.\n")
+	buf.WriteString("func (c *Container) NewRequestScope(ctx context.Context) (*RequestScope, error) {\n")
+	buf.WriteString("\tif c.newRequestScope == nil {\n")
+	buf.WriteString("\t\treturn nil, errors.New(\"autodi: no request scope: the running command has no dependencies\")\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\treturn c.newRequestScope(ctx)\n")
+	buf.WriteString("}\n\n")
+	buf.WriteString("// Close releases the scope's providers in reverse construction order.\n")
+	buf.WriteString("func (s *RequestScope) Close() {\n")
+	buf.WriteString("\tfor i := len(s.cleanups) - 1; i >= 0; i-- {\n")
+	buf.WriteString("\t\ts.cleanups[i]()\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("}\n")
+}
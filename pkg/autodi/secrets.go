@@ -0,0 +1,231 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// AnnotSecret marks a config struct field to be filled from the SecretsSource:
+//
+//	type Config struct {
+//		//autodi:secret database/password
+//		DBPassword string
+//	}
+const AnnotSecret = "secret"
+
+// SecretField is a string field of a provider's return struct resolved from
+// the SecretsSource before any dependent provider runs.
+type SecretField struct {
+	Field string // Go field name
+	Key   string // secret key passed to Secret(ctx, key)
+}
+
+// isSecretsSource reports whether t has the SecretsSource method shape:
+//
+//	Secret(ctx context.Context, key string) (string, error)
+func isSecretsSource(t types.Type) bool {
+	mset := types.NewMethodSet(t)
+	for i := 0; i < mset.Len(); i++ {
+		method := mset.At(i)
+		if method.Obj().Name() != "Secret" {
+			continue
+		}
+		sig, ok := method.Type().(*types.Signature)
+		if !ok || sig.Params().Len() != 2 || sig.Results().Len() != 2 {
+			continue
+		}
+		if !isContextType(sig.Params().At(0).Type()) || !isStringType(sig.Params().At(1).Type()) {
+			continue
+		}
+		if isStringType(sig.Results().At(0).Type()) && isErrorType(sig.Results().At(1).Type()) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStringType checks if a type is the basic string type.
+func isStringType(t types.Type) bool {
+	basic, ok := t.(*types.Basic)
+	return ok && basic.Kind() == types.String
+}
+
+// resolveSecrets collects //autodi:secret fields of the provider's struct
+// return types declared in the same package.
+func (s *Scanner) resolveSecrets(pkg *packages.Package, p *Provider) error {
+	for _, ret := range p.Returns {
+		t := ret.Type
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		named, ok := t.(*types.Named)
+		if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != pkg.PkgPath {
+			continue
+		}
+		st, ok := named.Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		spec := findStructSpec(pkg, named.Obj().Name())
+		if spec == nil {
+			continue
+		}
+		for _, field := range spec.Fields.List {
+			key := fieldDirective(field, AnnotSecret)
+			if key == "" {
+				continue
+			}
+			for _, name := range field.Names {
+				if !isStringType(fieldType(st, name.Name)) {
+					return fmt.Errorf("%s: //autodi:secret field %s.%s must be a string",
+						s.fset.Position(name.Pos()), named.Obj().Name(), name.Name)
+				}
+				p.Secrets = append(p.Secrets, SecretField{Field: name.Name, Key: key})
+			}
+		}
+	}
+	return nil
+}
+
+// findStructSpec finds the AST struct type declaration for a type name in pkg.
+func findStructSpec(pkg *packages.Package, name string) *ast.StructType {
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok || ts.Name.Name != name {
+					continue
+				}
+				if st, ok := ts.Type.(*ast.StructType); ok {
+					return st
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// fieldDirective returns the value of an //autodi:<kind> directive in a
+// struct field's doc or line comment, or "".
+func fieldDirective(field *ast.Field, kind string) string {
+	for _, group := range []*ast.CommentGroup{field.Doc, field.Comment} {
+		if group == nil {
+			continue
+		}
+		for _, c := range group.List {
+			text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+			if strings.HasPrefix(text, "autodi:"+kind+" ") {
+				return strings.TrimSpace(strings.TrimPrefix(text, "autodi:"+kind))
+			}
+		}
+	}
+	return ""
+}
+
+// fieldType returns the type of a struct field by name, or nil.
+func fieldType(st *types.Struct, name string) types.Type {
+	for i := 0; i < st.NumFields(); i++ {
+		if st.Field(i).Name() == name {
+			return st.Field(i).Type()
+		}
+	}
+	return nil
+}
+
+// resolveSecretsSource finds the single provider implementing SecretsSource
+// and makes every provider with //autodi:secret fields depend on it, so the
+// source is constructed first and secrets are filled before dependents run.
+func (g *Graph) resolveSecretsSource(providers []*Provider) []error {
+	var users []*Provider
+	for _, p := range providers {
+		if len(p.Secrets) > 0 {
+			users = append(users, p)
+		}
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	var sources []string
+	for _, typeStr := range g.sortedTypes {
+		p := g.ProviderMap[typeStr]
+		for _, ret := range p.Returns {
+			if ret.TypeStr == typeStr && isSecretsSource(ret.Type) {
+				sources = append(sources, typeStr)
+			}
+		}
+	}
+	switch len(sources) {
+	case 0:
+		return []error{fmt.Errorf("%s.%s uses //autodi:secret but no provider implements SecretsSource\n  hint: Secret(ctx context.Context, key string) (string, error)",
+			users[0].PkgName, users[0].FuncName)}
+	case 1:
+	default:
+		return []error{fmt.Errorf("multiple SecretsSource providers: %s", strings.Join(sources, ", "))}
+	}
+
+	g.SecretsSource = sources[0]
+	for _, p := range users {
+		if g.ProviderMap[g.SecretsSource] != p {
+			p.ExtraDeps = append(p.ExtraDeps, g.SecretsSource)
+		}
+	}
+	return nil
+}
+
+// secretTarget returns the variable holding the result of p whose
+// //autodi:secret fields are filled, or "" when p has none or its result
+// isn't kept.
+func secretTarget(p *Provider, varMap map[string]string) string {
+	if len(p.Secrets) == 0 {
+		return ""
+	}
+	for _, ret := range p.Returns {
+		if v, ok := varMap[ret.TypeStr]; ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// writeSecretResolution fills the //autodi:secret fields of target, a result
+// of p, from the SecretsSource right after p is constructed; a failing lookup
+// returns the way fail says. Callers emit it inside the //autodi:when block
+// of p, so a provider that wasn't constructed isn't touched.
+func (cg *CodeGen) writeSecretResolution(buf *bytes.Buffer, p *Provider, target string, varMap map[string]string, fail failReturn) {
+	if len(p.Secrets) == 0 || target == "" {
+		return
+	}
+	sourceVar, ok := varMap[cg.graph.SecretsSource]
+	if !ok {
+		return
+	}
+
+	context := cg.imports.Add("context", "context")
+	fmtQualifier := cg.imports.Add("fmt", "fmt")
+	results := fail.results
+	if results != "" {
+		results += ", "
+	}
+	for _, sf := range p.Secrets {
+		buf.WriteString("\t{\n")
+		fmt.Fprintf(buf, "\t\tv, err := %s.Secret(%s.Background(), %q)\n", sourceVar, context, sf.Key)
+		buf.WriteString("\t\tif err != nil {\n")
+		if fail.cleanup != "" {
+			fmt.Fprintf(buf, "\t\t\t%s\n", fail.cleanup)
+		}
+		fmt.Fprintf(buf, "\t\t\treturn %s%s.Errorf(\"secret %%q for %s.%s: %%w\", %q, err)\n", results, fmtQualifier, p.PkgName, sf.Field, sf.Key)
+		buf.WriteString("\t\t}\n")
+		fmt.Fprintf(buf, "\t\t%s.%s = v\n", target, sf.Field)
+		buf.WriteString("\t}\n")
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package autodi
 
 import (
 	"encoding/json"
@@ -159,12 +159,11 @@ func renderDIHTML(graph *Graph, commands []*DiscoveredCommand, cfg *Config) []by
 	// ── Dependency edges ──────────────────────────────────────────────────────
 	addParamEdges := func(consumerID string, params []TypeRef) {
 		for _, param := range params {
-			if strings.HasPrefix(param.TypeStr, "[]") {
-				elemType := param.TypeStr[2:]
-				sliceLabel := "[]" + briefTypeName(elemType)
+			if elemType, keyed, ok := collectedElem(param.TypeStr); ok {
+				sliceLabel := collectedLabel(param.TypeStr, elemType)
 				if ifaceSet[elemType] {
 					addEdge(mg.ifaceNodeID(elemType), consumerID, sgColorEdgeSlice, sliceLabel)
-				} else if groupName := mg.matchGroupByElem(elemType); groupName != "" {
+				} else if groupName := mg.matchGroupByElem(elemType); !keyed && groupName != "" {
 					for _, gp := range graph.Groups[groupName] {
 						addEdge(mg.nodeID(gp), consumerID, sgColorEdgeSlice, sliceLabel)
 					}
@@ -0,0 +1,197 @@
+package autodi
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// sizeReportTop is how many modules and providers --size-report lists per
+// command.
+const sizeReportTop = 5
+
+// importWeight is the estimated binary weight of a set of packages: how many
+// there are and the size of their Go sources.
+type importWeight struct {
+	pkgs  int
+	bytes int64
+}
+
+func (w importWeight) String() string {
+	return fmt.Sprintf("%d packages, %s", w.pkgs, formatSize(w.bytes))
+}
+
+// formatSize formats a byte count in KB or MB.
+func formatSize(n int64) string {
+	if n >= 1<<20 {
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	}
+	return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+}
+
+// runSizeReport prints, for every command, the packages its providers pull
+// into the binary — the total, the heaviest modules, and for each provider
+// the packages that only it imports — so the constructor that drags a cloud
+// SDK into a small CLI tool stands out.
+func runSizeReport(opts *Options) error {
+	proj, err := analyzeProject(opts)
+	if err != nil {
+		return err
+	}
+
+	// Import closures need every dependency, not just the scanned packages
+	roots := make(map[string]bool)
+	cmdProviders := make(map[*DiscoveredCommand][]*Provider)
+	for _, cmd := range proj.Commands {
+		providers, err := proj.Graph.CommandProviders(cmd)
+		if err != nil {
+			return fmt.Errorf("command %s: %w", cmd.Name, err)
+		}
+		cmdProviders[cmd] = providers
+		roots[cmd.PkgPath] = true
+		for _, p := range providers {
+			roots[importedPkg(p)] = true
+		}
+	}
+	pkgs, err := packages.Load(&packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedModule,
+		Dir:  proj.Root,
+	}, sortedKeys(roots)...)
+	if err != nil {
+		return fmt.Errorf("load imports: %w", err)
+	}
+	byPath := make(map[string]*packages.Package)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		byPath[pkg.PkgPath] = pkg
+	})
+	sizes := make(map[string]int64, len(byPath))
+	for path, pkg := range byPath {
+		for _, name := range pkg.GoFiles {
+			if info, err := os.Stat(name); err == nil {
+				sizes[path] += info.Size()
+			}
+		}
+	}
+	weigh := func(closure map[string]bool) importWeight {
+		w := importWeight{pkgs: len(closure)}
+		for path := range closure {
+			w.bytes += sizes[path]
+		}
+		return w
+	}
+
+	for i, cmd := range proj.Commands {
+		if i > 0 {
+			fmt.Fprintln(os.Stdout)
+		}
+		providers := cmdProviders[cmd]
+
+		// Constructors grouped by package: a package's imports come with any of them
+		ctors := make(map[string][]string)
+		for _, p := range providers {
+			ctors[importedPkg(p)] = append(ctors[importedPkg(p)], p.PkgName+"."+p.FuncName)
+		}
+		cmdClosure := importClosure(byPath, cmd.PkgPath)
+		all := maps.Clone(cmdClosure)
+		closures := make(map[string]map[string]bool, len(ctors))
+		users := make(map[string]int)
+		for pkgPath := range ctors {
+			closures[pkgPath] = importClosure(byPath, pkgPath)
+			for path := range closures[pkgPath] {
+				all[path] = true
+				users[path]++
+			}
+		}
+		fmt.Fprintf(os.Stdout, "%s: %d providers, %s\n", cmd.Name, len(providers), weigh(all))
+
+		// Heaviest modules, standard library excluded
+		modules := make(map[string]map[string]bool)
+		for path := range all {
+			if mod := byPath[path].Module; mod != nil && mod.Path != proj.Cfg.Module {
+				if modules[mod.Path] == nil {
+					modules[mod.Path] = make(map[string]bool)
+				}
+				modules[mod.Path][path] = true
+			}
+		}
+		modPaths := sortedKeys(modules)
+		sort.SliceStable(modPaths, func(a, b int) bool {
+			return weigh(modules[modPaths[a]]).bytes > weigh(modules[modPaths[b]]).bytes
+		})
+		for j, mod := range modPaths {
+			if j == sizeReportTop {
+				fmt.Fprintf(os.Stdout, "  ... %d more modules\n", len(modPaths)-j)
+				break
+			}
+			fmt.Fprintf(os.Stdout, "  module %s: %s\n", mod, weigh(modules[mod]))
+		}
+
+		// Packages only one provider package imports: dropping its
+		// constructors drops them from the binary
+		only := make(map[string]map[string]bool)
+		for pkgPath, closure := range closures {
+			for path := range closure {
+				if users[path] == 1 && !cmdClosure[path] {
+					if only[pkgPath] == nil {
+						only[pkgPath] = make(map[string]bool)
+					}
+					only[pkgPath][path] = true
+				}
+			}
+		}
+		pkgPaths := sortedKeys(only)
+		sort.SliceStable(pkgPaths, func(a, b int) bool {
+			return weigh(only[pkgPaths[a]]).bytes > weigh(only[pkgPaths[b]]).bytes
+		})
+		for j, pkgPath := range pkgPaths {
+			if j == sizeReportTop {
+				break
+			}
+			var via []string
+			for path := range only[pkgPath] {
+				if mod := byPath[path].Module; mod != nil && mod.Path != proj.Cfg.Module && !slices.Contains(via, mod.Path) {
+					via = append(via, mod.Path)
+				}
+			}
+			sort.Strings(via)
+			line := fmt.Sprintf("  only %s: %s", strings.Join(ctors[pkgPath], ", "), weigh(only[pkgPath]))
+			if len(via) > 0 {
+				line += " (" + strings.Join(via, ", ") + ")"
+			}
+			fmt.Fprintln(os.Stdout, line)
+		}
+	}
+	return nil
+}
+
+// importedPkg returns the package a provider adds to the imports: its own,
+// or the logger package of a generated default logger.
+func importedPkg(p *Provider) string {
+	if p.Logger != "" {
+		return p.Returns[0].PkgPath
+	}
+	return p.PkgPath
+}
+
+// importClosure returns a package and everything it imports, transitively.
+func importClosure(byPath map[string]*packages.Package, root string) map[string]bool {
+	closure := make(map[string]bool)
+	var visit func(path string)
+	visit = func(path string) {
+		pkg := byPath[path]
+		if pkg == nil || closure[path] {
+			return
+		}
+		closure[path] = true
+		for _, imp := range pkg.Imports {
+			visit(imp.PkgPath)
+		}
+	}
+	visit(root)
+	return closure
+}
@@ -0,0 +1,99 @@
+package autodi
+
+import (
+	"go/build/constraint"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Skipped code: example and snippet code often sits in testdata/ or
+// _-prefixed directories, or behind //go:build ignore, and must not
+// contribute providers. The go command leaves them out of "./..." patterns
+// but not when named directly (a top-level _examples/ scan root, an
+// //autodi:group path), so the scanner skips them explicitly and --verbose
+// lists what was left out.
+
+// skippedDirReason returns why a directory name is never scanned, or "".
+func skippedDirReason(name string) string {
+	switch {
+	case name == "testdata":
+		return "testdata directory"
+	case strings.HasPrefix(name, "_"):
+		return "_-prefixed directory"
+	}
+	return ""
+}
+
+// skipReason returns why a loaded package is left out of the scan, or "".
+func (s *Scanner) skipReason(pkg *packages.Package) string {
+	for _, elem := range strings.Split(s.cfg.RelPath(pkg.PkgPath), "/") {
+		if reason := skippedDirReason(elem); reason != "" {
+			return reason
+		}
+	}
+	if len(pkg.GoFiles) == 0 && len(pkg.IgnoredFiles) > 0 && !slices.ContainsFunc(pkg.IgnoredFiles, func(name string) bool { return !hasIgnoreTag(name) }) {
+		return "every file is //go:build ignore"
+	}
+	return ""
+}
+
+// dropSkippedPackages removes skipped packages from a scan, recording them
+// and the //go:build ignore files of the packages kept in cfg.Skipped.
+func (s *Scanner) dropSkippedPackages(pkgs []*packages.Package) []*packages.Package {
+	return slices.DeleteFunc(pkgs, func(pkg *packages.Package) bool {
+		if _, ok := s.imported[pkg.PkgPath]; ok {
+			return false
+		}
+		if reason := s.skipReason(pkg); reason != "" {
+			s.cfg.Skipped = append(s.cfg.Skipped, s.cfg.RelPath(pkg.PkgPath)+": "+reason)
+			return true
+		}
+		for _, name := range pkg.IgnoredFiles {
+			if hasIgnoreTag(name) {
+				s.cfg.Skipped = append(s.cfg.Skipped, s.relFile(name)+": //go:build ignore")
+			}
+		}
+		return false
+	})
+}
+
+// relFile returns a file path relative to the module root for reports.
+func (s *Scanner) relFile(name string) string {
+	if rel, ok := strings.CutPrefix(name, s.moduleRoot+string(os.PathSeparator)); ok {
+		return rel
+	}
+	return name
+}
+
+// hasIgnoreTag reports whether a file's build constraint names the ignore
+// tag (//go:build ignore, or a constraint combining it with others).
+func hasIgnoreTag(filename string) bool {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		if !constraint.IsGoBuild(line) {
+			continue
+		}
+		expr, err := constraint.Parse(line)
+		if err != nil {
+			return false
+		}
+		found := false
+		// Eval visits every tag, so each one is seen
+		expr.Eval(func(tag string) bool {
+			found = found || tag == "ignore"
+			return true
+		})
+		return found
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+)
+
+// commandFileName returns the file holding a command's init function in the
+// split layout.
+func commandFileName(cmd *DiscoveredCommand) string {
+	return "autodi_" + cmd.Name + "_gen.go"
+}
+
+// generateCommandFile generates the file holding one command's init function,
+// next to the entrypoint, for the split layout. Helpers it needs are emitted
+// into the entrypoint.
+func (cg *CodeGen) generateCommandFile(cmd *DiscoveredCommand) (GeneratedFile, error) {
+	name := filepath.Join(filepath.Dir(filepath.FromSlash(cg.cfg.Output)), commandFileName(cmd))
+	cg.imports.Reset()
+	alias := cg.imports.AddWithAlias(cmd.PkgPath, cmd.PkgName+"cmd")
+
+	var initBuf bytes.Buffer
+	if err := cg.generateInitFunc(&initBuf, cmd, alias); err != nil {
+		return GeneratedFile{}, fmt.Errorf("generate init for %s: %w", cmd.Name, err)
+	}
+
+	return cg.renderFile(name, "", cg.cfg.Package, initBuf.Bytes())
+}
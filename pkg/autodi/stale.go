@@ -0,0 +1,71 @@
+package autodi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// staleWarnings returns a warning for each annotation that no longer does
+// anything: a //autodi:bind to an interface no constructor or command takes,
+// a //autodi:group path without constructors and an //autodi:optional
+// matching no parameter. Left in place they mislead whoever reads the
+// constructor. candidates are the scanned providers, reachable or not.
+func (g *Graph) staleWarnings(candidates []*Provider, commands []*DiscoveredCommand) []string {
+	asked := make(map[string]bool)
+	ask := func(params []TypeRef) {
+		for _, param := range params {
+			asked[param.TypeStr] = true
+			if elem, _, ok := collectedElem(param.TypeStr); ok {
+				asked[elem] = true
+			}
+		}
+	}
+	for _, p := range candidates {
+		ask(p.Params)
+	}
+	for _, cmd := range commands {
+		ask(cmd.allParams())
+	}
+
+	var warnings []string
+	for _, p := range candidates {
+		// A library exposes every binding as a Container field
+		if g.cfg.Layout != LayoutLibrary {
+			for _, target := range GetAnnotationValues(p.Annotations, AnnotBind) {
+				if !asked[g.resolveConfigType(target)] {
+					warnings = append(warnings, fmt.Sprintf("%s: %s.%s: //autodi:bind %s: no constructor or command takes %s",
+						p.Position, p.PkgName, p.FuncName, target, target))
+				}
+			}
+		}
+		for _, opt := range GetAnnotationValues(p.Annotations, AnnotOptional) {
+			matched := false
+			for _, param := range p.Params {
+				if strings.HasSuffix(param.TypeStr, opt) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				warnings = append(warnings, fmt.Sprintf("%s: %s.%s: //autodi:optional %s matches no parameter",
+					p.Position, p.PkgName, p.FuncName, opt))
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(g.cfg.Groups) {
+		for _, gpath := range g.cfg.Groups[name].Paths {
+			found := false
+			for _, p := range candidates {
+				if g.cfg.inGroupPath(p.PkgPath, gpath) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				warnings = append(warnings, fmt.Sprintf("//autodi:group %s: %s has no constructors", name, gpath))
+			}
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,154 @@
+package autodi
+
+import (
+	"fmt"
+	"go/types"
+)
+
+// Stream directives fill a constructor parameter with a process stream or the
+// command line instead of a provider:
+//
+//	//autodi:stdout out
+//	//autodi:stdin in
+//	func NewPrinter(out io.Writer, in io.Reader) *Printer
+//
+// The value names the parameter. A parameter whose type is a named type
+// called Stdin, Stdout, Stderr or Args (type Stdout io.Writer, type Args
+// []string) is filled without a directive. Tests pass buffers to the
+// constructor instead of swapping os.Stdout.
+const (
+	AnnotStdin  = "stdin"  // //autodi:stdin param, an io.Reader filled with os.Stdin
+	AnnotStdout = "stdout" // //autodi:stdout param, an io.Writer filled with os.Stdout
+	AnnotStderr = "stderr" // //autodi:stderr param, an io.Writer filled with os.Stderr
+	AnnotArgs   = "args"   // //autodi:args param, a []string filled with os.Args
+)
+
+// streamKinds lists the stream directives in the order they're checked.
+var streamKinds = []string{AnnotStdin, AnnotStdout, AnnotStderr, AnnotArgs}
+
+// streamVars maps each stream to the os package variable passed for it.
+var streamVars = map[string]string{
+	AnnotStdin:  "Stdin",
+	AnnotStdout: "Stdout",
+	AnnotStderr: "Stderr",
+	AnnotArgs:   "Args",
+}
+
+// streamTypeNames maps the named types recognized without a directive to
+// their stream.
+var streamTypeNames = map[string]string{
+	"Stdin":  AnnotStdin,
+	"Stdout": AnnotStdout,
+	"Stderr": AnnotStderr,
+	"Args":   AnnotArgs,
+}
+
+// streamMethods lists the *os.File methods an interface filled with a stream
+// may require.
+var streamMethods = map[string]map[string]bool{
+	AnnotStdin:  {"Read": true, "Close": true},
+	AnnotStdout: {"Write": true, "WriteString": true, "Close": true},
+	AnnotStderr: {"Write": true, "WriteString": true, "Close": true},
+}
+
+// resolveStreams marks the provider parameters filled with a process stream.
+func resolveStreams(p *Provider) error {
+	return applyStreams(p.Params, p.Annotations, fmt.Sprintf("%s: %s.%s", p.Position, p.PkgName, p.FuncName))
+}
+
+// applyStreams sets Stream on the parameters named by stream directives and on
+// those of a Stdin/Stdout/Stderr/Args named type; where prefixes errors.
+func applyStreams(params []TypeRef, annotations []Annotation, where string) error {
+	for _, kind := range streamKinds {
+		for _, name := range GetAnnotationValues(annotations, kind) {
+			i := paramIndex(params, name)
+			if i < 0 {
+				return fmt.Errorf("%s: //autodi:%s %s: no parameter named %s", where, kind, name, name)
+			}
+			if params[i].Stream != "" && params[i].Stream != kind {
+				return fmt.Errorf("%s: parameter %s is filled with both %s and %s", where, name, params[i].Stream, kind)
+			}
+			if !fitsStream(kind, params[i].Type) {
+				return fmt.Errorf("%s: //autodi:%s %s: %s can't hold os.%s\n  hint: take %s",
+					where, kind, name, toShortTypeName(params[i].TypeStr), streamVars[kind], streamTypeHint(kind))
+			}
+			params[i].Stream = kind
+		}
+	}
+	for i, param := range params {
+		if param.Stream != "" || param.InStruct != nil {
+			continue
+		}
+		named, ok := param.Type.(*types.Named)
+		if !ok {
+			continue
+		}
+		if kind, ok := streamTypeNames[named.Obj().Name()]; ok && fitsStream(kind, named) {
+			params[i].Stream = kind
+		}
+	}
+	return nil
+}
+
+// paramIndex returns the index of the directly declared parameter called name,
+// or -1.
+func paramIndex(params []TypeRef, name string) int {
+	for i, param := range params {
+		if param.Name == name && param.InStruct == nil {
+			return i
+		}
+	}
+	return -1
+}
+
+// fitsStream reports whether a parameter of type t can hold the stream: an
+// interface that *os.File implements through Read/Write, *os.File itself, or a
+// []string for the arguments.
+func fitsStream(kind string, t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	if kind == AnnotArgs {
+		slice, ok := t.Underlying().(*types.Slice)
+		return ok && isStringType(slice.Elem())
+	}
+	if ptr, ok := t.(*types.Pointer); ok {
+		named, ok := ptr.Elem().(*types.Named)
+		return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "os" && named.Obj().Name() == "File"
+	}
+	iface, ok := t.Underlying().(*types.Interface)
+	if !ok || iface.NumMethods() == 0 {
+		return false
+	}
+	for i := 0; i < iface.NumMethods(); i++ {
+		if !streamMethods[kind][iface.Method(i).Name()] {
+			return false
+		}
+	}
+	return true
+}
+
+// streamTypeHint names the usual parameter type for a stream.
+func streamTypeHint(kind string) string {
+	switch kind {
+	case AnnotStdin:
+		return "an io.Reader"
+	case AnnotArgs:
+		return "a []string"
+	default:
+		return "an io.Writer"
+	}
+}
+
+// streamArg returns the expression passing a stream parameter, or the
+// Container.
+func (cg *CodeGen) streamArg(param TypeRef) string {
+	if param.Stream == containerStream {
+		cg.hasContainer = true
+		return "&" + containerVar
+	}
+	if param.Stream == flagsStream {
+		return cg.flagStructArg(param)
+	}
+	return cg.imports.Add("os", "os") + "." + streamVars[param.Stream]
+}
@@ -0,0 +1,283 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// TestContainerFile is the generated test container, next to each generated main.
+const TestContainerFile = "container_test_gen.go"
+
+// testContainerBuildTag keeps the test container out of production builds;
+// tests opt in with `go test -tags test`.
+const testContainerBuildTag = "test"
+
+// testField is one TestContainer field: a type provided by a singleton.
+type testField struct {
+	Name    string // exported field name (FieldName of the type)
+	TypeStr string
+	Type    string // qualified Go type expression
+}
+
+// generateTestContainers emits one test container per generated main: for the
+// whole graph in the single layout (next to each app's entrypoint), per
+// command in the multi-binary layout.
+// //autodi:test-replace fakes stand in for the types they replace.
+func (cg *CodeGen) generateTestContainers() ([]GeneratedFile, error) {
+	restore := cg.graph.swapTestReplacements()
+	defer restore()
+
+	if cg.cfg.Layout != LayoutMultiBinary {
+		providers, err := cg.graph.AllSingletonProviders()
+		if err != nil {
+			return nil, err
+		}
+		outputs := []string{cg.cfg.Output}
+		if len(cg.cfg.Apps) > 1 {
+			outputs = nil
+			for _, app := range cg.cfg.Apps {
+				outputs = append(outputs, app.Output)
+			}
+		}
+		var files []GeneratedFile
+		for _, out := range outputs {
+			name := filepath.Join(filepath.Dir(filepath.FromSlash(out)), TestContainerFile)
+			f, err := cg.generateTestContainer(name, cg.cfg.Package, providers)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, f)
+		}
+		return files, nil
+	}
+
+	var files []GeneratedFile
+	for _, cmd := range cg.commands {
+		providers, err := cg.graph.CommandProviders(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("command %s: %w", cmd.Name, err)
+		}
+		var singletons []*Provider
+		for _, p := range providers {
+			if !p.IsInvoke && len(p.Groups) == 0 {
+				singletons = append(singletons, p)
+			}
+		}
+		name := filepath.Join(filepath.FromSlash(cmd.Dir), TestContainerFile)
+		f, err := cg.generateTestContainer(name, "main", singletons)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// generateTestContainer emits NewTestContainer, which builds the given
+// singleton providers like the generated init functions do, except that any
+// provided type can be substituted with a With<Field> override. Overridden
+// providers are not called, but their dependencies are still built: every
+// type is a field the test may read. //autodi:invoke providers are not run.
+func (cg *CodeGen) generateTestContainer(name, pkgName string, providers []*Provider) (GeneratedFile, error) {
+	cg.imports.Reset()
+	cg.registerProviderImports(providers)
+
+	// Fields: every type a provider is registered for in the graph
+	fieldsOf := make(map[*Provider][]testField)
+	var fields []testField
+	for _, p := range providers {
+		for _, typeStr := range append(p.allAs(), returnTypeStrs(p)...) {
+			if cg.graph.ProviderMap[typeStr] != p {
+				continue
+			}
+			f := testField{
+				Name:    cg.graph.TypeToField[typeStr],
+				TypeStr: typeStr,
+				Type:    cg.qualifyType(typeStr, ""),
+			}
+			fieldsOf[p] = append(fieldsOf[p], f)
+			fields = append(fields, f)
+		}
+	}
+
+	var body bytes.Buffer
+	body.WriteString("// TestContainer holds the singleton providers of the graph for tests.\n")
+	body.WriteString("type TestContainer struct {\n")
+	for _, f := range fields {
+		fmt.Fprintf(&body, "\t%s %s\n", f.Name, f.Type)
+	}
+	body.WriteString("\n\toverridden map[string]bool\n")
+	body.WriteString("\tcleanups   []func()\n")
+	body.WriteString("}\n\n")
+
+	body.WriteString("// Override substitutes a provider in NewTestContainer.\n")
+	body.WriteString("type Override func(*TestContainer)\n\n")
+	for _, f := range fields {
+		fmt.Fprintf(&body, "// With%s replaces the %s provider.\n", f.Name, toShortTypeName(f.TypeStr))
+		fmt.Fprintf(&body, "func With%s(v %s) Override {\n", f.Name, f.Type)
+		fmt.Fprintf(&body, "\treturn func(c *TestContainer) {\n")
+		fmt.Fprintf(&body, "\t\tc.%s = v\n", f.Name)
+		fmt.Fprintf(&body, "\t\tc.overridden[%q] = true\n", f.Name)
+		body.WriteString("\t}\n")
+		body.WriteString("}\n\n")
+	}
+
+	// varMap points every provided type (and bound interface) at its field
+	varMap := make(map[string]string)
+	for _, f := range fields {
+		varMap[f.TypeStr] = "c." + f.Name
+	}
+	for ifaceStr, concreteStr := range cg.graph.Bindings {
+		if v, ok := varMap[concreteStr]; ok {
+			if _, exists := varMap[ifaceStr]; !exists {
+				varMap[ifaceStr] = v
+			}
+		}
+	}
+
+	body.WriteString("// NewTestContainer builds the dependency graph, skipping overridden providers.\n")
+	body.WriteString("// Call Close to release the providers it constructed.\n")
+	body.WriteString("func NewTestContainer(overrides ...Override) (*TestContainer, error) {\n")
+	body.WriteString("\tc := &TestContainer{overridden: make(map[string]bool)}\n")
+	body.WriteString("\tfor _, o := range overrides {\n")
+	body.WriteString("\t\to(c)\n")
+	body.WriteString("\t}\n\n")
+	for _, p := range providers {
+		if len(fieldsOf[p]) == 0 {
+			continue
+		}
+		if err := cg.writeTestProviderCall(&body, p, fieldsOf[p], varMap); err != nil {
+			return GeneratedFile{}, err
+		}
+	}
+	body.WriteString("\treturn c, nil\n")
+	body.WriteString("}\n\n")
+
+	body.WriteString("// Close releases the providers NewTestContainer constructed, in reverse order.\n")
+	body.WriteString("// Overrides are owned by the test.\n")
+	body.WriteString("func (c *TestContainer) Close() {\n")
+	body.WriteString("\tfor i := len(c.cleanups) - 1; i >= 0; i-- {\n")
+	body.WriteString("\t\tc.cleanups[i]()\n")
+	body.WriteString("\t}\n")
+	body.WriteString("}\n")
+
+	return cg.renderFile(name, buildConstraint(testContainerBuildTag), pkgName, body.Bytes())
+}
+
+// writeTestProviderCall emits one provider call guarded by its overrides.
+func (cg *CodeGen) writeTestProviderCall(buf *bytes.Buffer, p *Provider, fields []testField, varMap map[string]string) error {
+	var guards []string
+	for _, f := range fields {
+		guards = append(guards, fmt.Sprintf("!c.overridden[%q]", f.Name))
+	}
+	fmt.Fprintf(buf, "\tif %s {\n", strings.Join(guards, " || "))
+
+	usedVars := map[string]bool{"c": true, "o": true, "err": true}
+
+	// Slice and keyed map parameters are filled from group members or
+	// auto-collected implementations
+	for i, param := range p.Params {
+		members := cg.graph.SliceMembers(param.TypeStr)
+		if members == nil {
+			continue
+		}
+		cg.registerProviderImports(members)
+		for _, m := range members {
+			if m.HasError {
+				cg.addInitErrorImports()
+			}
+		}
+		elemType, keyed, _ := collectedElem(param.TypeStr)
+		sliceVar := cg.uniqueLocalVar(deriveSliceVarName(elemType), usedVars)
+		if err := cg.writeCollection(buf, sliceVar, elemType, keyed, members, varMap, usedVars); err != nil {
+			return err
+		}
+		varMap = withVar(varMap, p.Params[i].TypeStr, sliceVar)
+	}
+
+	qualifier := cg.replaySwitch(buf, p, usedVars)
+	args := cg.buildLocalArgs(p, varMap)
+
+	// Returns registered as fields get locals; //autodi:as assigns a
+	// return to each of its interface fields.
+	locals := make(map[string]string)  // field name → local var
+	retVars := make(map[string]string) // return type → local var
+	var lhs []string
+	var cleanups []string
+	for i, ret := range p.Returns {
+		var retFields []testField
+		for _, f := range fields {
+			if f.TypeStr == ret.TypeStr || slices.Contains(p.asTypes(i), f.TypeStr) {
+				retFields = append(retFields, f)
+			}
+		}
+		if len(retFields) == 0 {
+			lhs = append(lhs, "_")
+			continue
+		}
+		v := cg.uniqueLocalVar(keywordSafe(localVarName(cg.graph.varFieldName(ret.TypeStr))), usedVars)
+		lhs = append(lhs, v)
+		retVars[ret.TypeStr] = v
+		for _, f := range retFields {
+			locals[f.Name] = v
+		}
+		if isNilable(ret.Type) {
+			if cl := checkCloseable(ret.Type, v); cl != nil {
+				cleanups = append(cleanups, cg.cleanupAppend("c", cl))
+			}
+		}
+	}
+
+	lhs, post := cg.resultLHS(p, lhs, usedVars)
+	qualifier = cg.whenSwitch(buf, p, qualifier, usedVars)
+	assign, endWhen := cg.beginWhen(buf, p, lhs)
+	if p.HasError {
+		cg.addInitErrorImports()
+		fmt.Fprintf(buf, "\t%s %s %s(%s)\n", strings.Join(append(lhs, "err"), ", "), assign, qualifier, strings.Join(args, ", "))
+		buf.WriteString("\tif err != nil {\n")
+		buf.WriteString("\t\tc.Close()\n")
+		cg.writeInitError(buf, p, "\t\t", "nil")
+		buf.WriteString("\t}\n")
+	} else {
+		fmt.Fprintf(buf, "\t%s %s %s(%s)\n", strings.Join(lhs, ", "), assign, qualifier, strings.Join(args, ", "))
+	}
+	buf.WriteString(post)
+	// Only the constructed value gets secrets, not an override
+	cg.writeSecretResolution(buf, p, secretTarget(p, retVars), varMap, failReturn{results: "nil", cleanup: "c.Close()"})
+	buf.WriteString(endWhen)
+
+	for _, f := range fields {
+		if len(fields) == 1 {
+			fmt.Fprintf(buf, "\tc.%s = %s\n", f.Name, locals[f.Name])
+			continue
+		}
+		fmt.Fprintf(buf, "\tif !c.overridden[%q] {\n\t\tc.%s = %s\n\t}\n", f.Name, f.Name, locals[f.Name])
+	}
+	for _, cl := range cleanups {
+		buf.WriteString(cl)
+	}
+	buf.WriteString("\t}\n\n")
+	return nil
+}
+
+// returnTypeStrs returns the type strings of a provider's returns.
+func returnTypeStrs(p *Provider) []string {
+	strs := make([]string, len(p.Returns))
+	for i, ret := range p.Returns {
+		strs[i] = ret.TypeStr
+	}
+	return strs
+}
+
+// withVar returns a copy of varMap with typeStr mapped to v.
+func withVar(varMap map[string]string, typeStr, v string) map[string]string {
+	out := make(map[string]string, len(varMap)+1)
+	for k, val := range varMap {
+		out[k] = val
+	}
+	out[typeStr] = v
+	return out
+}
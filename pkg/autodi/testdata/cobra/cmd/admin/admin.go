@@ -0,0 +1,15 @@
+package admin
+
+import (
+	"example.com/cobra/cmd/billing"
+	"example.com/cobra/cmd/users"
+	"github.com/spf13/cobra"
+)
+
+type Admin struct{}
+
+func NewAdmin(u *users.Users, b *billing.Billing) *Admin { return &Admin{} }
+
+func (a *Admin) Command() *cobra.Command { return &cobra.Command{Use: "admin"} }
+
+func (a *Admin) Handle(cmd *cobra.Command) error { return nil }
@@ -0,0 +1,14 @@
+package billing
+
+import (
+	"example.com/cobra/internal/shop"
+	"github.com/spf13/cobra"
+)
+
+type Billing struct{ shop *shop.Shop }
+
+func NewBilling(s *shop.Shop) *Billing { return &Billing{shop: s} }
+
+func (b *Billing) Command() *cobra.Command { return &cobra.Command{Use: "billing"} }
+
+func (b *Billing) Handle(cmd *cobra.Command) error { return nil }
@@ -0,0 +1,14 @@
+package serve
+
+import (
+	"example.com/cobra/internal/shop"
+	"github.com/spf13/cobra"
+)
+
+type Serve struct{ shop *shop.Shop }
+
+func NewServe(s *shop.Shop) *Serve { return &Serve{shop: s} }
+
+func (s *Serve) Command() *cobra.Command { return &cobra.Command{Use: "serve"} }
+
+func (s *Serve) Handle(cmd *cobra.Command) error { return nil }
@@ -0,0 +1,14 @@
+package users
+
+import (
+	"example.com/cobra/internal/worker"
+	"github.com/spf13/cobra"
+)
+
+type Users struct{ w *worker.Worker }
+
+func NewUsers(w *worker.Worker) *Users { return &Users{w: w} }
+
+func (u *Users) Command() *cobra.Command { return &cobra.Command{Use: "users"} }
+
+func (u *Users) Handle(cmd *cobra.Command) error { return nil }
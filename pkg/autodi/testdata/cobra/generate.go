@@ -0,0 +1,4 @@
+//go:generate go run github.com/iVampireSP/autodi
+//autodi:app shop "Shop" "A shop"
+
+package main
@@ -0,0 +1,21 @@
+package pay
+
+import "example.com/cobra/internal/factory"
+
+type Stripe struct {
+	client *factory.Client
+
+	//autodi:secret stripe/key
+	Key string
+}
+
+func NewStripe(c *factory.Client) *Stripe { return &Stripe{client: c} }
+
+func (s *Stripe) Name() string { return "stripe" }
+
+type Cash struct{}
+
+//autodi:key cash
+func NewCash() (*Cash, error) { return &Cash{}, nil }
+
+func (c *Cash) Name() string { return "cash" }
@@ -0,0 +1,13 @@
+package shop
+
+import (
+	"example.com/cobra/internal/checkout"
+	"example.com/cobra/internal/mail"
+)
+
+type Shop struct {
+	checkout *checkout.Service
+	mailer   *mail.Mailer // nil unless MAIL_ENABLED is set
+}
+
+func NewShop(c *checkout.Service, m *mail.Mailer) *Shop { return &Shop{checkout: c, mailer: m} }
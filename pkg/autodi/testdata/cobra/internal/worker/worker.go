@@ -0,0 +1,23 @@
+package worker
+
+import (
+	"context"
+	"io"
+)
+
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) map[string]error
+}
+
+type Worker struct {
+	out    io.Writer
+	health HealthChecker
+}
+
+//autodi:stdout out
+func NewWorker(out io.Writer, h HealthChecker) *Worker { return &Worker{out: out, health: h} }
+
+func (w *Worker) Start(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
@@ -0,0 +1,14 @@
+package serve
+
+import (
+	"context"
+
+	"example.com/kong/internal/store"
+	"example.com/kong/internal/worker"
+)
+
+type Serve struct {
+	Port int `default:"8080" help:"listen port"`
+}
+
+func (s *Serve) Run(ctx context.Context, st *store.Store, w *worker.Worker) error { return nil }
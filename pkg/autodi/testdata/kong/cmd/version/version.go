@@ -0,0 +1,5 @@
+package version
+
+type Version struct{}
+
+func (v *Version) Run() error { return nil }
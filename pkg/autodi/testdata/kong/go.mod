@@ -0,0 +1,5 @@
+module example.com/kong
+
+go 1.23
+
+require github.com/alecthomas/kong v1.13.0
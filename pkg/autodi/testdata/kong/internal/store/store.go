@@ -0,0 +1,11 @@
+package store
+
+import "context"
+
+type Store struct{}
+
+func NewStore() (*Store, error) { return &Store{}, nil }
+
+func (s *Store) Ping(ctx context.Context) error { return nil }
+
+func (s *Store) Shutdown(ctx context.Context) error { return nil }
@@ -0,0 +1,17 @@
+package worker
+
+import (
+	"context"
+
+	"example.com/kong/internal/store"
+)
+
+type Worker struct{ store *store.Store }
+
+//autodi:nostart
+func NewWorker(s *store.Store) *Worker { return &Worker{store: s} }
+
+func (w *Worker) Start(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
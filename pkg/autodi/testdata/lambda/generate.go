@@ -0,0 +1,4 @@
+//go:generate go run github.com/iVampireSP/autodi
+//autodi:runtime lambda handler.NewHandler
+
+package main
@@ -0,0 +1,8 @@
+package config
+
+type Config struct {
+	//autodi:secret payments/token
+	Token string
+}
+
+func NewConfig() (*Config, error) { return &Config{}, nil }
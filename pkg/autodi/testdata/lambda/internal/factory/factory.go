@@ -0,0 +1,16 @@
+package factory
+
+import (
+	"context"
+
+	"example.com/lambda/internal/config"
+)
+
+type Client struct{ token string }
+
+//autodi:lazy
+func NewClient(cfg *config.Config) *Client { return &Client{token: cfg.Token} }
+
+func (c *Client) Shutdown(ctx context.Context) error { return nil }
+
+func (c *Client) Ping(ctx context.Context) error { return nil }
@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"context"
+
+	"example.com/lambda/internal/shop"
+)
+
+type Order struct{ ID string }
+
+type Handler struct{ shop *shop.Shop }
+
+func NewHandler(s *shop.Shop) *Handler { return &Handler{shop: s} }
+
+func (h *Handler) Handle(ctx context.Context, order Order) (string, error) { return order.ID, nil }
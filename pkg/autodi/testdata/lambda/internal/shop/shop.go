@@ -0,0 +1,13 @@
+package shop
+
+import (
+	"example.com/lambda/internal/checkout"
+	"example.com/lambda/internal/mail"
+)
+
+type Shop struct {
+	checkout *checkout.Service
+	mailer   *mail.Mailer // nil unless MAIL_ENABLED is set
+}
+
+func NewShop(c *checkout.Service, m *mail.Mailer) *Shop { return &Shop{checkout: c, mailer: m} }
@@ -0,0 +1,9 @@
+package vault
+
+import "context"
+
+type Vault struct{}
+
+func NewVault() *Vault { return &Vault{} }
+
+func (v *Vault) Secret(ctx context.Context, key string) (string, error) { return "s3cret", nil }
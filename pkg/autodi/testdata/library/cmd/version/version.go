@@ -0,0 +1,11 @@
+package version
+
+import "github.com/spf13/cobra"
+
+type Version struct{}
+
+func NewVersion() *Version { return &Version{} }
+
+func (v *Version) Command() *cobra.Command { return &cobra.Command{Use: "version"} }
+
+func (v *Version) Handle(cmd *cobra.Command) error { return nil }
@@ -0,0 +1,5 @@
+//go:generate go run github.com/iVampireSP/autodi
+//autodi:app shop "Shop" "A shop"
+//autodi:layout library
+
+package main
@@ -0,0 +1,7 @@
+package checkout
+
+type Method interface{ Name() string }
+
+type Service struct{ methods map[string]Method }
+
+func NewService(methods map[string]Method) *Service { return &Service{methods: methods} }
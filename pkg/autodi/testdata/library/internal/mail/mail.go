@@ -0,0 +1,9 @@
+package mail
+
+type Mailer struct {
+	//autodi:secret mail/password
+	Password string
+}
+
+//autodi:when env=MAIL_ENABLED
+func NewMailer() *Mailer { return &Mailer{} }
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"example.com/library/wiring"
+)
+
+func main() {
+	serve, cleanup, err := wiring.BuildServe(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer cleanup()
+	if err := serve.Command().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
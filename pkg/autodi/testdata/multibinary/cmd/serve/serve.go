@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+
+	"example.com/multibinary/internal/store"
+	"github.com/spf13/cobra"
+)
+
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) map[string]error
+}
+
+type Serve struct {
+	store  *store.Store
+	health HealthChecker
+}
+
+func NewServe(s *store.Store, h HealthChecker) *Serve { return &Serve{store: s, health: h} }
+
+func (s *Serve) Command() *cobra.Command { return &cobra.Command{Use: "serve"} }
+
+func (s *Serve) Handle(cmd *cobra.Command) error { return nil }
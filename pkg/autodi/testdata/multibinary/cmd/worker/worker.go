@@ -0,0 +1,14 @@
+package main
+
+import (
+	"example.com/multibinary/internal/queue"
+	"github.com/spf13/cobra"
+)
+
+type Worker struct{ consumer *queue.Consumer }
+
+func NewWorker(c *queue.Consumer) *Worker { return &Worker{consumer: c} }
+
+func (w *Worker) Command() *cobra.Command { return &cobra.Command{Use: "worker"} }
+
+func (w *Worker) Handle(cmd *cobra.Command) error { return nil }
@@ -0,0 +1,5 @@
+//go:generate go run github.com/iVampireSP/autodi
+//autodi:app ops "Ops" "Operations tools"
+//autodi:layout multi-binary
+
+package main
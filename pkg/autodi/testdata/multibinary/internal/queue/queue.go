@@ -0,0 +1,16 @@
+package queue
+
+import (
+	"context"
+
+	"example.com/multibinary/internal/store"
+)
+
+type Consumer struct{ store *store.Store }
+
+func NewConsumer(s *store.Store) *Consumer { return &Consumer{store: s} }
+
+func (c *Consumer) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
@@ -0,0 +1,10 @@
+package req
+
+import "context"
+
+type Tx struct{}
+
+//autodi:scope request
+func NewTx(ctx context.Context) *Tx { return &Tx{} }
+
+func (t *Tx) Shutdown(ctx context.Context) error { return nil }
@@ -0,0 +1,128 @@
+package autodi
+
+import (
+	"fmt"
+	"go/ast"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// extractTestReplacements returns the //autodi:test-replace constructors of a
+// package. They are fakes for the generated test container and never take part
+// in production wiring.
+func (s *Scanner) extractTestReplacements(pkg *packages.Package) []*Provider {
+	var fakes []*Provider
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || !fn.Name.IsExported() {
+				continue
+			}
+			annotations := ParseAnnotations(fn)
+			if !HasAnnotation(annotations, AnnotTestReplace) {
+				continue
+			}
+			if p := s.buildProvider(pkg, fn, annotations); p != nil {
+				fakes = append(fakes, p)
+			}
+		}
+	}
+	return fakes
+}
+
+// resolveTestReplacements validates //autodi:test-replace fakes against the
+// graph: the replaced type must have a provider (or be a bound interface), the
+// fake's first return must be that type or implement it, and the fake's own
+// dependencies must be provided without introducing a cycle.
+func (g *Graph) resolveTestReplacements(fakes []*Provider) []error {
+	var errs []error
+	for _, fake := range fakes {
+		targets := GetAnnotationValues(fake.Annotations, AnnotTestReplace)
+		if len(targets) == 0 || targets[0] == "" {
+			errs = append(errs, fmt.Errorf("%s: //autodi:test-replace needs the replaced type\n  hint: //autodi:test-replace pkg.RealClient", fake.Position))
+			continue
+		}
+		target := targets[0]
+		typeStr := g.resolveConfigType(target)
+		_, provided := g.ProviderMap[typeStr]
+		_, bound := g.Bindings[typeStr]
+		if !provided && !bound {
+			errs = append(errs, fmt.Errorf("%s: //autodi:test-replace %s: no provider supplies that type", fake.Position, target))
+			continue
+		}
+
+		ret := fake.Returns[0]
+		if ret.TypeStr != typeStr {
+			iface := g.findIfaceType(typeStr)
+			if iface == nil || !implementsIface(ret.Type, iface) {
+				errs = append(errs, fmt.Errorf("%s: //autodi:test-replace %s: %s is neither that type nor implements it",
+					fake.Position, target, toShortTypeName(ret.TypeStr)))
+				continue
+			}
+			fake.As = []string{typeStr}
+		}
+
+		if existing, ok := g.TestReplace[typeStr]; ok {
+			errs = append(errs, fmt.Errorf("type %s has multiple test replacements:\n  1. %s.%s (%s)\n  2. %s.%s (%s)",
+				typeStr, existing.PkgName, existing.FuncName, existing.Position,
+				fake.PkgName, fake.FuncName, fake.Position))
+			continue
+		}
+		for _, param := range fake.Params {
+			if param.Optional || g.SliceMembers(param.TypeStr) != nil {
+				continue
+			}
+			if _, ok := g.ProviderMap[g.resolveType(param.TypeStr)]; !ok {
+				errs = append(errs, fmt.Errorf("%s: test replacement %s.%s needs %s, which no provider supplies",
+					fake.Position, fake.PkgName, fake.FuncName, toShortTypeName(param.TypeStr)))
+			}
+		}
+		g.TestReplace[typeStr] = fake
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	restore := g.swapTestReplacements()
+	defer restore()
+	return g.VerifyAcyclic()
+}
+
+// swapTestReplacements points the graph at the test fakes, so the test
+// container is built from the same topological sort with the fakes in place.
+// Returns a function restoring the production graph.
+func (g *Graph) swapTestReplacements() func() {
+	type saved struct {
+		provider *Provider
+		binding  string
+		field    string
+	}
+	prev := make(map[string]saved, len(g.TestReplace))
+	for typeStr, fake := range g.TestReplace {
+		prev[typeStr] = saved{g.ProviderMap[typeStr], g.Bindings[typeStr], g.TypeToField[typeStr]}
+		g.ProviderMap[typeStr] = fake
+		// A bound interface resolves to the fake, not the production implementation
+		delete(g.Bindings, typeStr)
+		g.TypeToField[typeStr] = g.fieldName(typeStr)
+	}
+	g.rebuildSortedTypes()
+
+	return func() {
+		for typeStr, s := range prev {
+			if s.provider != nil {
+				g.ProviderMap[typeStr] = s.provider
+			} else {
+				delete(g.ProviderMap, typeStr)
+			}
+			if s.binding != "" {
+				g.Bindings[typeStr] = s.binding
+			}
+			if s.field != "" {
+				g.TypeToField[typeStr] = s.field
+			} else {
+				delete(g.TypeToField, typeStr)
+			}
+		}
+		g.rebuildSortedTypes()
+	}
+}
@@ -1,7 +1,8 @@
-package main
+package autodi
 
 import (
 	"fmt"
+	"go/token"
 	"strings"
 )
 
@@ -28,11 +29,15 @@ func (g *Graph) VerifyAcyclic() []error {
 			// Found cycle — extract it from trail
 			startIdx := path[typeStr]
 			cycle := append(trail[startIdx:], typeStr)
-			errs = append(errs, fmt.Errorf(
-				"cycle dependency detected:\n  %s\nproviders involved:\n%s",
-				strings.Join(cycle, " → "),
-				g.formatCycleProviders(cycle),
-			))
+			var pos token.Position
+			if p := g.ProviderMap[cycle[0]]; p != nil {
+				pos = p.Position
+			}
+			d := diagf(CodeCycle, pos, strings.Join(cycle, " → "), g.formatCycleProviders(cycle))
+			if fixes := g.formatCycleFixes(cycle); fixes != "" {
+				d.Message += "\n" + fixes
+			}
+			errs = append(errs, d)
 			return
 		}
 
@@ -46,6 +51,9 @@ func (g *Graph) VerifyAcyclic() []error {
 				depType := g.resolveType(param.TypeStr)
 				dfs(depType)
 			}
+			for _, dep := range provider.ExtraDeps {
+				dfs(g.resolveType(dep))
+			}
 		}
 
 		trail = trail[:len(trail)-1]
@@ -113,6 +121,11 @@ func (g *Graph) TopologicalSortWithExtraEdges(targetTypes []string, extraEdges m
 				return err
 			}
 		}
+		for _, dep := range provider.ExtraDeps {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
 
 		if extraEdges != nil {
 			for _, ret := range provider.Returns {
@@ -0,0 +1,65 @@
+package autodi
+
+import (
+	"fmt"
+	"io"
+)
+
+// treeShared marks a provider already expanded elsewhere in a --tree.
+const treeShared = " (*)"
+
+// writeProviderTree prints the dependency tree of a command for --tree: each
+// parameter with the provider satisfying it, indented under what takes it. A
+// provider expanded earlier in the command's tree is marked (*) instead of
+// repeated.
+func (g *Graph) writeProviderTree(w io.Writer, cmd *DiscoveredCommand) {
+	fmt.Fprintf(w, "%s (%s.%s)\n", cmd.Name, cmd.PkgName, cmd.FuncName)
+	expanded := make(map[*Provider]bool)
+
+	// Each parameter is one node, or one per member of a []Interface slice
+	type node struct {
+		label string
+		p     *Provider
+	}
+	nodes := func(params []TypeRef) []node {
+		var out []node
+		for _, param := range params {
+			short := toShortTypeName(param.TypeStr)
+			switch deps := archParamProviders(g, param); {
+			case param.Stream != "":
+				out = append(out, node{label: fmt.Sprintf("%s (%s)", short, param.Stream)})
+			case len(deps) == 0 && param.Optional:
+				out = append(out, node{label: short + " — unresolved, optional"})
+			case len(deps) == 0:
+				out = append(out, node{label: short + " — unresolved"})
+			default:
+				for _, dep := range deps {
+					out = append(out, node{label: fmt.Sprintf("%s — %s.%s", short, dep.PkgName, dep.FuncName), p: dep})
+				}
+			}
+		}
+		return out
+	}
+
+	var walk func(params []TypeRef, prefix string)
+	walk = func(params []TypeRef, prefix string) {
+		children := nodes(params)
+		for i, n := range children {
+			branch, next := "├── ", "│   "
+			if i == len(children)-1 {
+				branch, next = "└── ", "    "
+			}
+			if n.p != nil && expanded[n.p] {
+				fmt.Fprintf(w, "%s%s%s%s\n", prefix, branch, n.label, treeShared)
+				continue
+			}
+			fmt.Fprintf(w, "%s%s%s\n", prefix, branch, n.label)
+			if n.p != nil {
+				expanded[n.p] = true
+				walk(n.p.Params, prefix+next)
+			}
+		}
+	}
+	walk(cmd.Params, "")
+	fmt.Fprintln(w)
+}
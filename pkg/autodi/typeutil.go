@@ -1,15 +1,36 @@
-package main
+package autodi
 
 import (
+	"go/token"
 	"go/types"
+	"regexp"
 	"sort"
 	"strings"
 	"unicode"
 )
 
+// isFuncOrChanType reports whether typeStr is a function or channel type,
+// such as func(context.Context) error or chan example.com/app/bus.Event.
+// These are provided like any other type but have no name of their own.
+func isFuncOrChanType(typeStr string) bool {
+	for _, prefix := range []string{"func(", "chan ", "<-chan ", "chan<- "} {
+		if strings.HasPrefix(typeStr, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// qualifiedNameRe matches the package-qualified type names within a
+// function or channel type string.
+var qualifiedNameRe = regexp.MustCompile(`[\w\-.~]+(?:/[\w\-.~]+)*\.[A-Za-z_]\w*`)
+
 // toShortTypeName converts a full type string to its short form.
 // "*github.com/.../iam.IAM" → "*iam.IAM"
 func toShortTypeName(typeStr string) string {
+	if isFuncOrChanType(typeStr) {
+		return qualifiedNameRe.ReplaceAllStringFunc(typeStr, toShortTypeName)
+	}
 	prefix := ""
 	s := typeStr
 	if strings.HasPrefix(s, "*") {
@@ -80,6 +101,15 @@ func localVarName(fieldName string) string {
 	return strings.ToLower(string(runes[:upperCount-1])) + string(runes[upperCount-1:])
 }
 
+// keywordSafe appends "Val" to a local variable name that is a Go keyword,
+// like func for an unnamed function type.
+func keywordSafe(name string) string {
+	if token.IsKeyword(name) {
+		return name + "Val"
+	}
+	return name
+}
+
 // zeroValueForType returns the zero value literal for a Go type.
 func zeroValueForType(t types.Type) string {
 	switch u := t.Underlying().(type) {
@@ -99,6 +129,45 @@ func zeroValueForType(t types.Type) string {
 	}
 }
 
+// resolveAliases replaces type aliases, including those nested in pointer,
+// slice, array, map and channel types, with the types they denote, so
+// "type DB = sql.DB" and *sql.DB are one graph node. Named types such as
+// "type Port int" are kept: they are distinct from their underlying kinds.
+func resolveAliases(t types.Type) types.Type {
+	switch u := types.Unalias(t).(type) {
+	case *types.Pointer:
+		return types.NewPointer(resolveAliases(u.Elem()))
+	case *types.Slice:
+		return types.NewSlice(resolveAliases(u.Elem()))
+	case *types.Array:
+		return types.NewArray(resolveAliases(u.Elem()), u.Len())
+	case *types.Map:
+		return types.NewMap(resolveAliases(u.Key()), resolveAliases(u.Elem()))
+	case *types.Chan:
+		return types.NewChan(u.Dir(), resolveAliases(u.Elem()))
+	case *types.Signature:
+		// Parameter names aren't part of a function type's identity:
+		// func(ctx context.Context) error is func(context.Context) error
+		unnamed := func(tuple *types.Tuple) *types.Tuple {
+			vars := make([]*types.Var, tuple.Len())
+			for i := range vars {
+				vars[i] = types.NewParam(tuple.At(i).Pos(), tuple.At(i).Pkg(), "", resolveAliases(tuple.At(i).Type()))
+			}
+			return types.NewTuple(vars...)
+		}
+		return types.NewSignatureType(nil, nil, nil, unnamed(u.Params()), unnamed(u.Results()), u.Variadic())
+	default:
+		return u
+	}
+}
+
+// isPredeclaredBasic reports whether t is an unnamed basic type such as string
+// or int, which can't identify a dependency on its own.
+func isPredeclaredBasic(t types.Type) bool {
+	_, ok := types.Unalias(t).(*types.Basic)
+	return ok
+}
+
 // cmdExportName converts a command name to an exported function name.
 // "admin_api" → "AdminAPI", "admin" → "Admin"
 func cmdExportName(name string) string {
@@ -147,6 +216,16 @@ func sortedGroupNames(groups map[string]GroupConfig) []string {
 	return names
 }
 
+// sortedKeys returns the keys of a string-keyed map in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // typePkgPathFromTypeStr extracts the package path from a full type string.
 func typePkgPathFromTypeStr(typeStr string) string {
 	s := strings.TrimPrefix(typeStr, "*")
@@ -0,0 +1,152 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// outputFormat is the revision of the generated code layout (init function
+// signatures, Container, helpers). Bump it when generated files from an older
+// autodi can no longer be spliced or mixed with new output.
+const outputFormat = 1
+
+// minOutputFormat is the oldest output format this autodi regenerates in place.
+// Older outputs need an explicit --migrate-output.
+const minOutputFormat = 1
+
+// modulePath is autodi's module, the main module of the autodi binary and a
+// dependency of the tools embedding this package.
+const modulePath = "github.com/iVampireSP/autodi"
+
+// toolVersion returns the module version autodi was built at, e.g. "v0.4.1"
+// when run as `go run github.com/iVampireSP/autodi@v0.4.1`.
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	if info.Main.Path == modulePath && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath && dep.Version != "" {
+			return dep.Version
+		}
+	}
+	return "(devel)"
+}
+
+// configHashLen is how many hex digits of the generate.go hash the version
+// stamp records.
+const configHashLen = 12
+
+// versionStamp is the header line recording what produced a file: the autodi
+// version, the output format, the module's go directive and the hash of the
+// generate.go directives. It has no timestamp, so regenerating is reproducible.
+func versionStamp(cfg *Config) string {
+	return outputStamp{Version: toolVersion(), Format: outputFormat, Go: cfg.GoVersion, Config: cfg.ConfigHash}.String() + "\n"
+}
+
+var stampRe = regexp.MustCompile(`(?m)^// autodi (\S+), output format (\d+)(?:, go (\S+))?(?:, config ([0-9a-f]+))?\.$`)
+
+// outputStamp is the version information parsed from a generated file.
+type outputStamp struct {
+	Version string
+	Format  int
+	Go      string // "" in files stamped before the go version was recorded
+	Config  string // "" in files stamped before the config hash was recorded
+}
+
+func (s outputStamp) String() string {
+	line := fmt.Sprintf("// autodi %s, output format %d", s.Version, s.Format)
+	if s.Go != "" {
+		line += ", go " + s.Go
+	}
+	if s.Config != "" {
+		line += ", config " + s.Config
+	}
+	return line + "."
+}
+
+// parseStamp reads the version stamp of a previously generated file. Files
+// from before stamping report format 1, the layout they were written in.
+func parseStamp(src []byte) outputStamp {
+	m := stampRe.FindSubmatch(src)
+	if m == nil {
+		return outputStamp{Version: "unknown", Format: 1}
+	}
+	format, _ := strconv.Atoi(string(m[2]))
+	return outputStamp{Version: string(m[1]), Format: format, Go: string(m[3]), Config: string(m[4])}
+}
+
+// checkRequiredVersion checks an autodi version against a constraint such as
+// ">=v0.5" or ">=0.5,<0.7" (the v is optional). A development build can't tell
+// its version and passes.
+func checkRequiredVersion(constraint, version string) error {
+	if version == "(devel)" {
+		return nil
+	}
+	for _, term := range strings.Split(constraint, ",") {
+		i := strings.IndexAny(term, "v0123456789")
+		if i < 0 {
+			i = len(term)
+		}
+		op, want := term[:i], strings.TrimPrefix(term[i:], "v")
+		if want == "" || !semver.IsValid("v"+want) {
+			return fmt.Errorf("%q is not a version constraint\n  hint: e.g. >=v0.5 or >=0.5,<0.7", term)
+		}
+		cmp := semver.Compare(version, "v"+want)
+		ok := false
+		switch op {
+		case ">=":
+			ok = cmp >= 0
+		case ">":
+			ok = cmp > 0
+		case "<=":
+			ok = cmp <= 0
+		case "<":
+			ok = cmp < 0
+		case "=", "==", "":
+			ok = cmp == 0
+		default:
+			return fmt.Errorf("unknown operator %q\n  hint: use >=, >, <=, < or =", op)
+		}
+		if !ok {
+			return fmt.Errorf("this autodi is %s\n  hint: run a matching version: go run github.com/iVampireSP/autodi@<version>", version)
+		}
+	}
+	return nil
+}
+
+// checkOutputSkew reports whether an existing generated file can be updated by
+// this autodi. A file from a newer output format, or one older than
+// minOutputFormat, is an error unless migrate is set, so teams running
+// different autodi versions don't silently produce mixed-version code.
+// Returns true when the file must be rewritten from scratch.
+func checkOutputSkew(name string, old []byte, migrate bool) (bool, error) {
+	if !bytes.Contains(old, []byte(generatedMarker)) {
+		return false, nil
+	}
+	stamp := parseStamp(old)
+	switch {
+	case stamp.Format > outputFormat:
+		if !migrate {
+			return false, fmt.Errorf("%s was generated by autodi %s (output format %d), newer than this autodi %s (format %d)\n  hint: upgrade autodi, or rerun with --migrate-output to downgrade the file",
+				name, stamp.Version, stamp.Format, toolVersion(), outputFormat)
+		}
+		return true, nil
+	case stamp.Format < minOutputFormat:
+		if !migrate {
+			return false, fmt.Errorf("%s was generated by autodi %s (output format %d), which this autodi %s can't update in place\n  hint: rerun with --migrate-output to regenerate it",
+				name, stamp.Version, stamp.Format, toolVersion())
+		}
+		return true, nil
+	}
+	return migrate && stamp.Format != outputFormat, nil
+}
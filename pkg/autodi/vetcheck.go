@@ -0,0 +1,365 @@
+package autodi
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// directiveAnalyzer checks //autodi: directives where they are written, for
+// editors and CI, without generating anything:
+//
+//	go vet -vettool=$(which autodi) ./...
+//
+// autodi itself ignores directive kinds it doesn't know; the analyzer
+// reports them, with the closest known kind as a suggested fix, together
+// with bind targets that don't exist or aren't implemented, optional types
+// matching no parameter and generate.go groups whose path is missing.
+var directiveAnalyzer = &analysis.Analyzer{
+	Name: "autodi",
+	Doc:  "check //autodi: directives: unknown kinds, bind targets, optional parameters and group paths",
+	URL:  "https://github.com/iVampireSP/autodi",
+	Run:  runDirectiveAnalyzer,
+}
+
+// Directive kinds accepted on type declarations and struct fields.
+var (
+	typeDirectives  = []string{AnnotIn, AnnotOut, AnnotConfig, AnnotFlags}
+	fieldDirectives = []string{AnnotFlag, AnnotSecret, AnnotEnv}
+)
+
+// isVetInvocation reports whether autodi was started by go vet -vettool,
+// which asks for -V=full and -flags, then passes a vet.cfg file.
+func isVetInvocation(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	return args[0] == "-V=full" || args[0] == "-flags" || strings.HasSuffix(args[len(args)-1], ".cfg")
+}
+
+// directive is an //autodi: line of a comment.
+type directive struct {
+	comment *ast.Comment
+	kind    string
+	value   string
+	kindPos token.Pos // position of kind in the file
+}
+
+// parseDirective parses an //autodi: comment line; ok is false for other
+// comments.
+func parseDirective(c *ast.Comment) (d directive, ok bool) {
+	text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+	if !strings.HasPrefix(text, "autodi:") {
+		return directive{}, false
+	}
+	kind, value, _ := strings.Cut(strings.TrimPrefix(text, "autodi:"), " ")
+	return directive{
+		comment: c,
+		kind:    strings.TrimSpace(kind),
+		value:   strings.TrimSpace(value),
+		kindPos: c.Pos() + token.Pos(strings.Index(c.Text, "autodi:")+len("autodi:")),
+	}, true
+}
+
+// commentDirectives returns the //autodi: lines of a comment group.
+func commentDirectives(group *ast.CommentGroup) []directive {
+	if group == nil {
+		return nil
+	}
+	var directives []directive
+	for _, c := range group.List {
+		if d, ok := parseDirective(c); ok {
+			directives = append(directives, d)
+		}
+	}
+	return directives
+}
+
+func runDirectiveAnalyzer(pass *analysis.Pass) (any, error) {
+	for _, f := range pass.Files {
+		filename := pass.Fset.File(f.Pos()).Name()
+		if isGeneratedFile(f) {
+			continue
+		}
+		if root := generateFileRoot(filename); root != "" {
+			checkGenerateDirectives(pass, f, root)
+		} else {
+			checkKinds(pass, commentDirectives(f.Doc), pkgDirectives, "package")
+		}
+
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				directives := commentDirectives(n.Doc)
+				checkKinds(pass, directives, funcAnnotations, "function")
+				checkFuncDirectives(pass, f, n, directives)
+			case *ast.GenDecl:
+				for _, spec := range n.Specs {
+					ts, ok := spec.(*ast.TypeSpec)
+					if !ok {
+						continue
+					}
+					doc := ts.Doc
+					if doc == nil && len(n.Specs) == 1 {
+						doc = n.Doc
+					}
+					checkKinds(pass, commentDirectives(doc), typeDirectives, "type")
+				}
+			case *ast.Field:
+				checkKinds(pass, commentDirectives(n.Doc), fieldDirectives, "struct field")
+				checkKinds(pass, commentDirectives(n.Comment), fieldDirectives, "struct field")
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
+
+// generateFileRoot returns the module root when filename is the generate.go
+// of the module root or of a directory-level app, whose directives configure
+// the app; "" otherwise.
+func generateFileRoot(filename string) string {
+	if filepath.Base(filename) != "generate.go" {
+		return ""
+	}
+	dir := filepath.Dir(filename)
+	app := isAppGenerateFile(dir)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if !app || parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// checkKinds reports directives whose kind isn't accepted where they are
+// written, suggesting the closest accepted kind.
+func checkKinds(pass *analysis.Pass, directives []directive, known []string, where string) {
+	for _, d := range directives {
+		if slices.Contains(known, d.kind) || where == "function" && isCustomAnnotation(d.kind) {
+			continue
+		}
+		diag := analysis.Diagnostic{
+			Pos:     d.comment.Pos(),
+			End:     d.comment.End(),
+			Message: fmt.Sprintf("unknown %s directive //autodi:%s", where, d.kind),
+		}
+		if closest := closestKind(d.kind, known); closest != "" {
+			diag.Message += fmt.Sprintf(" (did you mean //autodi:%s?)", closest)
+			diag.SuggestedFixes = []analysis.SuggestedFix{{
+				Message:   "Replace with //autodi:" + closest,
+				TextEdits: []analysis.TextEdit{{Pos: d.kindPos, End: d.kindPos + token.Pos(len(d.kind)), NewText: []byte(closest)}},
+			}}
+		}
+		pass.Report(diag)
+	}
+}
+
+// closestKind returns the known kind within two edits of kind, or "".
+func closestKind(kind string, known []string) string {
+	best, bestDist := "", 3
+	for _, k := range known {
+		if d := editDistance(kind, k); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// checkGenerateDirectives checks the directives of a module's generate.go.
+func checkGenerateDirectives(pass *analysis.Pass, f *ast.File, root string) {
+	var directives []directive
+	for _, group := range f.Comments {
+		directives = append(directives, commentDirectives(group)...)
+	}
+	checkKinds(pass, directives, generateDirectives, "generate.go")
+
+	for _, d := range directives {
+		if d.kind != "group" {
+			continue
+		}
+		parts := strings.Fields(d.value)
+		if len(parts) < 3 {
+			pass.Reportf(d.comment.Pos(), "//autodi:group needs <name> []<Interface> <path>...")
+			continue
+		}
+		for _, gpath := range parts[2:] {
+			if info, err := os.Stat(filepath.Join(root, filepath.FromSlash(groupPathDir(root, gpath)))); err != nil || !info.IsDir() {
+				pass.Reportf(d.comment.Pos(), "//autodi:group %s: %s is not a directory of the module or its workspace", parts[0], gpath)
+			}
+		}
+	}
+}
+
+// groupPathDir maps a group path given as an import path of the module or a
+// go.work member to its directory relative to root; other paths are already
+// directories.
+func groupPathDir(root, gpath string) string {
+	modules := make(map[string]string)
+	if gm, err := parseGoMod(root); err == nil {
+		modules[gm.Module] = "."
+	}
+	if workFile := findWorkFile(root); workFile != "" {
+		if members, err := parseWorkspace(workFile, root); err == nil {
+			maps.Copy(modules, members)
+		}
+	}
+	best := ""
+	for mod := range modules {
+		if (gpath == mod || strings.HasPrefix(gpath, mod+"/")) && len(mod) > len(best) {
+			best = mod
+		}
+	}
+	if best == "" {
+		return gpath
+	}
+	return path.Join(modules[best], strings.TrimPrefix(gpath, best))
+}
+
+// checkFuncDirectives checks the bind and optional directives of a function
+// against its signature.
+func checkFuncDirectives(pass *analysis.Pass, f *ast.File, fn *ast.FuncDecl, directives []directive) {
+	obj, ok := pass.TypesInfo.Defs[fn.Name].(*types.Func)
+	if !ok {
+		return
+	}
+	sig := obj.Type().(*types.Signature)
+
+	for _, d := range directives {
+		switch d.kind {
+		case AnnotBind:
+			checkBindTarget(pass, f, sig, d)
+		case AnnotOptional:
+			if d.value != "" && !optionalMatches(sig, d.value) {
+				pass.Report(analysis.Diagnostic{
+					Pos:     d.comment.Pos(),
+					End:     d.comment.End(),
+					Message: fmt.Sprintf("//autodi:optional %s matches no parameter of %s", d.value, fn.Name.Name),
+					SuggestedFixes: []analysis.SuggestedFix{{
+						Message:   "Remove the directive",
+						TextEdits: []analysis.TextEdit{{Pos: d.comment.Pos(), End: d.comment.End()}},
+					}},
+				})
+			}
+		}
+	}
+}
+
+// checkBindTarget reports a bind target that names a missing type or a
+// non-interface, or that the function's first result doesn't implement.
+// Targets in packages the file doesn't import can't be checked here.
+func checkBindTarget(pass *analysis.Pass, f *ast.File, sig *types.Signature, d directive) {
+	pkg, name := bindTargetPackage(pass, f, d.value)
+	if pkg == nil {
+		return
+	}
+	obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		pass.Reportf(d.comment.Pos(), "//autodi:bind %s: package %s has no type %s", d.value, pkg.Path(), name)
+		return
+	}
+	iface, ok := obj.Type().Underlying().(*types.Interface)
+	if !ok {
+		pass.Reportf(d.comment.Pos(), "//autodi:bind %s: %s is not an interface", d.value, name)
+		return
+	}
+	if sig.Results().Len() == 0 {
+		return
+	}
+	result := sig.Results().At(0).Type()
+	if missing := missingMethods(result, iface); len(missing) > 0 {
+		pass.Reportf(d.comment.Pos(), "//autodi:bind %s: %s does not implement it:\n%s",
+			d.value, types.TypeString(result, types.RelativeTo(pass.Pkg)), strings.Join(missing, "\n"))
+	}
+}
+
+// bindTargetPackage resolves the package of a bind target — Name in the
+// current package, pkg.Name through the file's imports, or import/path.Name —
+// and returns it with the type name; the package is nil when it isn't known
+// to the file.
+func bindTargetPackage(pass *analysis.Pass, f *ast.File, target string) (*types.Package, string) {
+	target = strings.TrimPrefix(target, "*")
+	dot := strings.LastIndex(target, ".")
+	if dot < 0 {
+		return pass.Pkg, target
+	}
+	qualifier, name := target[:dot], target[dot+1:]
+
+	if strings.Contains(qualifier, "/") {
+		if qualifier == pass.Pkg.Path() {
+			return pass.Pkg, name
+		}
+		for _, imp := range pass.Pkg.Imports() {
+			if imp.Path() == qualifier {
+				return imp, name
+			}
+		}
+		return nil, name
+	}
+	if qualifier == pass.Pkg.Name() {
+		return pass.Pkg, name
+	}
+	for _, spec := range f.Imports {
+		if pkgName := pass.TypesInfo.PkgNameOf(spec); pkgName != nil && pkgName.Name() == qualifier {
+			return pkgName.Imported(), name
+		}
+	}
+	return nil, name
+}
+
+// optionalMatches reports whether an //autodi:optional type matches a
+// parameter of sig, or a field of a parameter object, as the scanner
+// matches it.
+func optionalMatches(sig *types.Signature, opt string) bool {
+	for i := 0; i < sig.Params().Len(); i++ {
+		t := resolveAliases(sig.Params().At(i).Type())
+		if strings.HasSuffix(types.TypeString(t, nil), opt) {
+			return true
+		}
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		if st, ok := t.Underlying().(*types.Struct); ok {
+			for j := 0; j < st.NumFields(); j++ {
+				if strings.HasSuffix(types.TypeString(resolveAliases(st.Field(j).Type()), nil), opt) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
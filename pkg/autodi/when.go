@@ -0,0 +1,167 @@
+package autodi
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// AnnotWhen makes a provider conditional on an environment variable:
+//
+//	//autodi:when env=FEATURE_SEARCH                 construct only when set
+//	//autodi:when env=SEARCH_BACKEND=elastic          ... only for this value
+//	//autodi:when env=FEATURE_SEARCH fallback=NewNoop call NewNoop otherwise
+//
+// Without a fallback the provided values stay nil when the condition is false,
+// so consumers must handle a nil dependency. A fallback must live in the same
+// package and have the same signature.
+const AnnotWhen = "when"
+
+// WhenCond is a parsed //autodi:when condition.
+type WhenCond struct {
+	Env      string // environment variable
+	Value    string // required value; "" = any non-empty value
+	Fallback string // constructor called when the condition is false
+}
+
+// expr returns the Go condition, which uses os.Getenv.
+func (w *WhenCond) expr() string {
+	if w.Value == "" {
+		return fmt.Sprintf("os.Getenv(%q) != \"\"", w.Env)
+	}
+	return fmt.Sprintf("os.Getenv(%q) == %q", w.Env, w.Value)
+}
+
+// parseWhen parses the value of an //autodi:when annotation.
+func parseWhen(value string) (*WhenCond, error) {
+	w := &WhenCond{}
+	for _, opt := range strings.Fields(value) {
+		key, val, _ := strings.Cut(opt, "=")
+		switch key {
+		case "env":
+			w.Env, w.Value, _ = strings.Cut(val, "=")
+		case "fallback":
+			w.Fallback = val
+		default:
+			return nil, fmt.Errorf("unknown option %q (want env=VAR[=value] and fallback=Func)", opt)
+		}
+	}
+	if w.Env == "" {
+		return nil, fmt.Errorf("missing env=VAR")
+	}
+	return w, nil
+}
+
+// whenFallbackNames returns the fallback constructors referenced by
+// //autodi:when in a package; they are not providers on their own.
+func whenFallbackNames(pkg *packages.Package) map[string]bool {
+	names := make(map[string]bool)
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil {
+				continue
+			}
+			for _, value := range GetAnnotationValues(ParseAnnotations(fn), AnnotWhen) {
+				if w, err := parseWhen(value); err == nil && w.Fallback != "" {
+					names[w.Fallback] = true
+				}
+			}
+		}
+	}
+	return names
+}
+
+// resolveWhen validates a provider's //autodi:when condition and fallback.
+func (s *Scanner) resolveWhen(pkg *packages.Package, p *Provider) error {
+	values := GetAnnotationValues(p.Annotations, AnnotWhen)
+	if len(values) == 0 {
+		return nil
+	}
+	if len(values) > 1 {
+		return fmt.Errorf("%s: %s.%s has more than one //autodi:when", p.Position, p.PkgName, p.FuncName)
+	}
+	w, err := parseWhen(values[0])
+	if err != nil {
+		return fmt.Errorf("%s: %s.%s: //autodi:when: %v", p.Position, p.PkgName, p.FuncName, err)
+	}
+
+	if w.Fallback != "" {
+		orig, _ := pkg.Types.Scope().Lookup(p.FuncName).(*types.Func)
+		fallback, ok := pkg.Types.Scope().Lookup(w.Fallback).(*types.Func)
+		if !ok {
+			return fmt.Errorf("%s: %s.%s: //autodi:when fallback %s.%s does not exist",
+				p.Position, p.PkgName, p.FuncName, p.PkgName, w.Fallback)
+		}
+		if orig == nil || !types.Identical(orig.Type(), fallback.Type()) {
+			return fmt.Errorf("%s: fallback constructor %s.%s must have the same signature as %s",
+				p.Position, p.PkgName, w.Fallback, p.FuncName)
+		}
+	} else {
+		for _, ret := range p.Returns {
+			if ret.OutStruct != nil {
+				return fmt.Errorf("%s: %s.%s: //autodi:when on a result object needs a fallback\n  hint: add fallback=<Func> with the same signature",
+					p.Position, p.PkgName, p.FuncName)
+			}
+			if !isNilable(ret.Type) {
+				return fmt.Errorf("%s: %s.%s: //autodi:when without a fallback leaves %s unset, but it can't be nil\n  hint: return a pointer or interface, or add fallback=<Func>",
+					p.Position, p.PkgName, p.FuncName, toShortTypeName(ret.TypeStr))
+			}
+		}
+	}
+
+	p.When = w
+	if !slices.Contains(p.Env, w.Env) {
+		p.Env = append(p.Env, w.Env)
+	}
+	return nil
+}
+
+// whenSwitch returns the expression to call for a provider with a fallback,
+// emitting the substitution the same way replaySwitch does.
+func (cg *CodeGen) whenSwitch(buf *bytes.Buffer, p *Provider, qualifier string, usedVars map[string]bool) string {
+	if p.When == nil || p.When.Fallback == "" {
+		return qualifier
+	}
+	cg.imports.Add("os", "os")
+	fnVar := qualifier
+	if qualifier == cg.qualifyFunc(p) {
+		fnVar = cg.uniqueLocalVar(localVarName(p.FuncName), usedVars)
+		fmt.Fprintf(buf, "\t%s := %s\n", fnVar, qualifier)
+	}
+	fallback := qualifiedName(cg.imports.Add(p.PkgPath, p.PkgName), p.When.Fallback)
+	fmt.Fprintf(buf, "\tif !(%s) {\n", p.When.expr())
+	fmt.Fprintf(buf, "\t\t%s = %s\n", fnVar, fallback)
+	buf.WriteString("\t}\n")
+	return fnVar
+}
+
+// beginWhen opens the conditional block of a provider without a fallback:
+// the result variables are declared nil up front and assigned inside. Returns
+// the assignment operator for the call and the closing text.
+func (cg *CodeGen) beginWhen(buf *bytes.Buffer, p *Provider, lhs []string) (string, string) {
+	if p.When == nil || p.When.Fallback != "" {
+		return ":=", ""
+	}
+	cg.imports.Add("os", "os")
+	declared := false
+	for i, name := range lhs {
+		if name != "_" {
+			fmt.Fprintf(buf, "\tvar %s %s\n", name, cg.shortType(p.Returns[i].TypeStr))
+			declared = true
+		}
+	}
+	fmt.Fprintf(buf, "\tif %s {\n", p.When.expr())
+	if !declared {
+		return ":=", "\t}\n"
+	}
+	if p.HasError {
+		buf.WriteString("\tvar err error\n")
+	}
+	return "=", "\t}\n"
+}
@@ -0,0 +1,144 @@
+package autodi
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// runWhy prints, for every command, the dependency path from the command's
+// constructor to the provider of the queried type — the answer to "why does
+// this binary link the Kafka client?".
+func runWhy(opts *Options, query string) error {
+	proj, err := analyzeProject(opts)
+	if err != nil {
+		return err
+	}
+	g := proj.Graph
+
+	targets := g.matchProviders(query)
+	if len(targets) == 0 {
+		return fmt.Errorf("no provider matches %q\n  hint: use a type (*db.Client, db.Client, full path) or a Container field name", query)
+	}
+
+	found := 0
+	for _, cmd := range proj.Commands {
+		providers, err := g.CommandProviders(cmd)
+		if err != nil {
+			return fmt.Errorf("command %s: %w", cmd.Name, err)
+		}
+		included := make(map[*Provider]bool, len(providers))
+		for _, p := range providers {
+			included[p] = true
+		}
+
+		var lines []string
+		for _, target := range targets {
+			if !included[target] {
+				continue
+			}
+			path := g.dependencyPath(cmd, target)
+			if path == nil {
+				// Invoke providers hang off the graph: they are wired once
+				// all of their dependencies are available.
+				lines = append(lines, fmt.Sprintf("  %s (//autodi:invoke: all dependencies available)", whyLabel(target)))
+				continue
+			}
+			lines = append(lines, "  "+cmd.PkgName+"."+cmd.FuncName)
+			for _, p := range path {
+				lines = append(lines, "    → "+whyLabel(p))
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		found++
+		fmt.Fprintf(os.Stdout, "%s:\n%s\n", cmd.Name, strings.Join(lines, "\n"))
+	}
+
+	if found == 0 {
+		fmt.Fprintf(os.Stdout, "no command depends on %s\n", query)
+	}
+	return nil
+}
+
+// matchProviders returns the providers whose return type matches query: a full
+// type string, a short type name with or without "*", a bound interface, or a
+// Container field name.
+func (g *Graph) matchProviders(query string) []*Provider {
+	seen := make(map[*Provider]bool)
+	var matched []*Provider
+	add := func(p *Provider) {
+		if p != nil && !seen[p] {
+			seen[p] = true
+			matched = append(matched, p)
+		}
+	}
+
+	matches := func(typeStr string) bool {
+		short := toShortTypeName(typeStr)
+		return typeStr == query || short == query ||
+			strings.TrimPrefix(short, "*") == query || g.TypeToField[typeStr] == query
+	}
+	for _, typeStr := range g.sortedTypes {
+		if matches(typeStr) {
+			add(g.ProviderMap[typeStr])
+		}
+	}
+	for _, iface := range sortedKeys(g.Bindings) {
+		if matches(iface) {
+			add(g.ProviderMap[g.Bindings[iface]])
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return whyLabel(matched[i]) < whyLabel(matched[j])
+	})
+	return matched
+}
+
+// dependencyPath returns the shortest chain of providers from a command's
+// constructor parameters to target, following the same edges the generated
+// init function wires. Returns nil when target isn't reachable through params.
+func (g *Graph) dependencyPath(cmd *DiscoveredCommand, target *Provider) []*Provider {
+	parent := make(map[*Provider]*Provider)
+	visited := make(map[*Provider]bool)
+	var queue []*Provider
+	for _, param := range cmd.Params {
+		for _, p := range archParamProviders(g, param) {
+			if !visited[p] {
+				visited[p] = true
+				queue = append(queue, p)
+			}
+		}
+	}
+
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if p == target {
+			var path []*Provider
+			for cur := p; cur != nil; cur = parent[cur] {
+				path = append([]*Provider{cur}, path...)
+			}
+			return path
+		}
+		for _, dep := range archDeps(g, p) {
+			if !visited[dep] {
+				visited[dep] = true
+				parent[dep] = p
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return nil
+}
+
+// whyLabel renders a provider as "*pkg.Type (pkg.NewType)".
+func whyLabel(p *Provider) string {
+	if len(p.Returns) == 0 {
+		return p.PkgName + "." + p.FuncName
+	}
+	return fmt.Sprintf("%s (%s.%s)", toShortTypeName(p.Returns[0].TypeStr), p.PkgName, p.FuncName)
+}
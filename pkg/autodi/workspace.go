@@ -0,0 +1,107 @@
+package autodi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// findWorkFile returns the go.work governing moduleRoot, following the go
+// command: $GOWORK when set ("off" disables workspaces), otherwise the nearest
+// go.work in moduleRoot or a parent. Returns "" outside a workspace.
+func findWorkFile(moduleRoot string) string {
+	switch gowork := os.Getenv("GOWORK"); gowork {
+	case "off":
+		return ""
+	case "":
+	default:
+		return gowork
+	}
+	dir := moduleRoot
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.work")); err == nil {
+			return filepath.Join(dir, "go.work")
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// parseWorkspace reads the modules a go.work uses, other than the one at
+// moduleRoot, keyed by module path with directories relative to moduleRoot.
+func parseWorkspace(workFile, moduleRoot string) (map[string]string, error) {
+	data, err := os.ReadFile(workFile)
+	if err != nil {
+		return nil, fmt.Errorf("read go.work: %w", err)
+	}
+	wf, err := modfile.ParseWork(workFile, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("parse go.work: %w", err)
+	}
+
+	members := make(map[string]string)
+	for _, use := range wf.Use {
+		dir := filepath.FromSlash(use.Path)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(workFile), dir)
+		}
+		if filepath.Clean(dir) == filepath.Clean(moduleRoot) {
+			continue
+		}
+		gm, err := parseGoMod(dir)
+		if err != nil {
+			return nil, fmt.Errorf("go.work: use %s: %w", use.Path, err)
+		}
+		rel, err := filepath.Rel(moduleRoot, dir)
+		if err != nil {
+			rel = dir
+		}
+		members[gm.Module] = filepath.ToSlash(rel)
+	}
+	return members, nil
+}
+
+// moduleOf returns the module a package belongs to: the main module, a
+// nested replaced module or a workspace member. Returns "" for dependencies.
+func (cfg *Config) moduleOf(pkgPath string) string {
+	best := ""
+	match := func(mod string) {
+		if (pkgPath == mod || strings.HasPrefix(pkgPath, mod+"/")) && len(mod) > len(best) {
+			best = mod
+		}
+	}
+	match(cfg.Module)
+	for mod := range cfg.Replaces {
+		match(mod)
+	}
+	for mod := range cfg.Workspace {
+		match(mod)
+	}
+	return best
+}
+
+// providerRef names a provider in diagnostics, adding its module when it comes
+// from another workspace module.
+func (cfg *Config) providerRef(p *Provider) string {
+	if p.Module != "" && p.Module != cfg.Module {
+		return fmt.Sprintf("%s.%s [%s] (%s)", p.PkgName, p.FuncName, p.Module, p.Position)
+	}
+	return fmt.Sprintf("%s.%s (%s)", p.PkgName, p.FuncName, p.Position)
+}
+
+// inGroupPath reports whether a package falls under a group path: a directory
+// relative to the module, which reaches workspace members as ../<member>/...,
+// or the import path of a package in the module or a workspace member, so a
+// group can collect implementations of a shared interface across modules.
+func (cfg *Config) inGroupPath(pkgPath, gpath string) bool {
+	if strings.HasPrefix(cfg.RelPath(pkgPath), gpath) {
+		return true
+	}
+	return cfg.moduleOf(gpath) != "" && strings.HasPrefix(pkgPath, gpath)
+}
@@ -0,0 +1,178 @@
+// Code generated by autodi, DO NOT EDIT.
+// autodi (devel), output format 1, go 1.23, config a44231db97ef.
+
+//go:build test
+
+package main
+
+import (
+	"example.com/testapp/ent"
+	"example.com/testapp/internal/cache"
+	"example.com/testapp/internal/config"
+	"example.com/testapp/internal/db"
+	"example.com/testapp/internal/mailer"
+	"example.com/testapp/internal/notify"
+	"example.com/testapp/internal/notify/email"
+	"example.com/testapp/internal/notify/slack"
+	"example.com/testapp/internal/orm"
+	"example.com/testapp/internal/user"
+)
+
+// TestContainer holds the singleton providers of the graph for tests.
+type TestContainer struct {
+	Config      *config.Config
+	EntClient   *ent.Client
+	Cache       *cache.Cache
+	DB          *db.DB
+	Mailer      *mailer.Mailer
+	Email       *email.Email
+	Slack       *slack.Slack
+	UserService *user.Service
+
+	overridden map[string]bool
+	cleanups   []func()
+}
+
+// Override substitutes a provider in NewTestContainer.
+type Override func(*TestContainer)
+
+// WithConfig replaces the *config.Config provider.
+func WithConfig(v *config.Config) Override {
+	return func(c *TestContainer) {
+		c.Config = v
+		c.overridden["Config"] = true
+	}
+}
+
+// WithEntClient replaces the *ent.Client provider.
+func WithEntClient(v *ent.Client) Override {
+	return func(c *TestContainer) {
+		c.EntClient = v
+		c.overridden["EntClient"] = true
+	}
+}
+
+// WithCache replaces the *cache.Cache provider.
+func WithCache(v *cache.Cache) Override {
+	return func(c *TestContainer) {
+		c.Cache = v
+		c.overridden["Cache"] = true
+	}
+}
+
+// WithDB replaces the *db.DB provider.
+func WithDB(v *db.DB) Override {
+	return func(c *TestContainer) {
+		c.DB = v
+		c.overridden["DB"] = true
+	}
+}
+
+// WithMailer replaces the *mailer.Mailer provider.
+func WithMailer(v *mailer.Mailer) Override {
+	return func(c *TestContainer) {
+		c.Mailer = v
+		c.overridden["Mailer"] = true
+	}
+}
+
+// WithEmail replaces the *email.Email provider.
+func WithEmail(v *email.Email) Override {
+	return func(c *TestContainer) {
+		c.Email = v
+		c.overridden["Email"] = true
+	}
+}
+
+// WithSlack replaces the *slack.Slack provider.
+func WithSlack(v *slack.Slack) Override {
+	return func(c *TestContainer) {
+		c.Slack = v
+		c.overridden["Slack"] = true
+	}
+}
+
+// WithUserService replaces the *user.Service provider.
+func WithUserService(v *user.Service) Override {
+	return func(c *TestContainer) {
+		c.UserService = v
+		c.overridden["UserService"] = true
+	}
+}
+
+// NewTestContainer builds the dependency graph, skipping overridden providers.
+// Call Close to release the providers it constructed.
+func NewTestContainer(overrides ...Override) (*TestContainer, error) {
+	c := &TestContainer{overridden: make(map[string]bool)}
+	for _, o := range overrides {
+		o(c)
+	}
+
+	if !c.overridden["Config"] {
+		config2 := config.NewConfig()
+		c.Config = config2
+	}
+
+	if !c.overridden["EntClient"] {
+		entClient := orm.NewORM(c.Config)
+		c.EntClient = entClient
+		c.cleanups = append(c.cleanups, func() {
+			if entClient != nil {
+				entClient.Close()
+			}
+		})
+	}
+
+	if !c.overridden["Cache"] {
+		cache2 := cache.NewCache(c.Config)
+		c.Cache = cache2
+		c.cleanups = append(c.cleanups, func() {
+			if cache2 != nil {
+				cache2.Close()
+			}
+		})
+	}
+
+	if !c.overridden["DB"] {
+		db2 := db.NewDB(c.Config)
+		c.DB = db2
+		c.cleanups = append(c.cleanups, func() {
+			if db2 != nil {
+				db2.Close()
+			}
+		})
+	}
+
+	if !c.overridden["Mailer"] {
+		notifiers := make([]notify.Notifier, 0, 2)
+		notifiers = append(notifiers, email.NewEmail(c.Config))
+		notifiers = append(notifiers, slack.NewSlack(c.Config))
+		mailer2 := mailer.NewMailer(notifiers)
+		c.Mailer = mailer2
+	}
+
+	if !c.overridden["Email"] {
+		email2 := email.NewEmail(c.Config)
+		c.Email = email2
+	}
+
+	if !c.overridden["Slack"] {
+		slack2 := slack.NewSlack(c.Config)
+		c.Slack = slack2
+	}
+
+	if !c.overridden["UserService"] {
+		userService := user.NewUser(c.DB, c.Cache, c.EntClient)
+		c.UserService = userService
+	}
+
+	return c, nil
+}
+
+// Close releases the providers NewTestContainer constructed, in reverse order.
+// Overrides are owned by the test.
+func (c *TestContainer) Close() {
+	for i := len(c.cleanups) - 1; i >= 0; i-- {
+		c.cleanups[i]()
+	}
+}
@@ -1,8 +1,15 @@
 // Code generated by autodi, DO NOT EDIT.
+// autodi (devel), output format 1, go 1.23, config a44231db97ef.
 
 package main
 
 import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
 	apicmd "example.com/testapp/cmd/api"
 	workercmd "example.com/testapp/cmd/worker"
 	"example.com/testapp/internal/cache"
@@ -14,9 +21,6 @@ import (
 	"example.com/testapp/internal/notify/slack"
 	"example.com/testapp/internal/orm"
 	"example.com/testapp/internal/user"
-	"github.com/spf13/cobra"
-	"os"
-	"strings"
 )
 
 func main() {
@@ -25,6 +29,7 @@ func main() {
 	type initFunc func(cmd, top *cobra.Command) (func(), error)
 	initFuncs := make(map[*cobra.Command]initFunc)
 
+	// autodi:begin api
 	{
 		stub := apicmd.NewAPI(nil, nil)
 		tree := stub.Command()
@@ -33,6 +38,8 @@ func main() {
 		root.AddCommand(tree)
 		initFuncs[tree] = initAPI
 	}
+	// autodi:end api
+	// autodi:begin worker
 	{
 		stub := workercmd.NewWorker(nil)
 		cmd := stub.Command()
@@ -40,6 +47,7 @@ func main() {
 		root.AddCommand(cmd)
 		initFuncs[cmd] = initWorker
 	}
+	// autodi:end worker
 
 	var cleanup func()
 	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
@@ -62,10 +70,11 @@ func main() {
 	}
 
 	if err := root.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
+// autodi:begin api
 func initAPI(cmd, top *cobra.Command) (func(), error) {
 	configSvc := config.NewConfig()
 
@@ -75,10 +84,9 @@ func initAPI(cmd, top *cobra.Command) (func(), error) {
 
 	dbSvc := db.NewDB(configSvc)
 
-	notifiers := []notify.Notifier{
-		email.NewEmail(configSvc),
-		slack.NewSlack(configSvc),
-	}
+	notifiers := make([]notify.Notifier, 0, 2)
+	notifiers = append(notifiers, email.NewEmail(configSvc))
+	notifiers = append(notifiers, slack.NewSlack(configSvc))
 
 	mailerSvc := mailer.NewMailer(notifiers)
 
@@ -103,13 +111,15 @@ func initAPI(cmd, top *cobra.Command) (func(), error) {
 	}, nil
 }
 
+// autodi:end api
+
+// autodi:begin worker
 func initWorker(cmd, top *cobra.Command) (func(), error) {
 	configSvc := config.NewConfig()
 
-	notifiers := []notify.Notifier{
-		email.NewEmail(configSvc),
-		slack.NewSlack(configSvc),
-	}
+	notifiers := make([]notify.Notifier, 0, 2)
+	notifiers = append(notifiers, email.NewEmail(configSvc))
+	notifiers = append(notifiers, slack.NewSlack(configSvc))
 
 	mailerSvc := mailer.NewMailer(notifiers)
 
@@ -121,25 +131,28 @@ func initWorker(cmd, top *cobra.Command) (func(), error) {
 	return nil, nil
 }
 
-// wireRunE connects a handler method to a subcommand's RunE by kebab-case name.
+// autodi:end worker
+
+// wireRunE connects a handler method to a subcommand's RunE by kebab-case name,
+// returning the subcommand, or nil when none matches.
 // For nested commands, the name segments form a path (e.g. "pool-list" matches pool→list).
-func wireRunE(parent *cobra.Command, name string, handler func(*cobra.Command) error) {
+func wireRunE(parent *cobra.Command, name string, handler func(*cobra.Command) error) *cobra.Command {
 	// Try exact match first (direct child)
 	for _, sub := range parent.Commands() {
 		if sub.Name() == name {
 			h := handler
 			sub.RunE = func(cmd *cobra.Command, _ []string) error { return h(cmd) }
-			return
+			return sub
 		}
 	}
 	// Try path-based match: split name by "-" and greedily match child commands
 	parts := strings.Split(name, "-")
-	wireRunEPath(parent, parts, handler)
+	return wireRunEPath(parent, parts, handler)
 }
 
-func wireRunEPath(parent *cobra.Command, parts []string, handler func(*cobra.Command) error) bool {
+func wireRunEPath(parent *cobra.Command, parts []string, handler func(*cobra.Command) error) *cobra.Command {
 	if len(parts) == 0 {
-		return false
+		return nil
 	}
 	// Try progressively longer prefixes as the child command name
 	for i := 1; i <= len(parts); i++ {
@@ -152,15 +165,15 @@ func wireRunEPath(parent *cobra.Command, parts []string, handler func(*cobra.Com
 				// Leaf match
 				h := handler
 				sub.RunE = func(cmd *cobra.Command, _ []string) error { return h(cmd) }
-				return true
+				return sub
 			}
 			// Try remaining parts as deeper path
-			if wireRunEPath(sub, parts[i:], handler) {
-				return true
+			if leaf := wireRunEPath(sub, parts[i:], handler); leaf != nil {
+				return leaf
 			}
 		}
 	}
-	return false
+	return nil
 }
 
 // swapRunE replaces the executing command's RunE with the real one from the DI-built tree.
@@ -184,3 +197,14 @@ func relativePath(cmd, ancestor *cobra.Command) []string {
 	}
 	return append(relativePath(cmd.Parent(), ancestor), cmd.Name())
 }
+
+// exitCode maps the error a command returned to the process exit code:
+// a sentinel of //autodi:exit-code-map, else the ExitCode() of an error
+// in its chain, else 1.
+func exitCode(err error) int {
+	var coder interface{ ExitCode() int }
+	if errors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+	return 1
+}